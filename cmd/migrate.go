@@ -1,15 +1,19 @@
 package cmd
 
 import (
+	"fmt"
+	"strconv"
+
 	"github.com/ScienceObjectsDB/CORE-Server/database"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"gorm.io/gorm"
 )
 
 var migrateCmd = &cobra.Command{
 	Use:   "migrate",
 	Short: "Performs migrations for the database",
-	Long:  ``,
+	Long:  `Applies all pending migrations, the subcommands apply, revert or list the versioned migrations of the database schema.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		err := database.MakeMigrationsStandalone()
 		if err != nil {
@@ -19,6 +23,98 @@ var migrateCmd = &cobra.Command{
 	},
 }
 
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Applies all pending migrations",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectMigrationDatabase()
+
+		err := database.MigrateUp(db)
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [steps]",
+	Short: "Reverts the newest applied migrations, one if no number of steps is given",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		steps := 1
+		if len(args) == 1 {
+			var err error
+			steps, err = strconv.Atoi(args[0])
+			if err != nil || steps < 1 {
+				log.Fatalf("invalid number of steps: %v", args[0])
+			}
+		}
+
+		db := connectMigrationDatabase()
+
+		err := database.MigrateDown(db, steps)
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+	},
+}
+
+var migrateToCmd = &cobra.Command{
+	Use:   "to <version>",
+	Short: "Applies or reverts migrations until the schema has the given version",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		version, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || version < 0 {
+			log.Fatalf("invalid version: %v", args[0])
+		}
+
+		db := connectMigrationDatabase()
+
+		err = database.MigrateTo(db, version)
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Lists the migrations and whether they have been applied",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectMigrationDatabase()
+
+		statuses, err := database.GetMigrationStatus(db)
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+		for _, status := range statuses {
+			applied := "pending"
+			if status.Applied {
+				applied = fmt.Sprintf("applied at %v", status.AppliedAt.Format("2006-01-02 15:04:05"))
+			}
+
+			fmt.Printf("%04d %-40v %v\n", status.Version, status.Name, applied)
+		}
+	},
+}
+
+func connectMigrationDatabase() *gorm.DB {
+	db, err := database.InitDatabaseConnection()
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	return db
+}
+
 func init() {
+	migrateCmd.AddCommand(migrateUpCmd)
+	migrateCmd.AddCommand(migrateDownCmd)
+	migrateCmd.AddCommand(migrateToCmd)
+	migrateCmd.AddCommand(migrateStatusCmd)
 	rootCmd.AddCommand(migrateCmd)
 }
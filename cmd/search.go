@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	"github.com/ScienceObjectsDB/CORE-Server/search"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search",
+	Short: "Manages the full-text search index",
+}
+
+var searchRebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Rebuilds the search index from the database",
+	Long:  `Removes the search index at Search.IndexPath and indexes all resources of the database again. The server has to be stopped, it uses the same index.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := database.InitDatabaseConnection()
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+		index, err := search.CreateIndex(viper.GetString(SEARCH_INDEX_PATH))
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+		defer index.Close()
+
+		commonHandler := &database.Common{DB: db}
+		indexer := &search.Indexer{
+			Index:  index,
+			Read:   &database.Read{Common: commonHandler},
+			Outbox: &database.Outbox{Common: commonHandler},
+		}
+
+		err = indexer.Rebuild()
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+	},
+}
+
+func init() {
+	searchCmd.AddCommand(searchRebuildCmd)
+	rootCmd.AddCommand(searchCmd)
+}
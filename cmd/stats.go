@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Manages the usage statistics of projects, datasets, object groups and dataset versions",
+}
+
+var statsRebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Rebuilds the usage statistics from the database",
+	Long:  `Recomputes the resource_stats table from the objects and object groups of every project. Each project is rebuilt in its own transaction, the server can keep running.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := database.InitDatabaseConnection()
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+		stats := &database.Stats{Common: &database.Common{DB: db}}
+
+		err = stats.RebuildStats()
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+	},
+}
+
+func init() {
+	statsCmd.AddCommand(statsRebuildCmd)
+	rootCmd.AddCommand(statsCmd)
+}
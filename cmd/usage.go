@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	"github.com/ScienceObjectsDB/CORE-Server/server"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var usageExportFormat string
+var usageExportStart string
+var usageExportEnd string
+var usageExportOutput string
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Reports the recorded storage usage of the projects",
+}
+
+var usageExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports the monthly storage usage of all projects as accounting report",
+	Long: `Writes one row per project and calendar month between --start and --end with the usage of the last snapshot in
+the month, the largest accumulated size and the byte-months, the accumulated size weighted with the part of the month
+it was stored. Dates are given as 2006-01, 2006-01-02 or RFC 3339 in UTC, the report covers the current month by default.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if usageExportFormat != "csv" && usageExportFormat != "json" {
+			log.Fatalf("unknown format %v, expected csv or json", usageExportFormat)
+		}
+
+		start, end, err := server.ParseUsageRange(usageExportStart, usageExportEnd)
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+		db, err := database.InitDatabaseConnection()
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+		usage := &database.Usage{Common: &database.Common{DB: db}}
+
+		projectSnapshots, err := usage.GetProjectsUsageSnapshots(start, end)
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+		projectIDs := make([]uuid.UUID, 0, len(projectSnapshots))
+		for projectID := range projectSnapshots {
+			projectIDs = append(projectIDs, projectID)
+		}
+
+		names, err := usage.GetProjectNames(projectIDs)
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+		accounts := make([]*server.UsageAccount, 0)
+		for _, projectID := range projectIDs {
+			for _, account := range database.AccountUsage(projectID, projectSnapshots[projectID], start, end) {
+				accounts = append(accounts, server.UsageAccountToHTTPModel(account, names[projectID]))
+			}
+		}
+
+		sort.Slice(accounts, func(i, j int) bool {
+			if accounts[i].Month != accounts[j].Month {
+				return accounts[i].Month < accounts[j].Month
+			}
+			return accounts[i].ResourceID < accounts[j].ResourceID
+		})
+
+		output := os.Stdout
+		if usageExportOutput != "" && usageExportOutput != "-" {
+			output, err = os.Create(usageExportOutput)
+			if err != nil {
+				log.Fatalln(err.Error())
+			}
+			defer output.Close()
+		}
+
+		if usageExportFormat == "json" {
+			err = writeUsageJSON(output, accounts)
+		} else {
+			err = writeUsageCSV(output, accounts)
+		}
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+	},
+}
+
+func writeUsageJSON(writer io.Writer, accounts []*server.UsageAccount) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(accounts)
+}
+
+func writeUsageCSV(writer io.Writer, accounts []*server.UsageAccount) error {
+	csvWriter := csv.NewWriter(writer)
+
+	err := csvWriter.Write([]string{"month", "project_id", "project_name", "object_count", "object_group_count", "acc_size", "max_acc_size", "byte_months"})
+	if err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		err := csvWriter.Write([]string{
+			account.Month,
+			account.ResourceID,
+			account.Name,
+			strconv.FormatInt(account.ObjectCount, 10),
+			strconv.FormatInt(account.ObjectGroupCount, 10),
+			strconv.FormatInt(account.AccSize, 10),
+			strconv.FormatInt(account.MaxAccSize, 10),
+			fmt.Sprintf("%.2f", account.ByteMonths),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func init() {
+	usageExportCmd.Flags().StringVar(&usageExportFormat, "format", "csv", "Format of the report, csv or json")
+	usageExportCmd.Flags().StringVar(&usageExportStart, "start", "", "First day of the report, e.g. 2022-01")
+	usageExportCmd.Flags().StringVar(&usageExportEnd, "end", "", "Day after the report, e.g. 2022-07, defaults to now")
+	usageExportCmd.Flags().StringVarP(&usageExportOutput, "output", "o", "", "File the report is written to, stdout by default")
+
+	usageCmd.AddCommand(usageExportCmd)
+	rootCmd.AddCommand(usageCmd)
+}
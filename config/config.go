@@ -28,19 +28,57 @@ const (
 	S3_ENDPOINT       = "S3.Endpoint"
 	S3_IMPLEMENTATION = "S3.Implementation"
 
-	EVENTNOTIFICATION_BACKEND               = "EventNotifications.Backend"
-	EVENTNOTIFICATION_NATS_HOST             = "EventNotifications.NATS.HOST"
-	EVENTNOTIFICATION_NATS_SUBJECTPREFIX    = "EventNotifications.NATS.SubjectPrefix"
-	EVENTNOTIFICATION_NATS_NKeySeedFileName = "EventNotifications.NATS.NKeySeedFileName"
-	EVENTNOTIFICATION_NATS_STREAM_NAME      = "EventNotifications.NATS.StreamName"
+	EVENTNOTIFICATION_BACKEND                 = "EventNotifications.Backend"
+	EVENTNOTIFICATION_FORMAT                  = "EventNotifications.Format"
+	EVENTNOTIFICATION_NATS_HOST               = "EventNotifications.NATS.HOST"
+	EVENTNOTIFICATION_NATS_SUBJECTPREFIX      = "EventNotifications.NATS.SubjectPrefix"
+	EVENTNOTIFICATION_NATS_NKeySeedFileName   = "EventNotifications.NATS.NKeySeedFileName"
+	EVENTNOTIFICATION_NATS_STREAM_NAME        = "EventNotifications.NATS.StreamName"
+	EVENTNOTIFICATION_NATS_CHUNK_SIZE         = "EventNotifications.NATS.ChunkSize"
+	EVENTNOTIFICATION_NATS_ACK_TIMEOUT        = "EventNotifications.NATS.AckTimeout"
+	EVENTNOTIFICATION_NATS_MAX_PENDING_CHUNKS = "EventNotifications.NATS.MaxPendingChunks"
+	EVENTNOTIFICATION_OUTBOX_POLL_INTERVAL    = "EventNotifications.Outbox.PollInterval"
+	EVENTNOTIFICATION_OUTBOX_BATCH_SIZE       = "EventNotifications.Outbox.BatchSize"
+	EVENTNOTIFICATION_OUTBOX_MAX_ATTEMPTS     = "EventNotifications.Outbox.MaxAttempts"
+	EVENTNOTIFICATION_OUTBOX_RETENTION        = "EventNotifications.Outbox.Retention"
+	EVENTNOTIFICATION_WEBHOOK_TIMEOUT         = "EventNotifications.Webhook.Timeout"
+	EVENTNOTIFICATION_WEBHOOK_MAX_ATTEMPTS    = "EventNotifications.Webhook.MaxAttempts"
+	EVENTNOTIFICATION_WEBHOOK_POLL_INTERVAL   = "EventNotifications.Webhook.PollInterval"
+	EVENTNOTIFICATION_WEBHOOK_BATCH_SIZE      = "EventNotifications.Webhook.BatchSize"
+	EVENTNOTIFICATION_WEBHOOK_ALLOW_HTTP      = "EventNotifications.Webhook.AllowHTTP"
+	EVENTNOTIFICATION_WEBHOOK_ALLOW_PRIVATE   = "EventNotifications.Webhook.AllowPrivateNetworks"
+	EVENTNOTIFICATION_MEMORY_BUFFER_SIZE      = "EventNotifications.Memory.BufferSize"
+	EVENTNOTIFICATION_MEMORY_ACK_WAIT         = "EventNotifications.Memory.AckWait"
+	EVENTNOTIFICATION_POSTGRES_CHANNEL        = "EventNotifications.Postgres.Channel"
+	EVENTNOTIFICATION_POSTGRES_ACK_WAIT       = "EventNotifications.Postgres.AckWait"
+	EVENTNOTIFICATION_POSTGRES_POLL_INTERVAL  = "EventNotifications.Postgres.PollInterval"
+	EVENTNOTIFICATION_POSTGRES_RETENTION      = "EventNotifications.Postgres.Retention"
 
 	AUTHENTICATION_TYPE                     = "Authentication.Type"
 	AUTHENTICATION_OAUTH2_USERINFOENDPOINT  = "Authentication.OIDC.UserInfoEndpoint"
 	AUTHENTICATION_OAUTH2_REALMINFOENDPOINT = "Authentication.OIDC.RealmInfoEndpoint"
 
-	STREAMING_ENDPOINT       = "Streaming.Endpoint"
-	STREAMING_PORT           = "Streaming.Port"
-	STREAMING_SECRET_ENV_VAR = "Streaming.SecretEnvVar"
+	STREAMING_ENDPOINT             = "Streaming.Endpoint"
+	STREAMING_PORT                 = "Streaming.Port"
+	STREAMING_LINKS_DEFAULT_EXPIRY = "Streaming.Links.DefaultExpiry"
+	STREAMING_LINKS_MAX_EXPIRY     = "Streaming.Links.MaxExpiry"
+	STREAMING_LINKS_MAX_DOWNLOADS  = "Streaming.Links.MaxDownloads"
+	STREAMING_PREFETCH_OBJECTS     = "Streaming.PrefetchObjects"
+	STREAMING_READ_TIMEOUT         = "Streaming.ReadTimeout"
+
+	TRASH_DEFAULT_RETENTION = "Trash.DefaultRetention"
+	TRASH_PURGE_INTERVAL    = "Trash.PurgeInterval"
+
+	SEARCH_ENABLED       = "Search.Enabled"
+	SEARCH_INDEX_PATH    = "Search.IndexPath"
+	SEARCH_POLL_INTERVAL = "Search.PollInterval"
+
+	PAGINATION_DEFAULT_PAGE_SIZE = "Pagination.DefaultPageSize"
+	PAGINATION_MAX_PAGE_SIZE     = "Pagination.MaxPageSize"
+
+	USAGE_SNAPSHOT_INTERVAL = "Usage.SnapshotInterval"
+
+	METRICS_ADDRESS = "Metrics.Address"
 )
 
 func HandleConfigFile() {
@@ -84,13 +122,51 @@ func SetDefaults() {
 	viper.SetDefault(S3_IMPLEMENTATION, "generic")
 
 	viper.SetDefault(EVENTNOTIFICATION_BACKEND, "Empty")
+	viper.SetDefault(EVENTNOTIFICATION_FORMAT, "protojson")
 	viper.SetDefault(EVENTNOTIFICATION_NATS_HOST, "http://localhost:4222")
 	viper.SetDefault(EVENTNOTIFICATION_NATS_SUBJECTPREFIX, "UPDATES")
 	viper.SetDefault(EVENTNOTIFICATION_NATS_STREAM_NAME, "UPDATES")
+	viper.SetDefault(EVENTNOTIFICATION_NATS_CHUNK_SIZE, 500)
+	viper.SetDefault(EVENTNOTIFICATION_NATS_ACK_TIMEOUT, "10s")
+	viper.SetDefault(EVENTNOTIFICATION_NATS_MAX_PENDING_CHUNKS, 3)
+	viper.SetDefault(EVENTNOTIFICATION_OUTBOX_POLL_INTERVAL, "1s")
+	viper.SetDefault(EVENTNOTIFICATION_OUTBOX_BATCH_SIZE, 100)
+	viper.SetDefault(EVENTNOTIFICATION_OUTBOX_MAX_ATTEMPTS, 20)
+	viper.SetDefault(EVENTNOTIFICATION_OUTBOX_RETENTION, "168h")
+	viper.SetDefault(EVENTNOTIFICATION_WEBHOOK_TIMEOUT, "10s")
+	viper.SetDefault(EVENTNOTIFICATION_WEBHOOK_MAX_ATTEMPTS, 10)
+	viper.SetDefault(EVENTNOTIFICATION_WEBHOOK_POLL_INTERVAL, "1s")
+	viper.SetDefault(EVENTNOTIFICATION_WEBHOOK_BATCH_SIZE, 50)
+	viper.SetDefault(EVENTNOTIFICATION_WEBHOOK_ALLOW_HTTP, false)
+	viper.SetDefault(EVENTNOTIFICATION_WEBHOOK_ALLOW_PRIVATE, false)
+	viper.SetDefault(EVENTNOTIFICATION_MEMORY_BUFFER_SIZE, 10000)
+	viper.SetDefault(EVENTNOTIFICATION_MEMORY_ACK_WAIT, "15s")
+	viper.SetDefault(EVENTNOTIFICATION_POSTGRES_CHANNEL, "sciobjsdb_events")
+	viper.SetDefault(EVENTNOTIFICATION_POSTGRES_ACK_WAIT, "15s")
+	viper.SetDefault(EVENTNOTIFICATION_POSTGRES_POLL_INTERVAL, "5s")
+	viper.SetDefault(EVENTNOTIFICATION_POSTGRES_RETENTION, "168h")
 
 	viper.SetDefault(STREAMING_ENDPOINT, "localhost")
 	viper.SetDefault(STREAMING_PORT, 443)
-	viper.SetDefault(STREAMING_SECRET_ENV_VAR, "STREAMING_SECRET")
+	viper.SetDefault(STREAMING_LINKS_DEFAULT_EXPIRY, "24h")
+	viper.SetDefault(STREAMING_LINKS_MAX_EXPIRY, "720h")
+	viper.SetDefault(STREAMING_LINKS_MAX_DOWNLOADS, 0)
+	viper.SetDefault(STREAMING_PREFETCH_OBJECTS, 4)
+	viper.SetDefault(STREAMING_READ_TIMEOUT, "60s")
+
+	viper.SetDefault(TRASH_DEFAULT_RETENTION, "720h")
+	viper.SetDefault(TRASH_PURGE_INTERVAL, "1h")
+
+	viper.SetDefault(SEARCH_ENABLED, false)
+	viper.SetDefault(SEARCH_INDEX_PATH, "search.bleve")
+	viper.SetDefault(SEARCH_POLL_INTERVAL, "5s")
+
+	viper.SetDefault(PAGINATION_DEFAULT_PAGE_SIZE, 1000)
+	viper.SetDefault(PAGINATION_MAX_PAGE_SIZE, 10000)
+
+	viper.SetDefault(USAGE_SNAPSHOT_INTERVAL, "24h")
+
+	viper.SetDefault(METRICS_ADDRESS, "127.0.0.1:9090")
 
 	viper.SetDefault(AUTHENTICATION_TYPE, "INSECURE")
 	viper.SetDefault(AUTHENTICATION_OAUTH2_USERINFOENDPOINT, "localhost:9051/auth/realms/DEFAULTREALM/protocol/openid-connect/userinfo")
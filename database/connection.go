@@ -5,7 +5,6 @@ import (
 	"os"
 
 	"github.com/ScienceObjectsDB/CORE-Server/config"
-	"github.com/ScienceObjectsDB/CORE-Server/models"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"gorm.io/driver/postgres"
@@ -98,26 +97,3 @@ func initPsqlConnection() (*gorm.DB, error) {
 
 	return db, nil
 }
-
-func makeMigrations(db *gorm.DB) *gorm.DB {
-	err := db.AutoMigrate(
-		&models.Project{},
-		&models.Dataset{},
-		&models.DatasetVersion{},
-		&models.ObjectGroup{},
-		&models.Object{},
-		&models.Location{},
-		&models.Label{},
-		&models.APIToken{},
-		&models.User{},
-		&models.StreamingEntry{},
-		&models.StreamGroup{},
-		&models.ObjectGroupRevision{},
-	)
-
-	if err != nil {
-		log.Fatalln(err.Error())
-	}
-
-	return db
-}
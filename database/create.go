@@ -12,6 +12,7 @@ import (
 
 	"github.com/ScienceObjectsDB/CORE-Server/models"
 	"github.com/ScienceObjectsDB/CORE-Server/util"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
 	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
 	v1storageservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/services/v1"
 )
@@ -52,7 +53,21 @@ func (create *Create) CreateProject(request *v1storageservices.CreateProjectRequ
 	}
 
 	err := crdbgorm.ExecuteTx(context.Background(), create.DB, nil, func(tx *gorm.DB) error {
-		return tx.Create(&project).Error
+		if err := tx.Create(&project).Error; err != nil {
+			return err
+		}
+
+		err := addStats(tx, &models.ResourceStats{
+			ResourceID: project.ID,
+			Resource:   v1storagemodels.Resource_RESOURCE_PROJECT.String(),
+			ProjectID:  project.ID,
+			UserCount:  int64(len(project.Users)),
+		})
+		if err != nil {
+			return err
+		}
+
+		return writeOutboxEvents(tx, models.NewProjectEvent(project.ID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED))
 	})
 	if err != nil {
 		log.Error(err.Error())
@@ -123,7 +138,15 @@ func (create *Create) CreateDataset(request *v1storageservices.CreateDatasetRequ
 	dataset.ID = datasetID
 
 	err = crdbgorm.ExecuteTx(context.Background(), create.DB, nil, func(tx *gorm.DB) error {
-		return tx.Create(&dataset).Error
+		if err := tx.Create(&dataset).Error; err != nil {
+			return err
+		}
+
+		if err := addObjectStats(tx, 1, "dataset_id = ?", datasetID); err != nil {
+			return err
+		}
+
+		return writeOutboxEvents(tx, models.NewDatasetEvent(projectID, datasetID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED))
 	})
 
 	if err != nil {
@@ -201,7 +224,7 @@ func (create *Create) CreateObjectGroup(request *v1storageservices.CreateObjectG
 	}
 
 	err := crdbgorm.ExecuteTx(context.Background(), create.DB, nil, func(tx *gorm.DB) error {
-		tx.Transaction(func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
 			if err := tx.Create(&objectGroup).Error; err != nil {
 				log.Errorln(err.Error())
 				return err
@@ -217,10 +240,23 @@ func (create *Create) CreateObjectGroup(request *v1storageservices.CreateObjectG
 				return err
 			}
 
+			if err := addObjectGroupStats(tx, 1, "id = ?", objectGroup.ID); err != nil {
+				log.Errorln(err.Error())
+				return err
+			}
+
+			if err := setRevisionStats(tx, objectGroupRevision); err != nil {
+				log.Errorln(err.Error())
+				return err
+			}
+
+			if err := writeOutboxEvents(tx, models.NewObjectGroupEvent(objectGroup.ProjectID, objectGroup.DatasetID, objectGroup.ID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED)); err != nil {
+				log.Errorln(err.Error())
+				return err
+			}
+
 			return nil
 		})
-
-		return nil
 	})
 
 	if err != nil {
@@ -282,7 +318,13 @@ func (create *Create) CreateDatasetVersion(request *v1storageservices.ReleaseDat
 			log.Errorln(err.Error())
 			return err
 		}
-		return nil
+
+		if err := setDatasetVersionStats(tx, version); err != nil {
+			log.Errorln(err.Error())
+			return err
+		}
+
+		return writeOutboxEvents(tx, models.NewDatasetVersionEvent(projectID, datasetID, version.ID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED))
 	})
 
 	if err != nil {
@@ -306,19 +348,36 @@ func (create *Create) AddUserToProject(request *v1storageservices.AddUserToProje
 	}
 
 	err = crdbgorm.ExecuteTx(context.Background(), create.DB, nil, func(tx *gorm.DB) error {
-		return tx.Create(user).Error
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		err := addStats(tx, &models.ResourceStats{
+			ResourceID: projectID,
+			Resource:   v1storagemodels.Resource_RESOURCE_PROJECT.String(),
+			ProjectID:  projectID,
+			UserCount:  1,
+		})
+		if err != nil {
+			return err
+		}
+
+		return writeOutboxEvents(tx, models.NewUserEvent(projectID, user.UserOauth2ID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED))
 	})
 
 	return err
 }
 
-func (create *Create) CreateStreamGroup(projectID uuid.UUID, resourceType string, resourceID uuid.UUID, subject string, subResource bool) (*models.StreamGroup, error) {
+func (create *Create) CreateStreamGroup(projectID uuid.UUID, resourceType string, resourceID uuid.UUID, subject string, subResource bool, start *models.StreamGroupStart) (*models.StreamGroup, error) {
 	streamGroupEntry := &models.StreamGroup{
 		ResourceID:     resourceID,
 		ProjectID:      projectID,
 		ResourceType:   resourceType,
 		UseSubResource: subResource,
 		Subject:        subject,
+		StartPolicy:    start.Policy,
+		StartSequence:  start.Sequence,
+		StartTime:      start.Time,
 	}
 
 	err := crdbgorm.ExecuteTx(context.Background(), create.DB, nil, func(tx *gorm.DB) error {
@@ -361,7 +420,11 @@ func (create *Create) CreateAPIToken(request *v1storageservices.CreateAPITokenRe
 	}
 
 	err = crdbgorm.ExecuteTx(context.Background(), create.DB, nil, func(tx *gorm.DB) error {
-		return tx.Create(apiToken).Error
+		if err := tx.Create(apiToken).Error; err != nil {
+			return err
+		}
+
+		return writeOutboxEvents(tx, models.NewAPITokenEvent(projectID, apiToken.ID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED))
 	})
 
 	if err != nil {
@@ -401,7 +464,18 @@ func (create *Create) CreateObject(request *v1storageservices.CreateObjectReques
 
 	object.ID = objectID
 
-	if err := create.DB.Create(object).Error; err != nil {
+	err := crdbgorm.ExecuteTx(context.Background(), create.DB, nil, func(tx *gorm.DB) error {
+		if err := tx.Create(object).Error; err != nil {
+			return err
+		}
+
+		if err := addObjectStats(tx, 1, "id = ?", object.ID); err != nil {
+			return err
+		}
+
+		return writeOutboxEvents(tx, models.NewObjectEvent(project.ID, dataset.ID, object.ID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED))
+	})
+	if err != nil {
 		log.Errorln(err.Error())
 		return nil, err
 	}
@@ -2,8 +2,11 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
 	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbgorm"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
@@ -12,19 +15,27 @@ import (
 
 type Delete struct {
 	*Common
+	// Retention of deleted datasets and object groups in projects without an own retention
+	DefaultTrashRetention time.Duration
 }
 
+// DeleteObjectGroup Moves an object group with its revisions and objects into the trash of its project
 func (handler *Delete) DeleteObjectGroup(objectGroupID uuid.UUID) error {
 	objectGroup := &models.ObjectGroup{}
 	objectGroup.ID = objectGroupID
 
 	err := crdbgorm.ExecuteTx(context.Background(), handler.DB, nil, func(tx *gorm.DB) error {
-		return tx.Select(
-			"Labels",
-			"CurrentObjectGroupRevision",
-			"ObjectGroupRevisions",
-			"ObjectGroupRevisions.Objects",
-			"ObjectGroupRevisions.MetaObjects").Unscoped().Delete(objectGroup).Error
+		if err := tx.Preload("CurrentObjectGroupRevision").First(objectGroup).Error; err != nil {
+			return err
+		}
+
+		err := writeOutboxEvents(tx, models.NewObjectGroupEvent(objectGroup.ProjectID, objectGroup.DatasetID, objectGroup.ID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_DELETED))
+		if err != nil {
+			return err
+		}
+
+		_, err = moveToTrash(tx, v1storagemodels.Resource_RESOURCE_OBJECT_GROUP, objectGroup.ID, objectGroup.ProjectID, objectGroup.DatasetID, objectGroup.CurrentObjectGroupRevision.Name, handler.DefaultTrashRetention)
+		return err
 	})
 
 	if err != nil {
@@ -34,58 +45,26 @@ func (handler *Delete) DeleteObjectGroup(objectGroupID uuid.UUID) error {
 	return nil
 }
 
+// DeleteDataset Moves a dataset with all its versions, object groups and objects into the trash of its project
 func (handler *Delete) DeleteDataset(datasetID uuid.UUID) error {
 	dataset := &models.Dataset{}
 	dataset.ID = datasetID
 
-	var datasetLabels []*models.Label
-	var objectGroups []*models.ObjectGroup
-
 	err := crdbgorm.ExecuteTx(context.Background(), handler.DB, nil, func(tx *gorm.DB) error {
-		return tx.Transaction(func(tx *gorm.DB) error {
-			// Get dataset Labels
-			err := tx.Model(&dataset).Association("Labels").Find(&datasetLabels)
-			if err != nil {
-				log.Println(err.Error())
-				return err
-			}
-
-			// Preemptively delete object groups registered under the dataset to prevent foreign key violation
-			err = tx.Model(&dataset).Association("ObjectGroups").Find(&objectGroups)
-			if err != nil {
-				log.Println(err.Error())
-				return err
-			}
-
-			if len(objectGroups) > 0 {
-				err = tx.Select(
-					"Labels",
-					"CurrentObjectGroupRevision",
-					"ObjectGroupRevisions",
-					"ObjectGroupRevisions.Objects",
-					"ObjectGroupRevisions.MetaObjects").Unscoped().Delete(objectGroups).Error
-				if err != nil {
-					log.Println(err.Error())
-					return err
-				}
-			}
-
-			// Delete Dataset labels
-			err = tx.Select(
-				"Labels",
-				"MetaObjects",
-				"ObjectGroups",
-				"DatasetVersions").Unscoped().Delete(dataset).Error
-
-			if len(datasetLabels) > 0 {
-				err = tx.
-					Unscoped().
-					Delete(&datasetLabels).Error
-			}
+		err := tx.First(dataset).Error
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
 
+		err = writeOutboxEvents(tx, models.NewDatasetEvent(dataset.ProjectID, dataset.ID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_DELETED))
+		if err != nil {
+			log.Println(err.Error())
 			return err
-		})
+		}
 
+		_, err = moveToTrash(tx, v1storagemodels.Resource_RESOURCE_DATASET, dataset.ID, dataset.ProjectID, dataset.ID, dataset.Name, handler.DefaultTrashRetention)
+		return err
 	})
 
 	if err != nil {
@@ -104,8 +83,20 @@ func (handler *Delete) DeleteDatasetVersion(datasetVersionID uuid.UUID) error {
 
 	err := crdbgorm.ExecuteTx(context.Background(), handler.DB, nil, func(tx *gorm.DB) error {
 		return tx.Transaction(func(tx *gorm.DB) error {
+			err := tx.First(version).Error
+			if err != nil {
+				log.Println(err.Error())
+				return err
+			}
+
+			err = writeOutboxEvents(tx, models.NewDatasetVersionEvent(version.ProjectID, version.DatasetID, version.ID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_DELETED))
+			if err != nil {
+				log.Println(err.Error())
+				return err
+			}
+
 			// Get dataset Labels
-			err := tx.Model(&version).Association("Labels").Find(&labels)
+			err = tx.Model(&version).Association("Labels").Find(&labels)
 			if err != nil {
 				log.Println(err.Error())
 				return err
@@ -114,6 +105,16 @@ func (handler *Delete) DeleteDatasetVersion(datasetVersionID uuid.UUID) error {
 			err = tx.Select(
 				"Labels",
 				"ObjectGroupRevisions").Unscoped().Delete(version).Error
+			if err != nil {
+				log.Println(err.Error())
+				return err
+			}
+
+			err = deleteStats(tx, "resource_id", []uuid.UUID{version.ID})
+			if err != nil {
+				log.Println(err.Error())
+				return err
+			}
 
 			// Delete dangling dataset Label records if available
 			if len(labels) > 0 {
@@ -142,8 +143,26 @@ func (handler *Delete) DeleteProject(projectID uuid.UUID) error {
 
 	err := crdbgorm.ExecuteTx(context.Background(), handler.DB, nil, func(tx *gorm.DB) error {
 		return tx.Transaction(func(tx *gorm.DB) error {
+			err := writeOutboxEvents(tx, models.NewProjectEvent(project.ID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_DELETED))
+			if err != nil {
+				log.Println(err.Error())
+				return err
+			}
+
 			// Get project Label records
-			err := tx.Model(&project).Association("Labels").Find(&labels)
+			err = tx.Model(&project).Association("Labels").Find(&labels)
+			if err != nil {
+				log.Println(err.Error())
+				return err
+			}
+
+			err = tx.Unscoped().Where("project_id = ?", project.ID).Delete(&models.TrashEntry{}).Error
+			if err != nil {
+				log.Println(err.Error())
+				return err
+			}
+
+			err = deleteStats(tx, "project_id", []uuid.UUID{project.ID})
 			if err != nil {
 				log.Println(err.Error())
 				return err
@@ -185,7 +204,15 @@ func (handler *Delete) DeleteAPIToken(tokenID uuid.UUID) error {
 	token.ID = tokenID
 
 	err := crdbgorm.ExecuteTx(context.Background(), handler.DB, nil, func(tx *gorm.DB) error {
-		return handler.DB.Delete(token).Error
+		if err := tx.First(token).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(token).Error; err != nil {
+			return err
+		}
+
+		return writeOutboxEvents(tx, models.NewAPITokenEvent(token.ProjectID, token.ID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_DELETED))
 	})
 
 	if err != nil {
@@ -195,3 +222,22 @@ func (handler *Delete) DeleteAPIToken(tokenID uuid.UUID) error {
 
 	return nil
 }
+
+// DeleteStreamGroup Deletes the database entry of a stream group
+func (handler *Delete) DeleteStreamGroup(streamGroupID uuid.UUID) error {
+	streamGroup := &models.StreamGroup{}
+	streamGroup.ID = streamGroupID
+
+	err := crdbgorm.ExecuteTx(context.Background(), handler.DB, nil, func(tx *gorm.DB) error {
+		return tx.Unscoped().Delete(streamGroup).Error
+	})
+
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	resetSnapshotConsumers()
+
+	return nil
+}
@@ -0,0 +1,323 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbgorm"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/stdlib"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EventLog Handles the event log and the stream group queues of the Postgres event notification backend
+type EventLog struct {
+	*Common
+}
+
+// StreamGroupEventCounts Delivery state of the queue of a stream group
+type StreamGroupEventCounts struct {
+	Pending      int64
+	AckPending   int64
+	Redelivered  int64
+	MinSequence  int64
+	LastSequence int64
+}
+
+// AppendEventLogEntry Stores an event and adds it to the queues of the given stream groups
+// The listeners of channel are notified when the transaction is committed. An entry is logged once per outbox event,
+// events that are published again, e.g. because the relay could not mark them as published, are ignored.
+func (eventLog *EventLog) AppendEventLogEntry(entry *models.EventLogEntry, streamGroupIDs []uuid.UUID, channel string) error {
+	err := crdbgorm.ExecuteTx(context.Background(), eventLog.DB, nil, func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "outbox_sequence"}}, DoNothing: true}).Create(entry)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		if len(streamGroupIDs) > 0 {
+			var queued []*models.StreamGroupEvent
+			for _, streamGroupID := range streamGroupIDs {
+				queued = append(queued, &models.StreamGroupEvent{StreamGroupID: streamGroupID, Sequence: entry.Sequence})
+			}
+
+			err := tx.Clauses(streamGroupEventConflict).Create(queued).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Exec("SELECT pg_notify(?, ?)", channel, entry.Subject).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// An event is queued only once per stream group
+var streamGroupEventConflict = clause.OnConflict{
+	Columns:   []clause.Column{{Name: "stream_group_id"}, {Name: "sequence"}},
+	DoNothing: true,
+}
+
+// GetEventLogEntries Returns the sequence and subject of the logged events from a start position on
+func (eventLog *EventLog) GetEventLogEntries(start *models.StreamGroupStart) ([]*models.EventLogEntry, error) {
+	var entries []*models.EventLogEntry
+
+	err := crdbgorm.ExecuteTx(context.Background(), eventLog.DB, nil, func(tx *gorm.DB) error {
+		var err error
+		entries, err = readEventLogEntries(tx, start)
+		return err
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Returns the sequence and subject of the logged events from a start position on within tx
+func readEventLogEntries(tx *gorm.DB, start *models.StreamGroupStart) ([]*models.EventLogEntry, error) {
+	var entries []*models.EventLogEntry
+
+	if start.Policy == models.StreamGroupStartNew {
+		return entries, nil
+	}
+
+	query := tx.Select("id", "sequence", "subject", "created_at").Order("sequence asc")
+
+	switch start.Policy {
+	case models.StreamGroupStartSequence:
+		query = query.Where("sequence >= ?", start.Sequence)
+	case models.StreamGroupStartTime:
+		query = query.Where("created_at >= ?", start.Time)
+	}
+
+	err := query.Find(&entries).Error
+
+	return entries, err
+}
+
+// ResetStreamGroupEvents Replaces the queue of a stream group with the logged events from the start position on that match
+// The queue is refilled in the same transaction in which it is cleared, events that are appended concurrently are either
+// kept in the queue or read from the log and are queued only once.
+func (eventLog *EventLog) ResetStreamGroupEvents(streamGroupID uuid.UUID, start *models.StreamGroupStart, matches func(subject string) bool) error {
+	err := crdbgorm.ExecuteTx(context.Background(), eventLog.DB, nil, func(tx *gorm.DB) error {
+		err := tx.Unscoped().Where("stream_group_id = ?", streamGroupID).Delete(&models.StreamGroupEvent{}).Error
+		if err != nil {
+			return err
+		}
+
+		entries, err := readEventLogEntries(tx, start)
+		if err != nil {
+			return err
+		}
+
+		var queued []*models.StreamGroupEvent
+		for _, entry := range entries {
+			if matches(entry.Subject) {
+				queued = append(queued, &models.StreamGroupEvent{StreamGroupID: streamGroupID, Sequence: entry.Sequence})
+			}
+		}
+
+		if len(queued) == 0 {
+			return nil
+		}
+
+		return tx.Clauses(streamGroupEventConflict).CreateInBatches(queued, 1000).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteStreamGroupEvents Removes the queue of a stream group
+func (eventLog *EventLog) DeleteStreamGroupEvents(streamGroupID uuid.UUID) error {
+	err := crdbgorm.ExecuteTx(context.Background(), eventLog.DB, nil, func(tx *gorm.DB) error {
+		return tx.Unscoped().Where("stream_group_id = ?", streamGroupID).Delete(&models.StreamGroupEvent{}).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// FetchStreamGroupEvents Assigns up to limit waiting or expired events of a stream group to a new chunk
+// Returns the logged events of the chunk in the order of their sequence and the number of events that are delivered again.
+// Rows that are locked by another streamer of the group are skipped.
+func (eventLog *EventLog) FetchStreamGroupEvents(streamGroupID uuid.UUID, chunkID uuid.UUID, limit int, ackWait time.Duration) ([]*models.EventLogEntry, int, error) {
+	var entries []*models.EventLogEntry
+	redelivered := 0
+
+	err := crdbgorm.ExecuteTx(context.Background(), eventLog.DB, nil, func(tx *gorm.DB) error {
+		entries = nil
+		redelivered = 0
+		now := time.Now()
+
+		var queued []*models.StreamGroupEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("stream_group_id = ? AND (chunk_id = ? OR deadline < ?)", streamGroupID, uuid.Nil, now).
+			Order("sequence asc").Limit(limit).Find(&queued).Error
+		if err != nil || len(queued) == 0 {
+			return err
+		}
+
+		var ids []uuid.UUID
+		var sequences []int64
+		for _, event := range queued {
+			ids = append(ids, event.ID)
+			sequences = append(sequences, event.Sequence)
+			if event.Deliveries > 0 {
+				redelivered++
+			}
+		}
+
+		err = tx.Model(&models.StreamGroupEvent{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"chunk_id":   chunkID,
+			"deadline":   now.Add(ackWait),
+			"deliveries": gorm.Expr("deliveries + 1"),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("sequence IN ?", sequences).Order("sequence asc").Find(&entries).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, 0, err
+	}
+
+	return entries, redelivered, nil
+}
+
+// AckStreamGroupChunk Removes the events of a chunk from the queue of a stream group
+// Returns false if the chunk is unknown, e.g. because its events have been assigned to a new chunk after its deadline.
+func (eventLog *EventLog) AckStreamGroupChunk(streamGroupID uuid.UUID, chunkID uuid.UUID) (bool, error) {
+	var acked int64
+
+	err := crdbgorm.ExecuteTx(context.Background(), eventLog.DB, nil, func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("stream_group_id = ? AND chunk_id = ?", streamGroupID, chunkID).Delete(&models.StreamGroupEvent{})
+		acked = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return false, err
+	}
+
+	return acked > 0, nil
+}
+
+// GetStreamGroupEventCounts Returns the delivery state of the queue of a stream group
+func (eventLog *EventLog) GetStreamGroupEventCounts(streamGroupID uuid.UUID) (*StreamGroupEventCounts, error) {
+	counts := &StreamGroupEventCounts{}
+
+	err := crdbgorm.ExecuteTx(context.Background(), eventLog.DB, nil, func(tx *gorm.DB) error {
+		queue := func() *gorm.DB {
+			return tx.Model(&models.StreamGroupEvent{}).Where("stream_group_id = ?", streamGroupID)
+		}
+
+		err := queue().Where("chunk_id = ?", uuid.Nil).Count(&counts.Pending).Error
+		if err != nil {
+			return err
+		}
+
+		err = queue().Where("chunk_id <> ?", uuid.Nil).Count(&counts.AckPending).Error
+		if err != nil {
+			return err
+		}
+
+		err = queue().Where("deliveries > 1").Count(&counts.Redelivered).Error
+		if err != nil {
+			return err
+		}
+
+		err = queue().Select("COALESCE(MIN(sequence), 0)").Scan(&counts.MinSequence).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.EventLogEntry{}).Select("COALESCE(MAX(sequence), 0)").Scan(&counts.LastSequence).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// DeleteEventLogEntries Removes the logged events that have been created before the given time and are not queued anymore
+func (eventLog *EventLog) DeleteEventLogEntries(before time.Time) error {
+	err := crdbgorm.ExecuteTx(context.Background(), eventLog.DB, nil, func(tx *gorm.DB) error {
+		return tx.Unscoped().
+			Where("created_at < ?", before).
+			Where("NOT EXISTS (SELECT 1 FROM stream_group_events WHERE stream_group_events.sequence = event_log_entries.sequence)").
+			Delete(&models.EventLogEntry{}).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Listen Calls notify for each notification on channel until ctx is done
+// A dedicated connection of the pool is used for the whole time, it requires the pgx driver of the Postgres database type.
+func (eventLog *EventLog) Listen(ctx context.Context, channel string, notify func()) error {
+	sqlDB, err := eventLog.DB.DB()
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, fmt.Sprintf("LISTEN %v", pgx.Identifier{channel}.Sanitize()))
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	for {
+		err = conn.Raw(func(driverConn interface{}) error {
+			pgxConn, ok := driverConn.(*stdlib.Conn)
+			if !ok {
+				return fmt.Errorf("listening for notifications requires the pgx driver")
+			}
+
+			_, err := pgxConn.Conn().WaitForNotification(ctx)
+			return err
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			log.Println(err.Error())
+			return err
+		}
+
+		notify()
+	}
+}
@@ -0,0 +1,376 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	"github.com/ScienceObjectsDB/CORE-Server/signing"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbgorm"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ImportUploadPath Path of the data streaming endpoint that accepts the archive uploads of import jobs
+const ImportUploadPath = "/import"
+
+// MaxReportedImportErrors Maximum number of entry errors that are returned with an import job
+const MaxReportedImportErrors = 1000
+
+// Imports Handles the import jobs that unpack uploaded archives into datasets
+type Imports struct {
+	*Common
+	StreamingEndpoint string
+}
+
+// ImportedObjectGroup An object group that has been uploaded by an import job and still has to be created
+type ImportedObjectGroup struct {
+	Name        string
+	Description string
+	Labels      []models.Label
+	DataObjects []models.Object
+	MetaObjects []models.Object
+}
+
+// CreateImportJob Creates a new import job for a dataset, the archive is staged in the bucket of the dataset
+func (imports *Imports) CreateImportJob(dataset *models.Dataset, format string, createdBy string) (*models.ImportJob, error) {
+	rndBytes := make([]byte, 64)
+	_, err := rand.Read(rndBytes)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	job := &models.ImportJob{
+		Secret:        base64.StdEncoding.EncodeToString(rndBytes),
+		Format:        format,
+		Status:        models.ImportJobStatusWaitingForUpload,
+		ProjectID:     dataset.ProjectID,
+		DatasetID:     dataset.ID,
+		CreatedBy:     createdBy,
+		StagingBucket: dataset.Bucket,
+	}
+	job.ID = uuid.New()
+	job.StagingKey = fmt.Sprintf("%v/%v/imports/%v/archive", dataset.ProjectID, dataset.ID, job.ID)
+
+	err = crdbgorm.ExecuteTx(context.Background(), imports.DB, nil, func(tx *gorm.DB) error {
+		return tx.Create(job).Error
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// GetImportJob Returns an import job with the first MaxReportedImportErrors entry errors
+func (imports *Imports) GetImportJob(jobID uuid.UUID) (*models.ImportJob, error) {
+	job := &models.ImportJob{}
+	job.ID = jobID
+
+	err := crdbgorm.ExecuteTx(context.Background(), imports.DB, nil, func(tx *gorm.DB) error {
+		return tx.
+			Preload("Errors", func(db *gorm.DB) *gorm.DB {
+				return db.Order("created_at asc").Limit(MaxReportedImportErrors)
+			}).
+			First(job).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// TransitionImportJob Sets the status of a job if it is currently in one of the expected states
+// Returns false if the job was not in one of the expected states, e.g. because it has been started concurrently.
+func (imports *Imports) TransitionImportJob(jobID uuid.UUID, expectedStatus []string, status string, message string) (bool, error) {
+	var rowsAffected int64
+
+	updates := map[string]interface{}{
+		"status":  status,
+		"message": message,
+	}
+
+	switch status {
+	case models.ImportJobStatusRunning:
+		updates["started_at"] = time.Now()
+	case models.ImportJobStatusFinished, models.ImportJobStatusFailed:
+		updates["finished_at"] = time.Now()
+	}
+
+	err := crdbgorm.ExecuteTx(context.Background(), imports.DB, nil, func(tx *gorm.DB) error {
+		result := tx.Model(&models.ImportJob{}).
+			Where("id = ? AND status IN ?", jobID, expectedStatus).
+			Updates(updates)
+		rowsAffected = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// SetImportJobArchiveSize Stores the size of the uploaded archive
+func (imports *Imports) SetImportJobArchiveSize(jobID uuid.UUID, size int64) error {
+	err := crdbgorm.ExecuteTx(context.Background(), imports.DB, nil, func(tx *gorm.DB) error {
+		return tx.Model(&models.ImportJob{}).Where("id = ?", jobID).Update("archive_size", size).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpdateImportJobProgress Stores the progress counters of a running job
+func (imports *Imports) UpdateImportJobProgress(job *models.ImportJob) error {
+	err := crdbgorm.ExecuteTx(context.Background(), imports.DB, nil, func(tx *gorm.DB) error {
+		return tx.Model(&models.ImportJob{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+			"read_bytes":            job.ReadBytes,
+			"processed_entries":     job.ProcessedEntries,
+			"failed_entries":        job.FailedEntries,
+			"imported_objects":      job.ImportedObjects,
+			"imported_bytes":        job.ImportedBytes,
+			"created_object_groups": job.CreatedObjectGroups,
+		}).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// TouchImportJob Marks a job that is uploading or running as alive
+// Jobs that have not been touched for a while are considered interrupted, see ExpireStaleImportJobs.
+func (imports *Imports) TouchImportJob(jobID uuid.UUID) error {
+	err := crdbgorm.ExecuteTx(context.Background(), imports.DB, nil, func(tx *gorm.DB) error {
+		return tx.Model(&models.ImportJob{}).Where("id = ?", jobID).Update("updated_at", time.Now()).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// ExpireStaleImportJobs Resets jobs whose upload has not been touched since before to waiting for upload and fails
+// jobs that have been running without being touched since before, e.g. because their server has been stopped.
+// Returns the failed jobs, their staging archives can be removed.
+func (imports *Imports) ExpireStaleImportJobs(before time.Time) ([]*models.ImportJob, error) {
+	var failed []*models.ImportJob
+
+	err := crdbgorm.ExecuteTx(context.Background(), imports.DB, nil, func(tx *gorm.DB) error {
+		err := tx.Model(&models.ImportJob{}).
+			Where("status = ? AND updated_at < ?", models.ImportJobStatusUploading, before).
+			Updates(map[string]interface{}{
+				"status":  models.ImportJobStatusWaitingForUpload,
+				"message": "upload of the archive was interrupted",
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		failed = nil
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND updated_at < ?", models.ImportJobStatusRunning, before).
+			Find(&failed).Error
+		if err != nil || len(failed) == 0 {
+			return err
+		}
+
+		jobIDs := make([]uuid.UUID, len(failed))
+		for i, job := range failed {
+			jobIDs[i] = job.ID
+		}
+
+		return tx.Model(&models.ImportJob{}).
+			Where("id IN ?", jobIDs).
+			Updates(map[string]interface{}{
+				"status":      models.ImportJobStatusFailed,
+				"message":     "import was interrupted",
+				"finished_at": time.Now(),
+			}).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return failed, nil
+}
+
+// AddImportJobError Records an archive entry that could not be imported
+func (imports *Imports) AddImportJobError(jobID uuid.UUID, entry string, message string) error {
+	importError := &models.ImportJobError{
+		ImportJobID: jobID,
+		Entry:       entry,
+		Message:     message,
+	}
+
+	err := crdbgorm.ExecuteTx(context.Background(), imports.DB, nil, func(tx *gorm.DB) error {
+		return tx.Create(importError).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// CreateImportedObjectGroup Creates an object group from objects that have already been uploaded by an import job
+// The objects are created as available, the group is created with a single revision.
+func (imports *Imports) CreateImportedObjectGroup(job *models.ImportJob, importedGroup *ImportedObjectGroup) (*models.ObjectGroup, error) {
+	objectGroup := &models.ObjectGroup{
+		CurrentRevisionCount: 1,
+		DatasetID:            job.DatasetID,
+		ProjectID:            job.ProjectID,
+		Status:               v1storagemodels.Status_STATUS_AVAILABLE.String(),
+	}
+	objectGroup.ID = uuid.New()
+
+	objectGroupRevision := &models.ObjectGroupRevision{
+		Name:           importedGroup.Name,
+		Description:    importedGroup.Description,
+		DataObjects:    importedGroup.DataObjects,
+		MetaObjects:    importedGroup.MetaObjects,
+		DatasetID:      job.DatasetID,
+		ProjectID:      job.ProjectID,
+		Status:         v1storagemodels.Status_STATUS_AVAILABLE.String(),
+		Generated:      time.Now(),
+		ObjectGroupID:  objectGroup.ID,
+		RevisionNumber: 1,
+		Labels:         importedGroup.Labels,
+	}
+
+	err := crdbgorm.ExecuteTx(context.Background(), imports.DB, nil, func(tx *gorm.DB) error {
+		if err := tx.Create(objectGroup).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(objectGroupRevision).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(objectGroup).Update("current_object_group_revision_id", objectGroupRevision.ID).Error; err != nil {
+			return err
+		}
+
+		if err := addObjectGroupStats(tx, 1, "id = ?", objectGroup.ID); err != nil {
+			return err
+		}
+
+		if err := addObjectStats(tx, 1, "id IN ?", importedObjectIDs(objectGroupRevision.DataObjects, objectGroupRevision.MetaObjects)); err != nil {
+			return err
+		}
+
+		if err := setRevisionStats(tx, objectGroupRevision); err != nil {
+			return err
+		}
+
+		return writeOutboxEvents(tx, models.NewObjectGroupEvent(objectGroup.ProjectID, objectGroup.DatasetID, objectGroup.ID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED))
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return objectGroup, nil
+}
+
+// AddImportedDatasetMetaObjects Adds objects that have already been uploaded by an import job as metadata objects to the dataset
+func (imports *Imports) AddImportedDatasetMetaObjects(job *models.ImportJob, objects []models.Object) error {
+	dataset := &models.Dataset{}
+	dataset.ID = job.DatasetID
+
+	err := crdbgorm.ExecuteTx(context.Background(), imports.DB, nil, func(tx *gorm.DB) error {
+		if err := tx.Model(dataset).Association("MetaObjects").Append(objects); err != nil {
+			return err
+		}
+
+		return addObjectStats(tx, 1, "id IN ?", importedObjectIDs(objects))
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Returns the ids of the objects created by an import job
+func importedObjectIDs(objectLists ...[]models.Object) []uuid.UUID {
+	ids := make([]uuid.UUID, 0)
+	for _, objects := range objectLists {
+		for _, object := range objects {
+			ids = append(ids, object.ID)
+		}
+	}
+
+	return ids
+}
+
+// NewImportedObject Creates the model of an object that is uploaded by an import job
+func (imports *Imports) NewImportedObject(job *models.ImportJob, filename string, bucket string) models.Object {
+	objectID := uuid.New()
+	location := imports.S3Handler.CreateLocation(job.ProjectID, job.DatasetID, objectID, filename, bucket)
+	location.ID = uuid.New()
+	location.Status = v1storagemodels.Status_STATUS_AVAILABLE.String()
+
+	object := models.Object{
+		Filename:          filename,
+		Status:            v1storagemodels.Status_STATUS_AVAILABLE.String(),
+		ProjectID:         job.ProjectID,
+		DatasetID:         job.DatasetID,
+		DefaultLocationID: location.ID,
+		DefaultLocation:   location,
+		Locations: []models.Location{
+			location,
+		},
+	}
+	object.ID = objectID
+
+	return object
+}
+
+// CreateImportUploadURL Creates the link of the data streaming server that accepts the archive of the job
+func (imports *Imports) CreateImportUploadURL(job *models.ImportJob) (string, error) {
+	parsedBaseURL, err := url.Parse(imports.StreamingEndpoint)
+	if err != nil {
+		log.Println(err.Error())
+		return "", err
+	}
+
+	parsedBaseURL.Path = ImportUploadPath
+
+	signature, err := signing.SignID([]byte(job.Secret), job.ID.String())
+	if err != nil {
+		log.Println(err.Error())
+		return "", err
+	}
+
+	q := parsedBaseURL.Query()
+	q.Set("id", job.ID.String())
+	q.Set("sign", signature)
+	parsedBaseURL.RawQuery = q.Encode()
+
+	return parsedBaseURL.String(), nil
+}
@@ -0,0 +1,478 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Maximum number of conditions of a single label query
+const maxLabelQueryConditions = 32
+
+// Maximum nesting depth of NOT and parentheses of a single label query
+const maxLabelQueryDepth = 16
+
+// Matches label values that can be compared as numbers, it is passed as argument because gorm replaces every ? of a
+// query string
+const numericLabelValuePattern = `^[-+]?[0-9]*\.?[0-9]+$`
+
+var numericLabelValueRegexp = regexp.MustCompile(numericLabelValuePattern)
+
+// LabelQuery A parsed label query that can be used as sql condition for datasets, objects and object groups
+//
+// Conditions:
+//
+//	key              the resource has a label with the key
+//	key = value      the resource has the label
+//	key != value     the resource does not have the label, also true if the key is missing
+//	key ^= prefix    the value of a label with the key starts with the prefix
+//	key ~ regex      the value of a label with the key matches the regular expression
+//	key < 10         numeric comparison of the value of a label with the key, also <=, > and >=
+//
+// Conditions can be combined with AND, OR, NOT and parentheses. Keys and values that contain whitespace or operators
+// have to be quoted with double quotes. Regular expressions are evaluated by the database and are limited to the syntax
+// that Postgres and CockroachDB interpret alike, see checkLabelQueryRegex.
+type LabelQuery struct {
+	Query string
+	root  labelQueryNode
+}
+
+// LabelQueryTarget The resource whose labels are matched by a label query
+type LabelQueryTarget struct {
+	// Join table between the resource and its labels
+	JoinTable string
+	// Column of the join table that references the resource
+	JoinColumn string
+	// Sql expression of the resource id, e.g. a column of the queried table
+	IDColumn string
+}
+
+var (
+	LabelQueryTargetDatasets = LabelQueryTarget{
+		JoinTable:  "dataset_labels",
+		JoinColumn: "dataset_id",
+		IDColumn:   "datasets.id",
+	}
+	LabelQueryTargetObjects = LabelQueryTarget{
+		JoinTable:  "object_labels",
+		JoinColumn: "object_id",
+		IDColumn:   "objects.id",
+	}
+	LabelQueryTargetObjectGroupRevisions = LabelQueryTarget{
+		JoinTable:  "object_group_revision_label",
+		JoinColumn: "object_group_revision_id",
+		IDColumn:   "object_group_revisions.id",
+	}
+	// Object groups are matched by the labels of their current revision
+	LabelQueryTargetObjectGroups = LabelQueryTarget{
+		JoinTable:  "object_group_revision_label",
+		JoinColumn: "object_group_revision_id",
+		IDColumn:   "object_groups.current_object_group_revision_id",
+	}
+)
+
+type labelQueryNode interface {
+	sql(target LabelQueryTarget) (string, []interface{})
+}
+
+type labelQueryAnd struct {
+	left, right labelQueryNode
+}
+
+type labelQueryOr struct {
+	left, right labelQueryNode
+}
+
+type labelQueryNot struct {
+	node labelQueryNode
+}
+
+type labelQueryCondition struct {
+	key      string
+	operator string
+	value    string
+}
+
+// ParseLabelQuery Parses a label query, returns an error that describes the position of invalid syntax
+func ParseLabelQuery(query string) (*LabelQuery, error) {
+	tokens, err := tokenizeLabelQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("label query is empty")
+	}
+
+	parser := &labelQueryParser{tokens: tokens}
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if parser.pos < len(parser.tokens) {
+		return nil, fmt.Errorf("unexpected %v at position %v", parser.tokens[parser.pos].text, parser.tokens[parser.pos].pos)
+	}
+
+	if parser.conditions > maxLabelQueryConditions {
+		return nil, fmt.Errorf("label query has more than %v conditions", maxLabelQueryConditions)
+	}
+
+	return &LabelQuery{Query: query, root: root}, nil
+}
+
+// SQL Returns the sql condition of the query for the given resource together with its arguments
+func (query *LabelQuery) SQL(target LabelQueryTarget) (string, []interface{}) {
+	return query.root.sql(target)
+}
+
+func (node *labelQueryAnd) sql(target LabelQueryTarget) (string, []interface{}) {
+	left, leftArgs := node.left.sql(target)
+	right, rightArgs := node.right.sql(target)
+
+	return fmt.Sprintf("(%v AND %v)", left, right), append(leftArgs, rightArgs...)
+}
+
+func (node *labelQueryOr) sql(target LabelQueryTarget) (string, []interface{}) {
+	left, leftArgs := node.left.sql(target)
+	right, rightArgs := node.right.sql(target)
+
+	return fmt.Sprintf("(%v OR %v)", left, right), append(leftArgs, rightArgs...)
+}
+
+func (node *labelQueryNot) sql(target LabelQueryTarget) (string, []interface{}) {
+	query, args := node.node.sql(target)
+
+	return fmt.Sprintf("NOT %v", query), args
+}
+
+// Every condition is an EXISTS subquery on the labels of the resource, the labels are looked up by key and value with
+// the idx_labels_key_value index
+func (condition *labelQueryCondition) sql(target LabelQueryTarget) (string, []interface{}) {
+	args := []interface{}{condition.key}
+	valueCondition := ""
+
+	switch condition.operator {
+	case "":
+	case "=", "!=":
+		valueCondition = " AND l.value = ?"
+		args = append(args, condition.value)
+	case "^=":
+		valueCondition = ` AND l.value LIKE ? ESCAPE '\'`
+		args = append(args, escapeLikePattern(condition.value)+"%")
+	case "~":
+		valueCondition = " AND l.value ~ ?"
+		args = append(args, condition.value)
+	case "<", "<=", ">", ">=":
+		valueCondition = fmt.Sprintf(" AND (CASE WHEN l.value ~ ? THEN CAST(l.value AS DECIMAL) END) %v CAST(? AS DECIMAL)", condition.operator)
+		args = append(args, numericLabelValuePattern, condition.value)
+	}
+
+	query := fmt.Sprintf(
+		"EXISTS (SELECT 1 FROM %v AS ql INNER JOIN labels AS l ON l.id = ql.label_id WHERE ql.%v = %v AND l.deleted_at IS NULL AND l.key = ?%v)",
+		target.JoinTable, target.JoinColumn, target.IDColumn, valueCondition)
+
+	if condition.operator == "!=" {
+		query = "NOT " + query
+	}
+
+	return query, args
+}
+
+func escapeLikePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+type labelQueryToken struct {
+	text   string
+	quoted bool
+	pos    int
+}
+
+// Characters that end an unquoted word
+const labelQuerySpecialChars = `()=!<>~^"`
+
+var labelQueryOperators = []string{"!=", "^=", "<=", ">=", "=", "~", "<", ">", "!", "(", ")"}
+
+func tokenizeLabelQuery(query string) ([]labelQueryToken, error) {
+	var tokens []labelQueryToken
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); {
+		switch {
+		case unicode.IsSpace(runes[i]):
+			i++
+		case runes[i] == '"':
+			start := i
+			var text strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				text.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %v", start)
+			}
+			i++
+			tokens = append(tokens, labelQueryToken{text: text.String(), quoted: true, pos: start})
+		case strings.ContainsRune(labelQuerySpecialChars, runes[i]):
+			operator := ""
+			for _, candidate := range labelQueryOperators {
+				if strings.HasPrefix(string(runes[i:]), candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				return nil, fmt.Errorf("unexpected %q at position %v", runes[i], i)
+			}
+			tokens = append(tokens, labelQueryToken{text: operator, pos: i})
+			i += len([]rune(operator))
+		default:
+			start := i
+			for ; i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(labelQuerySpecialChars, runes[i]); i++ {
+			}
+			tokens = append(tokens, labelQueryToken{text: string(runes[start:i]), pos: start})
+		}
+	}
+
+	return tokens, nil
+}
+
+type labelQueryParser struct {
+	tokens     []labelQueryToken
+	pos        int
+	conditions int
+	depth      int
+}
+
+func (parser *labelQueryParser) peek() (labelQueryToken, bool) {
+	if parser.pos >= len(parser.tokens) {
+		return labelQueryToken{}, false
+	}
+
+	return parser.tokens[parser.pos], true
+}
+
+// Checks if the next token is the given operator or keyword and consumes it
+func (parser *labelQueryParser) accept(texts ...string) bool {
+	token, ok := parser.peek()
+	if !ok || token.quoted {
+		return false
+	}
+
+	for _, text := range texts {
+		if strings.EqualFold(token.text, text) {
+			parser.pos++
+			return true
+		}
+	}
+
+	return false
+}
+
+func (parser *labelQueryParser) parseOr() (labelQueryNode, error) {
+	node, err := parser.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for parser.accept("OR") {
+		right, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		node = &labelQueryOr{left: node, right: right}
+	}
+
+	return node, nil
+}
+
+func (parser *labelQueryParser) parseAnd() (labelQueryNode, error) {
+	node, err := parser.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for parser.accept("AND") {
+		right, err := parser.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		node = &labelQueryAnd{left: node, right: right}
+	}
+
+	return node, nil
+}
+
+func (parser *labelQueryParser) parseNot() (labelQueryNode, error) {
+	token, ok := parser.peek()
+	if !ok || token.quoted || !(strings.EqualFold(token.text, "NOT") || token.text == "!" || token.text == "(") {
+		return parser.parseCondition()
+	}
+
+	if parser.depth >= maxLabelQueryDepth {
+		return nil, parser.errorf(fmt.Sprintf("label query is nested deeper than %v levels", maxLabelQueryDepth))
+	}
+	parser.depth++
+	defer func() { parser.depth-- }()
+
+	if parser.accept("NOT", "!") {
+		node, err := parser.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return &labelQueryNot{node: node}, nil
+	}
+
+	if parser.accept("(") {
+		node, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if !parser.accept(")") {
+			return nil, parser.errorf("expected )")
+		}
+
+		return node, nil
+	}
+
+	return parser.parseCondition()
+}
+
+func (parser *labelQueryParser) parseCondition() (labelQueryNode, error) {
+	key, ok := parser.peek()
+	if !ok {
+		return nil, parser.errorf("expected label key")
+	}
+	if !key.quoted && (isLabelQueryOperator(key.text) || isLabelQueryKeyword(key.text)) {
+		return nil, parser.errorf("expected label key")
+	}
+	parser.pos++
+	parser.conditions++
+
+	condition := &labelQueryCondition{key: key.text}
+
+	operator, ok := parser.peek()
+	if !ok || operator.quoted || !isLabelQueryComparison(operator.text) {
+		return condition, nil
+	}
+	parser.pos++
+	condition.operator = operator.text
+
+	value, ok := parser.peek()
+	if !ok || (!value.quoted && (isLabelQueryOperator(value.text) || isLabelQueryKeyword(value.text))) {
+		return nil, parser.errorf("expected value after " + operator.text)
+	}
+	parser.pos++
+	condition.value = value.text
+
+	switch condition.operator {
+	case "~":
+		if err := checkLabelQueryRegex(condition.value); err != nil {
+			return nil, fmt.Errorf("invalid regular expression at position %v: %v", value.pos, err.Error())
+		}
+	case "<", "<=", ">", ">=":
+		if !numericLabelValueRegexp.MatchString(condition.value) {
+			return nil, fmt.Errorf("expected number at position %v", value.pos)
+		}
+	}
+
+	return condition, nil
+}
+
+// Checks that a regular expression only uses syntax that the regex engines of Postgres (POSIX ARE) and CockroachDB
+// (RE2) interpret alike: literals, ., ^, $, |, groups and (?:...), bracket expressions, the quantifiers *, +, ? and
+// {m,n}, the classes \d, \s, \w and their negations and escaped punctuation
+func checkLabelQueryRegex(pattern string) error {
+	runes := []rune(pattern)
+	inBracket := false
+
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case runes[i] == '\\':
+			if i+1 >= len(runes) {
+				return fmt.Errorf("trailing backslash")
+			}
+			i++
+			if (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) && !strings.ContainsRune("dDsSwW", runes[i]) {
+				return fmt.Errorf("escape \\%c is not supported", runes[i])
+			}
+		case inBracket:
+			if runes[i] == '[' && i+1 < len(runes) && strings.ContainsRune(".=", runes[i+1]) {
+				return fmt.Errorf("collating elements and equivalence classes are not supported")
+			} else if runes[i] == '[' && i+1 < len(runes) && runes[i+1] == ':' {
+				// Character classes like [:alpha:] are skipped up to their closing :]
+				class := string(runes[i+2:])
+				end := strings.Index(class, ":]")
+				if end < 0 {
+					return fmt.Errorf("missing :] of character class")
+				}
+				i += len([]rune(class[:end])) + 3
+			} else if runes[i] == ']' {
+				inBracket = false
+			}
+		case runes[i] == '[':
+			inBracket = true
+			// A ] at the start of a bracket expression is a literal
+			if i+1 < len(runes) && runes[i+1] == '^' {
+				i++
+			}
+			if i+1 < len(runes) && runes[i+1] == ']' {
+				i++
+			}
+		case runes[i] == '(' && i+1 < len(runes) && runes[i+1] == '?':
+			if i+2 >= len(runes) || runes[i+2] != ':' {
+				return fmt.Errorf("only (?: groups are supported")
+			}
+		case runes[i] == '{':
+			repetition := labelQueryRepetitionRegexp.FindString(string(runes[i:]))
+			if repetition == "" {
+				return fmt.Errorf("{ has to start a repetition {m}, {m,} or {m,n}, use \\{ for a literal")
+			}
+			i += len(repetition) - 1
+		}
+	}
+
+	_, err := regexp.Compile(pattern)
+	return err
+}
+
+var labelQueryRepetitionRegexp = regexp.MustCompile(`^\{[0-9]+(,[0-9]*)?\}`)
+
+func (parser *labelQueryParser) errorf(message string) error {
+	token, ok := parser.peek()
+	if !ok {
+		return fmt.Errorf("%v at end of query", message)
+	}
+
+	return fmt.Errorf("%v at position %v", message, token.pos)
+}
+
+func isLabelQueryComparison(text string) bool {
+	switch text {
+	case "=", "!=", "^=", "~", "<", "<=", ">", ">=":
+		return true
+	}
+
+	return false
+}
+
+func isLabelQueryOperator(text string) bool {
+	for _, operator := range labelQueryOperators {
+		if text == operator {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isLabelQueryKeyword(text string) bool {
+	return strings.EqualFold(text, "AND") || strings.EqualFold(text, "OR") || strings.EqualFold(text, "NOT")
+}
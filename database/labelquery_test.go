@@ -0,0 +1,94 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLabelQuery(t *testing.T) {
+	query, err := ParseLabelQuery(`pipeline = ingest AND (NOT archived OR status != "in review") AND size >= 10.5`)
+	assert.NoError(t, err)
+
+	sql, args := query.SQL(LabelQueryTargetObjectGroups)
+	assert.True(t, strings.HasPrefix(sql, "((EXISTS (SELECT 1 FROM object_group_revision_label AS ql"))
+	assert.Contains(t, sql, "ql.object_group_revision_id = object_groups.current_object_group_revision_id")
+	assert.Contains(t, sql, "OR NOT EXISTS")
+	assert.Contains(t, sql, "CAST(l.value AS DECIMAL) END) >= CAST(? AS DECIMAL)")
+	assert.Equal(t, strings.Count(sql, "?"), len(args))
+	assert.Equal(t, []interface{}{"pipeline", "ingest", "archived", "status", "in review", "size", numericLabelValuePattern, "10.5"}, args)
+
+	query, err = ParseLabelQuery(`name ^= "50%_done" or name ~ "^run-[0-9]+$"`)
+	assert.NoError(t, err)
+
+	sql, args = query.SQL(LabelQueryTargetDatasets)
+	assert.Contains(t, sql, "ql.dataset_id = datasets.id")
+	assert.Contains(t, sql, `l.value LIKE ? ESCAPE '\'`)
+	assert.Equal(t, []interface{}{"name", `50\%\_done%`, "name", "^run-[0-9]+$"}, args)
+
+	query, err = ParseLabelQuery(`!"key with spaces"`)
+	assert.NoError(t, err)
+
+	sql, args = query.SQL(LabelQueryTargetObjects)
+	assert.True(t, strings.HasPrefix(sql, "NOT EXISTS"))
+	assert.Equal(t, []interface{}{"key with spaces"}, args)
+
+	invalidQueries := []string{
+		"",
+		"key =",
+		"key = value AND",
+		"(key",
+		"key > ten",
+		"key ~ \"[\"",
+		"key = \"unterminated",
+		"a b",
+		"AND key",
+		"key ~ \"(?i)run\"",
+		"key ~ \"(?P<name>run)\"",
+		"key ~ \"\\\\brun\"",
+		"key ~ \"run{\"",
+		"key ~ \"[[.a.]]\"",
+		strings.Repeat("(", maxLabelQueryDepth+1) + "key" + strings.Repeat(")", maxLabelQueryDepth+1),
+		strings.Repeat("NOT ", maxLabelQueryDepth+1) + "key",
+	}
+
+	for _, invalidQuery := range invalidQueries {
+		_, err := ParseLabelQuery(invalidQuery)
+		assert.Error(t, err, invalidQuery)
+	}
+}
+
+func TestCheckLabelQueryRegex(t *testing.T) {
+	validPatterns := []string{
+		"^run-[0-9]+$",
+		"(?:a|b)+c{2,3}",
+		"[[:alpha:]_]+[^]x]",
+		`\d+\.\w*\s?\{`,
+		`[\d.]+`,
+	}
+
+	for _, pattern := range validPatterns {
+		assert.NoError(t, checkLabelQueryRegex(pattern), pattern)
+	}
+
+	invalidPatterns := []string{
+		"(?i)run",
+		"(?=run)",
+		`(a)\1`,
+		`\brun\b`,
+		`\Arun\z`,
+		"run{",
+		"[[=a=]]",
+		"[[:alpha]",
+		"[",
+		`run\`,
+	}
+
+	for _, pattern := range invalidPatterns {
+		assert.Error(t, checkLabelQueryRegex(pattern), pattern)
+	}
+
+	_, err := ParseLabelQuery(strings.Repeat("(", maxLabelQueryDepth) + "key" + strings.Repeat(")", maxLabelQueryDepth))
+	assert.NoError(t, err)
+}
@@ -1,46 +1,351 @@
 package database
 
 import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
 	"github.com/ScienceObjectsDB/CORE-Server/models"
+	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbgorm"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
-func MakeMigrationsStandalone() error {
-	db, err := InitDatabaseConnection()
+// Versioned migrations of the database schema
+// Each migration consists of the files <version>_<name>.up.sql and <version>_<name>.down.sql, versions are applied in
+// ascending order. Applied migrations must not be changed, schema changes always require a new migration.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationFilenameRegex = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+const createSchemaMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL
+)`
+
+// Migration A versioned change of the database schema
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus A migration and whether it has been applied to the database
+type MigrationStatus struct {
+	*Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// LoadMigrations Returns the migrations that are embedded into the server ordered by their version
+func LoadMigrations() ([]*Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	migrationsByVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		version, name, direction, err := parseMigrationFilename(entry.Name())
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
+
+		migration, ok := migrationsByVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			migrationsByVersion[version] = migration
+		}
+
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration %v has the names %v and %v", version, migration.Name, name)
+		}
+
+		switch direction {
+		case "up":
+			migration.Up = string(content)
+		case "down":
+			migration.Down = string(content)
+		}
+	}
+
+	var migrations []*Migration
+	for _, migration := range migrationsByVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %v_%v requires an up and a down file", migration.Version, migration.Name)
+		}
+
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Splits the filename of a migration into its version, name and direction
+func parseMigrationFilename(filename string) (int64, string, string, error) {
+	matches := migrationFilenameRegex.FindStringSubmatch(filename)
+	if matches == nil {
+		return 0, "", "", fmt.Errorf("invalid migration filename %v, expected <version>_<name>.<up|down>.sql", filename)
+	}
+
+	version, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil || version < 1 {
+		return 0, "", "", fmt.Errorf("invalid version of migration %v", filename)
+	}
+
+	return version, matches[2], matches[3], nil
+}
+
+// LatestSchemaVersion Returns the version of the newest migration
+func LatestSchemaVersion() (int64, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// SchemaVersion Returns the version of the newest migration that has been applied to the database
+// Databases without migrations have the version 0.
+func SchemaVersion(db *gorm.DB) (int64, error) {
+	applied, err := readAppliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	var version int64
+	for appliedVersion := range applied {
+		if appliedVersion > version {
+			version = appliedVersion
+		}
+	}
+
+	return version, nil
+}
+
+// GetMigrationStatus Returns all migrations together with their state in the database
+func GetMigrationStatus(db *gorm.DB) ([]*MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := readAppliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []*MigrationStatus
+	for _, migration := range migrations {
+		appliedMigration, ok := applied[migration.Version]
+		status := &MigrationStatus{Migration: migration, Applied: ok}
+		if ok {
+			status.AppliedAt = appliedMigration.AppliedAt
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// CheckSchemaVersion Returns an error if migrations of the server have not been applied to the database
+func CheckSchemaVersion(db *gorm.DB) error {
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	current, err := SchemaVersion(db)
 	if err != nil {
-		log.Fatalln(err.Error())
+		return err
 	}
 
-	err = MakeMigrationsStandaloneFromDB(db)
+	if current < latest {
+		return fmt.Errorf("database schema version %v is behind the version %v of the server, run the migrate up command first", current, latest)
+	}
+
+	if current > latest {
+		log.Warnf("database schema version %v is newer than the version %v of the server", current, latest)
+	}
+
+	return nil
+}
+
+// MigrateUp Applies all migrations that have not been applied yet
+func MigrateUp(db *gorm.DB) error {
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	return MigrateTo(db, latest)
+}
+
+// MigrateDown Reverts the given number of applied migrations, starting with the newest
+func MigrateDown(db *gorm.DB, steps int) error {
+	applied, err := readAppliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	var versions []int64
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] > versions[j]
+	})
+
+	if steps > len(versions) {
+		steps = len(versions)
+	}
+
+	var target int64
+	if steps < len(versions) {
+		target = versions[steps]
+	}
+
+	return MigrateTo(db, target)
+}
+
+// MigrateTo Applies or reverts migrations until the schema has the given version
+// Migrations up to the version that are missing are applied in ascending order, applied migrations after the version
+// are reverted in descending order. Each migration runs in its own transaction together with the update of its version.
+func MigrateTo(db *gorm.DB, version int64) error {
+	migrations, err := LoadMigrations()
 	if err != nil {
-		log.Fatalln(err.Error())
+		return err
+	}
+
+	if version != 0 && !hasMigration(migrations, version) {
+		return fmt.Errorf("unknown schema version %v", version)
+	}
+
+	applied, err := readAppliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if _, ok := applied[migration.Version]; !ok || migration.Version <= version {
+			continue
+		}
+
+		log.Infof("reverting migration %v_%v", migration.Version, migration.Name)
+		err := crdbgorm.ExecuteTx(context.Background(), db, nil, func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+
+			return tx.Delete(&models.SchemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			log.Errorf("could not revert migration %v_%v: %v", migration.Version, migration.Name, err.Error())
+			return err
+		}
+	}
+
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok || migration.Version > version {
+			continue
+		}
+
+		log.Infof("applying migration %v_%v", migration.Version, migration.Name)
+		err := crdbgorm.ExecuteTx(context.Background(), db, nil, func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return err
+			}
+
+			return tx.Create(&models.SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			log.Errorf("could not apply migration %v_%v: %v", migration.Version, migration.Name, err.Error())
+			return err
+		}
 	}
 
 	return nil
 }
 
+// Reads the applied migrations by their version, the version table is created if it does not exist
+func readAppliedMigrations(db *gorm.DB) (map[int64]*models.SchemaMigration, error) {
+	err := db.Exec(createSchemaMigrationsTable).Error
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	var appliedMigrations []*models.SchemaMigration
+	err = db.Order("version asc").Find(&appliedMigrations).Error
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	applied := make(map[int64]*models.SchemaMigration)
+	for _, migration := range appliedMigrations {
+		applied[migration.Version] = migration
+	}
+
+	return applied, nil
+}
+
+func hasMigration(migrations []*Migration, version int64) bool {
+	for _, migration := range migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MakeMigrationsStandalone Connects to the configured database and applies all migrations
+func MakeMigrationsStandalone() error {
+	db, err := InitDatabaseConnection()
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return MakeMigrationsStandaloneFromDB(db)
+}
+
+// MakeMigrationsStandaloneFromDB Applies all migrations to the given database
 func MakeMigrationsStandaloneFromDB(db *gorm.DB) error {
-	err := db.AutoMigrate(
-		&models.Project{},
-		&models.Dataset{},
-		&models.DatasetVersion{},
-		&models.Object{},
-		&models.ObjectGroup{},
-		&models.Location{},
-		&models.Label{},
-		&models.User{},
-		&models.APIToken{},
-		&models.StreamingEntry{},
-		&models.StreamGroup{},
-		&models.ObjectGroupRevision{},
-	)
-
-	if err != nil && err.Error() != "ERROR: duplicate index name: \"idx_users_user_oauth2_id\" (SQLSTATE 42P07)" {
-		log.Fatalln(err.Error())
-	}
-
-	err = db.AutoMigrate(&models.User{})
+	err := MigrateUp(db)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
 
 	return nil
 }
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/schema"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+
+	for i, migration := range migrations {
+		assert.Equal(t, int64(i+1), migration.Version, "migration versions have to be consecutive")
+		assert.NotEmpty(t, migration.Up)
+		assert.NotEmpty(t, migration.Down)
+	}
+
+	latest, err := LatestSchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, migrations[len(migrations)-1].Version, latest)
+
+	version, name, direction, err := parseMigrationFilename("0012_add_resource_stats.down.sql")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), version)
+	assert.Equal(t, "add_resource_stats", name)
+	assert.Equal(t, "down", direction)
+
+	_, _, _, err = parseMigrationFilename("add_resource_stats.sql")
+	assert.Error(t, err)
+}
+
+// Statements of the migrations that change the columns of tables
+var migrationColumnRegex = regexp.MustCompile(`(?s)CREATE TABLE IF NOT EXISTS (\w+) \((.*?)\n\);|ALTER TABLE (\w+) ADD COLUMN IF NOT EXISTS (\w+)|ALTER TABLE (\w+) DROP COLUMN IF EXISTS (\w+)|DROP TABLE IF EXISTS (\w+)`)
+
+func TestMigratedSchemaMatchesModels(t *testing.T) {
+	migrations, err := LoadMigrations()
+	assert.NoError(t, err)
+
+	tables := make(map[string]map[string]bool)
+	for _, migration := range migrations {
+		for _, match := range migrationColumnRegex.FindAllStringSubmatch(migration.Up, -1) {
+			switch {
+			case match[1] != "":
+				if _, ok := tables[match[1]]; ok {
+					continue
+				}
+				columns := make(map[string]bool)
+				for _, line := range strings.Split(match[2], "\n") {
+					fields := strings.Fields(line)
+					if len(fields) == 0 || strings.ToUpper(fields[0]) == fields[0] {
+						continue
+					}
+					columns[fields[0]] = true
+				}
+				tables[match[1]] = columns
+			case match[3] != "":
+				tables[match[3]][match[4]] = true
+			case match[5] != "":
+				delete(tables[match[5]], match[6])
+			case match[7] != "":
+				delete(tables, match[7])
+			}
+		}
+	}
+
+	migratedModels := []interface{}{
+		&models.Project{},
+		&models.Dataset{},
+		&models.DatasetVersion{},
+		&models.Object{},
+		&models.ObjectGroup{},
+		&models.ObjectGroupRevision{},
+		&models.Location{},
+		&models.Label{},
+		&models.User{},
+		&models.APIToken{},
+		&models.StreamingEntry{},
+		&models.StreamGroup{},
+		&models.ImportJob{},
+		&models.ImportJobError{},
+		&models.OutboxEvent{},
+		&models.OutboxResource{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.EventLogEntry{},
+		&models.StreamGroupEvent{},
+		&models.TrashEntry{},
+		&models.ResourceStats{},
+		&models.UsageSnapshot{},
+	}
+
+	cache := &sync.Map{}
+	for _, model := range migratedModels {
+		modelSchema, err := schema.Parse(model, cache, schema.NamingStrategy{})
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		schemas := []*schema.Schema{modelSchema}
+		for _, relationship := range modelSchema.Relationships.Relations {
+			if relationship.JoinTable != nil {
+				schemas = append(schemas, relationship.JoinTable)
+			}
+		}
+
+		for _, tableSchema := range schemas {
+			columns, ok := tables[tableSchema.Table]
+			if !assert.True(t, ok, "table %v is not created by the migrations", tableSchema.Table) {
+				continue
+			}
+
+			for _, column := range tableSchema.DBNames {
+				assert.True(t, columns[column], "column %v.%v is not created by the migrations", tableSchema.Table, column)
+			}
+		}
+	}
+}
@@ -0,0 +1,29 @@
+DROP TABLE IF EXISTS stream_group_events;
+DROP TABLE IF EXISTS event_log_entries;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS import_job_errors;
+DROP TABLE IF EXISTS import_jobs;
+DROP TABLE IF EXISTS stream_groups;
+DROP TABLE IF EXISTS streaming_entry_object_groups;
+DROP TABLE IF EXISTS streaming_entries;
+DROP TABLE IF EXISTS api_tokens;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS object_group_revision_meta_objects;
+DROP TABLE IF EXISTS object_group_revision_data_objects;
+DROP TABLE IF EXISTS object_group_revision_label;
+DROP TABLE IF EXISTS object_labels;
+DROP TABLE IF EXISTS dataset_version_object_group_revisions;
+DROP TABLE IF EXISTS dataset_version_labels;
+DROP TABLE IF EXISTS dataset_meta_objects;
+DROP TABLE IF EXISTS dataset_labels;
+DROP TABLE IF EXISTS project_labels;
+DROP TABLE IF EXISTS labels;
+DROP TABLE IF EXISTS object_group_revisions;
+DROP TABLE IF EXISTS object_groups;
+DROP TABLE IF EXISTS objects;
+DROP TABLE IF EXISTS locations;
+DROP TABLE IF EXISTS dataset_versions;
+DROP TABLE IF EXISTS datasets;
+DROP TABLE IF EXISTS projects;
@@ -0,0 +1,440 @@
+-- Schema of the server before versioned migrations were introduced.
+-- All statements are idempotent so that databases that have been created by the former automatic migration are adopted.
+
+CREATE TABLE IF NOT EXISTS projects (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    description TEXT,
+    name TEXT,
+    status TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_projects_deleted_at ON projects (deleted_at);
+
+CREATE TABLE IF NOT EXISTS datasets (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    name TEXT,
+    description TEXT,
+    bucket TEXT,
+    is_public BOOLEAN,
+    status TEXT,
+    project_id UUID REFERENCES projects (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_datasets_deleted_at ON datasets (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_datasets_name ON datasets (name);
+CREATE INDEX IF NOT EXISTS idx_datasets_status ON datasets (status);
+CREATE INDEX IF NOT EXISTS idx_datasets_project_id ON datasets (project_id);
+
+CREATE TABLE IF NOT EXISTS dataset_versions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    name TEXT,
+    description TEXT,
+    major_version BIGINT,
+    minor_version BIGINT,
+    patch_version BIGINT,
+    revision_version BIGINT,
+    stage TEXT,
+    project_id UUID REFERENCES projects (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    dataset_id UUID REFERENCES datasets (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    status TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_dataset_versions_deleted_at ON dataset_versions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_dataset_versions_major_version ON dataset_versions (major_version);
+CREATE INDEX IF NOT EXISTS idx_dataset_versions_minor_version ON dataset_versions (minor_version);
+CREATE INDEX IF NOT EXISTS idx_dataset_versions_patch_version ON dataset_versions (patch_version);
+CREATE INDEX IF NOT EXISTS idx_dataset_versions_revision_version ON dataset_versions (revision_version);
+CREATE INDEX IF NOT EXISTS idx_dataset_versions_stage ON dataset_versions (stage);
+CREATE INDEX IF NOT EXISTS idx_dataset_versions_project_id ON dataset_versions (project_id);
+CREATE INDEX IF NOT EXISTS idx_dataset_versions_dataset_id ON dataset_versions (dataset_id);
+
+CREATE TABLE IF NOT EXISTS locations (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    endpoint TEXT,
+    bucket TEXT,
+    key TEXT,
+    upload_id TEXT,
+    status TEXT,
+    project_id UUID REFERENCES projects (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    dataset_id UUID REFERENCES datasets (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    object_id UUID
+);
+CREATE INDEX IF NOT EXISTS idx_locations_deleted_at ON locations (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_locations_project_id ON locations (project_id);
+CREATE INDEX IF NOT EXISTS idx_locations_dataset_id ON locations (dataset_id);
+CREATE INDEX IF NOT EXISTS idx_locations_object_id ON locations (object_id);
+
+CREATE TABLE IF NOT EXISTS objects (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    object_uuid UUID,
+    filename TEXT,
+    filetype TEXT,
+    content_len BIGINT,
+    status TEXT,
+    default_location_id UUID,
+    upload_id TEXT,
+    project_id UUID REFERENCES projects (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    dataset_id UUID REFERENCES datasets (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_objects_deleted_at ON objects (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_objects_object_uuid ON objects (object_uuid);
+CREATE INDEX IF NOT EXISTS idx_objects_filename ON objects (filename);
+CREATE INDEX IF NOT EXISTS idx_objects_status ON objects (status);
+CREATE INDEX IF NOT EXISTS idx_objects_default_location_id ON objects (default_location_id);
+CREATE INDEX IF NOT EXISTS idx_objects_project_id ON objects (project_id);
+CREATE INDEX IF NOT EXISTS idx_objects_dataset_id ON objects (dataset_id);
+
+CREATE TABLE IF NOT EXISTS object_groups (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    current_revision_count BIGINT,
+    current_object_group_revision_id UUID,
+    dataset_id UUID REFERENCES datasets (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    project_id UUID REFERENCES projects (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    status TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_object_groups_deleted_at ON object_groups (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_object_groups_current_object_group_revision_id ON object_groups (current_object_group_revision_id);
+CREATE INDEX IF NOT EXISTS idx_object_groups_dataset_id ON object_groups (dataset_id);
+CREATE INDEX IF NOT EXISTS idx_object_groups_project_id ON object_groups (project_id);
+
+CREATE TABLE IF NOT EXISTS object_group_revisions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    name TEXT,
+    description TEXT,
+    dataset_id UUID REFERENCES datasets (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    project_id UUID REFERENCES projects (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    status TEXT,
+    generated TIMESTAMPTZ,
+    object_group_id UUID,
+    revision_number BIGINT
+);
+CREATE INDEX IF NOT EXISTS idx_object_group_revisions_deleted_at ON object_group_revisions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_object_group_revisions_project_id ON object_group_revisions (project_id);
+CREATE INDEX IF NOT EXISTS idx_object_group_revisions_status ON object_group_revisions (status);
+CREATE INDEX IF NOT EXISTS revision_number ON object_group_revisions (object_group_id, revision_number);
+
+CREATE TABLE IF NOT EXISTS labels (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    key TEXT,
+    value TEXT,
+    parent_id UUID
+);
+CREATE INDEX IF NOT EXISTS idx_labels_deleted_at ON labels (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_labels_parent_id ON labels (parent_id);
+
+CREATE TABLE IF NOT EXISTS project_labels (
+    project_id UUID REFERENCES projects (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    label_id UUID REFERENCES labels (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    PRIMARY KEY (project_id, label_id)
+);
+
+CREATE TABLE IF NOT EXISTS dataset_labels (
+    dataset_id UUID REFERENCES datasets (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    label_id UUID REFERENCES labels (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    PRIMARY KEY (dataset_id, label_id)
+);
+
+CREATE TABLE IF NOT EXISTS dataset_meta_objects (
+    dataset_id UUID REFERENCES datasets (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    object_id UUID REFERENCES objects (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    PRIMARY KEY (dataset_id, object_id)
+);
+
+CREATE TABLE IF NOT EXISTS dataset_version_labels (
+    dataset_version_id UUID REFERENCES dataset_versions (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    label_id UUID REFERENCES labels (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    PRIMARY KEY (dataset_version_id, label_id)
+);
+
+CREATE TABLE IF NOT EXISTS dataset_version_object_group_revisions (
+    dataset_version_id UUID REFERENCES dataset_versions (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    object_group_revision_id UUID REFERENCES object_group_revisions (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    PRIMARY KEY (dataset_version_id, object_group_revision_id)
+);
+
+CREATE TABLE IF NOT EXISTS object_labels (
+    object_id UUID REFERENCES objects (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    label_id UUID REFERENCES labels (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    PRIMARY KEY (object_id, label_id)
+);
+
+CREATE TABLE IF NOT EXISTS object_group_revision_label (
+    object_group_revision_id UUID REFERENCES object_group_revisions (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    label_id UUID REFERENCES labels (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    PRIMARY KEY (object_group_revision_id, label_id)
+);
+
+CREATE TABLE IF NOT EXISTS object_group_revision_data_objects (
+    object_group_revision_id UUID REFERENCES object_group_revisions (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    object_id UUID REFERENCES objects (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    PRIMARY KEY (object_group_revision_id, object_id)
+);
+
+CREATE TABLE IF NOT EXISTS object_group_revision_meta_objects (
+    object_group_revision_id UUID REFERENCES object_group_revisions (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    object_id UUID REFERENCES objects (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    PRIMARY KEY (object_group_revision_id, object_id)
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    user_oauth2_id TEXT,
+    project_id UUID REFERENCES projects (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    PRIMARY KEY (user_oauth2_id, project_id)
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    token TEXT,
+    project_id UUID REFERENCES projects (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    user_uuid UUID
+);
+CREATE INDEX IF NOT EXISTS idx_api_tokens_deleted_at ON api_tokens (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_api_tokens_token ON api_tokens (token);
+CREATE INDEX IF NOT EXISTS idx_api_tokens_project_id ON api_tokens (project_id);
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user_uuid ON api_tokens (user_uuid);
+
+CREATE TABLE IF NOT EXISTS streaming_entries (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    secret TEXT,
+    resource_type TEXT,
+    stream_type TEXT,
+    dataset_id UUID,
+    dataset_version_id UUID,
+    start_date TIMESTAMPTZ,
+    end_date TIMESTAMPTZ,
+    project_id UUID,
+    created_by TEXT,
+    expires_at TIMESTAMPTZ,
+    max_downloads BIGINT,
+    download_count BIGINT,
+    revoked BOOLEAN
+);
+-- Columns that are missing in the tables created by the automatic migration of older versions
+ALTER TABLE streaming_entries ADD COLUMN IF NOT EXISTS resource_type TEXT;
+ALTER TABLE streaming_entries ADD COLUMN IF NOT EXISTS stream_type TEXT;
+ALTER TABLE streaming_entries ADD COLUMN IF NOT EXISTS dataset_version_id UUID;
+ALTER TABLE streaming_entries ADD COLUMN IF NOT EXISTS start_date TIMESTAMPTZ;
+ALTER TABLE streaming_entries ADD COLUMN IF NOT EXISTS end_date TIMESTAMPTZ;
+ALTER TABLE streaming_entries ADD COLUMN IF NOT EXISTS created_by TEXT;
+ALTER TABLE streaming_entries ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+ALTER TABLE streaming_entries ADD COLUMN IF NOT EXISTS max_downloads BIGINT;
+ALTER TABLE streaming_entries ADD COLUMN IF NOT EXISTS download_count BIGINT;
+ALTER TABLE streaming_entries ADD COLUMN IF NOT EXISTS revoked BOOLEAN;
+CREATE INDEX IF NOT EXISTS idx_streaming_entries_deleted_at ON streaming_entries (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_streaming_entries_dataset_id ON streaming_entries (dataset_id);
+CREATE INDEX IF NOT EXISTS idx_streaming_entries_project_id ON streaming_entries (project_id);
+CREATE INDEX IF NOT EXISTS idx_streaming_entries_expires_at ON streaming_entries (expires_at);
+CREATE INDEX IF NOT EXISTS idx_streaming_entries_revoked ON streaming_entries (revoked);
+
+CREATE TABLE IF NOT EXISTS streaming_entry_object_groups (
+    streaming_entry_id UUID REFERENCES streaming_entries (id) ON DELETE CASCADE,
+    object_group_revision_id UUID REFERENCES object_group_revisions (id) ON DELETE CASCADE,
+    PRIMARY KEY (streaming_entry_id, object_group_revision_id)
+);
+
+CREATE TABLE IF NOT EXISTS stream_groups (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    subject TEXT,
+    resource_id UUID,
+    resource_type TEXT,
+    use_sub_resource BOOLEAN,
+    project_id UUID,
+    start_policy TEXT,
+    start_sequence BIGINT,
+    start_time TIMESTAMPTZ,
+    resources TEXT,
+    update_types TEXT,
+    label_key TEXT,
+    label_value TEXT,
+    include_snapshots BOOLEAN
+);
+-- Columns that are missing in the tables created by the automatic migration of older versions
+ALTER TABLE stream_groups ADD COLUMN IF NOT EXISTS start_policy TEXT;
+ALTER TABLE stream_groups ADD COLUMN IF NOT EXISTS start_sequence BIGINT;
+ALTER TABLE stream_groups ADD COLUMN IF NOT EXISTS start_time TIMESTAMPTZ;
+ALTER TABLE stream_groups ADD COLUMN IF NOT EXISTS resources TEXT;
+ALTER TABLE stream_groups ADD COLUMN IF NOT EXISTS update_types TEXT;
+ALTER TABLE stream_groups ADD COLUMN IF NOT EXISTS label_key TEXT;
+ALTER TABLE stream_groups ADD COLUMN IF NOT EXISTS label_value TEXT;
+ALTER TABLE stream_groups ADD COLUMN IF NOT EXISTS include_snapshots BOOLEAN;
+CREATE INDEX IF NOT EXISTS idx_stream_groups_deleted_at ON stream_groups (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_stream_groups_resource_id ON stream_groups (resource_id);
+CREATE INDEX IF NOT EXISTS idx_stream_groups_resource_type ON stream_groups (resource_type);
+CREATE INDEX IF NOT EXISTS idx_stream_groups_project_id ON stream_groups (project_id);
+
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    secret TEXT,
+    format TEXT,
+    status TEXT,
+    project_id UUID,
+    dataset_id UUID,
+    created_by TEXT,
+    staging_bucket TEXT,
+    staging_key TEXT,
+    archive_size BIGINT,
+    read_bytes BIGINT,
+    processed_entries BIGINT,
+    failed_entries BIGINT,
+    imported_objects BIGINT,
+    imported_bytes BIGINT,
+    created_object_groups BIGINT,
+    message TEXT,
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_import_jobs_deleted_at ON import_jobs (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_import_jobs_status ON import_jobs (status);
+CREATE INDEX IF NOT EXISTS idx_import_jobs_project_id ON import_jobs (project_id);
+CREATE INDEX IF NOT EXISTS idx_import_jobs_dataset_id ON import_jobs (dataset_id);
+
+CREATE TABLE IF NOT EXISTS import_job_errors (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    import_job_id UUID REFERENCES import_jobs (id) ON DELETE CASCADE,
+    entry TEXT,
+    message TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_import_job_errors_deleted_at ON import_job_errors (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_import_job_errors_import_job_id ON import_job_errors (import_job_id);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    sequence BIGSERIAL,
+    resource TEXT,
+    resource_id UUID,
+    update_type TEXT,
+    project_id UUID,
+    dataset_id UUID,
+    object_group_id UUID,
+    dataset_version_id UUID,
+    published BOOLEAN,
+    published_at TIMESTAMPTZ,
+    attempts BIGINT,
+    next_attempt_at TIMESTAMPTZ,
+    last_error TEXT,
+    snapshot TEXT,
+    previous_revision_id UUID,
+    revision_id UUID,
+    added_labels TEXT,
+    removed_labels TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_deleted_at ON outbox_events (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_sequence ON outbox_events (sequence);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_resource ON outbox_events (resource, resource_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_published ON outbox_events (published);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_next_attempt_at ON outbox_events (next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    project_id UUID,
+    url TEXT,
+    secret TEXT,
+    description TEXT,
+    resources TEXT,
+    update_types TEXT,
+    enabled BOOLEAN,
+    include_snapshots BOOLEAN,
+    created_by TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_deleted_at ON webhook_subscriptions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_project_id ON webhook_subscriptions (project_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    subscription_id UUID,
+    event_id UUID,
+    resource TEXT,
+    resource_id UUID,
+    update_type TEXT,
+    payload TEXT,
+    headers TEXT,
+    status TEXT,
+    attempts BIGINT,
+    next_attempt_at TIMESTAMPTZ,
+    last_status_code BIGINT,
+    last_error TEXT,
+    delivered_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_deleted_at ON webhook_deliveries (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON webhook_deliveries (subscription_id, event_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS event_log_entries (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    sequence BIGSERIAL,
+    subject TEXT,
+    data TEXT,
+    headers TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_event_log_entries_deleted_at ON event_log_entries (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_event_log_entries_sequence ON event_log_entries (sequence);
+CREATE INDEX IF NOT EXISTS idx_event_log_entries_subject ON event_log_entries (subject);
+
+CREATE TABLE IF NOT EXISTS stream_group_events (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    stream_group_id UUID,
+    sequence BIGINT,
+    chunk_id UUID,
+    deadline TIMESTAMPTZ,
+    deliveries BIGINT
+);
+CREATE INDEX IF NOT EXISTS idx_stream_group_events_deleted_at ON stream_group_events (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_stream_group_events_sequence ON stream_group_events (stream_group_id, sequence);
+CREATE INDEX IF NOT EXISTS idx_stream_group_events_chunk_id ON stream_group_events (chunk_id);
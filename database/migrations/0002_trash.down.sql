@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS trash_entries;
+
+ALTER TABLE projects DROP COLUMN IF EXISTS trash_retention;
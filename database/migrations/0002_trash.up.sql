@@ -0,0 +1,20 @@
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS trash_retention BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS trash_entries (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    resource TEXT,
+    resource_id UUID,
+    project_id UUID,
+    dataset_id UUID,
+    name TEXT,
+    trashed_at TIMESTAMPTZ,
+    purge_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_trash_entries_deleted_at ON trash_entries (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_trash_entries_resource ON trash_entries (resource, resource_id);
+CREATE INDEX IF NOT EXISTS idx_trash_entries_project_id ON trash_entries (project_id);
+CREATE INDEX IF NOT EXISTS idx_trash_entries_dataset_id ON trash_entries (dataset_id);
+CREATE INDEX IF NOT EXISTS idx_trash_entries_purge_at ON trash_entries (purge_at);
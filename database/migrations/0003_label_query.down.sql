@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_labels_key_value;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_labels_key_value ON labels (key, value);
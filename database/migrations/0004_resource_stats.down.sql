@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_resource_stats_project_id;
+DROP TABLE IF EXISTS resource_stats;
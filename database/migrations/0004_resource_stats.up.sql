@@ -0,0 +1,63 @@
+CREATE TABLE IF NOT EXISTS resource_stats (
+    resource_id UUID PRIMARY KEY,
+    resource TEXT,
+    project_id UUID,
+    object_count BIGINT NOT NULL DEFAULT 0,
+    acc_size BIGINT NOT NULL DEFAULT 0,
+    object_group_count BIGINT NOT NULL DEFAULT 0,
+    meta_object_count BIGINT NOT NULL DEFAULT 0,
+    user_count BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_resource_stats_project_id ON resource_stats (project_id);
+
+-- Statistics of the existing resources, later changes are maintained by the server
+
+INSERT INTO resource_stats (resource_id, resource, project_id, object_count, acc_size, object_group_count, user_count, updated_at)
+SELECT projects.id, 'RESOURCE_PROJECT', projects.id,
+    (SELECT count(*) FROM objects WHERE objects.project_id = projects.id AND objects.deleted_at IS NULL),
+    (SELECT coalesce(sum(objects.content_len), 0) FROM objects WHERE objects.project_id = projects.id AND objects.deleted_at IS NULL),
+    (SELECT count(*) FROM object_groups WHERE object_groups.project_id = projects.id AND object_groups.deleted_at IS NULL),
+    (SELECT count(*) FROM users WHERE users.project_id = projects.id AND users.deleted_at IS NULL),
+    now()
+FROM projects WHERE projects.deleted_at IS NULL
+ON CONFLICT (resource_id) DO NOTHING;
+
+INSERT INTO resource_stats (resource_id, resource, project_id, object_count, acc_size, object_group_count, updated_at)
+SELECT datasets.id, 'RESOURCE_DATASET', datasets.project_id,
+    (SELECT count(*) FROM objects WHERE objects.dataset_id = datasets.id AND objects.deleted_at IS NULL),
+    (SELECT coalesce(sum(objects.content_len), 0) FROM objects WHERE objects.dataset_id = datasets.id AND objects.deleted_at IS NULL),
+    (SELECT count(*) FROM object_groups WHERE object_groups.dataset_id = datasets.id AND object_groups.deleted_at IS NULL),
+    now()
+FROM datasets WHERE datasets.deleted_at IS NULL
+ON CONFLICT (resource_id) DO NOTHING;
+
+INSERT INTO resource_stats (resource_id, resource, project_id, object_count, acc_size, meta_object_count, updated_at)
+SELECT revisions.id, 'RESOURCE_OBJECT_GROUP_REVISION', revisions.project_id,
+    (SELECT count(*) FROM object_group_revision_data_objects AS data_objects
+        INNER JOIN objects ON objects.id = data_objects.object_id
+        WHERE data_objects.object_group_revision_id = revisions.id),
+    (SELECT coalesce(sum(objects.content_len), 0) FROM object_group_revision_data_objects AS data_objects
+        INNER JOIN objects ON objects.id = data_objects.object_id
+        WHERE data_objects.object_group_revision_id = revisions.id),
+    (SELECT count(*) FROM object_group_revision_meta_objects AS meta_objects
+        WHERE meta_objects.object_group_revision_id = revisions.id),
+    now()
+FROM object_group_revisions AS revisions
+ON CONFLICT (resource_id) DO NOTHING;
+
+INSERT INTO resource_stats (resource_id, resource, project_id, object_count, acc_size, object_group_count, updated_at)
+SELECT versions.id, 'RESOURCE_DATASET_VERSION', versions.project_id,
+    (SELECT count(*) FROM dataset_version_object_group_revisions AS version_revisions
+        INNER JOIN object_group_revision_data_objects AS data_objects ON data_objects.object_group_revision_id = version_revisions.object_group_revision_id
+        INNER JOIN objects ON objects.id = data_objects.object_id
+        WHERE version_revisions.dataset_version_id = versions.id),
+    (SELECT coalesce(sum(objects.content_len), 0) FROM dataset_version_object_group_revisions AS version_revisions
+        INNER JOIN object_group_revision_data_objects AS data_objects ON data_objects.object_group_revision_id = version_revisions.object_group_revision_id
+        INNER JOIN objects ON objects.id = data_objects.object_id
+        WHERE version_revisions.dataset_version_id = versions.id),
+    (SELECT count(*) FROM dataset_version_object_group_revisions AS version_revisions
+        WHERE version_revisions.dataset_version_id = versions.id),
+    now()
+FROM dataset_versions AS versions
+ON CONFLICT (resource_id) DO NOTHING;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_usage_snapshots_resource;
+DROP INDEX IF EXISTS idx_usage_snapshots_project_id;
+DROP TABLE IF EXISTS usage_snapshots;
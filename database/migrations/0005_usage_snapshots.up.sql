@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS usage_snapshots (
+    resource_id UUID NOT NULL,
+    taken_at TIMESTAMPTZ NOT NULL,
+    resource TEXT,
+    project_id UUID,
+    object_count BIGINT NOT NULL DEFAULT 0,
+    acc_size BIGINT NOT NULL DEFAULT 0,
+    object_group_count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (resource_id, taken_at)
+);
+CREATE INDEX IF NOT EXISTS idx_usage_snapshots_project_id ON usage_snapshots (project_id);
+CREATE INDEX IF NOT EXISTS idx_usage_snapshots_resource ON usage_snapshots (resource, taken_at);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS outbox_resources;
+DROP INDEX IF EXISTS idx_outbox_events_dead_lettered;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS dead_lettered_at;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS dead_lettered;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS resource_sequence;
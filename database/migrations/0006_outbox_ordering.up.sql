@@ -0,0 +1,27 @@
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS resource_sequence BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS dead_lettered BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS dead_lettered_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_dead_lettered ON outbox_events (dead_lettered);
+
+CREATE TABLE IF NOT EXISTS outbox_resources (
+    resource TEXT NOT NULL,
+    resource_id UUID NOT NULL,
+    last_sequence BIGINT NOT NULL DEFAULT 0,
+    published_sequence BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (resource, resource_id)
+);
+
+-- Positions of the events that are still in the outbox, in the order of their sequence
+
+UPDATE outbox_events SET resource_sequence = numbered.position
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY resource, resource_id ORDER BY sequence) AS position FROM outbox_events
+) AS numbered
+WHERE outbox_events.id = numbered.id AND outbox_events.resource_sequence = 0;
+
+INSERT INTO outbox_resources (resource, resource_id, last_sequence, published_sequence)
+SELECT resource, resource_id, max(resource_sequence),
+    coalesce(min(CASE WHEN published THEN NULL ELSE resource_sequence END) - 1, max(resource_sequence))
+FROM outbox_events
+GROUP BY resource, resource_id
+ON CONFLICT (resource, resource_id) DO NOTHING;
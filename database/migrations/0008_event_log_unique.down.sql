@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_stream_group_events_sequence;
+CREATE INDEX IF NOT EXISTS idx_stream_group_events_sequence ON stream_group_events (stream_group_id, sequence);
+DROP INDEX IF EXISTS idx_event_log_entries_outbox_sequence;
+ALTER TABLE event_log_entries DROP COLUMN IF EXISTS outbox_sequence;
@@ -0,0 +1,10 @@
+ALTER TABLE event_log_entries ADD COLUMN IF NOT EXISTS outbox_sequence BIGINT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_log_entries_outbox_sequence ON event_log_entries (outbox_sequence);
+DELETE FROM stream_group_events WHERE id IN (
+    SELECT id FROM (
+        SELECT id, row_number() OVER (PARTITION BY stream_group_id, sequence ORDER BY id) AS duplicate
+        FROM stream_group_events
+    ) AS queued WHERE duplicate > 1
+);
+DROP INDEX IF EXISTS idx_stream_group_events_sequence;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stream_group_events_sequence ON stream_group_events (stream_group_id, sequence);
@@ -0,0 +1 @@
+ALTER TABLE trash_entries DROP COLUMN IF EXISTS purging_at;
@@ -0,0 +1 @@
+ALTER TABLE trash_entries ADD COLUMN IF NOT EXISTS purging_at TIMESTAMPTZ;
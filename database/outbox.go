@@ -0,0 +1,561 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbgorm"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Outbox Handles the event notifications that are waiting to be published
+// The events are written by the Create, Update and Delete handlers in the same transaction as the change they describe.
+type Outbox struct {
+	*Common
+}
+
+// Writes the provided events as part of the transaction tx
+// If a stream group or webhook subscription that receives an event requested snapshots, the current state of the
+// resource is read within tx and stored with the event.
+func writeOutboxEvents(tx *gorm.DB, events ...*models.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for _, event := range events {
+		event.NextAttemptAt = now
+
+		err := writeEventLabels(tx, event)
+		if err != nil {
+			return err
+		}
+
+		err = writeSnapshot(tx, event)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := assignResourceSequences(tx, events)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(events).Error
+}
+
+// Assigns the positions of the events among the events of their resources
+// The rows of the resources stay locked until tx commits, they are locked in a fixed order to avoid deadlocks.
+func assignResourceSequences(tx *gorm.DB, events []*models.OutboxEvent) error {
+	resourceEvents := make(map[string][]*models.OutboxEvent)
+	var keys []string
+	for _, event := range events {
+		key := event.ResourceKey()
+		if _, ok := resourceEvents[key]; !ok {
+			keys = append(keys, key)
+		}
+		resourceEvents[key] = append(resourceEvents[key], event)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		eventsOfResource := resourceEvents[key]
+		count := int64(len(eventsOfResource))
+
+		var lastSequence int64
+		err := tx.Raw(`INSERT INTO outbox_resources (resource, resource_id, last_sequence, published_sequence) VALUES (?, ?, ?, 0)
+			ON CONFLICT (resource, resource_id) DO UPDATE SET last_sequence = outbox_resources.last_sequence + excluded.last_sequence
+			RETURNING last_sequence`,
+			eventsOfResource[0].Resource, eventsOfResource[0].ResourceID, count).Scan(&lastSequence).Error
+		if err != nil {
+			return err
+		}
+
+		for i, event := range eventsOfResource {
+			event.ResourceSequence = lastSequence - count + int64(i) + 1
+		}
+	}
+
+	return nil
+}
+
+// Stores a snapshot of the resource of an event if a consumer that receives the event requested it
+// Deleted resources have no snapshot. The labels of the event have to be written before.
+func writeSnapshot(tx *gorm.DB, event *models.OutboxEvent) error {
+	if event.UpdateType == v1notificationservices.EventNotificationMessage_UPDATE_TYPE_DELETED.String() {
+		return nil
+	}
+
+	consumers, err := getSnapshotConsumers(tx, event.ProjectID)
+	if err != nil || !consumers.matches(event) {
+		return err
+	}
+
+	snapshot, err := readSnapshot(tx, event)
+	if err != nil || snapshot == nil {
+		return err
+	}
+
+	encoded, err := protojson.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	event.Snapshot = string(encoded)
+
+	return nil
+}
+
+// Duration for which the consumers of a project that requested snapshots are cached
+// Changes made by other servers are used after at most this duration, changes made by this server immediately.
+const snapshotConsumersCacheTTL = 30 * time.Second
+
+// snapshotConsumers The stream groups and enabled webhook subscriptions of a project that requested snapshots
+type snapshotConsumers struct {
+	streamGroups  []*models.StreamGroup
+	subscriptions []*models.WebhookSubscription
+	expiresAt     time.Time
+}
+
+var snapshotConsumersCache = struct {
+	sync.Mutex
+	projects map[uuid.UUID]*snapshotConsumers
+}{projects: make(map[uuid.UUID]*snapshotConsumers)}
+
+// Checks if one of the consumers receives the event
+func (consumers *snapshotConsumers) matches(event *models.OutboxEvent) bool {
+	for _, streamGroup := range consumers.streamGroups {
+		if streamGroup.MatchesEvent(event) {
+			return true
+		}
+	}
+
+	for _, subscription := range consumers.subscriptions {
+		if subscription.Matches(event) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the consumers of a project that requested snapshots, they are read within tx if they are not cached
+func getSnapshotConsumers(tx *gorm.DB, projectID uuid.UUID) (*snapshotConsumers, error) {
+	snapshotConsumersCache.Lock()
+	consumers, ok := snapshotConsumersCache.projects[projectID]
+	snapshotConsumersCache.Unlock()
+	if ok && time.Now().Before(consumers.expiresAt) {
+		return consumers, nil
+	}
+
+	consumers = &snapshotConsumers{expiresAt: time.Now().Add(snapshotConsumersCacheTTL)}
+
+	err := tx.Where("project_id = ? AND include_snapshots = ?", projectID, true).Find(&consumers.streamGroups).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Where("project_id = ? AND include_snapshots = ? AND enabled = ?", projectID, true, true).Find(&consumers.subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotConsumersCache.Lock()
+	snapshotConsumersCache.projects[projectID] = consumers
+	snapshotConsumersCache.Unlock()
+
+	return consumers, nil
+}
+
+// Drops the cached snapshot consumers, it is called after stream groups or webhook subscriptions have been changed
+func resetSnapshotConsumers() {
+	snapshotConsumersCache.Lock()
+	snapshotConsumersCache.projects = make(map[uuid.UUID]*snapshotConsumers)
+	snapshotConsumersCache.Unlock()
+}
+
+// Reads the resource of an event within tx and returns its proto representation
+// Api tokens have no snapshot, it would contain the token.
+func readSnapshot(tx *gorm.DB, event *models.OutboxEvent) (proto.Message, error) {
+	switch event.Resource {
+	case models.EventResourceAPIToken:
+		return nil, nil
+	case models.EventResourceUser:
+		user := &models.User{}
+		if err := tx.Where("user_oauth2_id = ? AND project_id = ?", event.UserOauth2ID, event.ProjectID).First(user).Error; err != nil {
+			return nil, err
+		}
+
+		return user.ToProtoModel(), nil
+	}
+
+	switch event.ResourceEnum() {
+	case v1storagemodels.Resource_RESOURCE_PROJECT:
+		project := &models.Project{}
+		project.ID = event.ResourceID
+		if err := tx.Preload("Users").Preload("Labels").First(project).Error; err != nil {
+			return nil, err
+		}
+
+		return project.ToProtoModel(nil)
+	case v1storagemodels.Resource_RESOURCE_DATASET:
+		dataset := &models.Dataset{}
+		dataset.ID = event.ResourceID
+		if err := tx.Preload("Labels").Preload("MetaObjects").First(dataset).Error; err != nil {
+			return nil, err
+		}
+
+		return dataset.ToProtoModel(nil)
+	case v1storagemodels.Resource_RESOURCE_DATASET_VERSION:
+		version := &models.DatasetVersion{}
+		version.ID = event.ResourceID
+		if err := tx.Preload("Labels").First(version).Error; err != nil {
+			return nil, err
+		}
+
+		return version.ToProtoModel(nil)
+	case v1storagemodels.Resource_RESOURCE_OBJECT:
+		object := &models.Object{}
+		object.ID = event.ResourceID
+		if err := tx.Preload("Locations").Preload("DefaultLocation").Preload("Labels").First(object).Error; err != nil {
+			return nil, err
+		}
+
+		return object.ToProtoModel()
+	case v1storagemodels.Resource_RESOURCE_OBJECT_GROUP:
+		objectGroup := &models.ObjectGroup{}
+		objectGroup.ID = event.ResourceID
+		if err := tx.First(objectGroup).Error; err != nil {
+			return nil, err
+		}
+
+		revision := &models.ObjectGroupRevision{}
+		revision.ID = objectGroup.CurrentObjectGroupRevisionID
+		err := tx.
+			Preload("Labels").
+			Preload("DataObjects.Locations").
+			Preload("DataObjects.DefaultLocation").
+			Preload("DataObjects.Labels").
+			Preload("MetaObjects.Locations").
+			Preload("MetaObjects.DefaultLocation").
+			Preload("MetaObjects.Labels").
+			First(revision).Error
+		if err != nil {
+			return nil, err
+		}
+		objectGroup.CurrentObjectGroupRevision = *revision
+
+		return objectGroup.ToProtoModel(nil)
+	default:
+		return nil, fmt.Errorf("snapshots of resource %v are not supported", event.Resource)
+	}
+}
+
+// Reads the parents of a resource within tx and returns an event for it
+func readResourceEvent(tx *gorm.DB, resourceType v1storagemodels.Resource, resourceID uuid.UUID, updateType v1notificationservices.EventNotificationMessage_UpdateType) (*models.OutboxEvent, error) {
+	switch resourceType {
+	case v1storagemodels.Resource_RESOURCE_PROJECT:
+		return models.NewProjectEvent(resourceID, updateType), nil
+	case v1storagemodels.Resource_RESOURCE_DATASET:
+		dataset := &models.Dataset{}
+		dataset.ID = resourceID
+		if err := tx.Select("id", "project_id").First(dataset).Error; err != nil {
+			return nil, err
+		}
+
+		return models.NewDatasetEvent(dataset.ProjectID, dataset.ID, updateType), nil
+	case v1storagemodels.Resource_RESOURCE_DATASET_VERSION:
+		version := &models.DatasetVersion{}
+		version.ID = resourceID
+		if err := tx.Select("id", "project_id", "dataset_id").First(version).Error; err != nil {
+			return nil, err
+		}
+
+		return models.NewDatasetVersionEvent(version.ProjectID, version.DatasetID, version.ID, updateType), nil
+	case v1storagemodels.Resource_RESOURCE_OBJECT_GROUP:
+		objectGroup := &models.ObjectGroup{}
+		objectGroup.ID = resourceID
+		if err := tx.Select("id", "project_id", "dataset_id").First(objectGroup).Error; err != nil {
+			return nil, err
+		}
+
+		return models.NewObjectGroupEvent(objectGroup.ProjectID, objectGroup.DatasetID, objectGroup.ID, updateType), nil
+	case v1storagemodels.Resource_RESOURCE_OBJECT:
+		object := &models.Object{}
+		object.ID = resourceID
+		if err := tx.Select("id", "project_id", "dataset_id").First(object).Error; err != nil {
+			return nil, err
+		}
+
+		return models.NewObjectEvent(object.ProjectID, object.DatasetID, object.ID, updateType), nil
+	default:
+		return nil, fmt.Errorf("events of resource %v are not supported", resourceType.String())
+	}
+}
+
+// Stores the labels of the resource of an event, the labels of deleted resources are stored as well
+// Object group events with a revision change store the labels of the new revision.
+func writeEventLabels(tx *gorm.DB, event *models.OutboxEvent) error {
+	if event.Resource == models.EventResourceUser || event.Resource == models.EventResourceAPIToken {
+		return nil
+	}
+
+	var labels []models.Label
+	var err error
+	if event.RevisionID != uuid.Nil {
+		labels, err = readRevisionLabels(tx, event.RevisionID)
+	} else {
+		labels, err = readResourceLabels(tx.Unscoped(), event.ResourceEnum(), event.ResourceID)
+	}
+	if err != nil {
+		return err
+	}
+
+	event.WithLabels(labels)
+
+	return nil
+}
+
+// Returns the labels of a resource within tx, object groups return the labels of their current revision
+func readResourceLabels(tx *gorm.DB, resource v1storagemodels.Resource, resourceID uuid.UUID) ([]models.Label, error) {
+	var owner interface{}
+
+	switch resource {
+	case v1storagemodels.Resource_RESOURCE_PROJECT:
+		project := &models.Project{}
+		project.ID = resourceID
+		owner = project
+	case v1storagemodels.Resource_RESOURCE_DATASET:
+		dataset := &models.Dataset{}
+		dataset.ID = resourceID
+		owner = dataset
+	case v1storagemodels.Resource_RESOURCE_DATASET_VERSION:
+		version := &models.DatasetVersion{}
+		version.ID = resourceID
+		owner = version
+	case v1storagemodels.Resource_RESOURCE_OBJECT:
+		object := &models.Object{}
+		object.ID = resourceID
+		owner = object
+	case v1storagemodels.Resource_RESOURCE_OBJECT_GROUP:
+		objectGroup := &models.ObjectGroup{}
+		objectGroup.ID = resourceID
+		if err := tx.Select("current_object_group_revision_id").First(objectGroup).Error; err != nil {
+			return nil, err
+		}
+
+		return readRevisionLabels(tx, objectGroup.CurrentObjectGroupRevisionID)
+	default:
+		return nil, fmt.Errorf("labels of resource %v are not supported", resource.String())
+	}
+
+	var labels []models.Label
+	err := tx.Model(owner).Association("Labels").Find(&labels)
+
+	return labels, err
+}
+
+// Returns the labels of an object group revision within tx
+func readRevisionLabels(tx *gorm.DB, revisionID uuid.UUID) ([]models.Label, error) {
+	revision := &models.ObjectGroupRevision{}
+	revision.ID = revisionID
+
+	var labels []models.Label
+	err := tx.Model(revision).Association("Labels").Find(&labels)
+
+	return labels, err
+}
+
+// ClaimPendingOutboxEvents Claims up to limit unpublished events that are due, ordered by their sequence number
+// Claimed events are not returned to other relays until the lease has expired, e.g. because the relay has been stopped.
+// The positions of the last published events of their resources are returned by the ResourceKey of the events.
+func (outbox *Outbox) ClaimPendingOutboxEvents(limit int, lease time.Duration) ([]*models.OutboxEvent, map[string]int64, error) {
+	var events []*models.OutboxEvent
+	publishedSequences := make(map[string]int64)
+
+	err := crdbgorm.ExecuteTx(context.Background(), outbox.DB, nil, func(tx *gorm.DB) error {
+		events = nil
+		now := time.Now()
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published = ? AND dead_lettered = ? AND next_attempt_at <= ?", false, false, now).
+			Order("sequence asc").
+			Limit(limit).
+			Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		var ids []uuid.UUID
+		var resources [][]interface{}
+		for _, event := range events {
+			ids = append(ids, event.ID)
+			resources = append(resources, []interface{}{event.Resource, event.ResourceID})
+		}
+
+		err = tx.Model(&models.OutboxEvent{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error
+		if err != nil {
+			return err
+		}
+
+		var outboxResources []*models.OutboxResource
+		err = tx.Where("(resource, resource_id) IN ?", resources).Find(&outboxResources).Error
+		if err != nil {
+			return err
+		}
+
+		for _, outboxResource := range outboxResources {
+			publishedSequences[outboxResource.Resource+"."+outboxResource.ResourceID.String()] = outboxResource.PublishedSequence
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, nil, err
+	}
+
+	return events, publishedSequences, nil
+}
+
+// ReleaseOutboxEvents Returns claimed events that have not been published to the pending events, they are due at nextAttempt
+func (outbox *Outbox) ReleaseOutboxEvents(eventIDs []uuid.UUID, nextAttempt time.Time) error {
+	if len(eventIDs) == 0 {
+		return nil
+	}
+
+	err := crdbgorm.ExecuteTx(context.Background(), outbox.DB, nil, func(tx *gorm.DB) error {
+		return tx.Model(&models.OutboxEvent{}).Where("id IN ? AND published = ?", eventIDs, false).Update("next_attempt_at", nextAttempt).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// MarkOutboxEventPublished Marks an event as published, the next event of its resource can be published afterwards
+func (outbox *Outbox) MarkOutboxEventPublished(event *models.OutboxEvent) error {
+	err := crdbgorm.ExecuteTx(context.Background(), outbox.DB, nil, func(tx *gorm.DB) error {
+		err := tx.Model(&models.OutboxEvent{}).Where("id = ?", event.ID).Updates(map[string]interface{}{
+			"published":    true,
+			"published_at": time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return advancePublishedSequence(tx, event)
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// MarkOutboxEventFailed Records a failed publish attempt, the event is retried at nextAttempt
+func (outbox *Outbox) MarkOutboxEventFailed(eventID uuid.UUID, nextAttempt time.Time, publishErr error) error {
+	err := crdbgorm.ExecuteTx(context.Background(), outbox.DB, nil, func(tx *gorm.DB) error {
+		return tx.Model(&models.OutboxEvent{}).Where("id = ?", eventID).Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": nextAttempt,
+			"last_error":      publishErr.Error(),
+		}).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeadLetterOutboxEvent Records the last failed publish attempt of an event, it is not retried anymore
+// The next event of its resource can be published afterwards.
+func (outbox *Outbox) DeadLetterOutboxEvent(event *models.OutboxEvent, publishErr error) error {
+	err := crdbgorm.ExecuteTx(context.Background(), outbox.DB, nil, func(tx *gorm.DB) error {
+		err := tx.Model(&models.OutboxEvent{}).Where("id = ?", event.ID).Updates(map[string]interface{}{
+			"attempts":         gorm.Expr("attempts + 1"),
+			"last_error":       publishErr.Error(),
+			"dead_lettered":    true,
+			"dead_lettered_at": time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return advancePublishedSequence(tx, event)
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func advancePublishedSequence(tx *gorm.DB, event *models.OutboxEvent) error {
+	return tx.Model(&models.OutboxResource{}).
+		Where("resource = ? AND resource_id = ? AND published_sequence < ?", event.Resource, event.ResourceID, event.ResourceSequence).
+		Update("published_sequence", event.ResourceSequence).Error
+}
+
+// DeletePublishedOutboxEvents Removes events that have been published or dead lettered before the provided time
+// The positions of resources without remaining events are removed as well.
+func (outbox *Outbox) DeletePublishedOutboxEvents(before time.Time) error {
+	err := crdbgorm.ExecuteTx(context.Background(), outbox.DB, nil, func(tx *gorm.DB) error {
+		err := tx.Unscoped().
+			Where("(published = ? AND published_at < ?) OR (dead_lettered = ? AND dead_lettered_at < ?)", true, before, true, before).
+			Delete(&models.OutboxEvent{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.
+			Where("published_sequence = last_sequence").
+			Where("NOT EXISTS (SELECT 1 FROM outbox_events WHERE outbox_events.resource = outbox_resources.resource AND outbox_events.resource_id = outbox_resources.resource_id)").
+			Delete(&models.OutboxResource{}).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetProjectOutboxEvents Returns up to limit events of a project that are still stored in the outbox, newest events first
+// If resourceID is not nil only the events of this resource are returned.
+func (outbox *Outbox) GetProjectOutboxEvents(projectID uuid.UUID, resourceID uuid.UUID, limit int) ([]*models.OutboxEvent, error) {
+	var events []*models.OutboxEvent
+
+	err := crdbgorm.ExecuteTx(context.Background(), outbox.DB, nil, func(tx *gorm.DB) error {
+		query := tx.Where("project_id = ?", projectID)
+		if resourceID != uuid.Nil {
+			query = query.Where("resource_id = ?", resourceID)
+		}
+
+		return query.Order("sequence desc").Limit(limit).Find(&events).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return events, nil
+}
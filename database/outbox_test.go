@@ -0,0 +1,48 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotConsumersMatch(t *testing.T) {
+	projectID := uuid.New()
+	datasetID := uuid.New()
+	otherDatasetID := uuid.New()
+	objectGroupID := uuid.New()
+
+	streamGroup := &models.StreamGroup{
+		ProjectID:        projectID,
+		ResourceID:       datasetID,
+		ResourceType:     v1notificationservices.CreateEventStreamingGroupRequest_EVENT_RESOURCES_DATASET_RESOURCE.String(),
+		Resources:        "RESOURCE_OBJECT_GROUP",
+		LabelKey:         "pipeline",
+		IncludeSnapshots: true,
+	}
+	consumers := &snapshotConsumers{streamGroups: []*models.StreamGroup{streamGroup}}
+
+	labels := []models.Label{{Key: "pipeline", Value: "ingest"}}
+
+	// Only the events the group receives get a snapshot
+	assert.True(t, consumers.matches(models.NewObjectGroupEvent(projectID, datasetID, objectGroupID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_AVAILABLE).WithLabels(labels)))
+	assert.False(t, consumers.matches(models.NewObjectGroupEvent(projectID, datasetID, objectGroupID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_AVAILABLE)))
+	assert.False(t, consumers.matches(models.NewObjectGroupEvent(projectID, otherDatasetID, objectGroupID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_AVAILABLE).WithLabels(labels)))
+	assert.False(t, consumers.matches(models.NewDatasetEvent(projectID, datasetID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_UPDATED).WithLabels(labels)))
+
+	// Project groups receive the events of all datasets
+	streamGroup.ResourceID = projectID
+	streamGroup.ResourceType = v1notificationservices.CreateEventStreamingGroupRequest_EVENT_RESOURCES_PROJECT_RESOURCE.String()
+	assert.True(t, consumers.matches(models.NewObjectGroupEvent(projectID, otherDatasetID, objectGroupID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_AVAILABLE).WithLabels(labels)))
+
+	subscription := &models.WebhookSubscription{ProjectID: projectID, UpdateTypes: "UPDATE_TYPE_CREATED", Enabled: true, IncludeSnapshots: true}
+	consumers = &snapshotConsumers{subscriptions: []*models.WebhookSubscription{subscription}}
+	assert.True(t, consumers.matches(models.NewDatasetEvent(projectID, datasetID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED)))
+	assert.False(t, consumers.matches(models.NewDatasetEvent(projectID, datasetID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_UPDATED)))
+	assert.False(t, consumers.matches(models.NewDatasetEvent(uuid.New(), datasetID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED)))
+
+	assert.False(t, (&snapshotConsumers{}).matches(models.NewProjectEvent(projectID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED)))
+}
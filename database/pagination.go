@@ -0,0 +1,271 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// Sort keys of list requests, a leading "-" sorts in descending order, e.g. -created
+const (
+	SortByID        = "id"
+	SortByCreated   = "created"
+	SortByName      = "name"
+	SortByRevision  = "revision"
+	SortByGenerated = "generated"
+)
+
+// Page Selects a page of a list read
+// Pages are read with keyset pagination: the cursor stores the sort value and the id of the last result of the
+// previous page, the next page starts after this position. Results with the same sort value are ordered by id.
+type Page struct {
+	// Maximum number of results, all results are returned if it is 0
+	Size int
+	// Cursor of the previous page, a plain uuid is accepted as cursor of a page sorted by id
+	Cursor string
+	// Sort key, defaults to id
+	Sort string
+	// Status enum names, e.g. STATUS_AVAILABLE, only results with one of the statuses are returned
+	Statuses []string
+}
+
+// Position of the last result of a page, encoded as opaque string
+type pageCursor struct {
+	Sort  string    `json:"s"`
+	Value string    `json:"v,omitempty"`
+	ID    uuid.UUID `json:"i"`
+}
+
+// A sort key of a table
+type sortColumn struct {
+	// Column including the table name
+	Column string
+	// Field of the model that holds the value of the column
+	Field string
+}
+
+// Columns of the sortable tables, every table can be sorted by id as well
+var (
+	projectSortColumns = map[string]sortColumn{
+		SortByCreated: {Column: "projects.created_at", Field: "CreatedAt"},
+		SortByName:    {Column: "projects.name", Field: "Name"},
+	}
+	datasetSortColumns = map[string]sortColumn{
+		SortByCreated: {Column: "datasets.created_at", Field: "CreatedAt"},
+		SortByName:    {Column: "datasets.name", Field: "Name"},
+	}
+	datasetVersionSortColumns = map[string]sortColumn{
+		SortByCreated: {Column: "dataset_versions.created_at", Field: "CreatedAt"},
+		SortByName:    {Column: "dataset_versions.name", Field: "Name"},
+	}
+	objectSortColumns = map[string]sortColumn{
+		SortByCreated: {Column: "objects.created_at", Field: "CreatedAt"},
+		SortByName:    {Column: "objects.filename", Field: "Filename"},
+	}
+	objectGroupSortColumns = map[string]sortColumn{
+		SortByCreated:  {Column: "object_groups.created_at", Field: "CreatedAt"},
+		SortByRevision: {Column: "object_groups.current_revision_count", Field: "CurrentRevisionCount"},
+	}
+	objectGroupRevisionSortColumns = map[string]sortColumn{
+		SortByCreated:   {Column: "object_group_revisions.created_at", Field: "CreatedAt"},
+		SortByName:      {Column: "object_group_revisions.name", Field: "Name"},
+		SortByRevision:  {Column: "object_group_revisions.revision_number", Field: "RevisionNumber"},
+		SortByGenerated: {Column: "object_group_revisions.generated", Field: "Generated"},
+	}
+	apiTokenSortColumns = map[string]sortColumn{
+		SortByCreated: {Column: "api_tokens.created_at", Field: "CreatedAt"},
+	}
+)
+
+// Returns the sort of the page including the direction, id if no sort is requested
+func (page *Page) sort() string {
+	if page.Sort == "" {
+		return SortByID
+	}
+
+	return page.Sort
+}
+
+// Returns the sort key without direction and whether the page is sorted in descending order
+func (page *Page) sortKey() (string, bool) {
+	sort := page.sort()
+	if strings.HasPrefix(sort, "-") {
+		return strings.TrimPrefix(sort, "-"), true
+	}
+
+	return sort, false
+}
+
+// Returns the column of the sort key of the page
+func (page *Page) sortColumn(table string, columns map[string]sortColumn) (sortColumn, error) {
+	key, _ := page.sortKey()
+	if key == SortByID {
+		return sortColumn{Column: table + ".id", Field: "ID"}, nil
+	}
+
+	column, ok := columns[key]
+	if !ok {
+		return sortColumn{}, status.Errorf(codes.InvalidArgument, "%v can not be sorted by %v", table, key)
+	}
+
+	return column, nil
+}
+
+// Applies the status filter, the position of the cursor, the order and the limit of the page to a query of table
+// The limit is one result larger than the page, the additional result is removed by next.
+// Tables without status column have to be passed with an empty statusColumn.
+func (page *Page) apply(query *gorm.DB, table string, columns map[string]sortColumn, statusColumn string) (*gorm.DB, error) {
+	if page == nil {
+		return query, nil
+	}
+
+	column, err := page.sortColumn(table, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(page.Statuses) > 0 {
+		if statusColumn == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "%v can not be filtered by status", table)
+		}
+		for _, value := range page.Statuses {
+			if _, ok := v1storagemodels.Status_value[value]; !ok {
+				return nil, status.Errorf(codes.InvalidArgument, "unknown status %v", value)
+			}
+		}
+		query = query.Where(fmt.Sprintf("%v IN ?", statusColumn), page.Statuses)
+	}
+
+	key, descending := page.sortKey()
+	direction, comparison := "asc", ">"
+	if descending {
+		direction, comparison = "desc", "<"
+	}
+
+	if page.Cursor != "" {
+		cursor, err := decodeCursor(page.Cursor)
+		if err != nil {
+			log.Debugln(err)
+			return nil, status.Error(codes.InvalidArgument, "could not parse page cursor")
+		}
+		if cursor.Sort != page.sort() {
+			return nil, status.Error(codes.InvalidArgument, "page cursor belongs to a different sort order")
+		}
+
+		if key == SortByID {
+			query = query.Where(fmt.Sprintf("%v %v ?", column.Column, comparison), cursor.ID)
+		} else {
+			value, err := cursorValue(key, cursor.Value)
+			if err != nil {
+				log.Debugln(err)
+				return nil, status.Error(codes.InvalidArgument, "could not parse page cursor")
+			}
+			query = query.Where(fmt.Sprintf("(%v, %v.id) %v (?, ?)", column.Column, table, comparison), value, cursor.ID)
+		}
+	}
+
+	query = query.Order(fmt.Sprintf("%v %v", column.Column, direction))
+	if key != SortByID {
+		query = query.Order(fmt.Sprintf("%v.id %v", table, direction))
+	}
+
+	if page.Size > 0 {
+		query = query.Limit(page.Size + 1)
+	}
+
+	return query, nil
+}
+
+// Removes the additional result that apply reads from results, a pointer to a slice of models, and returns the cursor
+// of the next page. The cursor is empty if there are no further results.
+func (page *Page) next(results interface{}, table string, columns map[string]sortColumn) (string, error) {
+	if page == nil || page.Size <= 0 {
+		return "", nil
+	}
+
+	slice := reflect.ValueOf(results).Elem()
+	if slice.Len() <= page.Size {
+		return "", nil
+	}
+	slice.Set(slice.Slice(0, page.Size))
+
+	column, err := page.sortColumn(table, columns)
+	if err != nil {
+		return "", err
+	}
+
+	last := reflect.Indirect(slice.Index(page.Size - 1))
+	cursor := &pageCursor{
+		Sort: page.sort(),
+		ID:   last.FieldByName("ID").Interface().(uuid.UUID),
+	}
+
+	key, _ := page.sortKey()
+	if key != SortByID {
+		switch value := last.FieldByName(column.Field).Interface().(type) {
+		case time.Time:
+			cursor.Value = value.UTC().Format(time.RFC3339Nano)
+		case uint64:
+			cursor.Value = strconv.FormatUint(value, 10)
+		case string:
+			cursor.Value = value
+		default:
+			return "", fmt.Errorf("can not encode %T as page cursor", value)
+		}
+	}
+
+	return encodeCursor(cursor)
+}
+
+func encodeCursor(cursor *pageCursor) (string, error) {
+	encoded, err := json.Marshal(cursor)
+	if err != nil {
+		log.Errorln(err.Error())
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+// Decodes a cursor, a plain uuid is the cursor of a page sorted by id as returned by the former LastUuid page requests
+func decodeCursor(encoded string) (*pageCursor, error) {
+	if id, err := uuid.Parse(encoded); err == nil {
+		return &pageCursor{Sort: SortByID, ID: id}, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := &pageCursor{}
+	err = json.Unmarshal(decoded, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return cursor, nil
+}
+
+// Converts the value of a cursor into the type of the column of the sort key
+func cursorValue(key string, value string) (interface{}, error) {
+	switch key {
+	case SortByCreated, SortByGenerated:
+		return time.Parse(time.RFC3339Nano, value)
+	case SortByRevision:
+		return strconv.ParseUint(value, 10, 64)
+	default:
+		return value, nil
+	}
+}
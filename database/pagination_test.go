@@ -0,0 +1,52 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageNext(t *testing.T) {
+	created := time.Date(2021, 6, 1, 12, 30, 0, 123456000, time.UTC)
+
+	revisions := make([]*models.ObjectGroupRevision, 3)
+	for i := range revisions {
+		revisions[i] = &models.ObjectGroupRevision{RevisionNumber: uint64(i + 1)}
+		revisions[i].ID = uuid.New()
+		revisions[i].CreatedAt = created.Add(time.Duration(i) * time.Second)
+	}
+
+	page := &Page{Size: 2, Sort: "-created"}
+	next, err := page.next(&revisions, "object_group_revisions", objectGroupRevisionSortColumns)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+
+	cursor, err := decodeCursor(next)
+	assert.NoError(t, err)
+	assert.Equal(t, "-created", cursor.Sort)
+	assert.Equal(t, revisions[1].ID, cursor.ID)
+
+	value, err := cursorValue(SortByCreated, cursor.Value)
+	assert.NoError(t, err)
+	assert.True(t, revisions[1].CreatedAt.Equal(value.(time.Time)))
+
+	// The last page has no next cursor
+	next, err = page.next(&revisions, "object_group_revisions", objectGroupRevisionSortColumns)
+	assert.NoError(t, err)
+	assert.Empty(t, next)
+
+	// Plain uuids are cursors of pages sorted by id
+	id := uuid.New()
+	cursor, err = decodeCursor(id.String())
+	assert.NoError(t, err)
+	assert.Equal(t, &pageCursor{Sort: SortByID, ID: id}, cursor)
+
+	_, err = decodeCursor("not a cursor")
+	assert.Error(t, err)
+
+	_, err = (&Page{Sort: SortByGenerated}).sortColumn("datasets", datasetSortColumns)
+	assert.Error(t, err)
+}
@@ -2,7 +2,6 @@ package database
 
 import (
 	"context"
-	"errors"
 	"time"
 
 	"github.com/ScienceObjectsDB/CORE-Server/models"
@@ -146,251 +145,121 @@ func (read *Read) GetObjectGroup(objectGroupID uuid.UUID) (*models.ObjectGroup,
 	return objectGroup, nil
 }
 
-// Get all datasets of the specific Project.
-func (read *Read) GetProjectDatasets(projectID uuid.UUID) ([]*models.Dataset, error) {
+// Get a page of the datasets of the specific Project.
+func (read *Read) GetProjectDatasets(projectID uuid.UUID, page *Page) ([]*models.Dataset, string, error) {
 	datasets := make([]*models.Dataset, 0)
 
 	err := crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
-		return tx.
+		find, err := page.apply(tx.
 			Preload("Project").
 			Preload("Labels").
 			Preload("MetaObjects").
-			Where("project_id = ?", projectID).
-			Find(&datasets).Error
+			Where("project_id = ?", projectID), "datasets", datasetSortColumns, "datasets.status")
+		if err != nil {
+			return err
+		}
+
+		return find.Find(&datasets).Error
 	})
 
 	if err != nil {
 		log.Println(err.Error())
-		return nil, err
-	}
-
-	return datasets, nil
-}
-
-func (read *Read) GetDatasetObjects(request *v1servicemodels.GetDatasetObjectsRequest) ([]*models.Object, error) {
-	var objects []*models.Object
-	var err error
-
-	if request.LabelFilter == nil || len(request.LabelFilter.Labels) == 0 {
-		objects, err = read.getDatasetObjects(request)
-		if err != nil {
-			log.Errorln(err.Error())
-			return nil, err
-		}
-	} else {
-		objects, err = read.getDatasetObjectsWithLabelFilter(request)
-		if err != nil {
-			log.Errorln(err.Error())
-			return nil, err
-		}
+		return nil, "", err
 	}
 
-	return objects, nil
-}
-
-func (read *Read) getDatasetObjects(request *v1servicemodels.GetDatasetObjectsRequest) ([]*models.Object, error) {
-	objects := make([]*models.Object, 0)
-
-	datasetUUID, err := uuid.Parse(request.GetId())
+	next, err := page.next(&datasets, "datasets", datasetSortColumns)
 	if err != nil {
-		log.Debugln(err)
-		return nil, status.Error(codes.InvalidArgument, "could not parse dataset id")
-	}
-
-	preload := read.DB.
-		Preload("Labels").
-		Preload("Locations").
-		Preload("DefaultLocation")
-
-	crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
-		if request.PageRequest == nil || request.PageRequest.PageSize == 0 {
-			return preload.Where("dataset_id = ?", datasetUUID).Find(&objects).Error
-
-		} else if request.PageRequest != nil && request.PageRequest.LastUuid == "" {
-			return preload.
-				Where("dataset_id = ?", datasetUUID).
-				Order("id asc").
-				Limit(int(request.PageRequest.PageSize)).
-				Find(&objects).Error
-
-		} else if request.PageRequest != nil && request.PageRequest.LastUuid != "" && request.PageRequest.PageSize > 0 {
-			return preload.
-				Where("dataset_id = ? AND id > ?", datasetUUID, request.PageRequest.LastUuid).
-				Order("id asc").
-				Limit(int(request.PageRequest.PageSize)).
-				Find(&objects).Error
-
-		} else {
-			log.Info("could not parse request")
-			return errors.New("could not parse request")
-		}
-	})
+		return nil, "", err
+	}
 
-	return objects, nil
+	return datasets, next, nil
 }
 
-func (read *Read) getDatasetObjectsWithLabelFilter(request *v1servicemodels.GetDatasetObjectsRequest) ([]*models.Object, error) {
+// Get a page of the objects of a Dataset, objects can be filtered by their labels.
+func (read *Read) GetDatasetObjects(request *v1servicemodels.GetDatasetObjectsRequest, page *Page) ([]*models.Object, string, error) {
 	objects := make([]*models.Object, 0)
 
 	datasetUUID, err := uuid.Parse(request.GetId())
 	if err != nil {
 		log.Debugln(err)
-		return nil, status.Error(codes.InvalidArgument, "could not parse dataset id")
+		return nil, "", status.Error(codes.InvalidArgument, "could not parse dataset id")
 	}
 
-	var labels [][]interface{}
-
-	for _, requestLabel := range request.LabelFilter.Labels {
-		labels = append(labels, []interface{}{requestLabel.Key, requestLabel.Value})
-	}
-
-	if len(labels) == 0 {
-		labels = nil
-	}
-
-	var objectIDs []ID
-
-	crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
-
-		if request.PageRequest == nil || request.PageRequest.PageSize == 0 {
-			if err := tx.Model(&models.Object{}).
-				Select("objects.id").
-				Joins("inner join object_labels on objects.id = object_labels.object_id").
-				Joins("inner join labels on object_labels.label_id = labels.id").
-				Where("dataset_id = ? AND (key, value) in (?)", datasetUUID, labels).
-				Group("objects.id").Having("COUNT(objects.id) = ?", len(request.LabelFilter.Labels)).
-				Find(&objectIDs).Error; err != nil {
-				log.Errorln(err.Error())
-				return err
-			}
-
-			ids := make([]uuid.UUID, len(objectIDs))
-			for i, id := range objectIDs {
-				ids[i] = id.ID
-			}
-
-			if err := tx.Model(&models.Object{}).
-				Preload("Labels").
-				Preload("Locations").
-				Preload("DefaultLocation").
-				Where("id in ?", ids).
-				Find(&objects).Error; err != nil {
-				log.Errorln(err.Error())
-				return err
-			}
-
-		} else if request.PageRequest != nil && request.PageRequest.LastUuid == "" {
-			if err := tx.Model(&models.Object{}).
-				Select("objects.id").
-				Joins("inner join object_labels on objects.id = object_labels.object_id").
-				Joins("inner join labels on object_labels.label_id = labels.id").
-				Where("dataset_id = ? AND (key, value) in (?)", datasetUUID, labels).
-				Group("objects.id").Having("COUNT(objects.id) = ?", len(request.LabelFilter.Labels)).
-				Order("id asc").
-				Limit(int(request.PageRequest.PageSize)).
-				Find(&objectIDs).Error; err != nil {
-				log.Errorln(err.Error())
-				return err
-			}
-
-			ids := make([]uuid.UUID, len(objectIDs))
-			for i, id := range objectIDs {
-				ids[i] = id.ID
-			}
+	err = crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
+		query := tx.
+			Preload("Labels").
+			Preload("Locations").
+			Preload("DefaultLocation").
+			Where("objects.dataset_id = ?", datasetUUID)
 
-			if err := tx.Model(&models.Object{}).
-				Preload("Labels").
-				Preload("Locations").
-				Preload("DefaultLocation").
-				Where("id in ?", ids).
-				Find(objects).Error; err != nil {
-				log.Errorln(err.Error())
-				return err
+		if request.LabelFilter != nil && len(request.LabelFilter.Labels) > 0 {
+			var labels [][]interface{}
+			for _, requestLabel := range request.LabelFilter.Labels {
+				labels = append(labels, []interface{}{requestLabel.Key, requestLabel.Value})
 			}
 
-		} else if request.PageRequest != nil && request.PageRequest.LastUuid != "" && request.PageRequest.PageSize > 0 {
-			if err := tx.Model(&models.Object{}).
+			labelFilter := tx.Model(&models.Object{}).
 				Select("objects.id").
 				Joins("inner join object_labels on objects.id = object_labels.object_id").
 				Joins("inner join labels on object_labels.label_id = labels.id").
-				Where("dataset_id = ? AND (key, value) in (?) AND objects.id > ?", datasetUUID, labels, request.PageRequest.LastUuid).
-				Group("objects.id").Having("COUNT(objects.id) = ?", len(request.LabelFilter.Labels)).
-				Order("id asc").
-				Limit(int(request.PageRequest.PageSize)).
-				Find(&objectIDs).Error; err != nil {
-				log.Errorln(err.Error())
-				return err
-			}
-
-			ids := make([]uuid.UUID, len(objectIDs))
-			for i, id := range objectIDs {
-				ids[i] = id.ID
-			}
+				Where("objects.dataset_id = ? AND (key, value) in (?)", datasetUUID, labels).
+				Group("objects.id").Having("COUNT(objects.id) = ?", len(request.LabelFilter.Labels))
 
-			if err := tx.Model(&models.Object{}).
-				Preload("Labels").
-				Preload("Locations").
-				Preload("DefaultLocation").
-				Where("id in ?", ids).
-				Find(objects).Error; err != nil {
-				log.Errorln(err.Error())
-				return err
-			}
+			query = query.Where("objects.id IN (?)", labelFilter)
+		}
 
-		} else {
-			log.Info("could not parse request")
-			return errors.New("could not parse request")
+		find, err := page.apply(query, "objects", objectSortColumns, "objects.status")
+		if err != nil {
+			return err
 		}
 
-		return nil
+		return find.Find(&objects).Error
 	})
 
-	return objects, nil
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, "", err
+	}
+
+	next, err := page.next(&objects, "objects", objectSortColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return objects, next, nil
 }
 
-// Get all object groups of the specific Dataset.
-func (read *Read) GetDatasetObjectGroups(datasetID uuid.UUID, page *v1storagemodels.PageRequest) ([]*models.ObjectGroup, error) {
+// Get a page of the object groups of the specific Dataset.
+func (read *Read) GetDatasetObjectGroups(datasetID uuid.UUID, page *Page) ([]*models.ObjectGroup, string, error) {
 	objectGroups := make([]*models.ObjectGroup, 0)
 
 	err := crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
-		preload := tx.
+		find, err := page.apply(tx.
 			Preload("Project").
 			Preload("Dataset").
 			Preload("CurrentObjectGroupRevision").
 			Preload("CurrentObjectGroupRevision.Labels").
 			Preload("CurrentObjectGroupRevision.DataObjects").
-			Preload("CurrentObjectGroupRevision.MetaObjects")
-
-		if page == nil || page.PageSize == 0 {
-			return preload.
-				Where("dataset_id = ?", datasetID).
-				Find(&objectGroups).Error
-
-		} else if page != nil && page.LastUuid == "" {
-			return preload.
-				Where("dataset_id = ?", datasetID).
-				Order("id asc").
-				Limit(int(page.PageSize)).
-				Find(&objectGroups).Error
-
-		} else if page != nil && page.LastUuid != "" && page.PageSize > 0 {
-			return preload.
-				Where("dataset_id = ? AND id > ?", datasetID, page.LastUuid).
-				Order("id asc").
-				Limit(int(page.PageSize)).
-				Find(&objectGroups).Error
-
-		} else {
-			log.Info("could not parse request")
-			return errors.New("could not parse request")
+			Preload("CurrentObjectGroupRevision.MetaObjects").
+			Where("object_groups.dataset_id = ?", datasetID), "object_groups", objectGroupSortColumns, "object_groups.status")
+		if err != nil {
+			return err
 		}
+
+		return find.Find(&objectGroups).Error
 	})
 
 	if err != nil {
 		log.Println(err.Error())
-		return nil, err
+		return nil, "", err
+	}
+
+	next, err := page.next(&objectGroups, "object_groups", objectGroupSortColumns)
+	if err != nil {
+		return nil, "", err
 	}
-	return objectGroups, nil
+
+	return objectGroups, next, nil
 }
 
 // Get the specific Object.
@@ -438,60 +307,67 @@ func (read *Read) GetDatasetVersion(versionID uuid.UUID) (*models.DatasetVersion
 	return datasetVersion, nil
 }
 
-// Get all dataset versions of the specific Dataset.
-func (read *Read) GetDatasetVersions(datasetID uuid.UUID) ([]models.DatasetVersion, error) {
+// Get a page of the dataset versions of the specific Dataset.
+func (read *Read) GetDatasetVersions(datasetID uuid.UUID, page *Page) ([]models.DatasetVersion, string, error) {
 	var datasetVersions []models.DatasetVersion
 
 	err := crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
-		return tx.
+		find, err := page.apply(tx.
 			Preload("Project").
 			Preload("Dataset").
 			Preload("Labels").
 			// ObjectGroupRevisions should be fetched on demand
-			Where("dataset_id = ?", datasetID).
-			Find(&datasetVersions).Error
+			Where("dataset_versions.dataset_id = ?", datasetID), "dataset_versions", datasetVersionSortColumns, "dataset_versions.status")
+		if err != nil {
+			return err
+		}
+
+		return find.Find(&datasetVersions).Error
 	})
 
 	if err != nil {
 		log.Println(err.Error())
-		return nil, err
+		return nil, "", err
 	}
 
-	return datasetVersions, nil
+	next, err := page.next(&datasetVersions, "dataset_versions", datasetVersionSortColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return datasetVersions, next, nil
 }
 
-// Get all API tokens registered for the user with the specific OAuth2ID.
-func (read *Read) GetAPIToken(userOAuth2ID uuid.UUID) ([]models.APIToken, error) {
-	user := &models.User{}
+// Get a page of the API tokens registered for the user with the specific OAuth2ID.
+func (read *Read) GetAPIToken(userOAuth2ID uuid.UUID, page *Page) ([]models.APIToken, string, error) {
 	token := make([]models.APIToken, 0)
 
 	err := crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
-		err := tx.
+		find, err := page.apply(tx.
 			Preload("Project").
-			Where("user_oauth2_id = ?", userOAuth2ID).
-			Find(user).Error
-
+			Where("api_tokens.user_uuid = ?", userOAuth2ID), "api_tokens", apiTokenSortColumns, "")
 		if err != nil {
-			log.Println(err.Error())
 			return err
 		}
 
-		return tx.
-			Preload("Project").
-			Where("user_uuid = ?", userOAuth2ID).
-			Find(&token).Error
+		return find.Find(&token).Error
 	})
 
 	if err != nil {
 		log.Println(err.Error())
-		return nil, err
+		return nil, "", err
+	}
+
+	next, err := page.next(&token, "api_tokens", apiTokenSortColumns)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return token, nil
+	return token, next, nil
 }
 
-// Get the specific DatasetVersion including the full ObjectGroupRevisions.
-func (read *Read) GetDatasetVersionWithObjectGroups(datasetVersionID uuid.UUID, page *v1storagemodels.PageRequest) (*models.DatasetVersion, error) {
+// Get the specific DatasetVersion including a page of its full ObjectGroupRevisions.
+func (read *Read) GetDatasetVersionWithObjectGroups(datasetVersionID uuid.UUID, page *Page) (*models.DatasetVersion, string, error) {
 	version := &models.DatasetVersion{}
 	version.ID = datasetVersionID
 
@@ -505,7 +381,7 @@ func (read *Read) GetDatasetVersionWithObjectGroups(datasetVersionID uuid.UUID,
 			return err
 		}
 
-		preload := tx.
+		find, err := page.apply(tx.
 			Preload("Labels").
 			Preload("DataObjects").
 			Preload("DataObjects.Labels").
@@ -515,37 +391,24 @@ func (read *Read) GetDatasetVersionWithObjectGroups(datasetVersionID uuid.UUID,
 			Preload("MetaObjects.Labels").
 			Preload("MetaObjects.Locations").
 			Preload("MetaObjects.DefaultLocation").
-			Joins("INNER JOIN dataset_version_object_group_revisions on dataset_version_object_group_revisions.object_group_revision_id=object_group_revisions.id")
-
-		if page == nil || page.PageSize == 0 {
-			return preload.
-				Where("dataset_version_object_group_revisions.dataset_version_id = ?", datasetVersionID).
-				Find(&objectGroupsRevisionRefs).Error
-
-		} else if page != nil && page.LastUuid == "" {
-			return preload.
-				Where("dataset_version_object_group_revisions.dataset_version_id = ?", datasetVersionID).
-				Order("id asc").
-				Limit(int(page.PageSize)).
-				Find(&objectGroupsRevisionRefs).Error
-
-		} else if page != nil && page.LastUuid != "" && page.PageSize > 0 {
-			return preload.
-				Where("dataset_version_object_group_revisions.dataset_version_id = ? AND id > ?", datasetVersionID, page.LastUuid).
-				Order("id asc").
-				Limit(int(page.PageSize)).
-				Find(&objectGroupsRevisionRefs).Error
-
-		} else {
-			log.Info("could not parse request")
-			return errors.New("could not parse request")
+			Joins("INNER JOIN dataset_version_object_group_revisions on dataset_version_object_group_revisions.object_group_revision_id=object_group_revisions.id").
+			Where("dataset_version_object_group_revisions.dataset_version_id = ?", datasetVersionID),
+			"object_group_revisions", objectGroupRevisionSortColumns, "object_group_revisions.status")
+		if err != nil {
+			return err
 		}
 
+		return find.Find(&objectGroupsRevisionRefs).Error
 	})
 
 	if err != nil {
 		log.Errorln(err.Error())
-		return nil, err
+		return nil, "", err
+	}
+
+	next, err := page.next(&objectGroupsRevisionRefs, "object_group_revisions", objectGroupRevisionSortColumns)
+	if err != nil {
+		return nil, "", err
 	}
 
 	objectGroupRevisions := make([]models.ObjectGroupRevision, len(objectGroupsRevisionRefs))
@@ -555,31 +418,35 @@ func (read *Read) GetDatasetVersionWithObjectGroups(datasetVersionID uuid.UUID,
 
 	version.ObjectGroupRevisions = objectGroupRevisions
 
-	return version, nil
+	return version, next, nil
 }
 
-//Get all projects the User is assigned to.
-func (read *Read) GetUserProjects(userIDOauth2 string) ([]*models.Project, error) {
-	var users []*models.User
+//Get a page of the projects the User is assigned to.
+func (read *Read) GetUserProjects(userIDOauth2 string, page *Page) ([]*models.Project, string, error) {
+	projects := make([]*models.Project, 0)
 
 	err := crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
-		return tx.
-			Preload("Project").
-			Where("user_oauth2_id = ?", userIDOauth2).
-			Find(&users).Error
+		find, err := page.apply(tx.
+			Joins("INNER JOIN users ON users.project_id = projects.id").
+			Where("users.user_oauth2_id = ? AND users.deleted_at IS NULL", userIDOauth2), "projects", projectSortColumns, "projects.status")
+		if err != nil {
+			return err
+		}
+
+		return find.Find(&projects).Error
 	})
 
 	if err != nil {
 		log.Println(err.Error())
-		return nil, err
+		return nil, "", err
 	}
 
-	var projects []*models.Project
-	for _, user := range users {
-		projects = append(projects, &user.Project)
+	next, err := page.next(&projects, "projects", projectSortColumns)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return projects, nil
+	return projects, next, nil
 }
 
 // Get all users assigned to the specific Project.
@@ -801,9 +668,9 @@ func (read *Read) GetAllObjectGroupRevisionMetaObjects(revisionID uuid.UUID) ([]
 	return objects, nil
 }
 
-// Get all object group revisions of the specific Dataset which were generated between
+// Get a page of the object group revisions of the specific Dataset which were generated between
 // the provided start and end date. The start and end date is inclusive.
-func (read *Read) GetObjectGroupRevisionsInDateRange(datasetID uuid.UUID, startDate time.Time, endDate time.Time) ([]*models.ObjectGroupRevision, error) {
+func (read *Read) GetObjectGroupRevisionsInDateRange(datasetID uuid.UUID, startDate time.Time, endDate time.Time, page *Page) ([]*models.ObjectGroupRevision, string, error) {
 	var objectGroupRevisions []*models.ObjectGroupRevision
 
 	err := crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
@@ -815,17 +682,27 @@ func (read *Read) GetObjectGroupRevisionsInDateRange(datasetID uuid.UUID, startD
 			Preload("DataObjects.DefaultLocation").
 			Preload("MetaObjects")
 
-		return preloadConf.
-			Where("dataset_id = ? AND generated BETWEEN ? AND ?", datasetID, startDate, endDate).
-			Find(&objectGroupRevisions).Error
+		find, err := page.apply(preloadConf.
+			Where("object_group_revisions.dataset_id = ? AND object_group_revisions.generated BETWEEN ? AND ?", datasetID, startDate, endDate),
+			"object_group_revisions", objectGroupRevisionSortColumns, "object_group_revisions.status")
+		if err != nil {
+			return err
+		}
+
+		return find.Find(&objectGroupRevisions).Error
 	})
 
 	if err != nil {
 		log.Error(err.Error())
-		return nil, err
+		return nil, "", err
+	}
+
+	next, err := page.next(&objectGroupRevisions, "object_group_revisions", objectGroupRevisionSortColumns)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return objectGroupRevisions, nil
+	return objectGroupRevisions, next, nil
 }
 
 // Get multiple Objects specified by the provided IDs.
@@ -866,9 +743,11 @@ func (read *Read) GetDatasetObjectGroupsBatches(datasetID uuid.UUID, objectGroup
 			Preload("CurrentObjectGroupRevision").
 			Preload("CurrentObjectGroupRevision.Labels").
 			Preload("CurrentObjectGroupRevision.DataObjects").
+			Preload("CurrentObjectGroupRevision.DataObjects.Labels").
 			Preload("CurrentObjectGroupRevision.DataObjects.Locations").
 			Preload("CurrentObjectGroupRevision.DataObjects.DefaultLocation").
 			Preload("CurrentObjectGroupRevision.MetaObjects").
+			Preload("CurrentObjectGroupRevision.MetaObjects.Labels").
 			Preload("CurrentObjectGroupRevision.MetaObjects.Locations").
 			Preload("CurrentObjectGroupRevision.MetaObjects.DefaultLocation").
 			Where("dataset_id = ?", datasetID).
@@ -902,9 +781,11 @@ func (read *Read) GetObjectGroupsInDateRangeBatches(datasetID uuid.UUID, startDa
 			Preload("CurrentObjectGroupRevision").
 			Preload("CurrentObjectGroupRevision.Labels").
 			Preload("CurrentObjectGroupRevision.DataObjects").
+			Preload("CurrentObjectGroupRevision.DataObjects.Labels").
 			Preload("CurrentObjectGroupRevision.DataObjects.Locations").
 			Preload("CurrentObjectGroupRevision.DataObjects.DefaultLocation").
 			Preload("CurrentObjectGroupRevision.MetaObjects").
+			Preload("CurrentObjectGroupRevision.MetaObjects.Labels").
 			Preload("CurrentObjectGroupRevision.MetaObjects.Locations").
 			Preload("CurrentObjectGroupRevision.MetaObjects.DefaultLocation")
 
@@ -955,6 +836,38 @@ func (read *Read) GetObjectGroupRevisionsByStatus(objectGroupID []string, status
 	return datasetVersions, nil
 }
 
+// GetResourceLabels Returns the labels of a resource, object groups return the labels of their current revision
+func (read *Read) GetResourceLabels(resource v1storagemodels.Resource, resourceID uuid.UUID) ([]models.Label, error) {
+	var labels []models.Label
+
+	err := crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
+		var err error
+		labels, err = readResourceLabels(tx, resource, resourceID)
+		return err
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+// GetProjectStreamGroups Returns the stream groups of a project
+func (read *Read) GetProjectStreamGroups(projectID uuid.UUID) ([]*models.StreamGroup, error) {
+	var streamGroups []*models.StreamGroup
+
+	err := crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
+		return tx.Where("project_id = ?", projectID).Order("created_at asc").Find(&streamGroups).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return streamGroups, nil
+}
+
 // Get the specific StreamGroup.
 func (read *Read) GetStreamGroup(streamGroupID uuid.UUID) (*models.StreamGroup, error) {
 	streamGroup := &models.StreamGroup{}
@@ -971,3 +884,131 @@ func (read *Read) GetStreamGroup(streamGroupID uuid.UUID) (*models.StreamGroup,
 
 	return streamGroup, nil
 }
+
+// FindProjectDatasets Returns the datasets of a project that match the label query
+func (read *Read) FindProjectDatasets(projectID uuid.UUID, query *LabelQuery, page *Page) ([]*models.Dataset, string, error) {
+	datasets := make([]*models.Dataset, 0)
+
+	err := crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
+		condition, args := query.SQL(LabelQueryTargetDatasets)
+		find, err := page.apply(tx.
+			Preload("Labels").
+			Preload("MetaObjects").
+			Where("datasets.project_id = ?", projectID).
+			Where(condition, args...), "datasets", datasetSortColumns, "datasets.status")
+		if err != nil {
+			return err
+		}
+
+		return find.Find(&datasets).Error
+	})
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, "", err
+	}
+
+	next, err := page.next(&datasets, "datasets", datasetSortColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return datasets, next, nil
+}
+
+// FindProjectObjectGroups Returns the object groups of a project whose current revision matches the label query
+func (read *Read) FindProjectObjectGroups(projectID uuid.UUID, query *LabelQuery, page *Page) ([]*models.ObjectGroup, string, error) {
+	objectGroups := make([]*models.ObjectGroup, 0)
+
+	err := crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
+		condition, args := query.SQL(LabelQueryTargetObjectGroups)
+		find, err := page.apply(tx.
+			Preload("CurrentObjectGroupRevision").
+			Preload("CurrentObjectGroupRevision.Labels").
+			Preload("CurrentObjectGroupRevision.DataObjects").
+			Preload("CurrentObjectGroupRevision.MetaObjects").
+			Where("object_groups.project_id = ?", projectID).
+			Where(condition, args...), "object_groups", objectGroupSortColumns, "object_groups.status")
+		if err != nil {
+			return err
+		}
+
+		return find.Find(&objectGroups).Error
+	})
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, "", err
+	}
+
+	next, err := page.next(&objectGroups, "object_groups", objectGroupSortColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return objectGroups, next, nil
+}
+
+// FindProjectObjectGroupRevisions Returns all revisions of the object groups of a project that match the label query
+func (read *Read) FindProjectObjectGroupRevisions(projectID uuid.UUID, query *LabelQuery, page *Page) ([]*models.ObjectGroupRevision, string, error) {
+	revisions := make([]*models.ObjectGroupRevision, 0)
+
+	err := crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
+		condition, args := query.SQL(LabelQueryTargetObjectGroupRevisions)
+		find, err := page.apply(tx.
+			Preload("Labels").
+			Preload("DataObjects").
+			Preload("MetaObjects").
+			Where("object_group_revisions.project_id = ?", projectID).
+			Where(condition, args...), "object_group_revisions", objectGroupRevisionSortColumns, "object_group_revisions.status")
+		if err != nil {
+			return err
+		}
+
+		return find.Find(&revisions).Error
+	})
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, "", err
+	}
+
+	next, err := page.next(&revisions, "object_group_revisions", objectGroupRevisionSortColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return revisions, next, nil
+}
+
+// FindProjectObjects Returns the objects of a project that match the label query
+func (read *Read) FindProjectObjects(projectID uuid.UUID, query *LabelQuery, page *Page) ([]*models.Object, string, error) {
+	objects := make([]*models.Object, 0)
+
+	err := crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
+		condition, args := query.SQL(LabelQueryTargetObjects)
+		find, err := page.apply(tx.
+			Preload("Labels").
+			Preload("Locations").
+			Preload("DefaultLocation").
+			Where("objects.project_id = ?", projectID).
+			Where(condition, args...), "objects", objectSortColumns, "objects.status")
+		if err != nil {
+			return err
+		}
+
+		return find.Find(&objects).Error
+	})
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, "", err
+	}
+
+	next, err := page.next(&objects, "objects", objectSortColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return objects, next, nil
+}
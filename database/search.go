@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbgorm"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Preloads of the resources that are required to build their search documents
+var (
+	SearchPreloadsProject     = []string{"Labels"}
+	SearchPreloadsDataset     = []string{"Labels"}
+	SearchPreloadsObject      = []string{"Labels"}
+	SearchPreloadsObjectGroup = []string{"CurrentObjectGroupRevision", "CurrentObjectGroupRevision.Labels", "CurrentObjectGroupRevision.DataObjects"}
+)
+
+// GetResourcesByID Reads the resources with the given ids into dest, a pointer to a slice of a model
+// Resources that do not exist or have been deleted are missing from the result.
+func (read *Read) GetResourcesByID(dest interface{}, ids []uuid.UUID, preloads ...string) error {
+	err := crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
+		for _, preload := range preloads {
+			tx = tx.Preload(preload)
+		}
+
+		return tx.Where("id IN ?", ids).Find(dest).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetResourcesAfter Reads up to limit resources into dest, a pointer to a slice of a model, ordered by their id
+// Only resources with an id greater than afterID are returned, it is used to read all resources in batches.
+func (read *Read) GetResourcesAfter(dest interface{}, afterID uuid.UUID, limit int, preloads ...string) error {
+	err := crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
+		for _, preload := range preloads {
+			tx = tx.Preload(preload)
+		}
+
+		return tx.Where("id > ?", afterID).Order("id asc").Limit(limit).Find(dest).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetObjectGroupObjectIDs Returns the ids of the data and meta objects of all revisions of an object group
+// Deleted revisions are included, the objects of deleted object groups can be found as well.
+func (read *Read) GetObjectGroupObjectIDs(objectGroupID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+
+	err := crdbgorm.ExecuteTx(context.Background(), read.DB, nil, func(tx *gorm.DB) error {
+		return tx.Raw(`
+			SELECT objects.object_id FROM object_group_revision_data_objects AS objects
+			INNER JOIN object_group_revisions AS revisions ON revisions.id = objects.object_group_revision_id
+			WHERE revisions.object_group_id = @id
+			UNION
+			SELECT objects.object_id FROM object_group_revision_meta_objects AS objects
+			INNER JOIN object_group_revisions AS revisions ON revisions.id = objects.object_group_revision_id
+			WHERE revisions.object_group_id = @id`,
+			map[string]interface{}{"id": objectGroupID}).
+			Scan(&ids).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// GetOutboxEventsSince Returns up to limit events that have been written since the given time, ordered by their sequence number
+// Only events with a sequence number greater than afterSequence are returned, it is used to read the events in batches.
+func (outbox *Outbox) GetOutboxEventsSince(since time.Time, afterSequence int64, limit int) ([]*models.OutboxEvent, error) {
+	var events []*models.OutboxEvent
+
+	err := crdbgorm.ExecuteTx(context.Background(), outbox.DB, nil, func(tx *gorm.DB) error {
+		return tx.
+			Where("created_at >= ? AND sequence > ?", since, afterSequence).
+			Order("sequence asc").
+			Limit(limit).
+			Find(&events).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -1,238 +1,371 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"time"
+
 	"github.com/ScienceObjectsDB/CORE-Server/models"
 	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbgorm"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
-	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// Stats Serves the usage statistics of the resources from the resource_stats table
+// The statistics are maintained in the transactions that change the objects and object groups, RebuildStats
+// recomputes them from the database. Every such transaction updates the rows of its project and dataset and holds their
+// locks until it commits, concurrent writes into the same project are therefore serialized on the project row. This
+// keeps the statistics exact without a background job, writes into different projects do not contend.
 type Stats struct {
 	*Common
 }
 
-func (stats *Stats) GetProjectStats(projectID uuid.UUID) (*v1storagemodels.ProjectStats, error) {
-	var object_groups_count int64
-	var objects_count int64
-	var acc_object_size int64
-	var avg_objects_size float64
-	var user_count int64
-
-	wg := errgroup.Group{}
-
-	wg.Go(func() error {
-		err := stats.DB.Model(&models.Object{}).Where("project_id = ?", projectID.String()).Select("count(*) as objects_count").Find(&objects_count).Error
-		if err != nil {
-			log.Errorln(err.Error())
-			return err
-		}
-
-		return nil
-	})
+// Statements that compute the statistics of the resources of a project, they match the statements of the
+// 0004_resource_stats migration
+const (
+	rebuildProjectStats = `INSERT INTO resource_stats (resource_id, resource, project_id, object_count, acc_size, object_group_count, user_count, updated_at)
+		SELECT projects.id, 'RESOURCE_PROJECT', projects.id,
+			(SELECT count(*) FROM objects WHERE objects.project_id = projects.id AND objects.deleted_at IS NULL),
+			(SELECT coalesce(sum(objects.content_len), 0) FROM objects WHERE objects.project_id = projects.id AND objects.deleted_at IS NULL),
+			(SELECT count(*) FROM object_groups WHERE object_groups.project_id = projects.id AND object_groups.deleted_at IS NULL),
+			(SELECT count(*) FROM users WHERE users.project_id = projects.id AND users.deleted_at IS NULL),
+			now()
+		FROM projects WHERE projects.id = @project AND projects.deleted_at IS NULL`
+
+	rebuildDatasetStats = `INSERT INTO resource_stats (resource_id, resource, project_id, object_count, acc_size, object_group_count, updated_at)
+		SELECT datasets.id, 'RESOURCE_DATASET', datasets.project_id,
+			(SELECT count(*) FROM objects WHERE objects.dataset_id = datasets.id AND objects.deleted_at IS NULL),
+			(SELECT coalesce(sum(objects.content_len), 0) FROM objects WHERE objects.dataset_id = datasets.id AND objects.deleted_at IS NULL),
+			(SELECT count(*) FROM object_groups WHERE object_groups.dataset_id = datasets.id AND object_groups.deleted_at IS NULL),
+			now()
+		FROM datasets WHERE datasets.project_id = @project AND datasets.deleted_at IS NULL`
+
+	rebuildRevisionStats = `INSERT INTO resource_stats (resource_id, resource, project_id, object_count, acc_size, meta_object_count, updated_at)
+		SELECT revisions.id, 'RESOURCE_OBJECT_GROUP_REVISION', revisions.project_id,
+			(SELECT count(*) FROM object_group_revision_data_objects AS data_objects
+				INNER JOIN objects ON objects.id = data_objects.object_id
+				WHERE data_objects.object_group_revision_id = revisions.id),
+			(SELECT coalesce(sum(objects.content_len), 0) FROM object_group_revision_data_objects AS data_objects
+				INNER JOIN objects ON objects.id = data_objects.object_id
+				WHERE data_objects.object_group_revision_id = revisions.id),
+			(SELECT count(*) FROM object_group_revision_meta_objects AS meta_objects
+				WHERE meta_objects.object_group_revision_id = revisions.id),
+			now()
+		FROM object_group_revisions AS revisions WHERE revisions.project_id = @project`
+
+	rebuildDatasetVersionStats = `INSERT INTO resource_stats (resource_id, resource, project_id, object_count, acc_size, object_group_count, updated_at)
+		SELECT versions.id, 'RESOURCE_DATASET_VERSION', versions.project_id,
+			(SELECT count(*) FROM dataset_version_object_group_revisions AS version_revisions
+				INNER JOIN object_group_revision_data_objects AS data_objects ON data_objects.object_group_revision_id = version_revisions.object_group_revision_id
+				INNER JOIN objects ON objects.id = data_objects.object_id
+				WHERE version_revisions.dataset_version_id = versions.id),
+			(SELECT coalesce(sum(objects.content_len), 0) FROM dataset_version_object_group_revisions AS version_revisions
+				INNER JOIN object_group_revision_data_objects AS data_objects ON data_objects.object_group_revision_id = version_revisions.object_group_revision_id
+				INNER JOIN objects ON objects.id = data_objects.object_id
+				WHERE version_revisions.dataset_version_id = versions.id),
+			(SELECT count(*) FROM dataset_version_object_group_revisions AS version_revisions
+				WHERE version_revisions.dataset_version_id = versions.id),
+			now()
+		FROM dataset_versions AS versions WHERE versions.project_id = @project`
+)
 
-	wg.Go(func() error {
-		err := stats.DB.Model(&models.Object{}).Where("project_id = ?", projectID.String()).Select("coalesce(avg(content_len), -1) as avg_objects_size").Find(&avg_objects_size).Error
-		if err != nil {
-			log.Errorln(err.Error())
-			return err
-		}
+// GetProjectStats Returns the statistics of a project
+// The accumulated and average object size are -1 for projects without objects.
+func (stats *Stats) GetProjectStats(projectID uuid.UUID) (*v1storagemodels.ProjectStats, error) {
+	projectStats, err := stats.getResourceStats(projectID)
+	if err != nil {
+		return nil, err
+	}
 
-		return nil
-	})
+	accSize, avgObjectSize := sizeStats(projectStats)
 
-	wg.Go(func() error {
-		err := stats.DB.Model(&models.ObjectGroup{}).Where("project_id = ?", projectID.String()).Select("count(*) as object_groups_count").Find(&object_groups_count).Error
-		if err != nil {
-			log.Errorln(err.Error())
-			return err
-		}
+	return &v1storagemodels.ProjectStats{
+		ObjectCount:      projectStats.ObjectCount,
+		ObjectGroupCount: projectStats.ObjectGroupCount,
+		AccSize:          accSize,
+		AvgObjectSize:    avgObjectSize,
+		UserCount:        projectStats.UserCount,
+	}, nil
+}
 
-		return nil
-	})
+// GetDatasetStats Returns the statistics of a dataset
+// The accumulated and average object size are -1 for datasets without objects.
+func (stats *Stats) GetDatasetStats(datasetID uuid.UUID) (*v1storagemodels.DatasetStats, error) {
+	datasetStats, err := stats.getResourceStats(datasetID)
+	if err != nil {
+		return nil, err
+	}
 
-	wg.Go(func() error {
-		err := stats.DB.Model(&models.Object{}).Where("project_id = ? and content_len is not null", projectID.String()).Select("coalesce(sum(content_len), -1) as acc_object_size").Find(&acc_object_size).Error
-		if err != nil {
-			log.Errorln(err.Error())
-			return err
-		}
+	accSize, avgObjectSize := sizeStats(datasetStats)
 
-		return nil
-	})
+	return &v1storagemodels.DatasetStats{
+		ObjectCount:      datasetStats.ObjectCount,
+		ObjectGroupCount: datasetStats.ObjectGroupCount,
+		AccSize:          accSize,
+		AvgObjectSize:    avgObjectSize,
+	}, nil
+}
 
-	wg.Go(func() error {
-		err := stats.DB.Model(&models.User{}).Where("project_id = ?", projectID.String()).Select("count(*) as user_count").Find(&user_count).Error
-		if err != nil {
-			log.Errorln(err.Error())
-			return err
-		}
+// GetObjectGroupRevisionStats Returns the statistics of the data and meta objects of a revision
+func (stats *Stats) GetObjectGroupRevisionStats(objectgroup *models.ObjectGroupRevision) (*v1storagemodels.ObjectGroupStats, error) {
+	revisionStats, err := stats.GetObjectGroupRevisionsStats([]uuid.UUID{objectgroup.ID})
+	if err != nil {
+		return nil, err
+	}
 
-		return nil
-	})
+	return revisionStats[objectgroup.ID], nil
+}
 
-	err := wg.Wait()
+// GetObjectGroupRevisionsStats Returns the statistics of multiple revisions by their id, e.g. of a page of object groups
+func (stats *Stats) GetObjectGroupRevisionsStats(revisionIDs []uuid.UUID) (map[uuid.UUID]*v1storagemodels.ObjectGroupStats, error) {
+	var rows []*models.ResourceStats
+	err := stats.DB.Where("resource_id IN ?", revisionIDs).Find(&rows).Error
 	if err != nil {
 		log.Errorln(err.Error())
 		return nil, err
 	}
 
-	projectStats := &v1storagemodels.ProjectStats{
-		ObjectCount:      objects_count,
-		ObjectGroupCount: object_groups_count,
-		AccSize:          acc_object_size,
-		AvgObjectSize:    avg_objects_size,
-		UserCount:        user_count,
+	rowsByID := make(map[uuid.UUID]*models.ResourceStats)
+	for _, row := range rows {
+		rowsByID[row.ResourceID] = row
 	}
 
-	return projectStats, nil
-}
-
-func (stats *Stats) GetDatasetStats(datasetID uuid.UUID) (*v1storagemodels.DatasetStats, error) {
-	var object_groups_count int64
-	var objects_count int64
-	var acc_object_size int64
-	var avg_objects_size float64
-
-	//This causes timeouts on datasets with a large number of objects, therefor it will be disabled for now
-	return &v1storagemodels.DatasetStats{}, nil
-
-	wg := errgroup.Group{}
-
-	wg.Go(func() error {
-		err := stats.DB.Model(&models.Object{}).Where("dataset_id = ?", datasetID.String()).Select("count(*) as objects_count").Find(&objects_count).Error
-		if err != nil {
-			log.Errorln(err.Error())
-			return err
+	revisionStats := make(map[uuid.UUID]*v1storagemodels.ObjectGroupStats)
+	for _, revisionID := range revisionIDs {
+		row, ok := rowsByID[revisionID]
+		if !ok {
+			row = &models.ResourceStats{}
 		}
 
-		return nil
-	})
-
-	wg.Go(func() error {
-		err := stats.DB.Model(&models.Object{}).Where("dataset_id = ? and content_len is not null", datasetID.String()).Select("coalesce(avg(content_len), -1) as avg_objects_size").Find(&avg_objects_size).Error
-		if err != nil {
-			log.Errorln(err.Error())
-			return err
+		revisionStats[revisionID] = &v1storagemodels.ObjectGroupStats{
+			ObjectCount:     row.ObjectCount,
+			AccSize:         row.AccSize,
+			AvgObjectSize:   row.AvgObjectSize(),
+			MetaObjectCount: row.MetaObjectCount,
 		}
+	}
 
-		return nil
-	})
+	return revisionStats, nil
+}
 
-	wg.Go(func() error {
-		err := stats.DB.Model(&models.ObjectGroup{}).Where("dataset_id = ?", datasetID.String()).Select("count(*) as object_groups_count").Find(&object_groups_count).Error
-		if err != nil {
-			log.Errorln(err.Error())
-			return err
-		}
+func (stats *Stats) GetObjectStats(objectID uuid.UUID) (*v1storagemodels.ObjectStats, error) {
 
-		return nil
-	})
+	objectStats := &v1storagemodels.ObjectStats{}
+	return objectStats, nil
+}
 
-	wg.Go(func() error {
-		err := stats.DB.Model(&models.Object{}).Where("dataset_id = ? and content_len is not null", datasetID.String()).Select("coalesce(sum(content_len), -1) as acc_object_size").Find(&acc_object_size).Error
-		if err != nil {
-			log.Errorln(err.Error())
-			return err
-		}
+// GetDatasetVersionStats Returns the statistics of the data objects of the revisions of a dataset version
+func (stats *Stats) GetDatasetVersionStats(datasetVersion *models.DatasetVersion) (*v1storagemodels.DatasetVersionStats, error) {
+	versionStats, err := stats.getResourceStats(datasetVersion.ID)
+	if err != nil {
+		return nil, err
+	}
 
-		return nil
-	})
+	return &v1storagemodels.DatasetVersionStats{
+		ObjectCount:      versionStats.ObjectCount,
+		ObjectGroupCount: versionStats.ObjectGroupCount,
+		AccSize:          versionStats.AccSize,
+		AvgObjectSize:    versionStats.AvgObjectSize(),
+	}, nil
+}
 
-	err := wg.Wait()
+// RebuildStats Recomputes the statistics of all resources, one project per transaction
+func (stats *Stats) RebuildStats() error {
+	var projectIDs []uuid.UUID
+	err := stats.DB.Model(&models.Project{}).Pluck("id", &projectIDs).Error
 	if err != nil {
 		log.Errorln(err.Error())
-		return nil, err
+		return err
 	}
 
-	datasetStats := &v1storagemodels.DatasetStats{
-		ObjectCount:      objects_count,
-		ObjectGroupCount: object_groups_count,
-		AccSize:          acc_object_size,
-		AvgObjectSize:    avg_objects_size,
+	// Statistics of deleted projects
+	err = stats.DB.Where("project_id NOT IN (SELECT id FROM projects WHERE deleted_at IS NULL)").Delete(&models.ResourceStats{}).Error
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
 	}
 
-	return datasetStats, nil
-}
+	for _, projectID := range projectIDs {
+		err := crdbgorm.ExecuteTx(context.Background(), stats.DB, nil, func(tx *gorm.DB) error {
+			if err := tx.Where("project_id = ?", projectID).Delete(&models.ResourceStats{}).Error; err != nil {
+				return err
+			}
 
-func (stats *Stats) GetObjectGroupRevisionStats(objectgroup *models.ObjectGroupRevision) (*v1storagemodels.ObjectGroupStats, error) {
-	var objectGroupStats v1storagemodels.ObjectGroupStats
-
-	wg := errgroup.Group{}
-	wg.Go(func() error {
-		err := stats.DB.Raw(`SELECT coalesce(sum(content_len)/count(*), 0) as avg_object_size, 
-									coalesce(sum(content_len), 0) as acc_size, 
-									coalesce(count(*), 0) as object_count 
-								FROM object_group_revision_data_objects 
-									INNER JOIN objects on object_group_revision_data_objects.object_id=objects.id 
-								WHERE object_group_revision_id=?`,
-			objectgroup.ID.String()).Scan(&objectGroupStats).Error
-		if err != nil {
-			log.Errorln(err.Error())
-			return err
-		}
+			for _, statement := range []string{rebuildProjectStats, rebuildDatasetStats, rebuildRevisionStats, rebuildDatasetVersionStats} {
+				if err := tx.Exec(statement, sql.Named("project", projectID)).Error; err != nil {
+					return err
+				}
+			}
 
-		err = stats.DB.Raw(`SELECT count(*) as meta_object_count 
-								FROM object_group_revision_meta_objects
-								WHERE object_group_revision_id=?;`,
-			objectgroup.ID.String()).
-			Scan(&objectGroupStats).Error
+			return nil
+		})
 		if err != nil {
 			log.Errorln(err.Error())
 			return err
 		}
+	}
 
-		return nil
-	})
+	log.Infof("rebuilt the statistics of %v projects", len(projectIDs))
 
-	err := wg.Wait()
+	return nil
+}
+
+// Returns the statistics of a resource, resources without statistics have no objects
+func (stats *Stats) getResourceStats(resourceID uuid.UUID) (*models.ResourceStats, error) {
+	var rows []*models.ResourceStats
+	err := stats.DB.Where("resource_id = ?", resourceID).Limit(1).Find(&rows).Error
 	if err != nil {
 		log.Errorln(err.Error())
 		return nil, err
 	}
 
-	return &objectGroupStats, nil
+	if len(rows) == 0 {
+		return &models.ResourceStats{ResourceID: resourceID}, nil
+	}
+
+	return rows[0], nil
 }
 
-func (stats *Stats) GetObjectStats(objectID uuid.UUID) (*v1storagemodels.ObjectStats, error) {
+// Returns the accumulated and the average object size, -1 without objects
+func sizeStats(resourceStats *models.ResourceStats) (int64, float64) {
+	if resourceStats.ObjectCount == 0 {
+		return -1, -1
+	}
 
-	objectStats := &v1storagemodels.ObjectStats{}
-	return objectStats, nil
+	return resourceStats.AccSize, resourceStats.AvgObjectSize()
 }
 
-func (stats *Stats) GetDatasetVersionStats(datasetVersion *models.DatasetVersion) (*v1storagemodels.DatasetVersionStats, error) {
-	type ObjectGroupStats struct {
-		ObjectsCount  int
-		AccObjectSize int
-		AvgObjectSize float64
+// Adds the counts of the statistics to the statistics of the resource
+func addStats(tx *gorm.DB, delta *models.ResourceStats) error {
+	delta.UpdatedAt = time.Now()
+
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "resource_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"object_count":       gorm.Expr("resource_stats.object_count + excluded.object_count"),
+			"acc_size":           gorm.Expr("resource_stats.acc_size + excluded.acc_size"),
+			"object_group_count": gorm.Expr("resource_stats.object_group_count + excluded.object_group_count"),
+			"meta_object_count":  gorm.Expr("resource_stats.meta_object_count + excluded.meta_object_count"),
+			"user_count":         gorm.Expr("resource_stats.user_count + excluded.user_count"),
+			"updated_at":         gorm.Expr("excluded.updated_at"),
+		}),
+	}).Create(delta).Error
+}
+
+// Adds the objects that match the condition to the statistics of their projects and datasets, a sign of -1 removes
+// them. Soft deleted objects match as well, the condition has to select them by their deletion time.
+func addObjectStats(tx *gorm.DB, sign int64, condition string, args ...interface{}) error {
+	var totals []struct {
+		ProjectID uuid.UUID
+		DatasetID uuid.UUID
+		Count     int64
+		Size      int64
 	}
 
-	wg := errgroup.Group{}
+	// The rows are locked in a fixed order so that concurrent transactions can not deadlock
+	err := tx.Unscoped().Model(&models.Object{}).
+		Select("project_id, dataset_id, count(*) AS count, coalesce(sum(content_len), 0) AS size").
+		Where(condition, args...).
+		Group("project_id, dataset_id").
+		Order("project_id, dataset_id").
+		Scan(&totals).Error
+	if err != nil {
+		return err
+	}
 
-	var objectGroupStats ObjectGroupStats
+	for _, total := range totals {
+		for _, resource := range []struct {
+			name string
+			id   uuid.UUID
+		}{
+			{v1storagemodels.Resource_RESOURCE_PROJECT.String(), total.ProjectID},
+			{v1storagemodels.Resource_RESOURCE_DATASET.String(), total.DatasetID},
+		} {
+			err := addStats(tx, &models.ResourceStats{
+				ResourceID:  resource.id,
+				Resource:    resource.name,
+				ProjectID:   total.ProjectID,
+				ObjectCount: sign * total.Count,
+				AccSize:     sign * total.Size,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
 
-	wg.Go(func() error {
-		err := stats.DB.Raw(`select coalesce(sum(content_len)/count(*), 0) as avg_object_size, coalesce(sum(content_len), 0) as acc_object_size, coalesce(count(*), 0) as objects_count from dataset_version_object_group_revisions 
-		inner join object_group_revision_data_objects 
-		on dataset_version_object_group_revisions.object_group_revision_id=object_group_revision_data_objects.object_group_revision_id 
-		inner join objects on object_group_revision_data_objects.object_id=objects.id 
-		where dataset_version_id=?;`, datasetVersion.ID).Scan(&objectGroupStats).Error
+	return nil
+}
 
-		if err != nil {
-			log.Errorln(err.Error())
-			return err
+// Adds the object groups that match the condition to the statistics of their projects and datasets, a sign of -1
+// removes them. Soft deleted object groups match as well.
+func addObjectGroupStats(tx *gorm.DB, sign int64, condition string, args ...interface{}) error {
+	var totals []struct {
+		ProjectID uuid.UUID
+		DatasetID uuid.UUID
+		Count     int64
+	}
+
+	err := tx.Unscoped().Model(&models.ObjectGroup{}).
+		Select("project_id, dataset_id, count(*) AS count").
+		Where(condition, args...).
+		Group("project_id, dataset_id").
+		Order("project_id, dataset_id").
+		Scan(&totals).Error
+	if err != nil {
+		return err
+	}
+
+	for _, total := range totals {
+		for _, resource := range []struct {
+			name string
+			id   uuid.UUID
+		}{
+			{v1storagemodels.Resource_RESOURCE_PROJECT.String(), total.ProjectID},
+			{v1storagemodels.Resource_RESOURCE_DATASET.String(), total.DatasetID},
+		} {
+			err := addStats(tx, &models.ResourceStats{
+				ResourceID:       resource.id,
+				Resource:         resource.name,
+				ProjectID:        total.ProjectID,
+				ObjectGroupCount: sign * total.Count,
+			})
+			if err != nil {
+				return err
+			}
 		}
+	}
+
+	return nil
+}
+
+// Computes the statistics of a revision, its objects do not change after it has been created
+func setRevisionStats(tx *gorm.DB, revision *models.ObjectGroupRevision) error {
+	if err := deleteStats(tx, "resource_id", []uuid.UUID{revision.ID}); err != nil {
+		return err
+	}
 
-		return nil
-	})
+	return tx.Exec(rebuildRevisionStats+" AND revisions.id = @id", sql.Named("project", revision.ProjectID), sql.Named("id", revision.ID)).Error
+}
 
-	wg.Wait()
+// Computes the statistics of a dataset version, its revisions do not change after it has been created
+func setDatasetVersionStats(tx *gorm.DB, version *models.DatasetVersion) error {
+	if err := deleteStats(tx, "resource_id", []uuid.UUID{version.ID}); err != nil {
+		return err
+	}
+
+	return tx.Exec(rebuildDatasetVersionStats+" AND versions.id = @id", sql.Named("project", version.ProjectID), sql.Named("id", version.ID)).Error
+}
 
-	versionStats := &v1storagemodels.DatasetVersionStats{
-		ObjectCount:   int64(objectGroupStats.ObjectsCount),
-		AccSize:       int64(objectGroupStats.AccObjectSize),
-		AvgObjectSize: objectGroupStats.AvgObjectSize,
+// Removes the statistics of resources whose rows are removed
+// Projects and datasets get a snapshot without usage, otherwise their last snapshot would be accounted forever.
+func deleteStats(tx *gorm.DB, column string, ids []uuid.UUID) error {
+	if err := takeDeletionUsageSnapshots(tx, column, ids, time.Now().UTC()); err != nil {
+		return err
 	}
 
-	return versionStats, nil
+	return tx.Where(column+" IN ?", ids).Delete(&models.ResourceStats{}).Error
 }
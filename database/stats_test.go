@@ -0,0 +1,21 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSizeStats(t *testing.T) {
+	accSize, avgObjectSize := sizeStats(&models.ResourceStats{})
+	assert.Equal(t, int64(-1), accSize)
+	assert.Equal(t, float64(-1), avgObjectSize)
+
+	accSize, avgObjectSize = sizeStats(&models.ResourceStats{ObjectCount: 4, AccSize: 10})
+	assert.Equal(t, int64(10), accSize)
+	assert.Equal(t, 2.5, avgObjectSize)
+
+	// Revisions and versions report 0 without objects
+	assert.Equal(t, float64(0), (&models.ResourceStats{}).AvgObjectSize())
+}
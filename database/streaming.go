@@ -2,12 +2,12 @@ package database
 
 import (
 	"context"
-	"crypto/hmac"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/ScienceObjectsDB/CORE-Server/models"
 	"github.com/ScienceObjectsDB/CORE-Server/signing"
@@ -15,155 +15,297 @@ import (
 	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbgorm"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"gorm.io/gorm"
 )
 
+// StreamingPath Path of the data streaming endpoint that handles the issued links
+const StreamingPath = "/stream"
+
+// MaxDownloadsMetadataKey Metadata key of the download limit that can be requested for a new link
+const MaxDownloadsMetadataKey = "max-downloads"
+
+// Streaming Handles the streaming entries that back the issued data streaming links
 type Streaming struct {
 	*Common
 	StreamingEndpoint string
-	SigningSecret     string
+	// Expiry of a link if none is requested
+	DefaultExpiry time.Duration
+	// Maximum expiry of a link, requested expiry times are capped to this value
+	MaxExpiry time.Duration
+	// Number of downloads allowed per link, 0 means unlimited
+	MaxDownloads int64
 }
 
-func (handler *Streaming) CreateStreamingLink(request *v1storageservices.GetObjectGroupsStreamLinkRequest, projectID uuid.UUID) (string, error) {
-	var url string
-	var err error
+// CreateStreamingLink Stores a new streaming entry for the request and returns the signed link that references the entry
+// The requested download limit is capped to the configured maximum, 0 requests the maximum.
+func (handler *Streaming) CreateStreamingLink(request *v1storageservices.GetObjectGroupsStreamLinkRequest, projectID uuid.UUID, createdBy string, maxDownloads int64) (string, error) {
+	rndBytes := make([]byte, 64)
+	_, err := rand.Read(rndBytes)
+	if err != nil {
+		log.Println(err.Error())
+		return "", err
+	}
+
+	entry := &models.StreamingEntry{
+		Secret:       base64.StdEncoding.EncodeToString(rndBytes),
+		StreamType:   request.GetStreamType().String(),
+		ProjectID:    projectID,
+		CreatedBy:    createdBy,
+		ExpiresAt:    handler.linkExpiry(request.GetExpiry()),
+		MaxDownloads: handler.linkMaxDownloads(maxDownloads),
+	}
+	entry.ID = uuid.New()
 
 	switch value := request.Query.(type) {
 	case *v1storageservices.GetObjectGroupsStreamLinkRequest_GroupIds:
 		{
-			var datasetID uuid.UUID
-			datasetID, err = uuid.Parse(request.GetGroupIds().GetDatasetId())
+			entry.ResourceType = models.StreamingEntryTypeObjectGroups
+			entry.DatasetID, err = uuid.Parse(value.GroupIds.GetDatasetId())
 			if err != nil {
 				log.Debug(err.Error())
 				return "", err
 			}
-			url, err = handler.createObjectGroupsRequest(value.GroupIds.GetObjectGroups(), datasetID, projectID)
+
+			for _, objectGroupID := range value.GroupIds.GetObjectGroups() {
+				objectGroupIDParsed, err := uuid.Parse(objectGroupID)
+				if err != nil {
+					log.Debug(err.Error())
+					return "", err
+				}
+
+				objectGroupRevision := models.ObjectGroupRevision{}
+				objectGroupRevision.ID = objectGroupIDParsed
+				entry.ObjectGroups = append(entry.ObjectGroups, objectGroupRevision)
+			}
 		}
 	case *v1storageservices.GetObjectGroupsStreamLinkRequest_Dataset:
 		{
-			var datasetID uuid.UUID
-			datasetID, err = uuid.Parse(request.GetDataset().GetDatasetId())
+			entry.ResourceType = models.StreamingEntryTypeDataset
+			entry.DatasetID, err = uuid.Parse(value.Dataset.GetDatasetId())
 			if err != nil {
 				log.Debug(err.Error())
 				return "", err
 			}
-			url, err = handler.createResourceObjectGroupsURL(datasetID, "/dataset")
 		}
 	case *v1storageservices.GetObjectGroupsStreamLinkRequest_DatasetVersion:
 		{
-			var datasetVersionID uuid.UUID
-			datasetVersionID, err = uuid.Parse(request.GetDatasetVersion().GetDatasetVersionId())
+			entry.ResourceType = models.StreamingEntryTypeDatasetVersion
+			entry.DatasetVersionID, err = uuid.Parse(value.DatasetVersion.GetDatasetVersionId())
+			if err != nil {
+				log.Debug(err.Error())
+				return "", err
+			}
+
+			version := &models.DatasetVersion{}
+			version.ID = entry.DatasetVersionID
+			err = handler.DB.Select("dataset_id").First(version).Error
+			if err != nil {
+				log.Println(err.Error())
+				return "", err
+			}
+			entry.DatasetID = version.DatasetID
+		}
+	case *v1storageservices.GetObjectGroupsStreamLinkRequest_DateRange:
+		{
+			entry.ResourceType = models.StreamingEntryTypeDateRange
+			entry.DatasetID, err = uuid.Parse(value.DateRange.GetDatasetId())
 			if err != nil {
 				log.Debug(err.Error())
 				return "", err
 			}
-			url, err = handler.createResourceObjectGroupsURL(datasetVersionID, "/datasetversion")
+			entry.StartDate = value.DateRange.GetStart().AsTime()
+			entry.EndDate = value.DateRange.GetEnd().AsTime()
 		}
 	default:
 		return "", fmt.Errorf("could not find request type")
 	}
 
+	err = crdbgorm.ExecuteTx(context.Background(), handler.DB, nil, func(tx *gorm.DB) error {
+		return tx.Create(entry).Error
+	})
 	if err != nil {
-		log.Println(err.Error())
+		log.Error(err.Error())
 		return "", err
 	}
 
-	return url, nil
+	return handler.createStreamingEntryURL(entry)
 }
 
-func (handler *Streaming) createResourceObjectGroupsURL(resourceID uuid.UUID, resourcePath string, queryParams ...string) (string, error) {
-	saltBytes := make([]byte, 64)
-	_, err := rand.Read(saltBytes)
-	if err != nil {
-		log.Println(err.Error())
-		return "", err
-	}
+// GetStreamingEntry Returns the streaming entry with the referenced object group revisions
+func (handler *Streaming) GetStreamingEntry(entryID uuid.UUID) (*models.StreamingEntry, error) {
+	entry := &models.StreamingEntry{}
+	entry.ID = entryID
 
-	parsedBaseURL, err := url.Parse(handler.StreamingEndpoint)
+	err := crdbgorm.ExecuteTx(context.Background(), handler.DB, nil, func(tx *gorm.DB) error {
+		return tx.
+			Preload("Dataset").
+			Preload("Dataset.Labels").
+			Preload("Dataset.MetaObjects").
+			Preload("Dataset.MetaObjects.Labels").
+			Preload("Dataset.MetaObjects.Locations").
+			Preload("Dataset.MetaObjects.DefaultLocation").
+			Preload("ObjectGroups").
+			Preload("ObjectGroups.Labels").
+			Preload("ObjectGroups.DataObjects").
+			Preload("ObjectGroups.DataObjects.Labels").
+			Preload("ObjectGroups.DataObjects.Locations").
+			Preload("ObjectGroups.DataObjects.DefaultLocation").
+			Preload("ObjectGroups.MetaObjects").
+			Preload("ObjectGroups.MetaObjects.Labels").
+			Preload("ObjectGroups.MetaObjects.Locations").
+			Preload("ObjectGroups.MetaObjects.DefaultLocation").
+			First(entry).Error
+	})
 	if err != nil {
 		log.Println(err.Error())
-		return "", err
+		return nil, err
 	}
 
-	parsedBaseURL.Path = resourcePath
-
-	q := parsedBaseURL.Query()
+	return entry, nil
+}
 
-	q.Set("id", fmt.Sprintf("%v", resourceID))
-	var key string
-	var value string
+// GetProjectStreamingEntries Returns all active streaming entries of a project
+func (handler *Streaming) GetProjectStreamingEntries(projectID uuid.UUID) ([]*models.StreamingEntry, error) {
+	var entries []*models.StreamingEntry
 
-	for i, queryParam := range queryParams {
-		if i%2 == 0 {
-			key = queryParam
-		}
-		if i%2 == 1 {
-			value = url.QueryEscape(queryParam)
-			q.Set(key, value)
-		}
+	err := crdbgorm.ExecuteTx(context.Background(), handler.DB, nil, func(tx *gorm.DB) error {
+		return tx.
+			Where("project_id = ? AND revoked = ? AND expires_at > ?", projectID, false, time.Now()).
+			Where("max_downloads = 0 OR download_count < max_downloads").
+			Order("created_at asc").
+			Find(&entries).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
 	}
 
-	parsedBaseURL.RawQuery = q.Encode()
+	return entries, nil
+}
 
-	signedURL, err := signing.SignURL([]byte(handler.SigningSecret), parsedBaseURL)
+// RevokeStreamingEntry Revokes a streaming entry, the link can not be used afterwards
+func (handler *Streaming) RevokeStreamingEntry(entryID uuid.UUID) error {
+	err := crdbgorm.ExecuteTx(context.Background(), handler.DB, nil, func(tx *gorm.DB) error {
+		return tx.Model(&models.StreamingEntry{}).
+			Where("id = ?", entryID).
+			Update("revoked", true).Error
+	})
 	if err != nil {
 		log.Println(err.Error())
-		return "", err
+		return err
 	}
 
-	return signedURL.String(), nil
+	return nil
 }
 
-func (handler *Streaming) createObjectGroupsRequest(objectGroupIDs []string, datasetID uuid.UUID, projectID uuid.UUID) (string, error) {
-	rndBytes := make([]byte, 64)
-	_, err := rand.Read(rndBytes)
+// ConsumeStreamingEntry Counts a download of the entry
+// Returns false if the entry is no longer valid, e.g. because it has been revoked in the meantime or the download limit has been reached.
+func (handler *Streaming) ConsumeStreamingEntry(entryID uuid.UUID) (bool, error) {
+	var rowsAffected int64
+
+	err := crdbgorm.ExecuteTx(context.Background(), handler.DB, nil, func(tx *gorm.DB) error {
+		result := tx.Model(&models.StreamingEntry{}).
+			Where("id = ? AND revoked = ? AND expires_at > ?", entryID, false, time.Now()).
+			Where("max_downloads = 0 OR download_count < max_downloads").
+			Update("download_count", gorm.Expr("download_count + 1"))
+		rowsAffected = result.RowsAffected
+		return result.Error
+	})
 	if err != nil {
 		log.Println(err.Error())
-		return "", err
+		return false, err
 	}
 
-	base64Secret := base64.StdEncoding.EncodeToString(rndBytes)
+	return rowsAffected == 1, nil
+}
 
-	objectRevisionGroups := make([]models.ObjectGroupRevision, len(objectGroupIDs))
-	for i, objectGroupID := range objectGroupIDs {
-		objectGroupIDParsed, err := uuid.Parse(objectGroupID)
-		if err != nil {
-			log.Debug(objectGroupIDParsed)
-			return "", err
+// Calculates the expiry of a new link based on the requested expiry and the configured limits
+func (handler *Streaming) linkExpiry(requestedExpiry *timestamppb.Timestamp) time.Time {
+	now := time.Now()
+	maxExpiry := now.Add(handler.MaxExpiry)
+
+	if requestedExpiry == nil || !requestedExpiry.IsValid() {
+		expiry := now.Add(handler.DefaultExpiry)
+		if handler.MaxExpiry > 0 && expiry.After(maxExpiry) {
+			return maxExpiry
 		}
+		return expiry
+	}
 
-		objectGroupRevisions := models.ObjectGroupRevision{}
-		objectGroupRevisions.ID = objectGroupIDParsed
-		objectRevisionGroups[i] = objectGroupRevisions
+	expiry := requestedExpiry.AsTime()
+	if handler.MaxExpiry > 0 && expiry.After(maxExpiry) {
+		return maxExpiry
 	}
 
-	uuid := uuid.NewString()
+	return expiry
+}
 
-	entry := models.StreamingEntry{
-		UUID:         uuid,
-		Secret:       base64Secret,
-		DatasetID:    datasetID,
-		ProjectID:    projectID,
-		ObjectGroups: objectRevisionGroups,
+// Returns the download limit of a new link, the requested limit is capped to the configured maximum
+func (handler *Streaming) linkMaxDownloads(requestedMaxDownloads int64) int64 {
+	if requestedMaxDownloads <= 0 {
+		return handler.MaxDownloads
 	}
 
-	err = crdbgorm.ExecuteTx(context.Background(), handler.DB, nil, func(tx *gorm.DB) error {
-		return tx.Save(&entry).Error
-	})
+	if handler.MaxDownloads > 0 && requestedMaxDownloads > handler.MaxDownloads {
+		return handler.MaxDownloads
+	}
 
+	return requestedMaxDownloads
+}
+
+// ParseLinkMaxDownloads Reads the download limit requested for a new link from the request metadata
+// Returns 0 if no limit is requested.
+func ParseLinkMaxDownloads(md metadata.MD) (int64, error) {
+	values := md.Get(MaxDownloadsMetadataKey)
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	maxDownloads, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil || maxDownloads < 0 {
+		return 0, status.Error(codes.InvalidArgument, "max downloads has to be a positive number")
+	}
+
+	return maxDownloads, nil
+}
+
+// ValidateLinkExpiry Checks if a requested expiry time can be used for a new link
+func ValidateLinkExpiry(requestedExpiry *timestamppb.Timestamp) error {
+	if requestedExpiry == nil {
+		return nil
+	}
+
+	if !requestedExpiry.IsValid() || !requestedExpiry.AsTime().After(time.Now()) {
+		return status.Error(codes.InvalidArgument, "link expiry has to be in the future")
+	}
+
+	return nil
+}
+
+// Creates the link for the entry, the link contains only the id of the entry and its signature
+func (handler *Streaming) createStreamingEntryURL(entry *models.StreamingEntry) (string, error) {
+	parsedBaseURL, err := url.Parse(handler.StreamingEndpoint)
 	if err != nil {
-		log.Error(err.Error())
+		log.Println(err.Error())
 		return "", err
 	}
 
-	mac := hmac.New(sha256.New, []byte(base64Secret))
-	_, err = mac.Write([]byte(uuid))
+	parsedBaseURL.Path = StreamingPath
+
+	signature, err := signing.SignID([]byte(entry.Secret), entry.ID.String())
 	if err != nil {
 		log.Println(err.Error())
 		return "", err
 	}
 
-	url := fmt.Sprintf("%v?uuid=%v&hmac=%v", handler.StreamingEndpoint, uuid, mac.Sum(nil))
+	q := parsedBaseURL.Query()
+	q.Set("id", entry.ID.String())
+	q.Set("sign", signature)
+	parsedBaseURL.RawQuery = q.Encode()
 
-	return url, nil
+	return parsedBaseURL.String(), nil
 }
@@ -5,98 +5,106 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ScienceObjectsDB/CORE-Server/models"
 	"github.com/ScienceObjectsDB/CORE-Server/signing"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-func TestCreateDatasetObjectGroupsURL(t *testing.T) {
+func TestCreateStreamingEntryURL(t *testing.T) {
 	streamingHandler := Streaming{
 		StreamingEndpoint: "http://testendpoint:9010",
-		SigningSecret:     "signing-secret",
 	}
-	startTime := time.Now()
-	endTime := time.Now()
 
-	startTimeString, err := startTime.MarshalText()
-	if err != nil {
-		log.Println(err.Error())
-		t.Log(err.Error())
+	entry := &models.StreamingEntry{
+		Secret: "entry-secret",
 	}
+	entry.ID = uuid.New()
 
-	endTimeString, err := endTime.MarshalText()
+	resultURL, err := streamingHandler.createStreamingEntryURL(entry)
 	if err != nil {
 		log.Println(err.Error())
-		t.Log(err.Error())
+		t.Fatal(err.Error())
 	}
 
-	resourceUUID, err := uuid.NewUUID()
+	parsedURL, err := url.Parse(resultURL)
 	if err != nil {
 		log.Println(err.Error())
-		t.Log(err.Error())
+		t.Fatal(err.Error())
 	}
 
-	resultURL, err := streamingHandler.createResourceObjectGroupsURL(resourceUUID, "/dataset", "starttime", string(startTimeString), "endtime", string(endTimeString))
-	if err != nil {
-		log.Println(err.Error())
-		t.Log(err.Error())
-	}
+	assert.Equal(t, parsedURL.Host, "testendpoint:9010")
+	assert.Equal(t, parsedURL.Scheme, "http")
+	assert.Equal(t, parsedURL.Path, StreamingPath)
+	assert.Equal(t, parsedURL.Query().Get("id"), entry.ID.String())
+	assert.Len(t, parsedURL.Query(), 2)
 
-	parsedURL, err := url.Parse(resultURL)
+	verified, err := signing.VerifyIDSignature([]byte(entry.Secret), parsedURL.Query().Get("id"), parsedURL.Query().Get("sign"))
 	if err != nil {
 		log.Println(err.Error())
-		t.Log(err.Error())
+		t.Fatal()
 	}
 
-	startTimeFromQuery, err := url.QueryUnescape(parsedURL.Query().Get("starttime"))
-	if err != nil {
-		log.Println(err.Error())
-		t.Log(err.Error())
-	}
-	parsedStartTime, err := time.Parse(time.RFC3339, startTimeFromQuery)
-	if err != nil {
-		log.Println(err.Error())
-		t.Log(err.Error())
+	if !verified {
+		t.Fatalf("could not verify signed link")
 	}
 
-	endTimeFromQuery, err := url.QueryUnescape(parsedURL.Query().Get("endtime"))
+	verified, err = signing.VerifyIDSignature([]byte("other-secret"), parsedURL.Query().Get("id"), parsedURL.Query().Get("sign"))
 	if err != nil {
 		log.Println(err.Error())
-		t.Log(err.Error())
-	}
-	parsedEndTime, err := time.Parse(time.RFC3339, endTimeFromQuery)
-	if err != nil {
-		log.Println(err.Error())
-		t.Log(err.Error())
+		t.Fatal()
 	}
 
-	queryStartTime, err := parsedStartTime.MarshalText()
-	if err != nil {
-		log.Println(err.Error())
-		t.Log(err.Error())
+	if verified {
+		t.Fatalf("link verified with the wrong secret")
 	}
+}
 
-	queryEndTime, err := parsedEndTime.MarshalText()
-	if err != nil {
-		log.Println(err.Error())
-		t.Log(err.Error())
+func TestLinkExpiry(t *testing.T) {
+	streamingHandler := Streaming{
+		DefaultExpiry: time.Hour,
+		MaxExpiry:     24 * time.Hour,
 	}
 
-	assert.Equal(t, parsedURL.Host, "testendpoint:9010")
-	assert.Equal(t, parsedURL.Scheme, "http")
-	assert.Equal(t, parsedURL.Path, "/dataset")
-	assert.Equal(t, parsedURL.Query().Get("id"), resourceUUID.String())
-	assert.Equal(t, string(startTimeString), string(queryStartTime))
-	assert.Equal(t, string(endTimeString), string(queryEndTime))
+	defaultExpiry := streamingHandler.linkExpiry(nil)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), defaultExpiry, time.Minute)
 
-	verified, err := signing.VerifyHMAC_sha256("signing-secret", parsedURL)
-	if err != nil {
-		log.Println(err.Error())
-		t.Fatal()
-	}
+	requestedExpiry := time.Now().Add(2 * time.Hour)
+	assert.WithinDuration(t, requestedExpiry, streamingHandler.linkExpiry(timestamppb.New(requestedExpiry)), time.Second)
 
-	if !verified {
-		t.Fatalf("could not verify signed link")
+	cappedExpiry := streamingHandler.linkExpiry(timestamppb.New(time.Now().Add(48 * time.Hour)))
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), cappedExpiry, time.Minute)
+
+	assert.Error(t, ValidateLinkExpiry(timestamppb.New(time.Now().Add(-time.Hour))))
+	assert.NoError(t, ValidateLinkExpiry(nil))
+}
+
+func TestLinkMaxDownloads(t *testing.T) {
+	streamingHandler := Streaming{
+		MaxDownloads: 10,
 	}
+
+	assert.Equal(t, int64(10), streamingHandler.linkMaxDownloads(0))
+	assert.Equal(t, int64(3), streamingHandler.linkMaxDownloads(3))
+	assert.Equal(t, int64(10), streamingHandler.linkMaxDownloads(100))
+
+	unlimitedHandler := Streaming{}
+	assert.Equal(t, int64(0), unlimitedHandler.linkMaxDownloads(0))
+	assert.Equal(t, int64(100), unlimitedHandler.linkMaxDownloads(100))
+
+	maxDownloads, err := ParseLinkMaxDownloads(metadata.Pairs(MaxDownloadsMetadataKey, "5"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), maxDownloads)
+
+	maxDownloads, err = ParseLinkMaxDownloads(metadata.MD{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), maxDownloads)
+
+	_, err = ParseLinkMaxDownloads(metadata.Pairs(MaxDownloadsMetadataKey, "-1"))
+	assert.Error(t, err)
+	_, err = ParseLinkMaxDownloads(metadata.Pairs(MaxDownloadsMetadataKey, "many"))
+	assert.Error(t, err)
 }
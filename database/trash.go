@@ -0,0 +1,535 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbgorm"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Time after which the purge of an entry that has been started by another server is taken over, e.g. after a crash
+const trashPurgeTimeout = time.Hour
+
+// ErrTrashEntryPurging The trash entry is already being purged by another server
+var ErrTrashEntryPurging = status.Error(codes.Aborted, "the trash entry is already being purged")
+
+// Trash Lists, restores and purges deleted datasets and object groups
+type Trash struct {
+	*Common
+	// Retention of projects without an own retention
+	DefaultRetention time.Duration
+}
+
+// Ids of the rows that belong to a dataset or object group in the trash
+type trashRows struct {
+	datasetIDs        []uuid.UUID
+	datasetVersionIDs []uuid.UUID
+	objectGroupIDs    []uuid.UUID
+	revisionIDs       []uuid.UUID
+	objectIDs         []uuid.UUID
+}
+
+// Reads the rows of a dataset or an object group including the soft deleted ones
+func readTrashRows(tx *gorm.DB, resource string, resourceID uuid.UUID) (*trashRows, error) {
+	rows := &trashRows{}
+	unscoped := tx.Unscoped()
+
+	switch resource {
+	case v1storagemodels.Resource_RESOURCE_DATASET.String():
+		rows.datasetIDs = []uuid.UUID{resourceID}
+
+		if err := unscoped.Model(&models.DatasetVersion{}).Where("dataset_id = ?", resourceID).Pluck("id", &rows.datasetVersionIDs).Error; err != nil {
+			return nil, err
+		}
+
+		if err := unscoped.Model(&models.ObjectGroup{}).Where("dataset_id = ?", resourceID).Pluck("id", &rows.objectGroupIDs).Error; err != nil {
+			return nil, err
+		}
+
+		if err := unscoped.Model(&models.ObjectGroupRevision{}).Where("dataset_id = ?", resourceID).Pluck("id", &rows.revisionIDs).Error; err != nil {
+			return nil, err
+		}
+
+		if err := unscoped.Model(&models.Object{}).Where("dataset_id = ?", resourceID).Pluck("id", &rows.objectIDs).Error; err != nil {
+			return nil, err
+		}
+	case v1storagemodels.Resource_RESOURCE_OBJECT_GROUP.String():
+		rows.objectGroupIDs = []uuid.UUID{resourceID}
+
+		if err := unscoped.Model(&models.ObjectGroupRevision{}).Where("object_group_id = ?", resourceID).Pluck("id", &rows.revisionIDs).Error; err != nil {
+			return nil, err
+		}
+
+		if len(rows.revisionIDs) == 0 {
+			return rows, nil
+		}
+
+		// Objects that have been added to revisions of other object groups are kept
+		err := tx.Raw(`SELECT object_id FROM object_group_revision_data_objects WHERE object_group_revision_id IN @revisions
+			UNION SELECT object_id FROM object_group_revision_meta_objects WHERE object_group_revision_id IN @revisions
+			EXCEPT (SELECT object_id FROM object_group_revision_data_objects WHERE object_group_revision_id NOT IN @revisions
+			UNION SELECT object_id FROM object_group_revision_meta_objects WHERE object_group_revision_id NOT IN @revisions)`,
+			sql.Named("revisions", rows.revisionIDs)).Scan(&rows.objectIDs).Error
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "resource %v can not be in the trash", resource)
+	}
+
+	return rows, nil
+}
+
+// Sets the deletion time of the rows, rows that have been deleted before keep their deletion time
+func (rows *trashRows) trash(tx *gorm.DB, trashedAt time.Time) error {
+	if err := rows.addStats(tx, -1, "deleted_at IS NULL"); err != nil {
+		return err
+	}
+
+	return rows.updateDeletedAt(tx, trashedAt, "deleted_at IS NULL")
+}
+
+// Removes the deletion time of the rows that have been deleted together with the trash entry
+func (rows *trashRows) restore(tx *gorm.DB, trashedAt time.Time) error {
+	if err := rows.addStats(tx, 1, "deleted_at = ?", trashedAt); err != nil {
+		return err
+	}
+
+	return rows.updateDeletedAt(tx, nil, "deleted_at = ?", trashedAt)
+}
+
+// Adds the objects and object groups that match the condition to the statistics of their projects and datasets
+func (rows *trashRows) addStats(tx *gorm.DB, sign int64, condition string, args ...interface{}) error {
+	if len(rows.objectIDs) > 0 {
+		if err := addObjectStats(tx, sign, "id IN ? AND "+condition, append([]interface{}{rows.objectIDs}, args...)...); err != nil {
+			return err
+		}
+	}
+
+	if len(rows.objectGroupIDs) > 0 {
+		if err := addObjectGroupStats(tx, sign, "id IN ? AND "+condition, append([]interface{}{rows.objectGroupIDs}, args...)...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (rows *trashRows) updateDeletedAt(tx *gorm.DB, deletedAt interface{}, condition string, args ...interface{}) error {
+	updates := []struct {
+		model  interface{}
+		column string
+		ids    []uuid.UUID
+	}{
+		{&models.Dataset{}, "id", rows.datasetIDs},
+		{&models.DatasetVersion{}, "id", rows.datasetVersionIDs},
+		{&models.ObjectGroup{}, "id", rows.objectGroupIDs},
+		{&models.ObjectGroupRevision{}, "id", rows.revisionIDs},
+		{&models.Object{}, "id", rows.objectIDs},
+		{&models.Location{}, "object_id", rows.objectIDs},
+	}
+
+	for _, update := range updates {
+		if len(update.ids) == 0 {
+			continue
+		}
+
+		err := tx.Unscoped().Model(update.model).Where(update.column+" IN ?", update.ids).Where(condition, args...).Update("deleted_at", deletedAt).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Removes the rows together with their labels and the entries of the association tables
+func (rows *trashRows) purge(tx *gorm.DB) error {
+	var labelIDs []uuid.UUID
+	err := tx.Raw(`SELECT label_id FROM object_labels WHERE object_id IN ?
+		UNION SELECT label_id FROM object_group_revision_label WHERE object_group_revision_id IN ?
+		UNION SELECT label_id FROM dataset_version_labels WHERE dataset_version_id IN ?
+		UNION SELECT label_id FROM dataset_labels WHERE dataset_id IN ?`,
+		rows.objectIDs, rows.revisionIDs, rows.datasetVersionIDs, rows.datasetIDs).Scan(&labelIDs).Error
+	if err != nil {
+		return err
+	}
+
+	associations := []struct {
+		statement string
+		ids       []uuid.UUID
+	}{
+		{"DELETE FROM object_labels WHERE object_id IN ?", rows.objectIDs},
+		{"DELETE FROM object_group_revision_label WHERE object_group_revision_id IN ?", rows.revisionIDs},
+		{"DELETE FROM dataset_version_labels WHERE dataset_version_id IN ?", rows.datasetVersionIDs},
+		{"DELETE FROM dataset_labels WHERE dataset_id IN ?", rows.datasetIDs},
+		{"DELETE FROM object_group_revision_data_objects WHERE object_group_revision_id IN ?", rows.revisionIDs},
+		{"DELETE FROM object_group_revision_meta_objects WHERE object_group_revision_id IN ?", rows.revisionIDs},
+		{"DELETE FROM dataset_version_object_group_revisions WHERE object_group_revision_id IN ?", rows.revisionIDs},
+		{"DELETE FROM dataset_version_object_group_revisions WHERE dataset_version_id IN ?", rows.datasetVersionIDs},
+		{"DELETE FROM streaming_entry_object_groups WHERE object_group_revision_id IN ?", rows.revisionIDs},
+		{"DELETE FROM dataset_meta_objects WHERE dataset_id IN ?", rows.datasetIDs},
+	}
+
+	for _, association := range associations {
+		if len(association.ids) == 0 {
+			continue
+		}
+
+		if err := tx.Exec(association.statement, association.ids).Error; err != nil {
+			return err
+		}
+	}
+
+	deletes := []struct {
+		model  interface{}
+		column string
+		ids    []uuid.UUID
+	}{
+		{&models.Label{}, "id", labelIDs},
+		{&models.Location{}, "object_id", rows.objectIDs},
+		{&models.Object{}, "id", rows.objectIDs},
+		{&models.ObjectGroupRevision{}, "id", rows.revisionIDs},
+		{&models.ObjectGroup{}, "id", rows.objectGroupIDs},
+		{&models.DatasetVersion{}, "id", rows.datasetVersionIDs},
+		{&models.Dataset{}, "id", rows.datasetIDs},
+	}
+
+	for _, row := range deletes {
+		if len(row.ids) == 0 {
+			continue
+		}
+
+		if err := tx.Unscoped().Where(row.column+" IN ?", row.ids).Delete(row.model).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, ids := range [][]uuid.UUID{rows.datasetIDs, rows.revisionIDs, rows.datasetVersionIDs} {
+		if len(ids) == 0 {
+			continue
+		}
+
+		if err := deleteStats(tx, "resource_id", ids); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Moves a dataset or object group into the trash of its project and returns the trash entry
+func moveToTrash(tx *gorm.DB, resource v1storagemodels.Resource, resourceID uuid.UUID, projectID uuid.UUID, datasetID uuid.UUID, name string, defaultRetention time.Duration) (*models.TrashEntry, error) {
+	project := &models.Project{}
+	project.ID = projectID
+	if err := tx.Select("id", "trash_retention").First(project).Error; err != nil {
+		return nil, err
+	}
+
+	retention := project.TrashRetention
+	if retention <= 0 {
+		retention = defaultRetention
+	}
+
+	// The database stores microseconds, the deletion time is compared when the entry is restored
+	trashedAt := time.Now().UTC().Truncate(time.Microsecond)
+
+	rows, err := readTrashRows(tx, resource.String(), resourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rows.trash(tx, trashedAt); err != nil {
+		return nil, err
+	}
+
+	entry := &models.TrashEntry{
+		Resource:   resource.String(),
+		ResourceID: resourceID,
+		ProjectID:  projectID,
+		DatasetID:  datasetID,
+		Name:       name,
+		TrashedAt:  trashedAt,
+		PurgeAt:    trashedAt.Add(retention),
+	}
+
+	if err := tx.Create(entry).Error; err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// GetProjectTrash Returns the trash entries of a project, the most recently deleted first
+func (trash *Trash) GetProjectTrash(projectID uuid.UUID) ([]*models.TrashEntry, error) {
+	var entries []*models.TrashEntry
+
+	err := crdbgorm.ExecuteTx(context.Background(), trash.DB, nil, func(tx *gorm.DB) error {
+		return tx.Where("project_id = ?", projectID).Order("trashed_at desc").Find(&entries).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetTrashEntry Returns a single trash entry
+func (trash *Trash) GetTrashEntry(entryID uuid.UUID) (*models.TrashEntry, error) {
+	entry := &models.TrashEntry{}
+	entry.ID = entryID
+
+	err := crdbgorm.ExecuteTx(context.Background(), trash.DB, nil, func(tx *gorm.DB) error {
+		return tx.First(entry).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// GetProjectTrashRetention Returns the time deleted resources of a project are kept in the trash
+func (trash *Trash) GetProjectTrashRetention(projectID uuid.UUID) (time.Duration, error) {
+	project := &models.Project{}
+	project.ID = projectID
+
+	err := crdbgorm.ExecuteTx(context.Background(), trash.DB, nil, func(tx *gorm.DB) error {
+		return tx.Select("id", "trash_retention").First(project).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return 0, err
+	}
+
+	if project.TrashRetention <= 0 {
+		return trash.DefaultRetention, nil
+	}
+
+	return project.TrashRetention, nil
+}
+
+// SetProjectTrashRetention Changes the trash retention of a project, 0 resets it to the default retention
+// The purge time of the resources that are already in the trash is moved accordingly.
+func (trash *Trash) SetProjectTrashRetention(projectID uuid.UUID, retention time.Duration) error {
+	effectiveRetention := retention
+	if effectiveRetention <= 0 {
+		effectiveRetention = trash.DefaultRetention
+	}
+
+	err := crdbgorm.ExecuteTx(context.Background(), trash.DB, nil, func(tx *gorm.DB) error {
+		result := tx.Model(&models.Project{}).Where("id = ?", projectID).Update("trash_retention", retention)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var entries []*models.TrashEntry
+		if err := tx.Where("project_id = ?", projectID).Find(&entries).Error; err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			err := tx.Model(entry).Update("purge_at", entry.TrashedAt.Add(effectiveRetention)).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// RestoreTrashEntry Restores a dataset or object group from the trash
+// Rows that had been deleted before the resource was moved into the trash stay deleted. Object groups of a dataset
+// that is in the trash can only be restored after the dataset. Entries whose purge has started can not be restored.
+func (trash *Trash) RestoreTrashEntry(entryID uuid.UUID) (*models.TrashEntry, error) {
+	entry := &models.TrashEntry{}
+	entry.ID = entryID
+
+	err := crdbgorm.ExecuteTx(context.Background(), trash.DB, nil, func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(entry).Error; err != nil {
+			return err
+		}
+
+		if !entry.PurgingAt.IsZero() {
+			return status.Error(codes.FailedPrecondition, "the trash entry is being purged and can not be restored")
+		}
+
+		var resource v1storagemodels.Resource
+		switch entry.Resource {
+		case v1storagemodels.Resource_RESOURCE_DATASET.String():
+			resource = v1storagemodels.Resource_RESOURCE_DATASET
+		case v1storagemodels.Resource_RESOURCE_OBJECT_GROUP.String():
+			resource = v1storagemodels.Resource_RESOURCE_OBJECT_GROUP
+
+			var datasets int64
+			if err := tx.Model(&models.Dataset{}).Where("id = ?", entry.DatasetID).Count(&datasets).Error; err != nil {
+				return err
+			}
+			if datasets == 0 {
+				return status.Error(codes.FailedPrecondition, "the dataset of the object group is in the trash, it has to be restored first")
+			}
+		}
+
+		rows, err := readTrashRows(tx, entry.Resource, entry.ResourceID)
+		if err != nil {
+			return err
+		}
+
+		if err := rows.restore(tx, entry.TrashedAt); err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Delete(entry).Error; err != nil {
+			return err
+		}
+
+		event, err := readResourceEvent(tx, resource, entry.ResourceID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED)
+		if err != nil {
+			return err
+		}
+
+		return writeOutboxEvents(tx, event)
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// GetExpiredTrashEntries Returns up to limit trash entries whose retention has expired before the given time
+// Entries that are being purged by another server are skipped.
+func (trash *Trash) GetExpiredTrashEntries(before time.Time, limit int) ([]*models.TrashEntry, error) {
+	var entries []*models.TrashEntry
+
+	err := crdbgorm.ExecuteTx(context.Background(), trash.DB, nil, func(tx *gorm.DB) error {
+		return tx.Where("purge_at < ?", before).
+			Where("purging_at IS NULL OR purging_at < ?", time.Now().Add(-trashPurgeTimeout)).
+			Order("purge_at asc").Limit(limit).Find(&entries).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// PurgeTrashEntry Removes the data of a trash entry from the object storage and its rows from the database
+// Purging a dataset also purges the object groups of the dataset that have been deleted before. The entry is marked as
+// purging before the data is removed, it can not be restored afterwards and other servers do not purge it at the same
+// time. ErrTrashEntryPurging is returned if another server has started the purge less than an hour ago.
+func (trash *Trash) PurgeTrashEntry(entry *models.TrashEntry) error {
+	var locations []*models.Location
+
+	err := crdbgorm.ExecuteTx(context.Background(), trash.DB, nil, func(tx *gorm.DB) error {
+		purging := &models.TrashEntry{}
+		purging.ID = entry.ID
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(purging).Error; err != nil {
+			return err
+		}
+
+		if purging.PurgingAt.After(time.Now().Add(-trashPurgeTimeout)) {
+			return ErrTrashEntryPurging
+		}
+
+		if err := tx.Model(purging).Update("purging_at", time.Now().UTC()).Error; err != nil {
+			return err
+		}
+
+		rows, err := readTrashRows(tx, entry.Resource, entry.ResourceID)
+		if err != nil {
+			return err
+		}
+
+		locations = nil
+		if len(rows.objectIDs) == 0 {
+			return nil
+		}
+
+		return tx.Unscoped().Where("object_id IN ?", rows.objectIDs).Find(&locations).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	if len(locations) > 0 {
+		err = trash.S3Handler.DeleteObjects(locations)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+	}
+
+	err = crdbgorm.ExecuteTx(context.Background(), trash.DB, nil, func(tx *gorm.DB) error {
+		rows, err := readTrashRows(tx, entry.Resource, entry.ResourceID)
+		if err != nil {
+			return err
+		}
+
+		if err := rows.purge(tx); err != nil {
+			return err
+		}
+
+		query := tx.Unscoped().Where("id = ?", entry.ID)
+		if entry.Resource == v1storagemodels.Resource_RESOURCE_DATASET.String() {
+			query = tx.Unscoped().Where("id = ? OR dataset_id = ?", entry.ID, entry.ResourceID)
+		}
+
+		return query.Delete(&models.TrashEntry{}).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// PurgeProjectTrash Purges all trash entries of a project, e.g. before the project is deleted
+func (trash *Trash) PurgeProjectTrash(projectID uuid.UUID) error {
+	entries, err := trash.GetProjectTrash(projectID)
+	if err != nil {
+		return err
+	}
+
+	// Datasets first, they include the object groups of the dataset that are in the trash
+	for _, resource := range []string{v1storagemodels.Resource_RESOURCE_DATASET.String(), v1storagemodels.Resource_RESOURCE_OBJECT_GROUP.String()} {
+		for _, entry := range entries {
+			if entry.Resource != resource {
+				continue
+			}
+
+			err := trash.PurgeTrashEntry(entry)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -3,14 +3,18 @@ package database
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
 	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
 	v1storageservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/services/v1"
 	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbgorm"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -34,14 +38,192 @@ func (update *Update) AddUploadID(objectID uuid.UUID, uploadID string) error {
 	return nil
 }
 
-func (update *Update) UpdateMetadata() error {
+// UpdateMetadata Changes the name and the description of a resource, fields that are nil are not changed
+// The name of an object is its filename, objects have no description. Released dataset versions can not be changed.
+func (update *Update) UpdateMetadata(resourceType v1storagemodels.Resource, resourceID uuid.UUID, name *string, description *string) error {
+	var model interface{}
+	fields := make(map[string]interface{})
+
+	switch resourceType {
+	case v1storagemodels.Resource_RESOURCE_PROJECT:
+		model = &models.Project{}
+	case v1storagemodels.Resource_RESOURCE_DATASET:
+		model = &models.Dataset{}
+	case v1storagemodels.Resource_RESOURCE_DATASET_VERSION:
+		model = &models.DatasetVersion{}
+	case v1storagemodels.Resource_RESOURCE_OBJECT:
+		model = &models.Object{}
+		if description != nil {
+			return status.Error(codes.InvalidArgument, "objects have no description")
+		}
+		if name != nil {
+			fields["filename"] = *name
+			name = nil
+		}
+	default:
+		return status.Errorf(codes.InvalidArgument, "metadata of resource %v can not be changed", resourceType.String())
+	}
+
+	if name != nil {
+		fields["name"] = *name
+	}
+	if description != nil {
+		fields["description"] = *description
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	err := crdbgorm.ExecuteTx(context.Background(), update.DB, nil, func(tx *gorm.DB) error {
+		if resourceType == v1storagemodels.Resource_RESOURCE_DATASET_VERSION {
+			if err := checkDatasetVersionMutable(tx, resourceID); err != nil {
+				return err
+			}
+		}
+
+		result := tx.Model(model).Where("id = ?", resourceID).Updates(fields)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		event, err := readResourceEvent(tx, resourceType, resourceID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_METADATA_UPDATED)
+		if err != nil {
+			return err
+		}
+
+		return writeOutboxEvents(tx, event)
+	})
+
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpdateLabels Adds and removes labels of a resource
+// Labels in remove without a value remove all labels with their key. With replace all existing labels are removed
+// before the new labels are added. The labels of object groups are changed by creating a new revision, the labels of
+// released dataset versions can not be changed.
+func (update *Update) UpdateLabels(resourceType v1storagemodels.Resource, resourceID uuid.UUID, add []models.Label, remove []models.Label, replace bool) error {
+	var owner interface{}
+
+	switch resourceType {
+	case v1storagemodels.Resource_RESOURCE_PROJECT:
+		project := &models.Project{}
+		project.ID = resourceID
+		owner = project
+	case v1storagemodels.Resource_RESOURCE_DATASET:
+		dataset := &models.Dataset{}
+		dataset.ID = resourceID
+		owner = dataset
+	case v1storagemodels.Resource_RESOURCE_DATASET_VERSION:
+		version := &models.DatasetVersion{}
+		version.ID = resourceID
+		owner = version
+	case v1storagemodels.Resource_RESOURCE_OBJECT:
+		object := &models.Object{}
+		object.ID = resourceID
+		owner = object
+	default:
+		return status.Errorf(codes.InvalidArgument, "labels of resource %v can not be changed, object groups require a new revision", resourceType.String())
+	}
+
+	err := crdbgorm.ExecuteTx(context.Background(), update.DB, nil, func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(owner).Error; err != nil {
+			return err
+		}
+
+		if resourceType == v1storagemodels.Resource_RESOURCE_DATASET_VERSION {
+			if err := checkDatasetVersionMutable(tx, resourceID); err != nil {
+				return err
+			}
+		}
+
+		var previousLabels []models.Label
+		if err := tx.Model(owner).Association("Labels").Find(&previousLabels); err != nil {
+			return err
+		}
+
+		var removedLabels []models.Label
+		for _, label := range previousLabels {
+			if replace || labelMatches(remove, label) {
+				removedLabels = append(removedLabels, label)
+			}
+		}
+
+		if len(removedLabels) > 0 {
+			if err := tx.Model(owner).Association("Labels").Delete(&removedLabels); err != nil {
+				return err
+			}
+
+			if err := tx.Delete(&removedLabels).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(add) > 0 {
+			addedLabels := make([]models.Label, len(add))
+			for i, label := range add {
+				addedLabels[i] = models.Label{Key: label.Key, Value: label.Value, ParentID: resourceID}
+			}
+
+			if err := tx.Model(owner).Association("Labels").Append(&addedLabels); err != nil {
+				return err
+			}
+		}
+
+		var labels []models.Label
+		if err := tx.Model(owner).Association("Labels").Find(&labels); err != nil {
+			return err
+		}
+
+		event, err := readResourceEvent(tx, resourceType, resourceID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_METADATA_UPDATED)
+		if err != nil {
+			return err
+		}
+
+		return writeOutboxEvents(tx, event.WithLabelChange(previousLabels, labels))
+	})
+
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
 	return nil
 }
 
-func (update *Update) UpdateLabels() error {
+// Checks if a dataset version can still be changed, released versions are immutable
+func checkDatasetVersionMutable(tx *gorm.DB, versionID uuid.UUID) error {
+	version := &models.DatasetVersion{}
+	if err := tx.Select("id", "status").Where("id = ?", versionID).First(version).Error; err != nil {
+		return err
+	}
+
+	if version.Status == v1storagemodels.Status_STATUS_AVAILABLE.String() {
+		return status.Error(codes.FailedPrecondition, "released dataset versions can not be changed")
+	}
+
 	return nil
 }
 
+// Checks if a label is matched by one of the filters, filters without a value match all values of their key
+func labelMatches(filters []models.Label, label models.Label) bool {
+	for _, filter := range filters {
+		if filter.Key == label.Key && (filter.Value == "" || filter.Value == label.Value) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (update *Update) UpdateStatus(status v1storagemodels.Status, resourceID uuid.UUID, resourceType v1storagemodels.Resource) error {
 	var model interface{}
 
@@ -58,8 +240,22 @@ func (update *Update) UpdateStatus(status v1storagemodels.Status, resourceID uui
 		model = models.DatasetVersion{}
 	}
 
+	updateType := v1notificationservices.EventNotificationMessage_UPDATE_TYPE_UPDATED
+	if status == v1storagemodels.Status_STATUS_AVAILABLE {
+		updateType = v1notificationservices.EventNotificationMessage_UPDATE_TYPE_AVAILABLE
+	}
+
 	err := crdbgorm.ExecuteTx(context.Background(), update.DB, nil, func(tx *gorm.DB) error {
-		return tx.Model(model).Where("id = ?", resourceID).Update("status", status.String()).Error
+		if err := tx.Model(model).Where("id = ?", resourceID).Update("status", status.String()).Error; err != nil {
+			return err
+		}
+
+		event, err := readResourceEvent(tx, resourceType, resourceID, updateType)
+		if err != nil {
+			return err
+		}
+
+		return writeOutboxEvents(tx, event)
 	})
 
 	if err != nil {
@@ -75,12 +271,17 @@ func (update *Update) FinishObjectUpload(objectID uuid.UUID) error {
 	object.ID = objectID
 
 	err := crdbgorm.ExecuteTx(context.Background(), update.DB, nil, func(tx *gorm.DB) error {
-		tx.Transaction(func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
 			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(object).Error; err != nil {
 				log.Errorln(err.Error())
 				return err
 			}
 
+			// Finishing an upload again, e.g. after a lost response, has no effect
+			if object.Status == v1storagemodels.Status_STATUS_AVAILABLE.String() {
+				return nil
+			}
+
 			if object.Status != v1storagemodels.Status_STATUS_STAGING.String() {
 				err := status.Error(codes.InvalidArgument, fmt.Sprintf("object is in status: %v but finishing upload requires object to be in status: %v", object.Status, v1storagemodels.Status_STATUS_STAGING))
 				log.Debugln(err.Error())
@@ -92,9 +293,13 @@ func (update *Update) FinishObjectUpload(objectID uuid.UUID) error {
 				return err
 			}
 
+			if err := writeOutboxEvents(tx, models.NewObjectEvent(object.ProjectID, object.DatasetID, object.ID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_AVAILABLE)); err != nil {
+				log.Errorln(err.Error())
+				return err
+			}
+
 			return nil
 		})
-		return nil
 	})
 
 	if err != nil {
@@ -105,76 +310,124 @@ func (update *Update) FinishObjectUpload(objectID uuid.UUID) error {
 	return nil
 }
 
-func (update *Update) UpdateObjectGroup(request *v1storageservices.UpdateObjectGroupRequest, dataset *models.Dataset, project *models.Project, objectGroup *models.ObjectGroup) (*models.ObjectGroupRevision, error) {
-	newObjectGroupRevision := &models.ObjectGroupRevision{
-		Name:          request.CreateRevisionRequest.Name,
-		Description:   request.CreateRevisionRequest.Description,
-		DatasetID:     dataset.ID,
-		ProjectID:     project.ID,
-		ObjectGroupID: objectGroup.ID,
+// CompleteMultipartUpload Removes the upload id of an object after its multipart upload has been completed
+func (update *Update) CompleteMultipartUpload(objectID uuid.UUID) error {
+	object := &models.Object{}
+	object.ID = objectID
+
+	err := crdbgorm.ExecuteTx(context.Background(), update.DB, nil, func(tx *gorm.DB) error {
+		if err := tx.First(object).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(object).Update("upload_id", "").Error; err != nil {
+			return err
+		}
+
+		return writeOutboxEvents(tx, models.NewObjectEvent(object.ProjectID, object.DatasetID, object.ID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_UPDATED))
+	})
+
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// ReplaceLabelsMetadataKey Metadata key of an object group update that replaces the labels even if the request has none
+const ReplaceLabelsMetadataKey = "replace-labels"
+
+// ParseReplaceLabels Reads from the request metadata if the labels of a new object group revision are replaced
+// Returns false if the key is not set.
+func ParseReplaceLabels(md metadata.MD) (bool, error) {
+	values := md.Get(ReplaceLabelsMetadataKey)
+	if len(values) == 0 {
+		return false, nil
 	}
 
+	replaceLabels, err := strconv.ParseBool(values[0])
+	if err != nil {
+		return false, status.Errorf(codes.InvalidArgument, "%v has to be true or false", ReplaceLabelsMetadataKey)
+	}
+
+	return replaceLabels, nil
+}
+
+// UpdateObjectGroup Creates a new revision of an object group from its current revision
+// The data and meta objects of the current revision are carried forward with the added and without the deleted objects
+// of the request. The labels of the request replace the labels of the current revision, they are carried forward if the
+// request has no labels. With replaceLabels the labels of the request are used even if there are none.
+func (update *Update) UpdateObjectGroup(request *v1storageservices.UpdateObjectGroupRequest, dataset *models.Dataset, project *models.Project, objectGroup *models.ObjectGroup, replaceLabels bool) (*models.ObjectGroupRevision, error) {
+	var newObjectGroupRevision *models.ObjectGroupRevision
+
 	err := crdbgorm.ExecuteTx(context.Background(), update.DB, nil, func(tx *gorm.DB) error {
+		newObjectGroupRevision = &models.ObjectGroupRevision{
+			Name:          request.CreateRevisionRequest.Name,
+			Description:   request.CreateRevisionRequest.Description,
+			DatasetID:     dataset.ID,
+			ProjectID:     project.ID,
+			ObjectGroupID: objectGroup.ID,
+		}
 
-		tx.Transaction(func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
 			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(objectGroup).Error; err != nil {
 				log.Errorln(err.Error())
 				return err
 			}
 
-			currentObjectGroupInTransaction := &models.ObjectGroup{}
-			currentObjectGroupInTransaction.ID = objectGroup.ID
-
-			if err := tx.First(currentObjectGroupInTransaction).Error; err != nil {
+			dataObjects := make([]models.Object, 0)
+			err := tx.Model(&models.Object{}).
+				Joins("inner join object_group_revision_data_objects on object_group_revision_data_objects.object_id = objects.id").
+				Where("object_group_revision_id = ?", objectGroup.CurrentObjectGroupRevisionID).
+				Find(&dataObjects).Error
+			if err != nil {
 				log.Errorln(err.Error())
 				return err
 			}
 
-			dataObjects := make([]models.Object, 0)
-
-			dataObjectPreloads := tx.Preload("Locations").Preload("DefaultLocation").Preload("Labels")
-			dataObjectPreloads.Model(&models.Object{}).
-				Joins("inner join object_group_revision_data_objects on object_group_revision_data_objects.object_id = objects.id").
-				Where("object_group_revision_id = ?", currentObjectGroupInTransaction.CurrentObjectGroupRevisionID).
-				Find(&dataObjects)
-
 			metaObjects := make([]models.Object, 0)
-
-			metaObjectsPreloads := tx.Preload("Locations").Preload("DefaultLocation").Preload("Labels")
-			metaObjectsPreloads.Model(&models.Object{}).
+			err = tx.Model(&models.Object{}).
 				Joins("inner join object_group_revision_meta_objects on object_group_revision_meta_objects.object_id = objects.id").
-				Where("object_group_revision_id = ?", currentObjectGroupInTransaction.CurrentObjectGroupRevisionID).
-				Find(&metaObjects)
+				Where("object_group_revision_id = ?", objectGroup.CurrentObjectGroupRevisionID).
+				Find(&metaObjects).Error
+			if err != nil {
+				log.Errorln(err.Error())
+				return err
+			}
 
 			currentObjectGroupRevision := &models.ObjectGroupRevision{}
-			currentObjectGroupRevision.ID = currentObjectGroupInTransaction.CurrentObjectGroupRevisionID
-			if err := tx.First(currentObjectGroupRevision).Error; err != nil {
+			currentObjectGroupRevision.ID = objectGroup.CurrentObjectGroupRevisionID
+			if err := tx.Preload("Labels").First(currentObjectGroupRevision).Error; err != nil {
 				log.Errorln(err.Error())
 				return err
 			}
 
-			new_data_objects, err := update.updateObjects(dataObjects, request.CreateRevisionRequest.UpdateObjects)
+			newDataObjects, err := update.updateObjects(tx, dataset.ID, dataObjects, request.CreateRevisionRequest.UpdateObjects)
 			if err != nil {
 				log.Errorln(err.Error())
 				return err
 			}
 
-			//new_meta_objects, err := update.updateObjects(metaObjects, request.CreateRevisionRequest.UpdateMetaObjects)
-			//if err != nil {
-			//	log.Errorln(err.Error())
-			//	return err
-			//}
+			newMetaObjects, err := update.updateObjects(tx, dataset.ID, metaObjects, request.CreateRevisionRequest.UpdateMetaObjects)
+			if err != nil {
+				log.Errorln(err.Error())
+				return err
+			}
 
-			labels := make([]models.Label, len(request.CreateRevisionRequest.Labels))
-			for i, labelRequest := range labels {
-				labels[i] = models.Label{
-					Key:   labelRequest.Key,
-					Value: labelRequest.Value,
+			labelRequests := request.CreateRevisionRequest.Labels
+			labels := make([]models.Label, 0, len(labelRequests))
+			for _, labelRequest := range labelRequests {
+				labels = append(labels, models.Label{Key: labelRequest.Key, Value: labelRequest.Value})
+			}
+			if len(labelRequests) == 0 && !replaceLabels {
+				for _, label := range currentObjectGroupRevision.Labels {
+					labels = append(labels, models.Label{Key: label.Key, Value: label.Value})
 				}
 			}
 
-			newObjectGroupRevision.DataObjects = new_data_objects
-			//newObjectGroupRevision.MetaObjects = new_meta_objects
+			newObjectGroupRevision.DataObjects = newDataObjects
+			newObjectGroupRevision.MetaObjects = newMetaObjects
 			newObjectGroupRevision.Labels = labels
 			newObjectGroupRevision.RevisionNumber = objectGroup.CurrentRevisionCount + 1
 
@@ -183,16 +436,26 @@ func (update *Update) UpdateObjectGroup(request *v1storageservices.UpdateObjectG
 				return err
 			}
 
+			if err := setRevisionStats(tx, newObjectGroupRevision); err != nil {
+				log.Errorln(err.Error())
+				return err
+			}
+
 			updateColumns := map[string]interface{}{"current_object_group_revision_id": newObjectGroupRevision.ID.String(), "current_revision_count": objectGroup.CurrentRevisionCount + 1}
 			if err := tx.Model(objectGroup).Updates(updateColumns).Error; err != nil {
 				log.Errorln(err.Error())
 				return err
 			}
 
+			event := models.NewObjectGroupEvent(objectGroup.ProjectID, objectGroup.DatasetID, objectGroup.ID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_UPDATED).
+				WithRevisionChange(currentObjectGroupRevision.ID, newObjectGroupRevision.ID, currentObjectGroupRevision.Labels, newObjectGroupRevision.Labels)
+			if err := writeOutboxEvents(tx, event); err != nil {
+				log.Errorln(err.Error())
+				return err
+			}
+
 			return nil
 		})
-
-		return nil
 	})
 
 	if err != nil {
@@ -203,34 +466,85 @@ func (update *Update) UpdateObjectGroup(request *v1storageservices.UpdateObjectG
 	return newObjectGroupRevision, nil
 }
 
-func (update *Update) updateObjects(originalObjects []models.Object, updateObjectsRequest *v1storageservices.UpdateObjectsRequests) ([]models.Object, error) {
-	deleteObjects := make(map[string]interface{})
+// Applies the added and deleted objects of a request to the objects of a revision
+// Added objects have to belong to the dataset, deleted objects have to be part of the revision. The order of the
+// remaining objects is kept, added objects are appended. Objects can not be updated in place, updated objects have to be
+// added as new objects.
+func (update *Update) updateObjects(tx *gorm.DB, datasetID uuid.UUID, originalObjects []models.Object, updateObjectsRequest *v1storageservices.UpdateObjectsRequests) ([]models.Object, error) {
+	if len(updateObjectsRequest.GetUpdateObjects()) > 0 {
+		return nil, status.Error(codes.Unimplemented, "objects can not be updated, add the new object and delete the old object instead")
+	}
 
+	originalIDs := make(map[uuid.UUID]struct{})
+	for _, originalObject := range originalObjects {
+		originalIDs[originalObject.ID] = struct{}{}
+	}
+
+	deleteObjects := make(map[uuid.UUID]struct{})
 	for _, deleteObject := range updateObjectsRequest.GetDeleteObjects() {
-		deleteObjects[deleteObject.GetId()] = struct{}{}
+		deleteObjectUUID, err := uuid.Parse(deleteObject.GetId())
+		if err != nil {
+			log.Errorln(err.Error())
+			return nil, status.Error(codes.InvalidArgument, "could not parse object id")
+		}
+
+		if _, ok := originalIDs[deleteObjectUUID]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "object %v is not part of the current revision", deleteObjectUUID.String())
+		}
+
+		deleteObjects[deleteObjectUUID] = struct{}{}
 	}
 
-	newDataObjects := make([]models.Object, 0)
+	newObjects := make([]models.Object, 0)
 	for _, originalObject := range originalObjects {
-		if _, ok := deleteObjects[originalObject.ID.String()]; !ok {
-			newDataObjects = append(newDataObjects, originalObject)
+		if _, ok := deleteObjects[originalObject.ID]; !ok {
+			newObjects = append(newObjects, originalObject)
 		}
 	}
 
+	var addObjectIDs []uuid.UUID
 	for _, addObjectRequest := range updateObjectsRequest.GetAddObjects() {
 		addObjectUUID, err := uuid.Parse(addObjectRequest.GetId())
 		if err != nil {
 			log.Errorln(err.Error())
-			return nil, err
+			return nil, status.Error(codes.InvalidArgument, "could not parse object id")
+		}
+
+		if _, ok := originalIDs[addObjectUUID]; ok {
+			if _, deleted := deleteObjects[addObjectUUID]; !deleted {
+				continue
+			}
 		}
 
-		addObject := models.Object{}
-		addObject.ID = addObjectUUID
+		addObjectIDs = append(addObjectIDs, addObjectUUID)
+	}
+
+	if len(addObjectIDs) == 0 {
+		return newObjects, nil
+	}
+
+	var addObjects []models.Object
+	err := tx.Where("id IN ? AND dataset_id = ?", addObjectIDs, datasetID).Find(&addObjects).Error
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
 
-		newDataObjects = append(newDataObjects, addObject)
+	addObjectsByID := make(map[uuid.UUID]models.Object)
+	for _, addObject := range addObjects {
+		addObjectsByID[addObject.ID] = addObject
 	}
 
-	return newDataObjects, nil
+	for _, addObjectID := range addObjectIDs {
+		addObject, ok := addObjectsByID[addObjectID]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "object %v is not part of the dataset", addObjectID.String())
+		}
+
+		newObjects = append(newObjects, addObject)
+	}
+
+	return newObjects, nil
 }
 
 func (update *Update) FinishObjectGroupRevisionUpload(objectGroupRevisionID uuid.UUID) error {
@@ -240,7 +554,7 @@ func (update *Update) FinishObjectGroupRevisionUpload(objectGroupRevisionID uuid
 	objectGroup := &models.ObjectGroup{}
 
 	err := crdbgorm.ExecuteTx(context.Background(), update.DB, nil, func(tx *gorm.DB) error {
-		tx.Transaction(func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
 			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(objectGroupRevision).Error; err != nil {
 				log.Errorln(err.Error())
 				return err
@@ -266,6 +580,12 @@ func (update *Update) FinishObjectGroupRevisionUpload(objectGroupRevisionID uuid
 				return err
 			}
 
+			if err := setRevisionStats(tx, objectGroupRevision); err != nil {
+				log.Errorln(err.Error())
+				return err
+			}
+
+			previousRevisionID := objectGroup.CurrentObjectGroupRevisionID
 			objectGroup.CurrentObjectGroupRevisionID = objectGroupRevision.ID
 
 			if err := tx.Model(objectGroup).Update("current_object_group_revision_id", objectGroupRevision.ID).Error; err != nil {
@@ -273,16 +593,85 @@ func (update *Update) FinishObjectGroupRevisionUpload(objectGroupRevisionID uuid
 				return err
 			}
 
+			previousLabels, err := readRevisionLabels(tx, previousRevisionID)
+			if err != nil {
+				log.Errorln(err.Error())
+				return err
+			}
+
+			labels, err := readRevisionLabels(tx, objectGroupRevision.ID)
+			if err != nil {
+				log.Errorln(err.Error())
+				return err
+			}
+
+			event := models.NewObjectGroupEvent(objectGroup.ProjectID, objectGroup.DatasetID, objectGroup.ID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_AVAILABLE).
+				WithRevisionChange(previousRevisionID, objectGroupRevision.ID, previousLabels, labels)
+			if err := writeOutboxEvents(tx, event); err != nil {
+				log.Errorln(err.Error())
+				return err
+			}
+
 			return nil
 		})
+	})
 
-		return nil
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpdateStreamGroupStart Stores a new start position of a stream group
+func (update *Update) UpdateStreamGroupStart(streamGroupID uuid.UUID, start *models.StreamGroupStart) error {
+	err := crdbgorm.ExecuteTx(context.Background(), update.DB, nil, func(tx *gorm.DB) error {
+		return tx.Model(&models.StreamGroup{}).Where("id = ?", streamGroupID).Updates(map[string]interface{}{
+			"start_policy":   start.Policy,
+			"start_sequence": start.Sequence,
+			"start_time":     start.Time,
+		}).Error
 	})
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
 
+// UpdateStreamGroupFilters Replaces the event filters of a stream group
+func (update *Update) UpdateStreamGroupFilters(streamGroupID uuid.UUID, resources []string, updateTypes []string, labelKey string, labelValue string) error {
+	err := crdbgorm.ExecuteTx(context.Background(), update.DB, nil, func(tx *gorm.DB) error {
+		return tx.Model(&models.StreamGroup{}).Where("id = ?", streamGroupID).Updates(map[string]interface{}{
+			"resources":    strings.Join(resources, ","),
+			"update_types": strings.Join(updateTypes, ","),
+			"label_key":    labelKey,
+			"label_value":  labelValue,
+		}).Error
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	resetSnapshotConsumers()
+
+	return nil
+}
+
+// SetStreamGroupIncludeSnapshots Requests or stops snapshots of the resources with the events of a stream group
+func (update *Update) SetStreamGroupIncludeSnapshots(streamGroupID uuid.UUID, includeSnapshots bool) error {
+	err := crdbgorm.ExecuteTx(context.Background(), update.DB, nil, func(tx *gorm.DB) error {
+		return tx.Model(&models.StreamGroup{}).Where("id = ?", streamGroupID).Update("include_snapshots", includeSnapshots).Error
+	})
 	if err != nil {
 		log.Error(err.Error())
 		return err
 	}
 
+	resetSnapshotConsumers()
+
 	return nil
 }
@@ -0,0 +1,183 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Usage Records the storage usage of projects and datasets over time
+type Usage struct {
+	*Common
+}
+
+// UsageAccount Storage usage of a project or dataset in a calendar month
+type UsageAccount struct {
+	ResourceID uuid.UUID
+	// First day of the month in UTC
+	Month time.Time
+	// Usage of the last snapshot in the month
+	ObjectCount      int64
+	ObjectGroupCount int64
+	AccSize          int64
+	MaxAccSize       int64
+	// Accumulated size weighted with the part of the month it was stored, 1 TB stored for half a month are 0.5 TB months
+	ByteMonths float64
+}
+
+// Snapshots of the usage between start and end including the last snapshot before start, it holds the usage at start
+const usageSnapshotsInRange = `taken_at < @end AND taken_at >= coalesce((SELECT max(previous.taken_at) FROM usage_snapshots AS previous
+	WHERE previous.resource_id = usage_snapshots.resource_id AND previous.taken_at <= @start), @start)`
+
+// TakeUsageSnapshot Records the current statistics of all projects and datasets as snapshot taken at takenAt
+// Resources that already have a snapshot at takenAt are skipped, e.g. if multiple servers record the same interval.
+func (usage *Usage) TakeUsageSnapshot(takenAt time.Time) (int64, error) {
+	result := usage.DB.Exec(`INSERT INTO usage_snapshots (resource_id, taken_at, resource, project_id, object_count, acc_size, object_group_count)
+		SELECT resource_id, ?, resource, project_id, object_count, acc_size, object_group_count FROM resource_stats
+		WHERE resource IN ?
+		ON CONFLICT (resource_id, taken_at) DO NOTHING`,
+		takenAt, []string{v1storagemodels.Resource_RESOURCE_PROJECT.String(), v1storagemodels.Resource_RESOURCE_DATASET.String()})
+	if result.Error != nil {
+		log.Errorln(result.Error.Error())
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// Records snapshots without usage at deletedAt for the projects and datasets whose statistics are removed
+func takeDeletionUsageSnapshots(tx *gorm.DB, column string, ids []uuid.UUID, deletedAt time.Time) error {
+	return tx.Exec(`INSERT INTO usage_snapshots (resource_id, taken_at, resource, project_id, object_count, acc_size, object_group_count)
+		SELECT resource_id, ?, resource, project_id, 0, 0, 0 FROM resource_stats
+		WHERE `+column+` IN ? AND resource IN ?
+		ON CONFLICT (resource_id, taken_at) DO UPDATE SET object_count = 0, acc_size = 0, object_group_count = 0`,
+		deletedAt, ids, []string{v1storagemodels.Resource_RESOURCE_PROJECT.String(), v1storagemodels.Resource_RESOURCE_DATASET.String()}).Error
+}
+
+// GetUsageSnapshots Returns the snapshots of a project or dataset between start and end ordered by time
+// The last snapshot before start is included, it holds the usage at start.
+func (usage *Usage) GetUsageSnapshots(resourceID uuid.UUID, start time.Time, end time.Time) ([]*models.UsageSnapshot, error) {
+	var snapshots []*models.UsageSnapshot
+	err := usage.DB.
+		Where("resource_id = @resource AND "+usageSnapshotsInRange, sql.Named("resource", resourceID), sql.Named("start", start), sql.Named("end", end)).
+		Order("taken_at asc").
+		Find(&snapshots).Error
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// GetProjectsUsageSnapshots Returns the snapshots of all projects between start and end by project id
+// The snapshots of deleted projects are included.
+func (usage *Usage) GetProjectsUsageSnapshots(start time.Time, end time.Time) (map[uuid.UUID][]*models.UsageSnapshot, error) {
+	var snapshots []*models.UsageSnapshot
+	err := usage.DB.
+		Where("resource = @resource AND "+usageSnapshotsInRange, sql.Named("resource", v1storagemodels.Resource_RESOURCE_PROJECT.String()), sql.Named("start", start), sql.Named("end", end)).
+		Order("resource_id asc").
+		Order("taken_at asc").
+		Find(&snapshots).Error
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	projectSnapshots := make(map[uuid.UUID][]*models.UsageSnapshot)
+	for _, snapshot := range snapshots {
+		projectSnapshots[snapshot.ResourceID] = append(projectSnapshots[snapshot.ResourceID], snapshot)
+	}
+
+	return projectSnapshots, nil
+}
+
+// GetProjectNames Returns the names of the projects by id, deleted projects are missing
+func (usage *Usage) GetProjectNames(projectIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+	var projects []*models.Project
+	err := usage.DB.Select("id", "name").Where("id IN ?", projectIDs).Find(&projects).Error
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	names := make(map[uuid.UUID]string)
+	for _, project := range projects {
+		names[project.ID] = project.Name
+	}
+
+	return names, nil
+}
+
+// AccountUsage Splits the usage of the snapshots of a resource, ordered by time, into the calendar months between start
+// and end. Every snapshot holds until the next snapshot, the last one until end. Months before the first snapshot are
+// omitted.
+func AccountUsage(resourceID uuid.UUID, snapshots []*models.UsageSnapshot, start time.Time, end time.Time) []*UsageAccount {
+	start, end = start.UTC(), end.UTC()
+
+	var accounts []*UsageAccount
+	month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for ; month.Before(end); month = month.AddDate(0, 1, 0) {
+		monthEnd := month.AddDate(0, 1, 0)
+		monthDuration := float64(monthEnd.Sub(month))
+
+		var account *UsageAccount
+		for i, snapshot := range snapshots {
+			from, until := snapshot.TakenAt, end
+			if i+1 < len(snapshots) {
+				until = snapshots[i+1].TakenAt
+			}
+
+			from = latestTime(from, month, start)
+			until = earliestTime(until, monthEnd, end)
+			if !from.Before(until) {
+				continue
+			}
+
+			if account == nil {
+				account = &UsageAccount{ResourceID: resourceID, Month: month}
+			}
+
+			account.ObjectCount = snapshot.ObjectCount
+			account.ObjectGroupCount = snapshot.ObjectGroupCount
+			account.AccSize = snapshot.AccSize
+			if snapshot.AccSize > account.MaxAccSize {
+				account.MaxAccSize = snapshot.AccSize
+			}
+			account.ByteMonths += float64(snapshot.AccSize) * float64(until.Sub(from)) / monthDuration
+		}
+
+		if account != nil {
+			accounts = append(accounts, account)
+		}
+	}
+
+	return accounts
+}
+
+func latestTime(times ...time.Time) time.Time {
+	latest := times[0]
+	for _, t := range times[1:] {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+
+	return latest
+}
+
+func earliestTime(times ...time.Time) time.Time {
+	earliest := times[0]
+	for _, t := range times[1:] {
+		if t.Before(earliest) {
+			earliest = t
+		}
+	}
+
+	return earliest
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountUsage(t *testing.T) {
+	projectID := uuid.New()
+
+	snapshots := []*models.UsageSnapshot{
+		// Before the report, holds the usage at its start
+		{ResourceID: projectID, TakenAt: time.Date(2021, 12, 20, 0, 0, 0, 0, time.UTC), ObjectCount: 1, AccSize: 100},
+		{ResourceID: projectID, TakenAt: time.Date(2022, 1, 16, 12, 0, 0, 0, time.UTC), ObjectCount: 3, AccSize: 300},
+		{ResourceID: projectID, TakenAt: time.Date(2022, 2, 15, 0, 0, 0, 0, time.UTC), ObjectCount: 2, AccSize: 200},
+	}
+
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	accounts := AccountUsage(projectID, snapshots, start, end)
+	assert.Len(t, accounts, 2)
+
+	// 100 bytes for the first half of January, 300 bytes for the second half
+	assert.Equal(t, start, accounts[0].Month)
+	assert.InDelta(t, 200, accounts[0].ByteMonths, 0.001)
+	assert.Equal(t, int64(300), accounts[0].AccSize)
+	assert.Equal(t, int64(300), accounts[0].MaxAccSize)
+	assert.Equal(t, int64(3), accounts[0].ObjectCount)
+
+	// 300 bytes for 14 and 200 bytes for 14 of the 28 days of February
+	assert.Equal(t, time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC), accounts[1].Month)
+	assert.InDelta(t, 250, accounts[1].ByteMonths, 0.001)
+	assert.Equal(t, int64(200), accounts[1].AccSize)
+	assert.Equal(t, int64(300), accounts[1].MaxAccSize)
+
+	// Months before the first snapshot are omitted
+	accounts = AccountUsage(projectID, snapshots[2:], start, end)
+	assert.Len(t, accounts, 1)
+	assert.InDelta(t, 100, accounts[0].ByteMonths, 0.001)
+
+	assert.Empty(t, AccountUsage(projectID, nil, start, end))
+
+	// Deleted resources are not accounted after the snapshot without usage of their deletion
+	deletedSnapshots := []*models.UsageSnapshot{
+		{ResourceID: projectID, TakenAt: time.Date(2021, 12, 20, 0, 0, 0, 0, time.UTC), ObjectCount: 3, AccSize: 300},
+		{ResourceID: projectID, TakenAt: time.Date(2022, 1, 16, 12, 0, 0, 0, time.UTC)},
+	}
+
+	accounts = AccountUsage(projectID, deletedSnapshots, start, end)
+	assert.Len(t, accounts, 2)
+	assert.InDelta(t, 150, accounts[0].ByteMonths, 0.001)
+	assert.Equal(t, int64(0), accounts[0].AccSize)
+	assert.Equal(t, int64(300), accounts[0].MaxAccSize)
+	assert.InDelta(t, 0, accounts[1].ByteMonths, 0.001)
+	assert.Equal(t, int64(0), accounts[1].MaxAccSize)
+}
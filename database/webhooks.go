@@ -0,0 +1,314 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbgorm"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Webhooks Handles the webhook subscriptions of projects and the deliveries of events to them
+type Webhooks struct {
+	*Common
+}
+
+// CreateWebhookSubscription Creates a subscription with a new random secret that is used to sign the deliveries
+func (webhooks *Webhooks) CreateWebhookSubscription(subscription *models.WebhookSubscription) error {
+	rndBytes := make([]byte, 32)
+	_, err := rand.Read(rndBytes)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	subscription.Secret = base64.RawURLEncoding.EncodeToString(rndBytes)
+	subscription.Enabled = true
+
+	err = crdbgorm.ExecuteTx(context.Background(), webhooks.DB, nil, func(tx *gorm.DB) error {
+		return tx.Create(subscription).Error
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	resetSnapshotConsumers()
+
+	return nil
+}
+
+// GetWebhookSubscription Returns a single subscription
+func (webhooks *Webhooks) GetWebhookSubscription(subscriptionID uuid.UUID) (*models.WebhookSubscription, error) {
+	subscription := &models.WebhookSubscription{}
+	subscription.ID = subscriptionID
+
+	err := crdbgorm.ExecuteTx(context.Background(), webhooks.DB, nil, func(tx *gorm.DB) error {
+		return tx.First(subscription).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// GetProjectWebhookSubscriptions Returns all subscriptions of a project
+func (webhooks *Webhooks) GetProjectWebhookSubscriptions(projectID uuid.UUID) ([]*models.WebhookSubscription, error) {
+	var subscriptions []*models.WebhookSubscription
+
+	err := crdbgorm.ExecuteTx(context.Background(), webhooks.DB, nil, func(tx *gorm.DB) error {
+		return tx.Where("project_id = ?", projectID).Order("created_at asc").Find(&subscriptions).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// SetWebhookSubscriptionEnabled Enables or disables a subscription, disabled subscriptions do not receive new events
+func (webhooks *Webhooks) SetWebhookSubscriptionEnabled(subscriptionID uuid.UUID, enabled bool) error {
+	err := crdbgorm.ExecuteTx(context.Background(), webhooks.DB, nil, func(tx *gorm.DB) error {
+		return tx.Model(&models.WebhookSubscription{}).Where("id = ?", subscriptionID).Update("enabled", enabled).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	resetSnapshotConsumers()
+
+	return nil
+}
+
+// SetWebhookSubscriptionIncludeSnapshots Requests or stops snapshots of the resources with the deliveries of a subscription
+func (webhooks *Webhooks) SetWebhookSubscriptionIncludeSnapshots(subscriptionID uuid.UUID, includeSnapshots bool) error {
+	err := crdbgorm.ExecuteTx(context.Background(), webhooks.DB, nil, func(tx *gorm.DB) error {
+		return tx.Model(&models.WebhookSubscription{}).Where("id = ?", subscriptionID).Update("include_snapshots", includeSnapshots).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	resetSnapshotConsumers()
+
+	return nil
+}
+
+// DeleteWebhookSubscription Deletes a subscription together with its delivery history
+func (webhooks *Webhooks) DeleteWebhookSubscription(subscriptionID uuid.UUID) error {
+	err := crdbgorm.ExecuteTx(context.Background(), webhooks.DB, nil, func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("subscription_id = ?", subscriptionID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Where("id = ?", subscriptionID).Delete(&models.WebhookSubscription{}).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	resetSnapshotConsumers()
+
+	return nil
+}
+
+// GetEnabledProjectWebhookSubscriptions Returns the enabled subscriptions of a project
+func (webhooks *Webhooks) GetEnabledProjectWebhookSubscriptions(projectID uuid.UUID) ([]*models.WebhookSubscription, error) {
+	var subscriptions []*models.WebhookSubscription
+
+	err := crdbgorm.ExecuteTx(context.Background(), webhooks.DB, nil, func(tx *gorm.DB) error {
+		return tx.Where("project_id = ? AND enabled = ?", projectID, true).Find(&subscriptions).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// CreateWebhookDeliveries Stores the deliveries of an event
+// A delivery is only created once per subscription and event, deliveries of events that are published again are ignored.
+func (webhooks *Webhooks) CreateWebhookDeliveries(deliveries []*models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for _, delivery := range deliveries {
+		delivery.Status = models.WebhookDeliveryStatusPending
+		delivery.NextAttemptAt = now
+	}
+
+	err := crdbgorm.ExecuteTx(context.Background(), webhooks.DB, nil, func(tx *gorm.DB) error {
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(deliveries).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// ClaimPendingWebhookDeliveries Claims up to limit pending deliveries that are due, ordered by their creation
+// The claimed deliveries are not returned to other servers until the lease has expired, they are due again afterwards
+// if they have not been attempted in the meantime. Deliveries that are locked by another server are skipped.
+func (webhooks *Webhooks) ClaimPendingWebhookDeliveries(limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+
+	err := crdbgorm.ExecuteTx(context.Background(), webhooks.DB, nil, func(tx *gorm.DB) error {
+		deliveries = nil
+		now := time.Now()
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryStatusPending, now).
+			Order("created_at asc").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		var ids []uuid.UUID
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.ID)
+		}
+
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// ReleaseWebhookDeliveries Returns claimed deliveries that have not been attempted to the pending deliveries
+func (webhooks *Webhooks) ReleaseWebhookDeliveries(deliveryIDs []uuid.UUID) error {
+	if len(deliveryIDs) == 0 {
+		return nil
+	}
+
+	err := crdbgorm.ExecuteTx(context.Background(), webhooks.DB, nil, func(tx *gorm.DB) error {
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("id IN ? AND status = ?", deliveryIDs, models.WebhookDeliveryStatusPending).
+			Update("next_attempt_at", time.Now()).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetWebhookDelivery Returns a single delivery
+func (webhooks *Webhooks) GetWebhookDelivery(deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	delivery.ID = deliveryID
+
+	err := crdbgorm.ExecuteTx(context.Background(), webhooks.DB, nil, func(tx *gorm.DB) error {
+		return tx.First(delivery).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// GetWebhookDeliveries Returns the latest deliveries of a subscription, optionally filtered by their status
+func (webhooks *Webhooks) GetWebhookDeliveries(subscriptionID uuid.UUID, status string, limit int) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+
+	err := crdbgorm.ExecuteTx(context.Background(), webhooks.DB, nil, func(tx *gorm.DB) error {
+		query := tx.Where("subscription_id = ?", subscriptionID)
+		if status != "" {
+			query = query.Where("status = ?", status)
+		}
+
+		return query.Order("created_at desc").Limit(limit).Find(&deliveries).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// MarkWebhookDeliveryDelivered Marks a delivery as successfully delivered
+func (webhooks *Webhooks) MarkWebhookDeliveryDelivered(deliveryID uuid.UUID, statusCode int) error {
+	err := crdbgorm.ExecuteTx(context.Background(), webhooks.DB, nil, func(tx *gorm.DB) error {
+		return tx.Model(&models.WebhookDelivery{}).Where("id = ?", deliveryID).Updates(map[string]interface{}{
+			"status":           models.WebhookDeliveryStatusDelivered,
+			"attempts":         gorm.Expr("attempts + 1"),
+			"last_status_code": statusCode,
+			"last_error":       "",
+			"delivered_at":     time.Now(),
+		}).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// MarkWebhookDeliveryFailed Records a failed delivery attempt
+// The delivery is retried at nextAttempt or moved to the dead letters if deadLetter is set.
+func (webhooks *Webhooks) MarkWebhookDeliveryFailed(deliveryID uuid.UUID, statusCode int, deliveryErr error, nextAttempt time.Time, deadLetter bool) error {
+	status := models.WebhookDeliveryStatusPending
+	if deadLetter {
+		status = models.WebhookDeliveryStatusDeadLetter
+	}
+
+	err := crdbgorm.ExecuteTx(context.Background(), webhooks.DB, nil, func(tx *gorm.DB) error {
+		return tx.Model(&models.WebhookDelivery{}).Where("id = ?", deliveryID).Updates(map[string]interface{}{
+			"status":           status,
+			"attempts":         gorm.Expr("attempts + 1"),
+			"last_status_code": statusCode,
+			"last_error":       deliveryErr.Error(),
+			"next_attempt_at":  nextAttempt,
+		}).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// RedeliverWebhookDelivery Schedules a delivery for another round of attempts, e.g. after it has been moved to the dead letters
+func (webhooks *Webhooks) RedeliverWebhookDelivery(deliveryID uuid.UUID) error {
+	err := crdbgorm.ExecuteTx(context.Background(), webhooks.DB, nil, func(tx *gorm.DB) error {
+		return tx.Model(&models.WebhookDelivery{}).Where("id = ?", deliveryID).Updates(map[string]interface{}{
+			"status":          models.WebhookDeliveryStatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		}).Error
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/ScienceObjectsDB/CORE-Server/database"
 	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
 	v1storageservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/services/v1"
 )
@@ -225,6 +226,12 @@ func TestDatasetObjects(t *testing.T) {
 		log.Fatalln(err.Error())
 	}
 
+	// Finishing an upload again has no effect
+	_, err = ServerEndpoints.object.FinishObjectUpload(context.Background(), &v1storageservices.FinishObjectUploadRequest{
+		Id: revReadObjectResponse.GetId(),
+	})
+	assert.NoError(t, err)
+
 	objects, err := ServerEndpoints.dataset.GetDatasetObjects(context.Background(), &v1storageservices.GetDatasetObjectsRequest{
 		Id: datasetCreateResponse.GetId(),
 		LabelFilter: &v1storagemodels.LabelFilter{
@@ -313,9 +320,8 @@ func TestDatasetObjectGroupsPagination(t *testing.T) {
 
 	handledObjectGroups := make(map[string]struct{})
 
-	objectGroups1, err := ServerEndpoints.dataset.ReadHandler.GetDatasetObjectGroups(uuid.MustParse(datasetCreateResponse.GetId()), &v1storagemodels.PageRequest{
-		LastUuid: "",
-		PageSize: 4,
+	objectGroups1, _, err := ServerEndpoints.dataset.ReadHandler.GetDatasetObjectGroups(uuid.MustParse(datasetCreateResponse.GetId()), &database.Page{
+		Size: 4,
 	})
 	if err != nil {
 		log.Fatalln(err.Error())
@@ -334,9 +340,9 @@ func TestDatasetObjectGroupsPagination(t *testing.T) {
 		}
 	}
 
-	objectGroups2, err := ServerEndpoints.dataset.ReadHandler.GetDatasetObjectGroups(uuid.MustParse(datasetCreateResponse.GetId()), &v1storagemodels.PageRequest{
-		LastUuid: lastUUID.String(),
-		PageSize: 4,
+	objectGroups2, _, err := ServerEndpoints.dataset.ReadHandler.GetDatasetObjectGroups(uuid.MustParse(datasetCreateResponse.GetId()), &database.Page{
+		Cursor: lastUUID.String(),
+		Size:   4,
 	})
 	if err != nil {
 		log.Fatalln(err.Error())
@@ -353,9 +359,9 @@ func TestDatasetObjectGroupsPagination(t *testing.T) {
 		}
 	}
 
-	objectGroups3, err := ServerEndpoints.dataset.ReadHandler.GetDatasetObjectGroups(uuid.MustParse(datasetCreateResponse.GetId()), &v1storagemodels.PageRequest{
-		LastUuid: lastUUID.String(),
-		PageSize: 2,
+	objectGroups3, _, err := ServerEndpoints.dataset.ReadHandler.GetDatasetObjectGroups(uuid.MustParse(datasetCreateResponse.GetId()), &database.Page{
+		Cursor: lastUUID.String(),
+		Size:   2,
 	})
 	if err != nil {
 		log.Fatalln(err.Error())
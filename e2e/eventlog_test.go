@@ -0,0 +1,74 @@
+package e2e
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ScienceObjectsDB/CORE-Server/config"
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventLogBackfill(t *testing.T) {
+	if viper.GetString(config.DB_DATABASETYPE) != "Postgres" {
+		t.Skip("the event log requires the Postgres database type")
+	}
+
+	eventLog := &database.EventLog{Common: ServerEndpoints.common}
+	streamGroupID := uuid.New()
+	subjectPrefix := fmt.Sprintf("UPDATES.%v", uuid.New())
+
+	matches := func(subject string) bool {
+		return strings.HasPrefix(subject, subjectPrefix+".")
+	}
+
+	outboxSequence := int64(uuid.New().ID())
+	var firstEntry *models.EventLogEntry
+	for i := 0; i < 3; i++ {
+		entry := &models.EventLogEntry{OutboxSequence: outboxSequence + int64(i), Subject: fmt.Sprintf("%v.%v._", subjectPrefix, i)}
+		assert.NoError(t, eventLog.AppendEventLogEntry(entry, []uuid.UUID{streamGroupID}, "test_events"))
+		if firstEntry == nil {
+			firstEntry = entry
+		}
+	}
+
+	// An event that is published again is neither logged nor queued a second time
+	duplicate := &models.EventLogEntry{OutboxSequence: outboxSequence, Subject: firstEntry.Subject}
+	assert.NoError(t, eventLog.AppendEventLogEntry(duplicate, []uuid.UUID{streamGroupID}, "test_events"))
+
+	counts, err := eventLog.GetStreamGroupEventCounts(streamGroupID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), counts.Pending)
+
+	// A reset from a sequence keeps the later events and queues each of them once
+	err = eventLog.ResetStreamGroupEvents(streamGroupID, &models.StreamGroupStart{Policy: models.StreamGroupStartSequence, Sequence: uint64(firstEntry.Sequence + 1)}, matches)
+	assert.NoError(t, err)
+
+	counts, err = eventLog.GetStreamGroupEventCounts(streamGroupID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), counts.Pending)
+	assert.Equal(t, firstEntry.Sequence+1, counts.MinSequence)
+
+	err = eventLog.ResetStreamGroupEvents(streamGroupID, &models.StreamGroupStart{Policy: models.StreamGroupStartAll}, matches)
+	assert.NoError(t, err)
+
+	counts, err = eventLog.GetStreamGroupEventCounts(streamGroupID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), counts.Pending)
+
+	// Events that are appended after a reset are queued in addition to the backfilled events
+	assert.NoError(t, eventLog.ResetStreamGroupEvents(streamGroupID, &models.StreamGroupStart{Policy: models.StreamGroupStartNew}, matches))
+	entry := &models.EventLogEntry{OutboxSequence: outboxSequence + 3, Subject: subjectPrefix + ".3._"}
+	assert.NoError(t, eventLog.AppendEventLogEntry(entry, []uuid.UUID{streamGroupID}, "test_events"))
+
+	counts, err = eventLog.GetStreamGroupEventCounts(streamGroupID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), counts.Pending)
+	assert.Equal(t, entry.Sequence, counts.MinSequence)
+
+	assert.NoError(t, eventLog.DeleteStreamGroupEvents(streamGroupID))
+}
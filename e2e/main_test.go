@@ -1,9 +1,11 @@
 package e2e
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -22,6 +24,8 @@ type ServerEndpointsTest struct {
 	object       *server.ObjectServerEndpoints
 	load         *server.LoadEndpoints
 	notification *server.NotificationEndpoints
+	http         *server.HTTPEndpoints
+	common       *database.Common
 }
 
 var ServerEndpoints = &ServerEndpointsTest{}
@@ -103,21 +107,43 @@ func init_test_endpoints() {
 			Common: &commonHandler,
 		},
 		DeleteHandler: &database.Delete{
-			Common: &commonHandler,
+			Common:                &commonHandler,
+			DefaultTrashRetention: time.Hour,
+		},
+		TrashHandler: &database.Trash{
+			Common:           &commonHandler,
+			DefaultRetention: time.Hour,
 		},
 		StatsHandler: &database.Stats{
 			Common: &commonHandler,
 		},
+		ImportHandler: &database.Imports{
+			Common: &commonHandler,
+		},
 		AuthzHandler:    authzHandler,
+		OutboxHandler:   &database.Outbox{Common: &commonHandler},
 		EventStreamMgmt: eventMgmt,
 	}
 
+	outboxRelay := &eventstreaming.OutboxRelay{
+		Outbox:          endpoints.OutboxHandler,
+		EventStreamMgmt: eventMgmt,
+		PollInterval:    100 * time.Millisecond,
+		BatchSize:       100,
+		MaxAttempts:     20,
+		Retention:       time.Hour,
+	}
+
+	go outboxRelay.Run(context.Background())
+
 	serverEndpoints := &ServerEndpointsTest{
 		project:      &server.ProjectEndpoints{Endpoints: endpoints},
 		dataset:      &server.DatasetEndpoints{Endpoints: endpoints},
 		object:       &server.ObjectServerEndpoints{Endpoints: endpoints},
 		load:         &server.LoadEndpoints{Endpoints: endpoints},
 		notification: &server.NotificationEndpoints{Endpoints: endpoints},
+		http:         &server.HTTPEndpoints{Endpoints: endpoints},
+		common:       &commonHandler,
 	}
 
 	ServerEndpoints = serverEndpoints
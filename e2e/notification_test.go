@@ -59,11 +59,11 @@ func TestNotificationStreamGroup(t *testing.T) {
 	streamErrGrp := errgroup.Group{}
 
 	streamErrGrp.Go(func() error {
-		return streamer1.StartStream()
+		return streamer1.StartStream(context.Background())
 	})
 
 	streamErrGrp.Go(func() error {
-		return streamer2.StartStream()
+		return streamer2.StartStream(context.Background())
 	})
 
 	streamErrGrp.Go(func() error {
@@ -9,10 +9,15 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/ScienceObjectsDB/CORE-Server/database"
 	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
 	v1storageservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/services/v1"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestObjectGroup(t *testing.T) {
@@ -422,6 +427,99 @@ func TestObjectGroupUpdate(t *testing.T) {
 	}
 
 	assert.Equal(t, 13, len(newCurrentRevision2.Objects))
+
+	// Labels are carried forward if the request has no labels, proto3 does not tell an empty list from an unset one.
+	// The requests are encoded and decoded like the requests of gRPC clients.
+	_, err = ServerEndpoints.object.UpdateObjectGroup(context.Background(), encodeUpdateObjectGroupRequest(t, &v1storageservices.UpdateObjectGroupRequest{
+		Id: objectGroup.GetObjectGroupId(),
+		CreateRevisionRequest: &v1storageservices.CreateObjectGroupRevisionRequest{
+			Name:   "labeled-revision",
+			Labels: []*v1storagemodels.Label{{Key: "status", Value: "raw"}},
+		},
+	}))
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	_, err = ServerEndpoints.object.UpdateObjectGroup(context.Background(), encodeUpdateObjectGroupRequest(t, &v1storageservices.UpdateObjectGroupRequest{
+		Id: objectGroup.GetObjectGroupId(),
+		CreateRevisionRequest: &v1storageservices.CreateObjectGroupRevisionRequest{
+			Name: "carried-revision",
+		},
+	}))
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	_, err = ServerEndpoints.object.UpdateObjectGroup(context.Background(), encodeUpdateObjectGroupRequest(t, &v1storageservices.UpdateObjectGroupRequest{
+		Id: objectGroup.GetObjectGroupId(),
+		CreateRevisionRequest: &v1storageservices.CreateObjectGroupRevisionRequest{
+			Name:   "empty-labels-revision",
+			Labels: []*v1storagemodels.Label{},
+		},
+	}))
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	carriedRevision, err := ServerEndpoints.object.GetObjectGroup(context.Background(), &v1storageservices.GetObjectGroupRequest{
+		Id: objectGroup.ObjectGroupId,
+	})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	assert.Equal(t, 1, len(carriedRevision.ObjectGroup.CurrentRevision.Labels))
+	assert.Equal(t, 13, len(carriedRevision.ObjectGroup.CurrentRevision.Objects))
+
+	// The labels are removed with the replace-labels metadata
+	replaceLabelsCtx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(database.ReplaceLabelsMetadataKey, "true"))
+	_, err = ServerEndpoints.object.UpdateObjectGroup(replaceLabelsCtx, encodeUpdateObjectGroupRequest(t, &v1storageservices.UpdateObjectGroupRequest{
+		Id: objectGroup.GetObjectGroupId(),
+		CreateRevisionRequest: &v1storageservices.CreateObjectGroupRevisionRequest{
+			Name: "unlabeled-revision",
+		},
+	}))
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	unlabeledRevision, err := ServerEndpoints.object.GetObjectGroup(context.Background(), &v1storageservices.GetObjectGroupRequest{
+		Id: objectGroup.ObjectGroupId,
+	})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	assert.Equal(t, 0, len(unlabeledRevision.ObjectGroup.CurrentRevision.Labels))
+
+	// Objects can not be updated in place
+	_, err = ServerEndpoints.object.UpdateObjectGroup(context.Background(), &v1storageservices.UpdateObjectGroupRequest{
+		Id: objectGroup.GetObjectGroupId(),
+		CreateRevisionRequest: &v1storageservices.CreateObjectGroupRevisionRequest{
+			Name: "updated-objects-revision",
+			UpdateObjects: &v1storageservices.UpdateObjectsRequests{
+				UpdateObjects: []*v1storageservices.UpdateObjectRequest{{}},
+			},
+		},
+	})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+// Encodes and decodes a request like the gRPC transport, unset and empty fields can not be told apart afterwards
+func encodeUpdateObjectGroupRequest(t *testing.T, request *v1storageservices.UpdateObjectGroupRequest) *v1storageservices.UpdateObjectGroupRequest {
+	data, err := proto.Marshal(request)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	decoded := &v1storageservices.UpdateObjectGroupRequest{}
+	err = proto.Unmarshal(data, decoded)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return decoded
 }
 
 func TestObjectGroupBatch(t *testing.T) {
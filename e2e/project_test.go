@@ -92,12 +92,13 @@ func TestProjectUsers(t *testing.T) {
 		log.Fatalln(err.Error())
 	}
 
-	userId02 := uuid.New()
+	// User ids are oidc subjects and do not have to be uuids
+	userId02 := "oidc|" + uuid.New().String()
 	scope = []v1storagemodels.Right{v1storagemodels.Right(v1storagemodels.Right_RIGHT_WRITE)}
 	addUserResponse02, err := ServerEndpoints.project.AddUserToProject(
 		context.Background(),
 		&v1storageservices.AddUserToProjectRequest{
-			UserId:    userId02.String(),
+			UserId:    userId02,
 			Scope:     scope,
 			ProjectId: projectID.String(),
 		})
@@ -126,7 +127,7 @@ func TestProjectUsers(t *testing.T) {
 	assert.Equal(t, projectID, projectUsers[1].ProjectID)
 
 	assert.NotNil(t, addUserResponse02)
-	assert.Contains(t, oauth2Ids, userId02.String())
+	assert.Contains(t, oauth2Ids, userId02)
 	assert.Equal(t, projectID, projectUsers[2].ProjectID)
 
 	// Try to add users with identical OAuth2IDs to project which should fail and return (nil, error)
@@ -145,7 +146,7 @@ func TestProjectUsers(t *testing.T) {
 	addIdenticalUserResponse02, err := ServerEndpoints.project.AddUserToProject(
 		context.Background(),
 		&v1storageservices.AddUserToProjectRequest{
-			UserId:    userId02.String(),
+			UserId:    userId02,
 			Scope:     scope,
 			ProjectId: projectID.String(),
 		})
@@ -0,0 +1,144 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ScienceObjectsDB/CORE-Server/server"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	v1storageservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/services/v1"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUpdateResources(t *testing.T) {
+	createResponse, err := ServerEndpoints.project.CreateProject(context.Background(), &v1storageservices.CreateProjectRequest{
+		Name:        "Test UpdateResources - Project 001",
+		Description: "Project to test the changes of metadata and labels",
+	})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	datasetCreateResponse, err := ServerEndpoints.dataset.CreateDataset(context.Background(), &v1storageservices.CreateDatasetRequest{
+		Name:      "Test UpdateResources - Dataset 001",
+		ProjectId: createResponse.GetId(),
+	})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	datasetID := uuid.MustParse(datasetCreateResponse.GetId())
+
+	// Metadata
+	name := "Test UpdateResources - Dataset 002"
+	_, err = ServerEndpoints.http.UpdateResource(context.Background(), &server.UpdateResourceRequest{
+		Resource: v1storagemodels.Resource_RESOURCE_DATASET,
+		ID:       datasetID.String(),
+		Name:     &name,
+	})
+	assert.NoError(t, err)
+
+	_, err = ServerEndpoints.dataset.UpdateDatasetField(context.Background(), &v1storageservices.UpdateDatasetFieldRequest{
+		UpdateRequest: &v1storagemodels.UpdateFieldsRequest{
+			Id:                  datasetID.String(),
+			UpdatedStringFields: map[string]string{"description": "changed description"},
+		},
+	})
+	assert.NoError(t, err)
+
+	dataset, err := ServerEndpoints.project.ReadHandler.GetDataset(datasetID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	assert.Equal(t, name, dataset.Name)
+	assert.Equal(t, "changed description", dataset.Description)
+
+	description := "changed project description"
+	_, err = ServerEndpoints.http.UpdateResource(context.Background(), &server.UpdateResourceRequest{
+		Resource:    v1storagemodels.Resource_RESOURCE_PROJECT,
+		ID:          createResponse.GetId(),
+		Description: &description,
+	})
+	assert.NoError(t, err)
+
+	empty := ""
+	_, err = ServerEndpoints.http.UpdateResource(context.Background(), &server.UpdateResourceRequest{
+		Resource: v1storagemodels.Resource_RESOURCE_DATASET,
+		ID:       datasetID.String(),
+		Name:     &empty,
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	objects, err := UploadObjects(ServerEndpoints.load, ServerEndpoints.object, 1, datasetID.String(), "updateresources-")
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	_, err = ServerEndpoints.http.UpdateResource(context.Background(), &server.UpdateResourceRequest{
+		Resource:    v1storagemodels.Resource_RESOURCE_OBJECT,
+		ID:          objects[0].ID.String(),
+		Description: &description,
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Labels
+	labelsResponse, err := ServerEndpoints.http.UpdateResourceLabels(context.Background(), &server.UpdateResourceLabelsRequest{
+		Resource: v1storagemodels.Resource_RESOURCE_DATASET,
+		ID:       datasetID.String(),
+		Add:      []server.Label{{Key: "status", Value: "raw"}, {Key: "pipeline", Value: "ingest"}},
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []server.Label{{Key: "status", Value: "raw"}, {Key: "pipeline", Value: "ingest"}}, labelsResponse.Labels)
+
+	labelsResponse, err = ServerEndpoints.http.UpdateResourceLabels(context.Background(), &server.UpdateResourceLabelsRequest{
+		Resource: v1storagemodels.Resource_RESOURCE_DATASET,
+		ID:       datasetID.String(),
+		Add:      []server.Label{{Key: "status", Value: "processed"}},
+		Remove:   []server.Label{{Key: "status"}},
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []server.Label{{Key: "status", Value: "processed"}, {Key: "pipeline", Value: "ingest"}}, labelsResponse.Labels)
+
+	labelsResponse, err = ServerEndpoints.http.UpdateResourceLabels(context.Background(), &server.UpdateResourceLabelsRequest{
+		Resource: v1storagemodels.Resource_RESOURCE_DATASET,
+		ID:       datasetID.String(),
+		Add:      []server.Label{{Key: "owner", Value: "lab-1"}},
+		Replace:  true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []server.Label{{Key: "owner", Value: "lab-1"}}, labelsResponse.Labels)
+
+	// Released dataset versions can not be changed
+	versionResponse, err := ServerEndpoints.dataset.ReleaseDatasetVersion(context.Background(), &v1storageservices.ReleaseDatasetVersionRequest{
+		Name:      "Test UpdateResources - Version 1.0.0.0",
+		DatasetId: datasetID.String(),
+		Version: &v1storagemodels.Version{
+			Major: 1,
+			Stage: v1storagemodels.Version_VERSION_STAGE_STABLE,
+		},
+	})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	versionName := "Test UpdateResources - Version 1.0.0.1"
+	_, err = ServerEndpoints.http.UpdateResource(context.Background(), &server.UpdateResourceRequest{
+		Resource: v1storagemodels.Resource_RESOURCE_DATASET_VERSION,
+		ID:       versionResponse.GetId(),
+		Name:     &versionName,
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = ServerEndpoints.http.UpdateResourceLabels(context.Background(), &server.UpdateResourceLabelsRequest{
+		Resource: v1storagemodels.Resource_RESOURCE_DATASET_VERSION,
+		ID:       versionResponse.GetId(),
+		Add:      []server.Label{{Key: "status", Value: "raw"}},
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
@@ -0,0 +1,182 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1storageservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/services/v1"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// Reads the statistics of a project, rows without counts are left out because rebuilt statistics do not contain them
+func readStats(projectID uuid.UUID) map[uuid.UUID]models.ResourceStats {
+	var rows []models.ResourceStats
+	err := ServerEndpoints.common.DB.Where("project_id = ?", projectID).Find(&rows).Error
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	stats := make(map[uuid.UUID]models.ResourceStats)
+	for _, row := range rows {
+		row.UpdatedAt = time.Time{}
+		if row.ObjectCount == 0 && row.AccSize == 0 && row.ObjectGroupCount == 0 && row.MetaObjectCount == 0 && row.UserCount == 0 {
+			continue
+		}
+		stats[row.ResourceID] = row
+	}
+
+	return stats
+}
+
+// Compares the incrementally maintained statistics of a project with the statistics computed by RebuildStats
+func assertStatsRebuilt(t *testing.T, step string, projectID uuid.UUID) {
+	incremental := readStats(projectID)
+
+	err := ServerEndpoints.http.StatsHandler.RebuildStats()
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	assert.Equal(t, readStats(projectID), incremental, step)
+}
+
+func TestStatsRebuild(t *testing.T) {
+	createResponse, err := ServerEndpoints.project.CreateProject(context.Background(), &v1storageservices.CreateProjectRequest{
+		Name:        "Test StatsRebuild - Project 001",
+		Description: "Project to compare the incremental statistics with rebuilt statistics",
+	})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	projectID := uuid.MustParse(createResponse.GetId())
+
+	datasetResponse, err := ServerEndpoints.dataset.CreateDataset(context.Background(), &v1storageservices.CreateDatasetRequest{
+		Name:      "Test StatsRebuild - Dataset 001",
+		ProjectId: projectID.String(),
+	})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	datasetID := uuid.MustParse(datasetResponse.GetId())
+
+	// Create
+	objects, err := UploadObjects(ServerEndpoints.load, ServerEndpoints.object, 3, datasetID.String(), "stats-")
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	objectGroupResponse, err := ServerEndpoints.object.CreateObjectGroup(context.Background(), &v1storageservices.CreateObjectGroupRequest{
+		DatasetId: datasetID.String(),
+		CreateRevisionRequest: &v1storageservices.CreateObjectGroupRevisionRequest{
+			Name: "stats-group",
+			UpdateObjects: &v1storageservices.UpdateObjectsRequests{
+				AddObjects: []*v1storageservices.AddObjectRequest{{Id: objects[0].ID.String()}, {Id: objects[1].ID.String()}},
+			},
+			UpdateMetaObjects: &v1storageservices.UpdateObjectsRequests{
+				AddObjects: []*v1storageservices.AddObjectRequest{{Id: objects[2].ID.String()}},
+			},
+		},
+	})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	assertStatsRebuilt(t, "create", projectID)
+
+	// Trash and restore an object group
+	_, err = ServerEndpoints.object.DeleteObjectGroup(context.Background(), &v1storageservices.DeleteObjectGroupRequest{Id: objectGroupResponse.GetObjectGroupId()})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	assertStatsRebuilt(t, "trash object group", projectID)
+
+	entries, err := ServerEndpoints.http.TrashHandler.GetProjectTrash(projectID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	_, err = ServerEndpoints.http.TrashHandler.RestoreTrashEntry(entries[0].ID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	assertStatsRebuilt(t, "restore object group", projectID)
+
+	// Import
+	dataset, err := ServerEndpoints.project.ReadHandler.GetDataset(datasetID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	imports := ServerEndpoints.http.ImportHandler
+	job, err := imports.CreateImportJob(dataset, "tar", "stats-test")
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	dataObject := imports.NewImportedObject(job, "imported.bin", job.StagingBucket)
+	dataObject.ContentLen = 42
+	metaObject := imports.NewImportedObject(job, "imported.json", job.StagingBucket)
+	metaObject.ContentLen = 7
+
+	_, err = imports.CreateImportedObjectGroup(job, &database.ImportedObjectGroup{
+		Name:        "imported-group",
+		DataObjects: []models.Object{dataObject},
+	})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	err = imports.AddImportedDatasetMetaObjects(job, []models.Object{metaObject})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	assertStatsRebuilt(t, "import", projectID)
+
+	// Trash, restore and delete the dataset
+	_, err = ServerEndpoints.dataset.DeleteDataset(context.Background(), &v1storageservices.DeleteDatasetRequest{Id: datasetID.String()})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	assertStatsRebuilt(t, "trash dataset", projectID)
+
+	entries, err = ServerEndpoints.http.TrashHandler.GetProjectTrash(projectID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	_, err = ServerEndpoints.http.TrashHandler.RestoreTrashEntry(entries[0].ID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	assertStatsRebuilt(t, "restore dataset", projectID)
+
+	_, err = ServerEndpoints.dataset.DeleteDataset(context.Background(), &v1storageservices.DeleteDatasetRequest{Id: datasetID.String()})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	entries, err = ServerEndpoints.http.TrashHandler.GetProjectTrash(projectID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	err = ServerEndpoints.http.TrashHandler.PurgeTrashEntry(entries[0])
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	assertStatsRebuilt(t, "delete dataset", projectID)
+}
@@ -0,0 +1,178 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	v1storageservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/services/v1"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTrash(t *testing.T) {
+	trash := ServerEndpoints.http.TrashHandler
+	stats := ServerEndpoints.http.StatsHandler
+
+	createResponse, err := ServerEndpoints.project.CreateProject(context.Background(), &v1storageservices.CreateProjectRequest{
+		Name:        "Test Trash - Project 001",
+		Description: "Project to test moving datasets into the trash, restoring and purging them",
+	})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	projectID := uuid.MustParse(createResponse.GetId())
+
+	createDataset := func(name string) uuid.UUID {
+		datasetResponse, err := ServerEndpoints.dataset.CreateDataset(context.Background(), &v1storageservices.CreateDatasetRequest{
+			Name:      name,
+			ProjectId: projectID.String(),
+		})
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+		_, err = UploadObjects(ServerEndpoints.load, ServerEndpoints.object, 2, datasetResponse.GetId(), "trash-")
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+		return uuid.MustParse(datasetResponse.GetId())
+	}
+
+	lastUpdateType := func(resourceID uuid.UUID) string {
+		events, err := ServerEndpoints.http.OutboxHandler.GetProjectOutboxEvents(projectID, resourceID, 1)
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+		if len(events) == 0 {
+			return ""
+		}
+
+		return events[0].UpdateType
+	}
+
+	datasetID := createDataset("Test Trash - Dataset 001")
+
+	statsBefore, err := stats.GetProjectStats(projectID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	assert.Equal(t, int64(2), statsBefore.ObjectCount)
+
+	// Move into the trash
+	_, err = ServerEndpoints.dataset.DeleteDataset(context.Background(), &v1storageservices.DeleteDatasetRequest{Id: datasetID.String()})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	entries, err := trash.GetProjectTrash(projectID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, datasetID, entries[0].ResourceID)
+
+	_, err = ServerEndpoints.project.ReadHandler.GetDataset(datasetID)
+	assert.Error(t, err)
+
+	statsTrashed, err := stats.GetProjectStats(projectID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	assert.Equal(t, int64(0), statsTrashed.ObjectCount)
+	assert.Equal(t, int64(0), statsTrashed.AccSize)
+	assert.Equal(t, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_DELETED.String(), lastUpdateType(datasetID))
+
+	// Restore
+	_, err = trash.RestoreTrashEntry(entries[0].ID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	_, err = ServerEndpoints.project.ReadHandler.GetDataset(datasetID)
+	assert.NoError(t, err)
+
+	statsRestored, err := stats.GetProjectStats(projectID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	assert.Equal(t, statsBefore.ObjectCount, statsRestored.ObjectCount)
+	assert.Equal(t, statsBefore.AccSize, statsRestored.AccSize)
+	assert.Equal(t, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED.String(), lastUpdateType(datasetID))
+
+	// Purge
+	_, err = ServerEndpoints.dataset.DeleteDataset(context.Background(), &v1storageservices.DeleteDatasetRequest{Id: datasetID.String()})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	entries, err = trash.GetProjectTrash(projectID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	assert.Equal(t, 1, len(entries))
+
+	err = trash.PurgeTrashEntry(entries[0])
+	assert.NoError(t, err)
+
+	entries, err = trash.GetProjectTrash(projectID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	assert.Equal(t, 0, len(entries))
+
+	var datasets int64
+	err = ServerEndpoints.common.DB.Unscoped().Model(&models.Dataset{}).Where("id = ?", datasetID).Count(&datasets).Error
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	assert.Equal(t, int64(0), datasets)
+
+	statsPurged, err := stats.GetProjectStats(projectID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	assert.Equal(t, int64(0), statsPurged.ObjectCount)
+
+	// Entries that are being purged can not be restored and are not purged by another server
+	purgingDatasetID := createDataset("Test Trash - Dataset 002")
+
+	_, err = ServerEndpoints.dataset.DeleteDataset(context.Background(), &v1storageservices.DeleteDatasetRequest{Id: purgingDatasetID.String()})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	entries, err = trash.GetProjectTrash(projectID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	assert.Equal(t, 1, len(entries))
+
+	err = ServerEndpoints.common.DB.Model(entries[0]).Update("purging_at", time.Now().UTC()).Error
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	_, err = trash.RestoreTrashEntry(entries[0].ID)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	err = trash.PurgeTrashEntry(entries[0])
+	assert.Equal(t, database.ErrTrashEntryPurging, err)
+
+	expired, err := trash.GetExpiredTrashEntries(time.Now().Add(2*time.Hour), 1000)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	for _, entry := range expired {
+		assert.NotEqual(t, entries[0].ID, entry.ID)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/ScienceObjectsDB/CORE-Server/database"
 	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
 	v1storageservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/services/v1"
 	"github.com/google/uuid"
@@ -277,9 +278,8 @@ func TestDatasetVersionPaginated(t *testing.T) {
 		log.Fatalln(err.Error())
 	}
 
-	objectGroups1, err := ServerEndpoints.dataset.ReadHandler.GetDatasetVersionWithObjectGroups(versionID, &v1storagemodels.PageRequest{
-		LastUuid: "",
-		PageSize: 4,
+	objectGroups1, _, err := ServerEndpoints.dataset.ReadHandler.GetDatasetVersionWithObjectGroups(versionID, &database.Page{
+		Size: 4,
 	})
 	if err != nil {
 		log.Fatalln(err.Error())
@@ -298,9 +298,9 @@ func TestDatasetVersionPaginated(t *testing.T) {
 		}
 	}
 
-	objectGroups2, err := ServerEndpoints.dataset.ReadHandler.GetDatasetVersionWithObjectGroups(versionID, &v1storagemodels.PageRequest{
-		LastUuid: lastUUID.String(),
-		PageSize: 4,
+	objectGroups2, _, err := ServerEndpoints.dataset.ReadHandler.GetDatasetVersionWithObjectGroups(versionID, &database.Page{
+		Cursor: lastUUID.String(),
+		Size:   4,
 	})
 	if err != nil {
 		log.Fatalln(err.Error())
@@ -317,9 +317,9 @@ func TestDatasetVersionPaginated(t *testing.T) {
 		}
 	}
 
-	objectGroups3, err := ServerEndpoints.dataset.ReadHandler.GetDatasetVersionWithObjectGroups(versionID, &v1storagemodels.PageRequest{
-		LastUuid: lastUUID.String(),
-		PageSize: 2,
+	objectGroups3, _, err := ServerEndpoints.dataset.ReadHandler.GetDatasetVersionWithObjectGroups(versionID, &database.Page{
+		Cursor: lastUUID.String(),
+		Size:   2,
 	})
 	if err != nil {
 		log.Fatalln(err.Error())
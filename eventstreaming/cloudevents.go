@@ -0,0 +1,220 @@
+package eventstreaming
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Formats in which events are published, configured with EventNotifications.Format
+const (
+	// The event data without envelope, the default
+	EventFormatProtoJSON = "protojson"
+	// A CloudEvents json document that contains the attributes and the event data
+	EventFormatCloudEventsStructured = "cloudevents-structured"
+	// The event data with the CloudEvents attributes as "ce-" headers
+	EventFormatCloudEventsBinary = "cloudevents-binary"
+)
+
+// CloudEventsSpecVersion Version of the CloudEvents specification the events are encoded with
+const CloudEventsSpecVersion = "1.0"
+
+// CloudEventsTypePrefix Prefix of the type attribute, the type is <prefix>.<resource>.<update type>
+const CloudEventsTypePrefix = "org.scienceobjectsdb"
+
+// Header of the encoded notification messages that carries the json encoded labels of the resource of the event
+const eventLabelsHeader = "sciobjsdb-labels"
+
+const cloudEventsContentType = "application/cloudevents+json"
+const cloudEventsHeaderPrefix = "ce-"
+const jsonContentType = "application/json"
+const contentTypeHeader = "content-type"
+
+// CloudEvent A CloudEvents 1.0 event in the structured json format
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+// EncodedEvent An event as it is handed to a backend, the headers are only set by the CloudEvents formats
+type EncodedEvent struct {
+	Data    []byte
+	Headers map[string]string
+}
+
+// ValidateEventFormat Checks if a format can be used to publish events
+func ValidateEventFormat(format string) error {
+	switch format {
+	case "", EventFormatProtoJSON, EventFormatCloudEventsStructured, EventFormatCloudEventsBinary:
+		return nil
+	default:
+		return fmt.Errorf("unknown event format %v in EventNotifications.Format, please specify either %v, %v or %v", format, EventFormatProtoJSON, EventFormatCloudEventsStructured, EventFormatCloudEventsBinary)
+	}
+}
+
+// NewCloudEvent Creates the CloudEvent of an outbox event with the json data of a backend
+func NewCloudEvent(event *models.OutboxEvent, data []byte) *CloudEvent {
+	return &CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              event.ID.String(),
+		Source:          CloudEventSource(event),
+		Type:            CloudEventType(event),
+		Subject:         event.ResourceID.String(),
+		Time:            event.CreatedAt.UTC(),
+		DataContentType: jsonContentType,
+		Data:            json.RawMessage(data),
+	}
+}
+
+// CloudEventSource Returns the source attribute of an event, the path of the resource in the project hierarchy
+// e.g. /projects/<ProjectID>/datasets/<DatasetID>/objectgroups/<ObjectGroupID>
+func CloudEventSource(event *models.OutboxEvent) string {
+	source := fmt.Sprintf("/projects/%v", event.ProjectID.String())
+	if event.DatasetID != uuid.Nil {
+		source = fmt.Sprintf("%v/datasets/%v", source, event.DatasetID.String())
+	}
+	if event.ObjectGroupID != uuid.Nil {
+		source = fmt.Sprintf("%v/objectgroups/%v", source, event.ObjectGroupID.String())
+	}
+	if event.DatasetVersionID != uuid.Nil {
+		source = fmt.Sprintf("%v/datasetversions/%v", source, event.DatasetVersionID.String())
+	}
+
+	return source
+}
+
+// CloudEventType Returns the type attribute of an event, e.g. org.scienceobjectsdb.object_group.created
+func CloudEventType(event *models.OutboxEvent) string {
+	resource := strings.ToLower(strings.TrimPrefix(event.Resource, "RESOURCE_"))
+	updateType := strings.ToLower(strings.TrimPrefix(event.UpdateType, "UPDATE_TYPE_"))
+
+	return fmt.Sprintf("%v.%v.%v", CloudEventsTypePrefix, resource, updateType)
+}
+
+// EncodeEvent Wraps the json data of an event in the given format
+func EncodeEvent(format string, event *models.OutboxEvent, data []byte) (*EncodedEvent, error) {
+	switch format {
+	case "", EventFormatProtoJSON:
+		return &EncodedEvent{Data: data}, nil
+	case EventFormatCloudEventsStructured:
+		encoded, err := json.Marshal(NewCloudEvent(event, data))
+		if err != nil {
+			return nil, err
+		}
+
+		return &EncodedEvent{
+			Data:    encoded,
+			Headers: map[string]string{contentTypeHeader: cloudEventsContentType},
+		}, nil
+	case EventFormatCloudEventsBinary:
+		cloudEvent := NewCloudEvent(event, data)
+
+		return &EncodedEvent{
+			Data: data,
+			Headers: map[string]string{
+				contentTypeHeader:                       jsonContentType,
+				cloudEventsHeaderPrefix + "specversion": cloudEvent.SpecVersion,
+				cloudEventsHeaderPrefix + "id":          cloudEvent.ID,
+				cloudEventsHeaderPrefix + "source":      cloudEvent.Source,
+				cloudEventsHeaderPrefix + "type":        cloudEvent.Type,
+				cloudEventsHeaderPrefix + "subject":     cloudEvent.Subject,
+				cloudEventsHeaderPrefix + "time":        cloudEvent.Time.Format(time.RFC3339Nano),
+			},
+		}, nil
+	default:
+		return nil, ValidateEventFormat(format)
+	}
+}
+
+// DecodeEventData Returns the json data of an event in any of the formats
+func DecodeEventData(encoded *EncodedEvent) ([]byte, error) {
+	for key, value := range encoded.Headers {
+		if strings.EqualFold(key, contentTypeHeader) && strings.HasPrefix(value, cloudEventsContentType) {
+			cloudEvent := &CloudEvent{}
+			err := json.Unmarshal(encoded.Data, cloudEvent)
+			if err != nil {
+				return nil, err
+			}
+
+			return cloudEvent.Data, nil
+		}
+	}
+
+	return encoded.Data, nil
+}
+
+// Encodes the notification message of an event, the message is the event data of the stream group backends
+// The labels and the snapshot of the resource are added as headers, the label filters of the stream groups match on
+// the labels and the snapshot is sent to the stream groups that requested snapshots.
+func encodeNotificationMessage(format string, event *models.OutboxEvent) (*EncodedEvent, error) {
+	data, err := protojson.Marshal(event.ToProtoModel())
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := EncodeEvent(format, event, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if encoded.Headers == nil {
+		encoded.Headers = make(map[string]string)
+	}
+	if event.Labels != "" {
+		encoded.Headers[eventLabelsHeader] = event.Labels
+	}
+
+	return encoded, nil
+}
+
+// Returns the labels of the resource of an encoded notification message, messages without labels return an empty list
+func decodeNotificationLabels(encoded *EncodedEvent) ([]models.EventLabel, error) {
+	labels := []models.EventLabel{}
+	if value := notificationHeader(encoded, eventLabelsHeader); value != "" {
+		err := json.Unmarshal([]byte(value), &labels)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return labels, nil
+}
+
+// Returns a header of an encoded notification message, backends like nats change the case of the header names
+func notificationHeader(encoded *EncodedEvent, name string) string {
+	for key, value := range encoded.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// Decodes the notification message of an event that has been encoded with encodeNotificationMessage
+func decodeNotificationMessage(encoded *EncodedEvent) (*v1notificationservices.EventNotificationMessage, error) {
+	data, err := DecodeEventData(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &v1notificationservices.EventNotificationMessage{}
+	err = protojson.Unmarshal(data, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,54 @@
+package eventstreaming
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudEventsEncoding(t *testing.T) {
+	projectID := uuid.New()
+	datasetID := uuid.New()
+	objectGroupID := uuid.New()
+
+	event := models.NewObjectGroupEvent(projectID, datasetID, objectGroupID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_METADATA_UPDATED)
+	event.ID = uuid.New()
+
+	assert.Equal(t, fmt.Sprintf("/projects/%v/datasets/%v/objectgroups/%v", projectID, datasetID, objectGroupID), CloudEventSource(event))
+	assert.Equal(t, "org.scienceobjectsdb.object_group.metadata_updated", CloudEventType(event))
+
+	for _, format := range []string{EventFormatProtoJSON, EventFormatCloudEventsStructured, EventFormatCloudEventsBinary} {
+		encoded, err := encodeNotificationMessage(format, event)
+		assert.NoError(t, err)
+
+		msg, err := decodeNotificationMessage(encoded)
+		assert.NoError(t, err)
+		assert.Equal(t, v1storagemodels.Resource_RESOURCE_OBJECT_GROUP, msg.Resource)
+		assert.Equal(t, objectGroupID.String(), msg.ResourceId)
+		assert.Equal(t, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_METADATA_UPDATED, msg.UpdatedType)
+	}
+
+	structured, err := EncodeEvent(EventFormatCloudEventsStructured, event, []byte(`{"resource_id":"test"}`))
+	assert.NoError(t, err)
+	cloudEvent := &CloudEvent{}
+	assert.NoError(t, json.Unmarshal(structured.Data, cloudEvent))
+	assert.Equal(t, CloudEventsSpecVersion, cloudEvent.SpecVersion)
+	assert.Equal(t, event.ID.String(), cloudEvent.ID)
+	assert.Equal(t, objectGroupID.String(), cloudEvent.Subject)
+	assert.JSONEq(t, `{"resource_id":"test"}`, string(cloudEvent.Data))
+
+	binary, err := EncodeEvent(EventFormatCloudEventsBinary, event, []byte(`{}`))
+	assert.NoError(t, err)
+	assert.Equal(t, CloudEventSource(event), binary.Headers["ce-source"])
+	assert.Equal(t, CloudEventType(event), binary.Headers["ce-type"])
+	assert.Equal(t, "application/json", binary.Headers["content-type"])
+
+	_, err = EncodeEvent("xml", event, []byte(`{}`))
+	assert.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package eventstreaming
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/ScienceObjectsDB/CORE-Server/models"
@@ -18,17 +19,29 @@ func (mgmt *emptyEventStreamMgmt) EnableTestMode() error {
 	return nil
 }
 
-func (mgmt *emptyEventStreamMgmt) CreateStreamGroup(projectID uuid.UUID, resourceID uuid.UUID, resourceType *v1notificationservices.CreateEventStreamingGroupRequest_EventResources, includeSubResources bool) (*models.StreamGroup, error) {
+func (mgmt *emptyEventStreamMgmt) CreateStreamGroup(projectID uuid.UUID, resourceID uuid.UUID, resourceType *v1notificationservices.CreateEventStreamingGroupRequest_EventResources, includeSubResources bool, start *models.StreamGroupStart) (*models.StreamGroup, error) {
 	err := fmt.Errorf("the event streaming backend does not support stream groups")
 
 	return nil, err
 }
 
+func (mgmt *emptyEventStreamMgmt) GetStreamGroupInfo(streamGroup *models.StreamGroup) (*StreamGroupInfo, error) {
+	return nil, fmt.Errorf("the event streaming backend does not support stream groups")
+}
+
+func (mgmt *emptyEventStreamMgmt) ResetStreamGroup(streamGroup *models.StreamGroup, start *models.StreamGroupStart) error {
+	return fmt.Errorf("the event streaming backend does not support stream groups")
+}
+
+func (mgmt *emptyEventStreamMgmt) DeleteStreamGroup(streamGroup *models.StreamGroup) error {
+	return fmt.Errorf("the event streaming backend does not support stream groups")
+}
+
 func (mgmt *emptyEventStreamMgmt) CreateMessageStreamGroupHandler(streamGroup *models.StreamGroup) (EventStreamer, error) {
 	return emptyEventStreamer{}, nil
 }
 
-func (mgmt *emptyEventStreamMgmt) PublishMessage(request *v1notificationservices.EventNotificationMessage) error {
+func (mgmt *emptyEventStreamMgmt) PublishMessage(event *models.OutboxEvent) error {
 	return nil
 }
 
@@ -36,7 +49,7 @@ func (streamer emptyEventStreamer) GetResponseMessageChan() chan *v1notification
 	return make(chan *v1notificationservices.NotificationStreamGroupResponse)
 }
 
-func (streamer emptyEventStreamer) StartStream() error {
+func (streamer emptyEventStreamer) StartStream(ctx context.Context) error {
 	return nil
 }
 
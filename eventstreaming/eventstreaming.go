@@ -1,6 +1,7 @@
 package eventstreaming
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/ScienceObjectsDB/CORE-Server/config"
@@ -14,16 +15,26 @@ import (
 func New(dbRead *database.Read, dbCreate *database.Create) (EventStreamMgmt, error) {
 	eventStreamBackendConfString := viper.GetString(config.EVENTNOTIFICATION_BACKEND)
 
+	err := ValidateEventFormat(viper.GetString(config.EVENTNOTIFICATION_FORMAT))
+	if err != nil {
+		return nil, err
+	}
+
 	var streamMgmt EventStreamMgmt
-	var err error
 
 	switch eventStreamBackendConfString {
 	case "Empty":
 		streamMgmt = &emptyEventStreamMgmt{}
+	case "Memory":
+		streamMgmt, err = NewMemoryEventStreamMgmt(dbRead, dbCreate)
 	case "NATS":
 		streamMgmt, err = NewNatsEventStreamMgmt(dbRead, dbCreate)
+	case "Postgres":
+		streamMgmt, err = NewPostgresEventStreamMgmt(dbRead, dbCreate)
+	case "Webhook":
+		streamMgmt, err = NewWebhookEventStreamMgmt(&database.Webhooks{Common: dbRead.Common})
 	default:
-		err = fmt.Errorf("no valid eventstreaming config found in EventNotifications.Backend, please specify either NATS or Empty")
+		err = fmt.Errorf("no valid eventstreaming config found in EventNotifications.Backend, please specify either Memory, NATS, Postgres, Webhook or Empty")
 	}
 
 	return streamMgmt, err
@@ -31,14 +42,58 @@ func New(dbRead *database.Read, dbCreate *database.Create) (EventStreamMgmt, err
 
 type EventStreamMgmt interface {
 	CreateMessageStreamGroupHandler(streamGroup *models.StreamGroup) (EventStreamer, error)
-	CreateStreamGroup(projectID uuid.UUID, resourceID uuid.UUID, resourceType *v1notificationservices.CreateEventStreamingGroupRequest_EventResources, includeSubResources bool) (*models.StreamGroup, error)
-	PublishMessage(request *v1notificationservices.EventNotificationMessage) error
+	CreateStreamGroup(projectID uuid.UUID, resourceID uuid.UUID, resourceType *v1notificationservices.CreateEventStreamingGroupRequest_EventResources, includeSubResources bool, start *models.StreamGroupStart) (*models.StreamGroup, error)
+	// GetStreamGroupInfo Returns the delivery state of a stream group
+	GetStreamGroupInfo(streamGroup *models.StreamGroup) (*StreamGroupInfo, error)
+	// ResetStreamGroup Restarts the delivery of a stream group at a new position, pending acknowledgements are dropped
+	ResetStreamGroup(streamGroup *models.StreamGroup, start *models.StreamGroupStart) error
+	// DeleteStreamGroup Removes the consumer of a stream group from the backend
+	DeleteStreamGroup(streamGroup *models.StreamGroup) error
+	PublishMessage(event *models.OutboxEvent) error
 	EnableTestMode() error
 }
 
+// StreamGroupInfo Delivery state of a stream group
+type StreamGroupInfo struct {
+	// Number of events that have not been delivered yet
+	Pending uint64
+	// Number of delivered events that have not been acknowledged
+	AckPending uint64
+	// Number of events that have been delivered again
+	Redelivered uint64
+	// Sequence of the last delivered event
+	LastDeliveredSequence uint64
+	// Sequence up to which all events have been acknowledged
+	AckFloorSequence uint64
+}
+
+// StreamGroupStartFromRequest Returns the start position that is requested by the stream type of a create request
+// Groups without a stream type start with all retained events like StreamAll.
+func StreamGroupStartFromRequest(request *v1notificationservices.CreateEventStreamingGroupRequest) (*models.StreamGroupStart, error) {
+	switch streamType := request.GetStreamType().(type) {
+	case nil, *v1notificationservices.CreateEventStreamingGroupRequest_StreamAll:
+		return &models.StreamGroupStart{Policy: models.StreamGroupStartAll}, nil
+	case *v1notificationservices.CreateEventStreamingGroupRequest_StreamFromSequence:
+		if streamType.StreamFromSequence.GetSequence() == 0 {
+			return nil, fmt.Errorf("start sequence has to be greater than 0")
+		}
+		return &models.StreamGroupStart{Policy: models.StreamGroupStartSequence, Sequence: streamType.StreamFromSequence.GetSequence()}, nil
+	case *v1notificationservices.CreateEventStreamingGroupRequest_StreamFromDate:
+		if streamType.StreamFromDate.GetTimestamp() == nil {
+			return nil, fmt.Errorf("start timestamp is required")
+		}
+		return &models.StreamGroupStart{Policy: models.StreamGroupStartTime, Time: streamType.StreamFromDate.GetTimestamp().AsTime()}, nil
+	default:
+		return nil, fmt.Errorf("unknown stream type")
+	}
+}
+
+// EventStreamer Streams the events of a stream group to a single client
+// StartStream runs until the context is cancelled or CloseStream is called, the response channel is closed afterwards.
+// AckChunk returns ErrUnknownChunk for chunks that are not pending anymore.
 type EventStreamer interface {
 	GetResponseMessageChan() chan *v1notificationservices.NotificationStreamGroupResponse
-	StartStream() error
+	StartStream(ctx context.Context) error
 	CloseStream() error
 	AckChunk(chunkID string) error
 }
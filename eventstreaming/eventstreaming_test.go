@@ -0,0 +1,32 @@
+package eventstreaming
+
+import (
+	"testing"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamGroupStartFromRequest(t *testing.T) {
+	// Groups without a stream type start with all events
+	start, err := StreamGroupStartFromRequest(&v1notificationservices.CreateEventStreamingGroupRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, models.StreamGroupStartAll, start.Policy)
+
+	start, err = StreamGroupStartFromRequest(&v1notificationservices.CreateEventStreamingGroupRequest{
+		StreamType: &v1notificationservices.CreateEventStreamingGroupRequest_StreamFromSequence{
+			StreamFromSequence: &v1notificationservices.StreamFromSequence{Sequence: 42},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, models.StreamGroupStartSequence, start.Policy)
+	assert.Equal(t, uint64(42), start.Sequence)
+
+	_, err = StreamGroupStartFromRequest(&v1notificationservices.CreateEventStreamingGroupRequest{
+		StreamType: &v1notificationservices.CreateEventStreamingGroupRequest_StreamFromSequence{
+			StreamFromSequence: &v1notificationservices.StreamFromSequence{},
+		},
+	})
+	assert.Error(t, err)
+}
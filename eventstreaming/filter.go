@@ -0,0 +1,50 @@
+package eventstreaming
+
+import (
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// streamGroupFilter Applies the filters and options of a stream group to the events before they are sent to a client
+// Events that do not pass the filters are acknowledged without being delivered.
+type streamGroupFilter struct {
+	streamGroup *models.StreamGroup
+}
+
+func newStreamGroupFilter(streamGroup *models.StreamGroup) *streamGroupFilter {
+	return &streamGroupFilter{
+		streamGroup: streamGroup,
+	}
+}
+
+// Checks if an encoded event passes the filters of the stream group
+// The labels are the labels of the resource at the time of the event that are carried by the encoded event.
+func (filter *streamGroupFilter) matches(message *v1notificationservices.EventNotificationMessage, encoded *EncodedEvent) bool {
+	if !filter.streamGroup.MatchesMessage(message) {
+		return false
+	}
+
+	if !filter.streamGroup.HasLabelFilter() {
+		return true
+	}
+
+	labels, err := decodeNotificationLabels(encoded)
+	if err != nil {
+		log.Debugf("could not decode labels of %v %v: %v", message.GetResource().String(), message.GetResourceId(), err.Error())
+		return false
+	}
+
+	return filter.streamGroup.MatchesLabels(labels)
+}
+
+// Returns the response that delivers an event to the client
+// Snapshots and change details are not part of the notification api, clients read them from the events endpoint.
+func (filter *streamGroupFilter) response(message *v1notificationservices.EventNotificationMessage, sequence uint64) *v1notificationservices.NotificationStreamResponse {
+	return &v1notificationservices.NotificationStreamResponse{
+		Message:   message,
+		Sequence:  sequence,
+		Timestamp: timestamppb.Now(),
+	}
+}
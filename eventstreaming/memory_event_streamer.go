@@ -0,0 +1,469 @@
+package eventstreaming
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/config"
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Maximum number of messages that are sent in a single chunk
+const memoryChunkSize = 500
+
+// Maximum duration a streamer waits for new messages before it checks if it has been closed
+const memoryFetchWait = time.Second
+
+// MemoryEventStreamMgmt In-process event notification backend for single node deployments and tests
+//
+// It uses the same subjects as the NATS backend, stream groups therefore receive the same events. The last BufferSize
+// events are retained, a new stream group starts with the retained events that match its subject and its start policy. Each stream group
+// buffers up to BufferSize undelivered events, the oldest events are dropped if a group is not consumed. Chunks that
+// are not acknowledged within AckWait are delivered again. Events are not persisted, they are lost on restart.
+type MemoryEventStreamMgmt struct {
+	DatabaseRead   *database.Read
+	DatabaseCreate *database.Create
+	SubjectPrefix  string
+	BufferSize     int
+	AckWait        time.Duration
+	// Format the events are encoded in, see EncodeEvent
+	EventFormat string
+
+	mutex    sync.Mutex
+	sequence uint64
+	retained []*memoryMessage
+	groups   map[uuid.UUID]*memoryStreamGroup
+}
+
+type memoryMessage struct {
+	sequence  uint64
+	subject   string
+	published time.Time
+	event     *EncodedEvent
+}
+
+type memoryChunk struct {
+	messages []*memoryMessage
+	deadline time.Time
+}
+
+// Buffer of a single stream group, it is shared by all streamers of the group
+type memoryStreamGroup struct {
+	subject    string
+	bufferSize int
+	ackWait    time.Duration
+	mutex      sync.Mutex
+	queue      []*memoryMessage
+	pending    map[string]*memoryChunk
+	notify     chan struct{}
+
+	redelivered   uint64
+	lastDelivered uint64
+}
+
+// NewMemoryEventStreamMgmt Creates the in-memory backend from the config
+func NewMemoryEventStreamMgmt(databaseReader *database.Read, databaseCreate *database.Create) (*MemoryEventStreamMgmt, error) {
+	mgmt := &MemoryEventStreamMgmt{
+		DatabaseRead:   databaseReader,
+		DatabaseCreate: databaseCreate,
+		SubjectPrefix:  viper.GetString(config.EVENTNOTIFICATION_NATS_SUBJECTPREFIX),
+		BufferSize:     viper.GetInt(config.EVENTNOTIFICATION_MEMORY_BUFFER_SIZE),
+		AckWait:        viper.GetDuration(config.EVENTNOTIFICATION_MEMORY_ACK_WAIT),
+		EventFormat:    viper.GetString(config.EVENTNOTIFICATION_FORMAT),
+		groups:         make(map[uuid.UUID]*memoryStreamGroup),
+	}
+
+	return mgmt, nil
+}
+
+func (mgmt *MemoryEventStreamMgmt) EnableTestMode() error {
+	return nil
+}
+
+func (mgmt *MemoryEventStreamMgmt) CreateStreamGroup(projectID uuid.UUID, resourceID uuid.UUID, resourceType *v1notificationservices.CreateEventStreamingGroupRequest_EventResources, includeSubResources bool, start *models.StreamGroupStart) (*models.StreamGroup, error) {
+	targetSubject, err := subscriptionSubject(mgmt.SubjectPrefix, mgmt.DatabaseRead, resourceID, *resourceType, includeSubResources)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	group, err := mgmt.DatabaseCreate.CreateStreamGroup(projectID, resourceType.Enum().String(), resourceID, targetSubject, includeSubResources, start)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	mgmt.getGroup(group)
+
+	return group, nil
+}
+
+// GetStreamGroupInfo Returns the delivery state of the buffer of a stream group
+func (mgmt *MemoryEventStreamMgmt) GetStreamGroupInfo(streamGroup *models.StreamGroup) (*StreamGroupInfo, error) {
+	group := mgmt.getGroup(streamGroup)
+
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	info := &StreamGroupInfo{
+		Pending:               uint64(len(group.queue)),
+		Redelivered:           group.redelivered,
+		LastDeliveredSequence: group.lastDelivered,
+		AckFloorSequence:      group.lastDelivered,
+	}
+
+	for _, chunk := range group.pending {
+		info.AckPending += uint64(len(chunk.messages))
+		for _, msg := range chunk.messages {
+			if msg.sequence <= info.AckFloorSequence {
+				info.AckFloorSequence = msg.sequence - 1
+			}
+		}
+	}
+
+	for _, msg := range group.queue {
+		if msg.sequence <= info.AckFloorSequence {
+			info.AckFloorSequence = msg.sequence - 1
+		}
+	}
+
+	return info, nil
+}
+
+// ResetStreamGroup Refills the buffer of a stream group from the retained events, pending chunks are dropped
+func (mgmt *MemoryEventStreamMgmt) ResetStreamGroup(streamGroup *models.StreamGroup, start *models.StreamGroupStart) error {
+	group := mgmt.getGroup(streamGroup)
+
+	mgmt.mutex.Lock()
+	defer mgmt.mutex.Unlock()
+
+	group.mutex.Lock()
+	group.queue = nil
+	group.pending = make(map[string]*memoryChunk)
+	group.redelivered = 0
+	group.lastDelivered = 0
+	group.mutex.Unlock()
+
+	mgmt.backfill(group, start)
+
+	return nil
+}
+
+// DeleteStreamGroup Removes the buffer of a stream group
+func (mgmt *MemoryEventStreamMgmt) DeleteStreamGroup(streamGroup *models.StreamGroup) error {
+	mgmt.mutex.Lock()
+	defer mgmt.mutex.Unlock()
+
+	delete(mgmt.groups, streamGroup.ID)
+
+	return nil
+}
+
+func (mgmt *MemoryEventStreamMgmt) CreateMessageStreamGroupHandler(streamGroup *models.StreamGroup) (EventStreamer, error) {
+	streamer := &MemoryEventStreamer{
+		group:           mgmt.getGroup(streamGroup),
+		filter:          newStreamGroupFilter(streamGroup),
+		ResponseMsgChan: make(chan *v1notificationservices.NotificationStreamGroupResponse, 3),
+		MaxPendingAck:   make(chan bool, 3),
+		Close:           make(chan bool, 1),
+	}
+
+	return streamer, nil
+}
+
+// PublishMessage Adds an event to the buffers of all stream groups with a matching subject
+func (mgmt *MemoryEventStreamMgmt) PublishMessage(event *models.OutboxEvent) error {
+	subject, err := publishSubject(mgmt.SubjectPrefix, event)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	encoded, err := encodeNotificationMessage(mgmt.EventFormat, event)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	mgmt.mutex.Lock()
+	defer mgmt.mutex.Unlock()
+
+	mgmt.sequence++
+	msg := &memoryMessage{
+		sequence:  mgmt.sequence,
+		subject:   subject,
+		published: time.Now(),
+		event:     encoded,
+	}
+
+	mgmt.retained = append(mgmt.retained, msg)
+	if len(mgmt.retained) > mgmt.BufferSize {
+		mgmt.retained = mgmt.retained[len(mgmt.retained)-mgmt.BufferSize:]
+	}
+
+	for _, group := range mgmt.groups {
+		if subjectMatches(group.subject, subject) {
+			group.push(msg)
+		}
+	}
+
+	return nil
+}
+
+// Returns the buffer of a stream group, the buffer is created with the retained matching events if it does not exist yet
+func (mgmt *MemoryEventStreamMgmt) getGroup(streamGroup *models.StreamGroup) *memoryStreamGroup {
+	mgmt.mutex.Lock()
+	defer mgmt.mutex.Unlock()
+
+	if group, ok := mgmt.groups[streamGroup.ID]; ok {
+		return group
+	}
+
+	group := &memoryStreamGroup{
+		subject:    streamGroup.Subject,
+		bufferSize: mgmt.BufferSize,
+		ackWait:    mgmt.AckWait,
+		pending:    make(map[string]*memoryChunk),
+		notify:     make(chan struct{}, 1),
+	}
+
+	mgmt.backfill(group, streamGroup.Start())
+	mgmt.groups[streamGroup.ID] = group
+
+	return group
+}
+
+// Adds the retained events from the start position on to the buffer of a group, has to be called with the lock held
+func (mgmt *MemoryEventStreamMgmt) backfill(group *memoryStreamGroup, start *models.StreamGroupStart) {
+	for _, msg := range mgmt.retained {
+		if !subjectMatches(group.subject, msg.subject) {
+			continue
+		}
+
+		switch start.Policy {
+		case models.StreamGroupStartNew:
+			continue
+		case models.StreamGroupStartSequence:
+			if msg.sequence < start.Sequence {
+				continue
+			}
+		case models.StreamGroupStartTime:
+			if msg.published.Before(start.Time) {
+				continue
+			}
+		}
+
+		group.push(msg)
+	}
+}
+
+func (group *memoryStreamGroup) push(msg *memoryMessage) {
+	group.mutex.Lock()
+	group.queue = append(group.queue, msg)
+	if len(group.queue) > group.bufferSize {
+		log.Warnf("buffer of stream group with subject %v is full, dropping oldest event", group.subject)
+		group.queue = group.queue[len(group.queue)-group.bufferSize:]
+	}
+	group.mutex.Unlock()
+
+	select {
+	case group.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Takes up to max messages from the buffer as new pending chunk, waits up to wait for messages if the buffer is empty
+func (group *memoryStreamGroup) fetch(max int, wait time.Duration) (string, []*memoryMessage) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		group.mutex.Lock()
+		group.requeueExpired()
+
+		if len(group.queue) > 0 {
+			count := len(group.queue)
+			if count > max {
+				count = max
+			}
+
+			messages := make([]*memoryMessage, count)
+			copy(messages, group.queue[:count])
+			group.queue = group.queue[count:]
+			if last := messages[count-1].sequence; last > group.lastDelivered {
+				group.lastDelivered = last
+			}
+
+			chunkID := uuid.New().String()
+			group.pending[chunkID] = &memoryChunk{
+				messages: messages,
+				deadline: time.Now().Add(group.ackWait),
+			}
+			group.mutex.Unlock()
+
+			return chunkID, messages
+		}
+		nextDeadline := group.nextDeadline()
+		group.mutex.Unlock()
+
+		var redelivery <-chan time.Time
+		if !nextDeadline.IsZero() {
+			redelivery = time.After(time.Until(nextDeadline))
+		}
+
+		select {
+		case <-group.notify:
+		case <-redelivery:
+		case <-timer.C:
+			return "", nil
+		}
+	}
+}
+
+// Returns the earliest deadline of the pending chunks, has to be called with the lock held
+func (group *memoryStreamGroup) nextDeadline() time.Time {
+	var deadline time.Time
+	for _, chunk := range group.pending {
+		if deadline.IsZero() || chunk.deadline.Before(deadline) {
+			deadline = chunk.deadline
+		}
+	}
+
+	return deadline
+}
+
+// Moves the messages of chunks that have not been acknowledged in time back into the buffer, has to be called with the lock held
+func (group *memoryStreamGroup) requeueExpired() {
+	now := time.Now()
+	requeued := false
+
+	for chunkID, chunk := range group.pending {
+		if now.After(chunk.deadline) {
+			group.queue = append(group.queue, chunk.messages...)
+			group.redelivered += uint64(len(chunk.messages))
+			delete(group.pending, chunkID)
+			requeued = true
+		}
+	}
+
+	if requeued {
+		sort.Slice(group.queue, func(i, j int) bool {
+			return group.queue[i].sequence < group.queue[j].sequence
+		})
+	}
+}
+
+// Removes a pending chunk, returns false if the chunk is not pending anymore
+func (group *memoryStreamGroup) ack(chunkID string) bool {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	_, ok := group.pending[chunkID]
+	delete(group.pending, chunkID)
+
+	return ok
+}
+
+// MemoryEventStreamer Streams the events of a stream group of the in-memory backend
+type MemoryEventStreamer struct {
+	group           *memoryStreamGroup
+	filter          *streamGroupFilter
+	ResponseMsgChan chan *v1notificationservices.NotificationStreamGroupResponse
+	MaxPendingAck   chan bool
+	Close           chan bool
+}
+
+func (streamer *MemoryEventStreamer) GetResponseMessageChan() chan *v1notificationservices.NotificationStreamGroupResponse {
+	return streamer.ResponseMsgChan
+}
+
+func (streamer *MemoryEventStreamer) StartStream(ctx context.Context) error {
+	defer close(streamer.ResponseMsgChan)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-streamer.Close:
+			return nil
+		case streamer.MaxPendingAck <- true:
+		}
+
+		chunkID, messages := streamer.group.fetch(memoryChunkSize, memoryFetchWait)
+		if len(messages) == 0 {
+			<-streamer.MaxPendingAck
+			continue
+		}
+
+		var responseChunk []*v1notificationservices.NotificationStreamResponse
+		for _, msg := range messages {
+			notificationMsg, err := decodeNotificationMessage(msg.event)
+			if err != nil {
+				log.Errorln(err.Error())
+				return err
+			}
+
+			if !streamer.filter.matches(notificationMsg, msg.event) {
+				continue
+			}
+
+			responseChunk = append(responseChunk, streamer.filter.response(notificationMsg, msg.sequence))
+		}
+
+		// Chunks without any event that passed the filters are acknowledged directly
+		if len(responseChunk) == 0 {
+			streamer.group.ack(chunkID)
+			<-streamer.MaxPendingAck
+			continue
+		}
+
+		response := &v1notificationservices.NotificationStreamGroupResponse{
+			Notification: responseChunk,
+			AckChunkId:   chunkID,
+		}
+
+		select {
+		case streamer.ResponseMsgChan <- response:
+		case <-ctx.Done():
+			return nil
+		case <-streamer.Close:
+			return nil
+		}
+	}
+}
+
+func (streamer *MemoryEventStreamer) CloseStream() error {
+	select {
+	case streamer.Close <- true:
+	default:
+	}
+
+	return nil
+}
+
+func (streamer *MemoryEventStreamer) AckChunk(chunkID string) error {
+	if chunkID == "" {
+		return fmt.Errorf("no chunk id provided")
+	}
+
+	acked := streamer.group.ack(chunkID)
+
+	select {
+	case <-streamer.MaxPendingAck:
+	default:
+	}
+
+	if !acked {
+		return fmt.Errorf("%w: %v", ErrUnknownChunk, chunkID)
+	}
+
+	return nil
+}
@@ -0,0 +1,261 @@
+package eventstreaming
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSubjectMatches(t *testing.T) {
+	assert.True(t, subjectMatches("UPDATES.a._", "UPDATES.a._"))
+	assert.False(t, subjectMatches("UPDATES.a._", "UPDATES.a.b._"))
+	assert.True(t, subjectMatches("UPDATES.a.>", "UPDATES.a._"))
+	assert.True(t, subjectMatches("UPDATES.a.>", "UPDATES.a.b.objectgroup.c._"))
+	assert.False(t, subjectMatches("UPDATES.a.>", "UPDATES.a"))
+	assert.False(t, subjectMatches("UPDATES.a.>", "UPDATES.b._"))
+	assert.True(t, subjectMatches("UPDATES.*._", "UPDATES.a._"))
+}
+
+func TestMemoryEventStreamer(t *testing.T) {
+	mgmt := &MemoryEventStreamMgmt{
+		SubjectPrefix: "UPDATES",
+		BufferSize:    100,
+		AckWait:       100 * time.Millisecond,
+		groups:        make(map[uuid.UUID]*memoryStreamGroup),
+	}
+
+	projectID := uuid.New()
+	datasetID := uuid.New()
+
+	// Retained events are delivered to groups that are created later
+	err := mgmt.PublishMessage(models.NewProjectEvent(projectID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED))
+	assert.NoError(t, err)
+
+	projectGroup := &models.StreamGroup{Subject: fmt.Sprintf("UPDATES.%v.>", projectID)}
+	projectGroup.ID = uuid.New()
+	projectOnlyGroup := &models.StreamGroup{Subject: fmt.Sprintf("UPDATES.%v._", projectID)}
+	projectOnlyGroup.ID = uuid.New()
+
+	streamer, err := mgmt.CreateMessageStreamGroupHandler(projectGroup)
+	assert.NoError(t, err)
+	projectOnlyStreamer, err := mgmt.CreateMessageStreamGroupHandler(projectOnlyGroup)
+	assert.NoError(t, err)
+
+	err = mgmt.PublishMessage(models.NewDatasetEvent(projectID, datasetID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED))
+	assert.NoError(t, err)
+	err = mgmt.PublishMessage(models.NewDatasetEvent(uuid.New(), uuid.New(), v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED))
+	assert.NoError(t, err)
+
+	go streamer.StartStream(context.Background())
+	go projectOnlyStreamer.StartStream(context.Background())
+
+	response := <-streamer.GetResponseMessageChan()
+	assert.Len(t, response.Notification, 2)
+	assert.Equal(t, projectID.String(), response.Notification[0].Message.ResourceId)
+	assert.Equal(t, datasetID.String(), response.Notification[1].Message.ResourceId)
+
+	projectOnlyResponse := <-projectOnlyStreamer.GetResponseMessageChan()
+	assert.Len(t, projectOnlyResponse.Notification, 1)
+	assert.NoError(t, projectOnlyStreamer.AckChunk(projectOnlyResponse.AckChunkId))
+
+	// The unacknowledged chunk is delivered again after the ack wait
+	redelivered := <-streamer.GetResponseMessageChan()
+	assert.Len(t, redelivered.Notification, 2)
+	assert.Equal(t, response.Notification[0].Sequence, redelivered.Notification[0].Sequence)
+	assert.NotEqual(t, response.AckChunkId, redelivered.AckChunkId)
+	assert.NoError(t, streamer.AckChunk(redelivered.AckChunkId))
+
+	select {
+	case unexpected := <-streamer.GetResponseMessageChan():
+		t.Fatalf("unexpected redelivery of %v events", len(unexpected.Notification))
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	assert.NoError(t, streamer.CloseStream())
+	assert.NoError(t, projectOnlyStreamer.CloseStream())
+}
+
+func TestMemoryStreamGroupStart(t *testing.T) {
+	mgmt := &MemoryEventStreamMgmt{
+		SubjectPrefix: "UPDATES",
+		BufferSize:    100,
+		AckWait:       time.Minute,
+		groups:        make(map[uuid.UUID]*memoryStreamGroup),
+	}
+
+	projectID := uuid.New()
+	for i := 0; i < 3; i++ {
+		err := mgmt.PublishMessage(models.NewDatasetEvent(projectID, uuid.New(), v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED))
+		assert.NoError(t, err)
+	}
+
+	newGroup := &models.StreamGroup{Subject: fmt.Sprintf("UPDATES.%v.>", projectID), StartPolicy: models.StreamGroupStartNew}
+	newGroup.ID = uuid.New()
+	sequenceGroup := &models.StreamGroup{Subject: fmt.Sprintf("UPDATES.%v.>", projectID), StartPolicy: models.StreamGroupStartSequence, StartSequence: 2}
+	sequenceGroup.ID = uuid.New()
+
+	info, err := mgmt.GetStreamGroupInfo(newGroup)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), info.Pending)
+
+	info, err = mgmt.GetStreamGroupInfo(sequenceGroup)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), info.Pending)
+
+	chunkID, messages := mgmt.getGroup(sequenceGroup).fetch(1, time.Millisecond)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, uint64(2), messages[0].sequence)
+
+	info, err = mgmt.GetStreamGroupInfo(sequenceGroup)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), info.Pending)
+	assert.Equal(t, uint64(1), info.AckPending)
+	assert.Equal(t, uint64(2), info.LastDeliveredSequence)
+
+	mgmt.getGroup(sequenceGroup).ack(chunkID)
+	info, err = mgmt.GetStreamGroupInfo(sequenceGroup)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), info.AckFloorSequence)
+
+	// A reset starts again at the new position and drops the delivery state
+	err = mgmt.ResetStreamGroup(newGroup, &models.StreamGroupStart{Policy: models.StreamGroupStartAll})
+	assert.NoError(t, err)
+	info, err = mgmt.GetStreamGroupInfo(newGroup)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), info.Pending)
+
+	assert.NoError(t, mgmt.DeleteStreamGroup(newGroup))
+	assert.NotContains(t, mgmt.groups, newGroup.ID)
+}
+
+func TestMemoryEventStreamerFilter(t *testing.T) {
+	mgmt := &MemoryEventStreamMgmt{
+		SubjectPrefix: "UPDATES",
+		BufferSize:    100,
+		AckWait:       time.Minute,
+		groups:        make(map[uuid.UUID]*memoryStreamGroup),
+	}
+
+	projectID := uuid.New()
+	datasetID := uuid.New()
+	objectGroupID := uuid.New()
+
+	group := &models.StreamGroup{
+		Subject:     fmt.Sprintf("UPDATES.%v.>", projectID),
+		Resources:   "RESOURCE_OBJECT_GROUP",
+		UpdateTypes: "UPDATE_TYPE_AVAILABLE",
+	}
+	group.ID = uuid.New()
+
+	streamer, err := mgmt.CreateMessageStreamGroupHandler(group)
+	assert.NoError(t, err)
+
+	events := []*models.OutboxEvent{
+		models.NewDatasetEvent(projectID, datasetID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_AVAILABLE),
+		models.NewObjectGroupEvent(projectID, datasetID, objectGroupID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED),
+		models.NewObjectGroupEvent(projectID, datasetID, objectGroupID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_AVAILABLE),
+	}
+	for _, event := range events {
+		assert.NoError(t, mgmt.PublishMessage(event))
+	}
+
+	go streamer.StartStream(context.Background())
+
+	response := <-streamer.GetResponseMessageChan()
+	assert.Len(t, response.Notification, 1)
+	assert.Equal(t, objectGroupID.String(), response.Notification[0].Message.ResourceId)
+	assert.Equal(t, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_AVAILABLE, response.Notification[0].Message.UpdatedType)
+	assert.NoError(t, streamer.AckChunk(response.AckChunkId))
+
+	// Chunks without matching events are acknowledged without being sent
+	assert.NoError(t, mgmt.PublishMessage(models.NewDatasetEvent(projectID, datasetID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_UPDATED)))
+	select {
+	case unexpected := <-streamer.GetResponseMessageChan():
+		t.Fatalf("unexpected delivery of %v events", len(unexpected.Notification))
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	info, err := mgmt.GetStreamGroupInfo(group)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), info.Pending)
+	assert.Equal(t, uint64(0), info.AckPending)
+
+	assert.NoError(t, streamer.CloseStream())
+
+	// Label filters match the labels the resource had when the event was written
+	labelGroup := &models.StreamGroup{
+		Subject:    fmt.Sprintf("UPDATES.%v.>", projectID),
+		LabelKey:   "pipeline",
+		LabelValue: "ingest",
+	}
+	labelGroup.ID = uuid.New()
+
+	labelStreamer, err := mgmt.CreateMessageStreamGroupHandler(labelGroup)
+	assert.NoError(t, err)
+
+	labelEvents := []*models.OutboxEvent{
+		models.NewDatasetEvent(projectID, datasetID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_UPDATED),
+		models.NewDatasetEvent(projectID, datasetID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_UPDATED).WithLabels([]models.Label{{Key: "pipeline", Value: "export"}}),
+		models.NewObjectGroupEvent(projectID, datasetID, objectGroupID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_DELETED).WithLabels([]models.Label{{Key: "pipeline", Value: "ingest"}}),
+	}
+	for _, event := range labelEvents {
+		assert.NoError(t, mgmt.PublishMessage(event))
+	}
+
+	go labelStreamer.StartStream(context.Background())
+
+	response = <-labelStreamer.GetResponseMessageChan()
+	assert.Len(t, response.Notification, 1)
+	assert.Equal(t, objectGroupID.String(), response.Notification[0].Message.ResourceId)
+	assert.Equal(t, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_DELETED, response.Notification[0].Message.UpdatedType)
+	assert.NoError(t, labelStreamer.AckChunk(response.AckChunkId))
+
+	assert.NoError(t, labelStreamer.CloseStream())
+}
+
+func TestMemoryEventStreamerSnapshots(t *testing.T) {
+	mgmt := &MemoryEventStreamMgmt{
+		SubjectPrefix: "UPDATES",
+		BufferSize:    100,
+		AckWait:       time.Minute,
+		groups:        make(map[uuid.UUID]*memoryStreamGroup),
+	}
+
+	projectID := uuid.New()
+	datasetID := uuid.New()
+
+	group := &models.StreamGroup{Subject: fmt.Sprintf("UPDATES.%v.>", projectID), IncludeSnapshots: true}
+	group.ID = uuid.New()
+
+	streamer, err := mgmt.CreateMessageStreamGroupHandler(group)
+	assert.NoError(t, err)
+
+	snapshot := fmt.Sprintf(`{"id":"%v","name":"dataset"}`, datasetID)
+	event := models.NewDatasetEvent(projectID, datasetID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED)
+	event.Snapshot = snapshot
+	assert.NoError(t, mgmt.PublishMessage(event))
+	assert.NoError(t, mgmt.PublishMessage(models.NewDatasetEvent(projectID, datasetID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_DELETED)))
+
+	go streamer.StartStream(context.Background())
+
+	response := <-streamer.GetResponseMessageChan()
+	assert.Len(t, response.Notification, 2)
+
+	// Snapshots are only provided by the events endpoint, the responses contain nothing besides the api fields
+	data, err := proto.Marshal(response.Notification[0])
+	assert.NoError(t, err)
+	received := &v1notificationservices.NotificationStreamResponse{}
+	assert.NoError(t, proto.Unmarshal(data, received))
+	assert.Empty(t, received.ProtoReflect().GetUnknown())
+	assert.NotContains(t, string(data), snapshot)
+
+	assert.NoError(t, streamer.AckChunk(response.AckChunkId))
+	assert.NoError(t, streamer.CloseStream())
+}
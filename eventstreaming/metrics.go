@@ -0,0 +1,22 @@
+package eventstreaming
+
+import (
+	"errors"
+	"expvar"
+)
+
+// ErrUnknownChunk Is returned when a chunk is acknowledged that is not pending, e.g. because its ack deadline has passed
+var ErrUnknownChunk = errors.New("unknown or expired chunk id")
+
+// Names of the notification stream metrics
+const (
+	metricActiveStreams       = "active_streams"
+	metricPendingChunks       = "pending_chunks"
+	metricPendingMessages     = "pending_messages"
+	metricAckedChunks         = "acked_chunks"
+	metricExpiredChunks       = "expired_chunks"
+	metricRedeliveredMessages = "redelivered_messages"
+)
+
+// Counters of the notification streams of this server, they are published by expvar as "eventstreaming"
+var streamMetrics = expvar.NewMap("eventstreaming")
@@ -1,6 +1,7 @@
 package eventstreaming
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
@@ -9,8 +10,6 @@ import (
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
-	"google.golang.org/protobuf/encoding/protojson"
-	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/ScienceObjectsDB/CORE-Server/config"
 	"github.com/ScienceObjectsDB/CORE-Server/database"
@@ -18,13 +17,8 @@ import (
 	"github.com/nats-io/nats.go"
 
 	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
-	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
 )
 
-const OBJECTGROUPSUBJECTNAME = "objectgroup"
-const DATASETVERSIONSUBJECTNAME = "datasetversion"
-const DEFAULTSUBJECTSUFFIX = "_"
-
 type NatsEventStreamMgmt struct {
 	Connection       *nats.Conn
 	JetStreamContext nats.JetStreamContext
@@ -32,9 +26,31 @@ type NatsEventStreamMgmt struct {
 	DatabaseRead     *database.Read
 	DatabaseCreate   *database.Create
 	SubjectPrefix    string
+	ChunkSize        int
+	AckTimeout       time.Duration
+	MaxPendingChunks int
+	// Format the events are published in, see EncodeEvent
+	EventFormat string
 }
 
+// Maximum duration a streamer waits for new messages before it checks its ack deadlines and if it has been closed
+const natsFetchWait = time.Second
+
+// Additional time the consumers of stream groups wait for acknowledgements before JetStream delivers messages again
+// on its own, it has to be longer than the ack timeout of the streamers
+const natsAckWaitMargin = 5 * time.Second
+
 func NewNatsEventStreamMgmt(databaseReader *database.Read, databaseCreate *database.Create) (*NatsEventStreamMgmt, error) {
+	chunkSize := viper.GetInt(config.EVENTNOTIFICATION_NATS_CHUNK_SIZE)
+	ackTimeout := viper.GetDuration(config.EVENTNOTIFICATION_NATS_ACK_TIMEOUT)
+	maxPendingChunks := viper.GetInt(config.EVENTNOTIFICATION_NATS_MAX_PENDING_CHUNKS)
+
+	err := validateNatsStreamLimits(chunkSize, ackTimeout, maxPendingChunks)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
 	urls := viper.GetStringSlice(config.EVENTNOTIFICATION_NATS_HOST)
 	streamSubjectPrefix := viper.GetString(config.EVENTNOTIFICATION_NATS_SUBJECTPREFIX)
 
@@ -79,11 +95,32 @@ func NewNatsEventStreamMgmt(databaseReader *database.Read, databaseCreate *datab
 		SubjectPrefix:    streamSubjectPrefix,
 		DatabaseRead:     databaseReader,
 		DatabaseCreate:   databaseCreate,
+		ChunkSize:        chunkSize,
+		AckTimeout:       ackTimeout,
+		MaxPendingChunks: maxPendingChunks,
+		EventFormat:      viper.GetString(config.EVENTNOTIFICATION_FORMAT),
 	}
 
 	return streaming, nil
 }
 
+// Checks the limits of the streamers, a streamer without pending chunks would not send any events
+func validateNatsStreamLimits(chunkSize int, ackTimeout time.Duration, maxPendingChunks int) error {
+	if chunkSize <= 0 {
+		return fmt.Errorf("%v has to be greater than 0, got %v", config.EVENTNOTIFICATION_NATS_CHUNK_SIZE, chunkSize)
+	}
+
+	if ackTimeout <= 0 {
+		return fmt.Errorf("%v has to be greater than 0, got %v", config.EVENTNOTIFICATION_NATS_ACK_TIMEOUT, ackTimeout)
+	}
+
+	if maxPendingChunks <= 0 {
+		return fmt.Errorf("%v has to be greater than 0, got %v", config.EVENTNOTIFICATION_NATS_MAX_PENDING_CHUNKS, maxPendingChunks)
+	}
+
+	return nil
+}
+
 func (eventStreamManager *NatsEventStreamMgmt) CreateMessageStreamGroupHandler(streamGroup *models.StreamGroup) (EventStreamer, error) {
 	sub, err := eventStreamManager.JetStreamContext.PullSubscribe(streamGroup.Subject, streamGroup.ID.String(), nats.Bind(eventStreamManager.SubjectPrefix, streamGroup.ID.String()))
 	if err != nil {
@@ -94,39 +131,35 @@ func (eventStreamManager *NatsEventStreamMgmt) CreateMessageStreamGroupHandler(s
 	responseMsgChan := make(chan *v1notificationservices.NotificationStreamGroupResponse, 3)
 
 	streamer := &NatsEventStreamer{
-		ResponseMsgChan: responseMsgChan,
-		Subscription:    sub,
-		MsgMap:          make(map[string][]*nats.Msg),
-		MsgMapMutex:     &sync.Mutex{},
-		MaxPendingAck:   make(chan bool, 3),
-		Close:           make(chan bool, 1),
+		ResponseMsgChan:  responseMsgChan,
+		Subscription:     sub,
+		ChunkSize:        eventStreamManager.ChunkSize,
+		AckTimeout:       eventStreamManager.AckTimeout,
+		MaxPendingChunks: eventStreamManager.MaxPendingChunks,
+		Close:            make(chan bool, 1),
+		ID:               uuid.New().String(),
+		filter:           newStreamGroupFilter(streamGroup),
+		pending:          make(map[string]*natsChunk),
+		acked:            make(chan struct{}, 1),
 	}
 
 	return streamer, nil
 }
 
-func (eventStreamManager *NatsEventStreamMgmt) CreateStreamGroup(projectID uuid.UUID, resourceID uuid.UUID, resourceType *v1notificationservices.CreateEventStreamingGroupRequest_EventResources, includeSubResources bool) (*models.StreamGroup, error) {
+func (eventStreamManager *NatsEventStreamMgmt) CreateStreamGroup(projectID uuid.UUID, resourceID uuid.UUID, resourceType *v1notificationservices.CreateEventStreamingGroupRequest_EventResources, includeSubResources bool, start *models.StreamGroupStart) (*models.StreamGroup, error) {
 	targetSubject, err := eventStreamManager.getSubscriptionSubject(resourceID, *resourceType, includeSubResources)
 	if err != nil {
 		log.Errorln(err.Error())
 		return nil, err
 	}
 
-	group, err := eventStreamManager.DatabaseCreate.CreateStreamGroup(projectID, resourceType.Enum().String(), resourceID, targetSubject, includeSubResources)
+	group, err := eventStreamManager.DatabaseCreate.CreateStreamGroup(projectID, resourceType.Enum().String(), resourceID, targetSubject, includeSubResources, start)
 	if err != nil {
 		log.Errorln(err.Error())
 		return nil, err
 	}
 
-	cfg := &nats.ConsumerConfig{
-		Durable:       group.ID.String(),
-		FilterSubject: targetSubject,
-		DeliverPolicy: nats.DeliverAllPolicy,
-		AckPolicy:     nats.AckExplicitPolicy,
-		AckWait:       time.Second * 15,
-	}
-
-	_, err = eventStreamManager.JetStreamManager.AddConsumer(viper.GetString(config.EVENTNOTIFICATION_NATS_STREAM_NAME), cfg)
+	err = eventStreamManager.addConsumer(group, start)
 	if err != nil {
 		log.Errorln(err.Error())
 		return nil, err
@@ -135,20 +168,33 @@ func (eventStreamManager *NatsEventStreamMgmt) CreateStreamGroup(projectID uuid.
 	return group, err
 }
 
-func (eventStreamManager *NatsEventStreamMgmt) PublishMessage(request *v1notificationservices.EventNotificationMessage) error {
-	data, err := protojson.Marshal(request)
+// GetStreamGroupInfo Returns the state of the durable consumer of a stream group
+func (eventStreamManager *NatsEventStreamMgmt) GetStreamGroupInfo(streamGroup *models.StreamGroup) (*StreamGroupInfo, error) {
+	consumerInfo, err := eventStreamManager.JetStreamManager.ConsumerInfo(viper.GetString(config.EVENTNOTIFICATION_NATS_STREAM_NAME), streamGroup.ID.String())
 	if err != nil {
 		log.Errorln(err.Error())
-		return err
+		return nil, err
 	}
 
-	publishSubject, err := eventStreamManager.getPublishSubject(request)
+	info := &StreamGroupInfo{
+		Pending:               consumerInfo.NumPending,
+		AckPending:            uint64(consumerInfo.NumAckPending),
+		Redelivered:           uint64(consumerInfo.NumRedelivered),
+		LastDeliveredSequence: consumerInfo.Delivered.Stream,
+		AckFloorSequence:      consumerInfo.AckFloor.Stream,
+	}
+
+	return info, nil
+}
+
+// ResetStreamGroup Recreates the durable consumer of a stream group with a new start position
+func (eventStreamManager *NatsEventStreamMgmt) ResetStreamGroup(streamGroup *models.StreamGroup, start *models.StreamGroupStart) error {
+	err := eventStreamManager.DeleteStreamGroup(streamGroup)
 	if err != nil {
-		log.Errorln(err.Error())
 		return err
 	}
 
-	_, err = eventStreamManager.JetStreamContext.Publish(publishSubject, data)
+	err = eventStreamManager.addConsumer(streamGroup, start)
 	if err != nil {
 		log.Errorln(err.Error())
 		return err
@@ -157,11 +203,10 @@ func (eventStreamManager *NatsEventStreamMgmt) PublishMessage(request *v1notific
 	return nil
 }
 
-func (eventStreamManager *NatsEventStreamMgmt) EnableTestMode() error {
-	targetSubject := fmt.Sprintf("%v.>", viper.GetString(config.EVENTNOTIFICATION_NATS_SUBJECTPREFIX))
-
-	_, err := eventStreamManager.JetStreamContext.AddStream(&nats.StreamConfig{Name: viper.GetString(config.EVENTNOTIFICATION_NATS_STREAM_NAME), Subjects: []string{targetSubject}, Storage: nats.MemoryStorage})
-	if err != nil {
+// DeleteStreamGroup Removes the durable consumer of a stream group
+func (eventStreamManager *NatsEventStreamMgmt) DeleteStreamGroup(streamGroup *models.StreamGroup) error {
+	err := eventStreamManager.JetStreamManager.DeleteConsumer(viper.GetString(config.EVENTNOTIFICATION_NATS_STREAM_NAME), streamGroup.ID.String())
+	if err != nil && err != nats.ErrConsumerNotFound {
 		log.Errorln(err.Error())
 		return err
 	}
@@ -169,159 +214,156 @@ func (eventStreamManager *NatsEventStreamMgmt) EnableTestMode() error {
 	return nil
 }
 
-func (eventStreamManager *NatsEventStreamMgmt) getSubscriptionSubject(resourceID uuid.UUID, resourceType v1notificationservices.CreateEventStreamingGroupRequest_EventResources, useSubResource bool) (string, error) {
-	subject := ""
-
-	finalSymbol := DEFAULTSUBJECTSUFFIX
-	if useSubResource {
-		finalSymbol = ">"
+// Creates the durable consumer of a stream group, unacknowledged messages of the consumer survive client restarts
+func (eventStreamManager *NatsEventStreamMgmt) addConsumer(streamGroup *models.StreamGroup, start *models.StreamGroupStart) error {
+	cfg := &nats.ConsumerConfig{
+		Durable:       streamGroup.ID.String(),
+		FilterSubject: streamGroup.Subject,
+		DeliverPolicy: nats.DeliverAllPolicy,
+		AckPolicy:     nats.AckExplicitPolicy,
+		AckWait:       eventStreamManager.AckTimeout + natsAckWaitMargin,
 	}
 
-	switch resourceType {
-	case v1notificationservices.CreateEventStreamingGroupRequest_EVENT_RESOURCES_PROJECT_RESOURCE:
-		{
-			subject = fmt.Sprintf("%v.%v.%v", eventStreamManager.SubjectPrefix, resourceID.String(), finalSymbol)
-		}
-
-	case v1notificationservices.CreateEventStreamingGroupRequest_EVENT_RESOURCES_DATASET_RESOURCE:
-		{
-			dataset, err := eventStreamManager.DatabaseRead.GetDataset(resourceID)
-			if err != nil {
-				log.Errorln(err.Error())
-				return "", err
-			}
-
-			subject = fmt.Sprintf("%v.%v.%v.%v", eventStreamManager.SubjectPrefix, dataset.ProjectID.String(), dataset.ID.String(), finalSymbol)
-
-		}
-
-	case v1notificationservices.CreateEventStreamingGroupRequest_EVENT_RESOURCES_DATASET_VERSION_RESOURCE:
-		{
-			datasetVersion, err := eventStreamManager.DatabaseRead.GetDatasetVersion(resourceID)
-			if err != nil {
-				log.Errorln(err.Error())
-				return "", err
-			}
-
-			subject = fmt.Sprintf("%v.%v.%v.%v.%v.%v", eventStreamManager.SubjectPrefix, datasetVersion.ProjectID.String(), datasetVersion.DatasetID.String(), DATASETVERSIONSUBJECTNAME, datasetVersion.ID.String(), finalSymbol)
-		}
-
-	case v1notificationservices.CreateEventStreamingGroupRequest_EVENT_RESOURCES_OBJECT_GROUP_RESOURCE:
-		{
-			objectGroup, err := eventStreamManager.DatabaseRead.GetObjectGroup(resourceID)
-			if err != nil {
-				log.Errorln(err.Error())
-				return "", err
-			}
-
-			subject = fmt.Sprintf("%v.%v.%v.%v.%v.%v", eventStreamManager.SubjectPrefix, objectGroup.ProjectID.String(), objectGroup.DatasetID.String(), OBJECTGROUPSUBJECTNAME, objectGroup.ID.String(), finalSymbol)
-		}
-
-	default:
-		{
-			return "", fmt.Errorf("queried resource not implemented")
-		}
+	switch start.Policy {
+	case models.StreamGroupStartNew:
+		cfg.DeliverPolicy = nats.DeliverNewPolicy
+	case models.StreamGroupStartSequence:
+		cfg.DeliverPolicy = nats.DeliverByStartSequencePolicy
+		cfg.OptStartSeq = start.Sequence
+	case models.StreamGroupStartTime:
+		startTime := start.Time
+		cfg.DeliverPolicy = nats.DeliverByStartTimePolicy
+		cfg.OptStartTime = &startTime
 	}
 
-	return subject, nil
+	_, err := eventStreamManager.JetStreamManager.AddConsumer(viper.GetString(config.EVENTNOTIFICATION_NATS_STREAM_NAME), cfg)
+	return err
 }
 
-func (eventStreamManager *NatsEventStreamMgmt) getPublishSubject(request *v1notificationservices.EventNotificationMessage) (string, error) {
-	idAsUUID, err := uuid.Parse(request.ResourceId)
+// PublishMessage Publishes an event from the outbox, the id of the event is used as message id
+// to let JetStream discard duplicates when an event is published again after a failed attempt.
+func (eventStreamManager *NatsEventStreamMgmt) PublishMessage(event *models.OutboxEvent) error {
+	encoded, err := encodeNotificationMessage(eventStreamManager.EventFormat, event)
 	if err != nil {
 		log.Errorln(err.Error())
-		return "", err
+		return err
 	}
 
-	switch request.Resource {
-	case v1storagemodels.Resource_RESOURCE_PROJECT:
-		{
-			subject := fmt.Sprintf("%v.%v._", eventStreamManager.SubjectPrefix, request.ResourceId)
-			return subject, nil
-		}
-	case v1storagemodels.Resource_RESOURCE_DATASET:
-		{
-			dataset, err := eventStreamManager.DatabaseRead.GetDataset(idAsUUID)
-			if err != nil {
-				log.Errorln(err.Error())
-				return "", err
-			}
-
-			subject := fmt.Sprintf("%v.%v.%v._", eventStreamManager.SubjectPrefix, dataset.ProjectID.String(), dataset.ID.String())
+	publishSubject, err := eventStreamManager.getPublishSubject(event)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
 
-			return subject, nil
-		}
+	msg := nats.NewMsg(publishSubject)
+	msg.Data = encoded.Data
+	for key, value := range encoded.Headers {
+		// The headers are set directly to keep the lower case names of the CloudEvents NATS binding
+		msg.Header[key] = []string{value}
+	}
 
-	case v1storagemodels.Resource_RESOURCE_OBJECT_GROUP:
-		{
-			objectgroup, err := eventStreamManager.DatabaseRead.GetObjectGroup(idAsUUID)
-			if err != nil {
-				log.Errorln(err.Error())
-				return "", err
-			}
+	_, err = eventStreamManager.JetStreamContext.PublishMsg(msg, nats.MsgId(event.ID.String()))
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
 
-			subject := fmt.Sprintf("%v.%v.%v.%v.%v._", eventStreamManager.SubjectPrefix, objectgroup.ProjectID.String(), objectgroup.DatasetID.String(), OBJECTGROUPSUBJECTNAME, objectgroup.ID.String())
+	return nil
+}
 
-			return subject, nil
-		}
-	case v1storagemodels.Resource_RESOURCE_DATASET_VERSION:
-		{
-			datasetVersion, err := eventStreamManager.DatabaseRead.GetDatasetVersion(idAsUUID)
-			if err != nil {
-				log.Errorln(err.Error())
-				return "", err
-			}
+func (eventStreamManager *NatsEventStreamMgmt) EnableTestMode() error {
+	targetSubject := fmt.Sprintf("%v.>", viper.GetString(config.EVENTNOTIFICATION_NATS_SUBJECTPREFIX))
 
-			subject := fmt.Sprintf("%v.%v.%v.%v.%v._", eventStreamManager.SubjectPrefix, datasetVersion.ProjectID.String(), datasetVersion.DatasetID.String(), DATASETVERSIONSUBJECTNAME, datasetVersion.ID.String())
+	_, err := eventStreamManager.JetStreamContext.AddStream(&nats.StreamConfig{Name: viper.GetString(config.EVENTNOTIFICATION_NATS_STREAM_NAME), Subjects: []string{targetSubject}, Storage: nats.MemoryStorage})
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
 
-			return subject, nil
-		}
+	return nil
+}
 
-	default:
-		{
-			return "", fmt.Errorf("provided resource not implemented")
-		}
-	}
+func (eventStreamManager *NatsEventStreamMgmt) getSubscriptionSubject(resourceID uuid.UUID, resourceType v1notificationservices.CreateEventStreamingGroupRequest_EventResources, useSubResource bool) (string, error) {
+	return subscriptionSubject(eventStreamManager.SubjectPrefix, eventStreamManager.DatabaseRead, resourceID, resourceType, useSubResource)
+}
 
+func (eventStreamManager *NatsEventStreamMgmt) getPublishSubject(event *models.OutboxEvent) (string, error) {
+	return publishSubject(eventStreamManager.SubjectPrefix, event)
 }
 
+// NatsEventStreamer Streams the events of a stream group from a JetStream pull subscription
+//
+// Up to ChunkSize messages are fetched and sent as a single chunk, at most MaxPendingChunks chunks can wait for their
+// acknowledgement. Chunks that are not acknowledged within AckTimeout are negatively acknowledged, JetStream delivers
+// their messages again to one of the streamers of the group. The stream ends when its context is cancelled or it is
+// closed, its pending chunks are negatively acknowledged then.
 type NatsEventStreamer struct {
-	Subscription    *nats.Subscription
-	ResponseMsgChan chan *v1notificationservices.NotificationStreamGroupResponse
-	MsgMap          map[string][]*nats.Msg
-	MsgMapMutex     *sync.Mutex
-	MaxPendingAck   chan bool
-	Close           chan bool
-	ID              string
+	Subscription     *nats.Subscription
+	ResponseMsgChan  chan *v1notificationservices.NotificationStreamGroupResponse
+	ChunkSize        int
+	AckTimeout       time.Duration
+	MaxPendingChunks int
+	Close            chan bool
+	ID               string
+	filter           *streamGroupFilter
+
+	mutex   sync.Mutex
+	pending map[string]*natsChunk
+	acked   chan struct{}
+}
+
+type natsChunk struct {
+	msgs     []*nats.Msg
+	deadline time.Time
 }
 
 func (streamer *NatsEventStreamer) GetResponseMessageChan() chan *v1notificationservices.NotificationStreamGroupResponse {
 	return streamer.ResponseMsgChan
 }
 
-func (streamer *NatsEventStreamer) StartStream() error {
-	for {
-		var responseChunk []*v1notificationservices.NotificationStreamResponse
+func (streamer *NatsEventStreamer) StartStream(ctx context.Context) error {
+	streamMetrics.Add(metricActiveStreams, 1)
+	defer streamMetrics.Add(metricActiveStreams, -1)
+	defer streamer.shutdown()
 
+	for {
 		select {
+		case <-ctx.Done():
+			return nil
 		case <-streamer.Close:
-			close(streamer.ResponseMsgChan)
 			return nil
 		default:
 		}
 
-		streamer.MaxPendingAck <- true
+		streamer.nakExpired()
+
+		pendingCount, nextDeadline := streamer.pendingState()
+		if pendingCount >= streamer.MaxPendingChunks {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-streamer.Close:
+				return nil
+			case <-streamer.acked:
+			case <-time.After(time.Until(nextDeadline)):
+			}
+			continue
+		}
 
-		chunk, err := streamer.Subscription.Fetch(500, nats.MaxWait(1*time.Second))
+		chunk, err := streamer.Subscription.Fetch(streamer.ChunkSize, nats.MaxWait(natsFetchWait))
 		if err != nil && err != nats.ErrTimeout {
 			log.Errorln(err.Error())
 			return err
 		}
 
-		for _, msg := range chunk {
-			notificationMsg := &v1notificationservices.EventNotificationMessage{}
+		if len(chunk) == 0 {
+			continue
+		}
 
-			err := protojson.Unmarshal(msg.Data, notificationMsg)
+		var responseChunk []*v1notificationservices.NotificationStreamResponse
+		for _, msg := range chunk {
+			encoded := natsEncodedEvent(msg)
+			notificationMsg, err := decodeNotificationMessage(encoded)
 			if err != nil {
 				log.Errorln(err.Error())
 				return err
@@ -333,43 +375,155 @@ func (streamer *NatsEventStreamer) StartStream() error {
 				return err
 			}
 
-			responseMsg := &v1notificationservices.NotificationStreamResponse{
-				Message:   notificationMsg,
-				Sequence:  metadata.Sequence.Stream,
-				Timestamp: timestamppb.Now(),
+			if !streamer.filter.matches(notificationMsg, encoded) {
+				continue
 			}
 
-			responseChunk = append(responseChunk, responseMsg)
+			responseChunk = append(responseChunk, streamer.filter.response(notificationMsg, metadata.Sequence.Stream))
+		}
+
+		// Chunks without any event that passed the filters are acknowledged directly
+		if len(responseChunk) == 0 {
+			err = ackMessages(chunk)
+			if err != nil {
+				return err
+			}
+			continue
 		}
 
 		ackUUID := uuid.New()
 
+		// The chunk is registered before it is sent, so that an immediate acknowledgement finds it
+		streamer.mutex.Lock()
+		streamer.pending[ackUUID.String()] = &natsChunk{
+			msgs:     chunk,
+			deadline: time.Now().Add(streamer.AckTimeout),
+		}
+		streamer.mutex.Unlock()
+		streamMetrics.Add(metricPendingChunks, 1)
+		streamMetrics.Add(metricPendingMessages, int64(len(chunk)))
+
 		response := &v1notificationservices.NotificationStreamGroupResponse{
 			Notification: responseChunk,
 			AckChunkId:   ackUUID.String(),
 		}
 
-		streamer.ResponseMsgChan <- response
-
-		streamer.MsgMapMutex.Lock()
-		streamer.MsgMap[ackUUID.String()] = chunk
-		streamer.MsgMapMutex.Unlock()
+		select {
+		case streamer.ResponseMsgChan <- response:
+		case <-ctx.Done():
+			return nil
+		case <-streamer.Close:
+			return nil
+		}
 	}
 }
 
 func (streamer *NatsEventStreamer) CloseStream() error {
-	streamer.Close <- true
+	select {
+	case streamer.Close <- true:
+	default:
+	}
 
 	return nil
 }
 
 func (streamer *NatsEventStreamer) AckChunk(chunkID string) error {
-	streamer.MsgMapMutex.Lock()
-	response := streamer.MsgMap[chunkID]
-	delete(streamer.MsgMap, chunkID)
-	streamer.MsgMapMutex.Unlock()
+	streamer.mutex.Lock()
+	chunk, ok := streamer.pending[chunkID]
+	delete(streamer.pending, chunkID)
+	streamer.mutex.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrUnknownChunk, chunkID)
+	}
 
-	for _, msg := range response {
+	streamMetrics.Add(metricPendingChunks, -1)
+	streamMetrics.Add(metricPendingMessages, -int64(len(chunk.msgs)))
+	streamMetrics.Add(metricAckedChunks, 1)
+
+	err := ackMessages(chunk.msgs)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case streamer.acked <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Returns the number of pending chunks and the earliest deadline of them
+func (streamer *NatsEventStreamer) pendingState() (int, time.Time) {
+	streamer.mutex.Lock()
+	defer streamer.mutex.Unlock()
+
+	var nextDeadline time.Time
+	for _, chunk := range streamer.pending {
+		if nextDeadline.IsZero() || chunk.deadline.Before(nextDeadline) {
+			nextDeadline = chunk.deadline
+		}
+	}
+
+	return len(streamer.pending), nextDeadline
+}
+
+// Negatively acknowledges the chunks whose ack deadline has passed, their messages are delivered again by JetStream
+func (streamer *NatsEventStreamer) nakExpired() {
+	now := time.Now()
+
+	var expired []*natsChunk
+	streamer.mutex.Lock()
+	for chunkID, chunk := range streamer.pending {
+		if now.After(chunk.deadline) {
+			expired = append(expired, chunk)
+			delete(streamer.pending, chunkID)
+		}
+	}
+	streamer.mutex.Unlock()
+
+	for _, chunk := range expired {
+		log.Debugf("chunk of stream %v with %v messages was not acknowledged in time", streamer.ID, len(chunk.msgs))
+		streamMetrics.Add(metricExpiredChunks, 1)
+		streamer.nakChunk(chunk)
+	}
+}
+
+// Negatively acknowledges all pending chunks and releases the subscription
+func (streamer *NatsEventStreamer) shutdown() {
+	streamer.mutex.Lock()
+	pending := streamer.pending
+	streamer.pending = make(map[string]*natsChunk)
+	streamer.mutex.Unlock()
+
+	for _, chunk := range pending {
+		streamer.nakChunk(chunk)
+	}
+
+	err := streamer.Subscription.Unsubscribe()
+	if err != nil {
+		log.Debugln(err.Error())
+	}
+
+	close(streamer.ResponseMsgChan)
+}
+
+func (streamer *NatsEventStreamer) nakChunk(chunk *natsChunk) {
+	streamMetrics.Add(metricPendingChunks, -1)
+	streamMetrics.Add(metricPendingMessages, -int64(len(chunk.msgs)))
+	streamMetrics.Add(metricRedeliveredMessages, int64(len(chunk.msgs)))
+
+	for _, msg := range chunk.msgs {
+		err := msg.Nak()
+		if err != nil {
+			log.Debugln(err.Error())
+		}
+	}
+}
+
+func ackMessages(msgs []*nats.Msg) error {
+	for _, msg := range msgs {
 		err := msg.Ack()
 		if err != nil {
 			log.Errorln(err.Error())
@@ -377,7 +531,17 @@ func (streamer *NatsEventStreamer) AckChunk(chunkID string) error {
 		}
 	}
 
-	<-streamer.MaxPendingAck
-
 	return nil
 }
+
+// Returns the data and the headers of a received message
+func natsEncodedEvent(msg *nats.Msg) *EncodedEvent {
+	encoded := &EncodedEvent{Data: msg.Data, Headers: make(map[string]string)}
+	for key, values := range msg.Header {
+		if len(values) > 0 {
+			encoded.Headers[key] = values[0]
+		}
+	}
+
+	return encoded
+}
@@ -0,0 +1,112 @@
+package eventstreaming
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestNatsStreamer(maxPendingChunks int) *NatsEventStreamer {
+	return &NatsEventStreamer{
+		ResponseMsgChan:  make(chan *v1notificationservices.NotificationStreamGroupResponse, 3),
+		ChunkSize:        10,
+		AckTimeout:       time.Minute,
+		MaxPendingChunks: maxPendingChunks,
+		Close:            make(chan bool, 1),
+		ID:               "test",
+		filter:           newStreamGroupFilter(&models.StreamGroup{}),
+		pending:          make(map[string]*natsChunk),
+		acked:            make(chan struct{}, 1),
+	}
+}
+
+func TestNatsStreamLimits(t *testing.T) {
+	assert.NoError(t, validateNatsStreamLimits(500, 10*time.Second, 3))
+	assert.Error(t, validateNatsStreamLimits(0, 10*time.Second, 3))
+	assert.Error(t, validateNatsStreamLimits(500, 0, 3))
+	assert.Error(t, validateNatsStreamLimits(500, 10*time.Second, 0))
+	assert.Error(t, validateNatsStreamLimits(500, 10*time.Second, -1))
+}
+
+func TestNatsStreamerPending(t *testing.T) {
+	streamer := newTestNatsStreamer(3)
+
+	now := time.Now()
+	streamer.pending["expired"] = &natsChunk{msgs: []*nats.Msg{{}, {}}, deadline: now.Add(-time.Second)}
+	streamer.pending["next"] = &natsChunk{deadline: now.Add(time.Minute)}
+	streamer.pending["later"] = &natsChunk{msgs: []*nats.Msg{{}}, deadline: now.Add(time.Hour)}
+
+	count, nextDeadline := streamer.pendingState()
+	assert.Equal(t, 3, count)
+	assert.Equal(t, now.Add(-time.Second), nextDeadline)
+
+	// Expired chunks are negatively acknowledged and can not be acknowledged anymore
+	streamer.nakExpired()
+	count, nextDeadline = streamer.pendingState()
+	assert.Equal(t, 2, count)
+	assert.Equal(t, now.Add(time.Minute), nextDeadline)
+	assert.ErrorIs(t, streamer.AckChunk("expired"), ErrUnknownChunk)
+
+	assert.NoError(t, streamer.AckChunk("next"))
+	assert.ErrorIs(t, streamer.AckChunk("next"), ErrUnknownChunk)
+
+	count, _ = streamer.pendingState()
+	assert.Equal(t, 1, count)
+}
+
+func TestNatsStreamerBackpressure(t *testing.T) {
+	streamer := newTestNatsStreamer(2)
+	streamer.pending["first"] = &natsChunk{deadline: time.Now().Add(time.Minute)}
+	streamer.pending["second"] = &natsChunk{deadline: time.Now().Add(time.Minute)}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- streamer.StartStream(context.Background())
+	}()
+
+	// The streamer has no subscription, it would fail as soon as it fetches further events
+	select {
+	case err := <-done:
+		t.Fatalf("streamer fetched events with the maximum number of pending chunks: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// An acknowledgement allows the next fetch
+	assert.NoError(t, streamer.AckChunk("first"))
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, nats.ErrBadSubscription)
+	case <-time.After(5 * time.Second):
+		t.Fatal("streamer did not fetch after an acknowledgement")
+	}
+
+	// The pending chunks of a stopped streamer are released
+	count, _ := streamer.pendingState()
+	assert.Equal(t, 0, count)
+}
+
+func TestNatsStreamerClose(t *testing.T) {
+	streamer := newTestNatsStreamer(1)
+	streamer.pending["first"] = &natsChunk{deadline: time.Now().Add(time.Minute)}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- streamer.StartStream(context.Background())
+	}()
+
+	assert.NoError(t, streamer.CloseStream())
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("streamer was not closed")
+	}
+
+	_, open := <-streamer.ResponseMsgChan
+	assert.False(t, open)
+}
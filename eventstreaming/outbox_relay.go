@@ -0,0 +1,199 @@
+package eventstreaming
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// Backoff of the first retry of an event that could not be published, it is doubled with every failed attempt
+const outboxMinBackoff = time.Second
+
+// Maximum backoff between two attempts to publish an event
+const outboxMaxBackoff = 5 * time.Minute
+
+// Interval in which published events that are older than the retention are removed
+const outboxCleanupInterval = time.Hour
+
+// Number of attempts after which an event is dead lettered if MaxAttempts is not set
+const defaultOutboxMaxAttempts = 20
+
+// Duration for which claimed events are not returned to other relays, it has to exceed the time needed to publish a batch
+const outboxClaimLease = time.Minute
+
+// OutboxRelay Publishes the events of the transactional outbox to the event notification backend
+//
+// Events are delivered at least once: an event is marked as published only after the backend accepted it,
+// if the relay stops in between, the event is published again once its claim has expired. The relays of multiple
+// servers claim separate batches. Events of the same resource are published in the order in which their
+// transactions committed, an event is held back until all earlier events of its resource have been published.
+// Failed events are retried with an exponential backoff, after MaxAttempts they are dead lettered and the next
+// events of the resource are published.
+type OutboxRelay struct {
+	Outbox          *database.Outbox
+	EventStreamMgmt EventStreamMgmt
+	PollInterval    time.Duration
+	BatchSize       int
+	// Number of attempts after which an event is dead lettered, values <= 0 use the default of 20 attempts
+	MaxAttempts int
+	// Published and dead lettered events are removed after the retention
+	Retention time.Duration
+}
+
+// Run Publishes pending events until the context is cancelled
+func (relay *OutboxRelay) Run(ctx context.Context) error {
+	pollTicker := time.NewTicker(relay.PollInterval)
+	defer pollTicker.Stop()
+
+	cleanupTicker := time.NewTicker(outboxCleanupInterval)
+	defer cleanupTicker.Stop()
+
+	for {
+		published, err := relay.PublishPending()
+		if err != nil {
+			log.Errorln(err.Error())
+		}
+
+		// Continue immediately if the batch was full, more events might be pending
+		if err == nil && published == relay.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-cleanupTicker.C:
+			err := relay.Outbox.DeletePublishedOutboxEvents(time.Now().Add(-relay.Retention))
+			if err != nil {
+				log.Errorln(err.Error())
+			}
+		case <-pollTicker.C:
+		}
+	}
+}
+
+// PublishPending Publishes a single batch of pending events and returns the number of published events
+func (relay *OutboxRelay) PublishPending() (int, error) {
+	events, publishedSequences, err := relay.Outbox.ClaimPendingOutboxEvents(relay.BatchSize, outboxClaimLease)
+	if err != nil {
+		log.Errorln(err.Error())
+		return 0, err
+	}
+
+	published := 0
+	for _, resourceEvents := range groupResourceEvents(events) {
+		expected := publishedSequences[resourceEvents[0].ResourceKey()] + 1
+
+		for i, event := range resourceEvents {
+			// An earlier event of the resource has been claimed by another relay or is retried later
+			if event.ResourceSequence != expected {
+				err = relay.release(resourceEvents[i:], time.Now())
+				break
+			}
+
+			publishErr := relay.EventStreamMgmt.PublishMessage(event)
+			if publishErr == nil {
+				err = relay.Outbox.MarkOutboxEventPublished(event)
+				if err != nil {
+					break
+				}
+
+				published++
+				expected++
+				continue
+			}
+
+			log.Errorln(publishErr.Error())
+
+			if event.Attempts+1 >= relay.maxAttempts() {
+				log.Errorf("dead lettering event %v of %v %v after %v attempts", event.ID.String(), event.Resource, event.ResourceID.String(), event.Attempts+1)
+				err = relay.Outbox.DeadLetterOutboxEvent(event, publishErr)
+				if err != nil {
+					break
+				}
+
+				expected++
+				continue
+			}
+
+			nextAttempt := time.Now().Add(outboxBackoff(event))
+			err = relay.Outbox.MarkOutboxEventFailed(event.ID, nextAttempt, publishErr)
+			if err != nil {
+				break
+			}
+
+			// The following events of the resource wait for the retry
+			err = relay.release(resourceEvents[i+1:], nextAttempt)
+			break
+		}
+
+		if err != nil {
+			log.Errorln(err.Error())
+			return published, err
+		}
+	}
+
+	return published, nil
+}
+
+func (relay *OutboxRelay) release(events []*models.OutboxEvent, nextAttempt time.Time) error {
+	ids := make([]uuid.UUID, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+
+	return relay.Outbox.ReleaseOutboxEvents(ids, nextAttempt)
+}
+
+// Groups events by their resource, the events of a resource are ordered by their position
+// The groups are ordered by the sequence of their first event.
+func groupResourceEvents(events []*models.OutboxEvent) [][]*models.OutboxEvent {
+	var groups [][]*models.OutboxEvent
+	groupIndex := make(map[string]int)
+
+	for _, event := range events {
+		index, ok := groupIndex[event.ResourceKey()]
+		if !ok {
+			index = len(groups)
+			groupIndex[event.ResourceKey()] = index
+			groups = append(groups, nil)
+		}
+
+		groups[index] = append(groups[index], event)
+	}
+
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].ResourceSequence < group[j].ResourceSequence
+		})
+	}
+
+	return groups
+}
+
+// Returns the duration until the next attempt to publish an event after it failed again
+func outboxBackoff(event *models.OutboxEvent) time.Duration {
+	backoff := outboxMinBackoff
+	for i := 0; i < event.Attempts; i++ {
+		backoff *= 2
+		if backoff >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+
+	return backoff
+}
+
+// Returns the number of attempts after which an event is dead lettered
+func (relay *OutboxRelay) maxAttempts() int {
+	if relay.MaxAttempts <= 0 {
+		return defaultOutboxMaxAttempts
+	}
+
+	return relay.MaxAttempts
+}
@@ -0,0 +1,103 @@
+package eventstreaming
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboxBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, outboxBackoff(&models.OutboxEvent{Attempts: 0}))
+	assert.Equal(t, 8*time.Second, outboxBackoff(&models.OutboxEvent{Attempts: 3}))
+	assert.Equal(t, outboxMaxBackoff, outboxBackoff(&models.OutboxEvent{Attempts: 100}))
+}
+
+func TestOutboxMaxAttempts(t *testing.T) {
+	assert.Equal(t, defaultOutboxMaxAttempts, (&OutboxRelay{}).maxAttempts())
+	assert.Equal(t, defaultOutboxMaxAttempts, (&OutboxRelay{MaxAttempts: -1}).maxAttempts())
+	assert.Equal(t, 3, (&OutboxRelay{MaxAttempts: 3}).maxAttempts())
+}
+
+func TestGroupResourceEvents(t *testing.T) {
+	objectGroupID := uuid.New()
+	datasetID := uuid.New()
+
+	event := func(sequence int64, resource string, resourceID uuid.UUID, resourceSequence int64) *models.OutboxEvent {
+		return &models.OutboxEvent{Sequence: sequence, Resource: resource, ResourceID: resourceID, ResourceSequence: resourceSequence}
+	}
+
+	// The second transaction of the object group committed first
+	events := []*models.OutboxEvent{
+		event(10, "RESOURCE_OBJECT_GROUP", objectGroupID, 4),
+		event(11, "RESOURCE_DATASET", datasetID, 1),
+		event(12, "RESOURCE_OBJECT_GROUP", objectGroupID, 3),
+	}
+
+	groups := groupResourceEvents(events)
+	assert.Len(t, groups, 2)
+	assert.Equal(t, []int64{12, 10}, []int64{groups[0][0].Sequence, groups[0][1].Sequence})
+	assert.Equal(t, int64(11), groups[1][0].Sequence)
+
+	assert.Empty(t, groupResourceEvents(nil))
+}
+
+func TestOutboxEventPublishSubject(t *testing.T) {
+	mgmt := &NatsEventStreamMgmt{SubjectPrefix: "UPDATES"}
+
+	projectID := uuid.New()
+	datasetID := uuid.New()
+	objectGroupID := uuid.New()
+	versionID := uuid.New()
+
+	subject, err := mgmt.getPublishSubject(models.NewProjectEvent(projectID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED))
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("UPDATES.%v._", projectID), subject)
+
+	subject, err = mgmt.getPublishSubject(models.NewDatasetEvent(projectID, datasetID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED))
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("UPDATES.%v.%v._", projectID, datasetID), subject)
+
+	subject, err = mgmt.getPublishSubject(models.NewObjectGroupEvent(projectID, datasetID, objectGroupID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_DELETED))
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("UPDATES.%v.%v.objectgroup.%v._", projectID, datasetID, objectGroupID), subject)
+
+	event := models.NewDatasetVersionEvent(projectID, datasetID, versionID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_AVAILABLE)
+	subject, err = mgmt.getPublishSubject(event)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("UPDATES.%v.%v.datasetversion.%v._", projectID, datasetID, versionID), subject)
+
+	message := event.ToProtoModel()
+	assert.Equal(t, v1storagemodels.Resource_RESOURCE_DATASET_VERSION, message.Resource)
+	assert.Equal(t, versionID.String(), message.ResourceId)
+	assert.Equal(t, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_AVAILABLE, message.UpdatedType)
+
+	objectID := uuid.New()
+	subject, err = mgmt.getPublishSubject(models.NewObjectEvent(projectID, datasetID, objectID, v1notificationservices.EventNotificationMessage_UPDATE_TYPE_AVAILABLE))
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("UPDATES.%v.%v.object.%v._", projectID, datasetID, objectID), subject)
+	assert.True(t, subjectMatches(fmt.Sprintf("UPDATES.%v.%v.>", projectID, datasetID), subject))
+
+	// Users are identified by the subject of their token, their events have a stable name based id
+	userEvent := models.NewUserEvent(projectID, "oidc|user-1", v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED)
+	assert.Equal(t, userEvent.ResourceID, models.NewUserEvent(projectID, "oidc|user-1", v1notificationservices.EventNotificationMessage_UPDATE_TYPE_DELETED).ResourceID)
+	assert.NotEqual(t, userEvent.ResourceID, models.NewUserEvent(uuid.New(), "oidc|user-1", v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED).ResourceID)
+	subject, err = mgmt.getPublishSubject(userEvent)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("UPDATES.%v.user.%v._", projectID, userEvent.ResourceID), subject)
+
+	tokenEvent := models.NewAPITokenEvent(projectID, uuid.New(), v1notificationservices.EventNotificationMessage_UPDATE_TYPE_DELETED)
+	subject, err = mgmt.getPublishSubject(tokenEvent)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("UPDATES.%v.apitoken.%v._", projectID, tokenEvent.ResourceID), subject)
+	assert.False(t, subjectMatches(fmt.Sprintf("UPDATES.%v._", projectID), subject))
+
+	message = tokenEvent.ToProtoModel()
+	assert.Equal(t, v1storagemodels.Resource_RESOURCE_PROJECT, message.Resource)
+	assert.Equal(t, projectID.String(), message.ResourceId)
+}
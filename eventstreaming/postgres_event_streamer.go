@@ -0,0 +1,409 @@
+package eventstreaming
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/config"
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Maximum number of events that are sent in a single chunk
+const postgresChunkSize = 500
+
+// Maximum number of unacknowledged chunks of a streamer
+const postgresMaxPendingChunks = 3
+
+// PostgresEventStreamMgmt Event notification backend that only requires the Postgres database
+//
+// Published events are stored in an event log and added to the queue of each stream group with a matching subject,
+// the subjects are the same as the ones of the NATS backend. Streamers are woken up by LISTEN/NOTIFY on Channel and
+// poll their queue every PollInterval in case a notification got lost. Chunks that are not acknowledged within AckWait
+// are delivered again. The log is kept for Retention, new stream groups start with the logged events that match their
+// subject and their start policy.
+type PostgresEventStreamMgmt struct {
+	DatabaseRead   *database.Read
+	DatabaseCreate *database.Create
+	EventLog       *database.EventLog
+	SubjectPrefix  string
+	Channel        string
+	AckWait        time.Duration
+	PollInterval   time.Duration
+	Retention      time.Duration
+	// Format the events are encoded in, see EncodeEvent
+	EventFormat string
+
+	mutex  sync.Mutex
+	wakeup chan struct{}
+}
+
+// NewPostgresEventStreamMgmt Creates the Postgres backend from the config
+func NewPostgresEventStreamMgmt(databaseReader *database.Read, databaseCreate *database.Create) (*PostgresEventStreamMgmt, error) {
+	if viper.GetString(config.DB_DATABASETYPE) != "Postgres" {
+		return nil, fmt.Errorf("the Postgres event notification backend requires DB.Databasetype Postgres")
+	}
+
+	mgmt := &PostgresEventStreamMgmt{
+		DatabaseRead:   databaseReader,
+		DatabaseCreate: databaseCreate,
+		EventLog:       &database.EventLog{Common: databaseReader.Common},
+		SubjectPrefix:  viper.GetString(config.EVENTNOTIFICATION_NATS_SUBJECTPREFIX),
+		Channel:        viper.GetString(config.EVENTNOTIFICATION_POSTGRES_CHANNEL),
+		AckWait:        viper.GetDuration(config.EVENTNOTIFICATION_POSTGRES_ACK_WAIT),
+		PollInterval:   viper.GetDuration(config.EVENTNOTIFICATION_POSTGRES_POLL_INTERVAL),
+		Retention:      viper.GetDuration(config.EVENTNOTIFICATION_POSTGRES_RETENTION),
+		EventFormat:    viper.GetString(config.EVENTNOTIFICATION_FORMAT),
+		wakeup:         make(chan struct{}),
+	}
+
+	return mgmt, nil
+}
+
+// Run Listens for notifications of new events and removes expired events from the log until ctx is done
+// The listener is restarted after PollInterval if its connection fails, streamers poll in the meantime.
+func (mgmt *PostgresEventStreamMgmt) Run(ctx context.Context) error {
+	go func() {
+		for {
+			err := mgmt.EventLog.Listen(ctx, mgmt.Channel, mgmt.notify)
+			if err != nil {
+				log.Errorln(err.Error())
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(mgmt.PollInterval):
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		err := mgmt.EventLog.DeleteEventLogEntries(time.Now().Add(-mgmt.Retention))
+		if err != nil {
+			log.Errorln(err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (mgmt *PostgresEventStreamMgmt) EnableTestMode() error {
+	return nil
+}
+
+func (mgmt *PostgresEventStreamMgmt) CreateStreamGroup(projectID uuid.UUID, resourceID uuid.UUID, resourceType *v1notificationservices.CreateEventStreamingGroupRequest_EventResources, includeSubResources bool, start *models.StreamGroupStart) (*models.StreamGroup, error) {
+	targetSubject, err := subscriptionSubject(mgmt.SubjectPrefix, mgmt.DatabaseRead, resourceID, *resourceType, includeSubResources)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	group, err := mgmt.DatabaseCreate.CreateStreamGroup(projectID, resourceType.Enum().String(), resourceID, targetSubject, includeSubResources, start)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	err = mgmt.backfill(group, group.Start())
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// GetStreamGroupInfo Returns the delivery state of the queue of a stream group
+func (mgmt *PostgresEventStreamMgmt) GetStreamGroupInfo(streamGroup *models.StreamGroup) (*StreamGroupInfo, error) {
+	counts, err := mgmt.EventLog.GetStreamGroupEventCounts(streamGroup.ID)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	info := &StreamGroupInfo{
+		Pending:          uint64(counts.Pending),
+		AckPending:       uint64(counts.AckPending),
+		Redelivered:      uint64(counts.Redelivered),
+		AckFloorSequence: uint64(counts.LastSequence),
+	}
+
+	if counts.MinSequence > 0 {
+		info.AckFloorSequence = uint64(counts.MinSequence - 1)
+	}
+	info.LastDeliveredSequence = info.AckFloorSequence
+
+	return info, nil
+}
+
+// ResetStreamGroup Refills the queue of a stream group from the event log, pending chunks are dropped
+func (mgmt *PostgresEventStreamMgmt) ResetStreamGroup(streamGroup *models.StreamGroup, start *models.StreamGroupStart) error {
+	err := mgmt.backfill(streamGroup, start)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	mgmt.notify()
+
+	return nil
+}
+
+// DeleteStreamGroup Removes the queue of a stream group
+func (mgmt *PostgresEventStreamMgmt) DeleteStreamGroup(streamGroup *models.StreamGroup) error {
+	return mgmt.EventLog.DeleteStreamGroupEvents(streamGroup.ID)
+}
+
+func (mgmt *PostgresEventStreamMgmt) CreateMessageStreamGroupHandler(streamGroup *models.StreamGroup) (EventStreamer, error) {
+	streamer := &PostgresEventStreamer{
+		mgmt:            mgmt,
+		streamGroup:     streamGroup,
+		filter:          newStreamGroupFilter(streamGroup),
+		ResponseMsgChan: make(chan *v1notificationservices.NotificationStreamGroupResponse, postgresMaxPendingChunks),
+		MaxPendingAck:   make(chan bool, postgresMaxPendingChunks),
+		Close:           make(chan bool, 1),
+	}
+
+	return streamer, nil
+}
+
+// PublishMessage Stores an event in the log and queues it for all stream groups of its project with a matching subject
+func (mgmt *PostgresEventStreamMgmt) PublishMessage(event *models.OutboxEvent) error {
+	subject, err := publishSubject(mgmt.SubjectPrefix, event)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	encoded, err := encodeNotificationMessage(mgmt.EventFormat, event)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	entry := &models.EventLogEntry{
+		OutboxSequence: event.Sequence,
+		Subject:        subject,
+		Data:           string(encoded.Data),
+	}
+
+	if len(encoded.Headers) > 0 {
+		headers, err := json.Marshal(encoded.Headers)
+		if err != nil {
+			log.Errorln(err.Error())
+			return err
+		}
+		entry.Headers = string(headers)
+	}
+
+	streamGroups, err := mgmt.DatabaseRead.GetProjectStreamGroups(event.ProjectID)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	var streamGroupIDs []uuid.UUID
+	for _, streamGroup := range streamGroups {
+		if subjectMatches(streamGroup.Subject, subject) {
+			streamGroupIDs = append(streamGroupIDs, streamGroup.ID)
+		}
+	}
+
+	err = mgmt.EventLog.AppendEventLogEntry(entry, streamGroupIDs, mgmt.Channel)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Replaces the queue of a stream group with the logged events from the start position on that match its subject
+func (mgmt *PostgresEventStreamMgmt) backfill(streamGroup *models.StreamGroup, start *models.StreamGroupStart) error {
+	return mgmt.EventLog.ResetStreamGroupEvents(streamGroup.ID, start, func(subject string) bool {
+		return subjectMatches(streamGroup.Subject, subject)
+	})
+}
+
+// Wakes up all waiting streamers
+func (mgmt *PostgresEventStreamMgmt) notify() {
+	mgmt.mutex.Lock()
+	defer mgmt.mutex.Unlock()
+
+	close(mgmt.wakeup)
+	mgmt.wakeup = make(chan struct{})
+}
+
+// Returns a channel that is closed on the next notification
+func (mgmt *PostgresEventStreamMgmt) waitChan() chan struct{} {
+	mgmt.mutex.Lock()
+	defer mgmt.mutex.Unlock()
+
+	return mgmt.wakeup
+}
+
+// PostgresEventStreamer Streams the queue of a stream group of the Postgres backend
+// Multiple streamers of the same group share the queue, each chunk is only delivered to one of them.
+type PostgresEventStreamer struct {
+	mgmt            *PostgresEventStreamMgmt
+	streamGroup     *models.StreamGroup
+	filter          *streamGroupFilter
+	ResponseMsgChan chan *v1notificationservices.NotificationStreamGroupResponse
+	MaxPendingAck   chan bool
+	Close           chan bool
+}
+
+func (streamer *PostgresEventStreamer) GetResponseMessageChan() chan *v1notificationservices.NotificationStreamGroupResponse {
+	return streamer.ResponseMsgChan
+}
+
+func (streamer *PostgresEventStreamer) StartStream(ctx context.Context) error {
+	defer close(streamer.ResponseMsgChan)
+
+	streamMetrics.Add(metricActiveStreams, 1)
+	defer streamMetrics.Add(metricActiveStreams, -1)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-streamer.Close:
+			return nil
+		case streamer.MaxPendingAck <- true:
+		}
+
+		// The wait channel is taken before the fetch, so that events published during the fetch are not missed
+		wakeup := streamer.mgmt.waitChan()
+
+		chunkID := uuid.New()
+		entries, redelivered, err := streamer.mgmt.EventLog.FetchStreamGroupEvents(streamer.streamGroup.ID, chunkID, postgresChunkSize, streamer.mgmt.AckWait)
+		if err != nil {
+			log.Errorln(err.Error())
+			return err
+		}
+
+		if len(entries) == 0 {
+			<-streamer.MaxPendingAck
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-streamer.Close:
+				return nil
+			case <-wakeup:
+			case <-time.After(streamer.mgmt.PollInterval):
+			}
+			continue
+		}
+
+		streamMetrics.Add(metricRedeliveredMessages, int64(redelivered))
+
+		var responseChunk []*v1notificationservices.NotificationStreamResponse
+		for _, entry := range entries {
+			encoded := postgresEncodedEvent(entry)
+			notificationMsg, err := decodeNotificationMessage(encoded)
+			if err != nil {
+				log.Errorln(err.Error())
+				return err
+			}
+
+			if !streamer.filter.matches(notificationMsg, encoded) {
+				continue
+			}
+
+			responseChunk = append(responseChunk, streamer.filter.response(notificationMsg, uint64(entry.Sequence)))
+		}
+
+		// Chunks without any event that passed the filters are acknowledged directly
+		if len(responseChunk) == 0 {
+			_, err = streamer.mgmt.EventLog.AckStreamGroupChunk(streamer.streamGroup.ID, chunkID)
+			if err != nil {
+				log.Errorln(err.Error())
+				return err
+			}
+			<-streamer.MaxPendingAck
+			continue
+		}
+
+		response := &v1notificationservices.NotificationStreamGroupResponse{
+			Notification: responseChunk,
+			AckChunkId:   chunkID.String(),
+		}
+
+		select {
+		case streamer.ResponseMsgChan <- response:
+		case <-ctx.Done():
+			return nil
+		case <-streamer.Close:
+			return nil
+		}
+	}
+}
+
+func (streamer *PostgresEventStreamer) CloseStream() error {
+	select {
+	case streamer.Close <- true:
+	default:
+	}
+
+	return nil
+}
+
+// AckChunk Removes the events of a chunk from the queue, chunks that have been assigned again after their deadline are unknown
+func (streamer *PostgresEventStreamer) AckChunk(chunkID string) error {
+	if chunkID == "" {
+		return fmt.Errorf("no chunk id provided")
+	}
+
+	select {
+	case <-streamer.MaxPendingAck:
+	default:
+	}
+
+	parsedChunkID, err := uuid.Parse(chunkID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnknownChunk, chunkID)
+	}
+
+	acked, err := streamer.mgmt.EventLog.AckStreamGroupChunk(streamer.streamGroup.ID, parsedChunkID)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	if !acked {
+		return fmt.Errorf("%w: %v", ErrUnknownChunk, chunkID)
+	}
+
+	streamMetrics.Add(metricAckedChunks, 1)
+
+	return nil
+}
+
+// Returns the data and the headers of a logged event
+func postgresEncodedEvent(entry *models.EventLogEntry) *EncodedEvent {
+	encoded := &EncodedEvent{Data: []byte(entry.Data)}
+	if entry.Headers != "" {
+		err := json.Unmarshal([]byte(entry.Headers), &encoded.Headers)
+		if err != nil {
+			log.Errorln(err.Error())
+		}
+	}
+
+	return encoded
+}
@@ -0,0 +1,158 @@
+package eventstreaming
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const OBJECTGROUPSUBJECTNAME = "objectgroup"
+const DATASETVERSIONSUBJECTNAME = "datasetversion"
+const OBJECTSUBJECTNAME = "object"
+const USERSUBJECTNAME = "user"
+const APITOKENSUBJECTNAME = "apitoken"
+const DEFAULTSUBJECTSUFFIX = "_"
+
+// Subjects of the events are built as hierarchy of the resource ids:
+//   <prefix>.<ProjectID>._
+//   <prefix>.<ProjectID>.<DatasetID>._
+//   <prefix>.<ProjectID>.<DatasetID>.objectgroup|datasetversion.<ObjectGroupID|DatasetVersionID>._
+//   <prefix>.<ProjectID>.<DatasetID>.object.<ObjectID>._
+//   <prefix>.<ProjectID>.user|apitoken.<UserID|APITokenID>._
+// A stream group subscribes either to the events of the resource itself (suffix "_") or to the events of the resource
+// and all its subresources (suffix ">"). The subjects are shared by all backends.
+
+// Returns the subject a stream group on the given resource subscribes to
+func subscriptionSubject(prefix string, databaseRead *database.Read, resourceID uuid.UUID, resourceType v1notificationservices.CreateEventStreamingGroupRequest_EventResources, useSubResource bool) (string, error) {
+	subject := ""
+
+	finalSymbol := DEFAULTSUBJECTSUFFIX
+	if useSubResource {
+		finalSymbol = ">"
+	}
+
+	switch resourceType {
+	case v1notificationservices.CreateEventStreamingGroupRequest_EVENT_RESOURCES_PROJECT_RESOURCE:
+		{
+			subject = fmt.Sprintf("%v.%v.%v", prefix, resourceID.String(), finalSymbol)
+		}
+
+	case v1notificationservices.CreateEventStreamingGroupRequest_EVENT_RESOURCES_DATASET_RESOURCE:
+		{
+			dataset, err := databaseRead.GetDataset(resourceID)
+			if err != nil {
+				log.Errorln(err.Error())
+				return "", err
+			}
+
+			subject = fmt.Sprintf("%v.%v.%v.%v", prefix, dataset.ProjectID.String(), dataset.ID.String(), finalSymbol)
+
+		}
+
+	case v1notificationservices.CreateEventStreamingGroupRequest_EVENT_RESOURCES_DATASET_VERSION_RESOURCE:
+		{
+			datasetVersion, err := databaseRead.GetDatasetVersion(resourceID)
+			if err != nil {
+				log.Errorln(err.Error())
+				return "", err
+			}
+
+			subject = fmt.Sprintf("%v.%v.%v.%v.%v.%v", prefix, datasetVersion.ProjectID.String(), datasetVersion.DatasetID.String(), DATASETVERSIONSUBJECTNAME, datasetVersion.ID.String(), finalSymbol)
+		}
+
+	case v1notificationservices.CreateEventStreamingGroupRequest_EVENT_RESOURCES_OBJECT_GROUP_RESOURCE:
+		{
+			objectGroup, err := databaseRead.GetObjectGroup(resourceID)
+			if err != nil {
+				log.Errorln(err.Error())
+				return "", err
+			}
+
+			subject = fmt.Sprintf("%v.%v.%v.%v.%v.%v", prefix, objectGroup.ProjectID.String(), objectGroup.DatasetID.String(), OBJECTGROUPSUBJECTNAME, objectGroup.ID.String(), finalSymbol)
+		}
+
+	default:
+		{
+			return "", fmt.Errorf("queried resource not implemented")
+		}
+	}
+
+	return subject, nil
+}
+
+// Returns the subject an event is published on
+func publishSubject(prefix string, event *models.OutboxEvent) (string, error) {
+	switch event.Resource {
+	case models.EventResourceUser:
+		{
+			subject := fmt.Sprintf("%v.%v.%v.%v._", prefix, event.ProjectID.String(), USERSUBJECTNAME, event.ResourceID.String())
+			return subject, nil
+		}
+	case models.EventResourceAPIToken:
+		{
+			subject := fmt.Sprintf("%v.%v.%v.%v._", prefix, event.ProjectID.String(), APITOKENSUBJECTNAME, event.ResourceID.String())
+			return subject, nil
+		}
+	}
+
+	switch event.ResourceEnum() {
+	case v1storagemodels.Resource_RESOURCE_PROJECT:
+		{
+			subject := fmt.Sprintf("%v.%v._", prefix, event.ProjectID.String())
+			return subject, nil
+		}
+	case v1storagemodels.Resource_RESOURCE_DATASET:
+		{
+			subject := fmt.Sprintf("%v.%v.%v._", prefix, event.ProjectID.String(), event.DatasetID.String())
+			return subject, nil
+		}
+	case v1storagemodels.Resource_RESOURCE_OBJECT_GROUP:
+		{
+			subject := fmt.Sprintf("%v.%v.%v.%v.%v._", prefix, event.ProjectID.String(), event.DatasetID.String(), OBJECTGROUPSUBJECTNAME, event.ObjectGroupID.String())
+			return subject, nil
+		}
+	case v1storagemodels.Resource_RESOURCE_DATASET_VERSION:
+		{
+			subject := fmt.Sprintf("%v.%v.%v.%v.%v._", prefix, event.ProjectID.String(), event.DatasetID.String(), DATASETVERSIONSUBJECTNAME, event.DatasetVersionID.String())
+			return subject, nil
+		}
+	case v1storagemodels.Resource_RESOURCE_OBJECT:
+		{
+			subject := fmt.Sprintf("%v.%v.%v.%v.%v._", prefix, event.ProjectID.String(), event.DatasetID.String(), OBJECTSUBJECTNAME, event.ResourceID.String())
+			return subject, nil
+		}
+	default:
+		{
+			return "", fmt.Errorf("provided resource not implemented")
+		}
+	}
+}
+
+// Checks if a subject matches a subscription subject, the subscription can contain the wildcards
+// "*" which matches a single token and ">" which matches all remaining tokens, the same as NATS subjects.
+func subjectMatches(subscription string, subject string) bool {
+	subscriptionTokens := strings.Split(subscription, ".")
+	subjectTokens := strings.Split(subject, ".")
+
+	for i, token := range subscriptionTokens {
+		if token == ">" {
+			return i == len(subscriptionTokens)-1 && len(subjectTokens) > i
+		}
+
+		if i >= len(subjectTokens) {
+			return false
+		}
+
+		if token != "*" && token != subjectTokens[i] {
+			return false
+		}
+	}
+
+	return len(subscriptionTokens) == len(subjectTokens)
+}
@@ -0,0 +1,541 @@
+package eventstreaming
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/config"
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	"github.com/ScienceObjectsDB/CORE-Server/signing"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"golang.org/x/sync/errgroup"
+)
+
+// Headers that are sent with each webhook delivery
+const (
+	WebhookSignatureHeader = "X-SciObjsDB-Signature"
+	WebhookTimestampHeader = "X-SciObjsDB-Timestamp"
+	WebhookEventHeader     = "X-SciObjsDB-Event"
+	WebhookDeliveryHeader  = "X-SciObjsDB-Delivery"
+)
+
+// Backoff of the first retry of a failed delivery, it is doubled with every failed attempt
+const webhookMinBackoff = 10 * time.Second
+
+// Maximum backoff between two attempts of a delivery
+const webhookMaxBackoff = time.Hour
+
+// Duration for which claimed deliveries are not returned to other servers
+const webhookClaimLease = 5 * time.Minute
+
+// Time after which no further deliveries of a claimed batch are started, together with the timeout of a single delivery
+// it has to stay below the claim lease
+const webhookDeliveryWindow = 2 * time.Minute
+
+// Maximum number of bytes of a response body that are stored as error of a failed delivery
+const webhookMaxErrorBody = 512
+
+// Timeout of resolving the host of a webhook url during its validation
+const webhookResolveTimeout = 5 * time.Second
+
+// Networks that are not publicly routable besides the loopback, link-local and private networks of the net package
+var nonPublicNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("192.0.0.0/24"),
+	mustParseCIDR("198.18.0.0/15"),
+	mustParseCIDR("240.0.0.0/4"),
+	mustParseCIDR("64:ff9b::/96"),
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+
+	return network
+}
+
+// WebhookPayload The json body that is posted to the webhook endpoints
+type WebhookPayload struct {
+	EventID          string    `json:"event_id"`
+	Resource         string    `json:"resource"`
+	ResourceID       string    `json:"resource_id"`
+	UpdateType       string    `json:"update_type"`
+	ProjectID        string    `json:"project_id"`
+	DatasetID        string    `json:"dataset_id,omitempty"`
+	ObjectGroupID    string    `json:"object_group_id,omitempty"`
+	DatasetVersionID string    `json:"dataset_version_id,omitempty"`
+	Created          time.Time `json:"created"`
+	// Proto json of the resource at the time of the event, only sent to subscriptions that requested snapshots
+	Snapshot json.RawMessage `json:"snapshot,omitempty"`
+	Changes  *EventChanges   `json:"changes,omitempty"`
+}
+
+// EventChanges Revisions and labels that have been changed by an event
+type EventChanges struct {
+	PreviousRevisionID string              `json:"previous_revision_id,omitempty"`
+	RevisionID         string              `json:"revision_id,omitempty"`
+	AddedLabels        []models.EventLabel `json:"added_labels"`
+	RemovedLabels      []models.EventLabel `json:"removed_labels"`
+}
+
+// WebhookEventStreamMgmt Delivers event notifications to the http endpoints that have been registered by projects
+//
+// Publishing an event only stores a delivery for each matching subscription, the deliveries are posted by Run.
+// Each delivery is signed with the secret of its subscription. Failed deliveries are retried with an exponential
+// backoff, after MaxAttempts failed attempts a delivery is moved to the dead letters.
+// Stream groups are not supported by this backend.
+type WebhookEventStreamMgmt struct {
+	Webhooks     *database.Webhooks
+	Client       *http.Client
+	MaxAttempts  int
+	PollInterval time.Duration
+	BatchSize    int
+	// Allows plain http endpoints, https is required otherwise
+	AllowHTTP bool
+	// Allows endpoints in loopback, link-local and private networks, they are rejected otherwise
+	AllowPrivateNetworks bool
+	// Format the payloads are delivered in, see EncodeEvent
+	EventFormat string
+}
+
+// NewWebhookEventStreamMgmt Creates the webhook backend from the config
+func NewWebhookEventStreamMgmt(webhooks *database.Webhooks) (*WebhookEventStreamMgmt, error) {
+	mgmt := &WebhookEventStreamMgmt{
+		Webhooks:             webhooks,
+		MaxAttempts:          viper.GetInt(config.EVENTNOTIFICATION_WEBHOOK_MAX_ATTEMPTS),
+		PollInterval:         viper.GetDuration(config.EVENTNOTIFICATION_WEBHOOK_POLL_INTERVAL),
+		BatchSize:            viper.GetInt(config.EVENTNOTIFICATION_WEBHOOK_BATCH_SIZE),
+		AllowHTTP:            viper.GetBool(config.EVENTNOTIFICATION_WEBHOOK_ALLOW_HTTP),
+		AllowPrivateNetworks: viper.GetBool(config.EVENTNOTIFICATION_WEBHOOK_ALLOW_PRIVATE),
+		EventFormat:          viper.GetString(config.EVENTNOTIFICATION_FORMAT),
+	}
+
+	timeout := viper.GetDuration(config.EVENTNOTIFICATION_WEBHOOK_TIMEOUT)
+	if timeout <= 0 || timeout > webhookClaimLease-webhookDeliveryWindow {
+		return nil, fmt.Errorf("%v has to be greater than 0 and at most %v, got %v", config.EVENTNOTIFICATION_WEBHOOK_TIMEOUT, webhookClaimLease-webhookDeliveryWindow, timeout)
+	}
+
+	// The addresses are checked again when connecting, the host of a validated url might resolve to another address
+	// by now. Proxies are not used, the address of the endpoint could not be checked otherwise.
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: mgmt.checkDialAddress,
+	}
+
+	mgmt.Client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+
+	return mgmt, nil
+}
+
+func (mgmt *WebhookEventStreamMgmt) EnableTestMode() error {
+	mgmt.AllowHTTP = true
+	mgmt.AllowPrivateNetworks = true
+	return nil
+}
+
+func (mgmt *WebhookEventStreamMgmt) CreateStreamGroup(projectID uuid.UUID, resourceID uuid.UUID, resourceType *v1notificationservices.CreateEventStreamingGroupRequest_EventResources, includeSubResources bool, start *models.StreamGroupStart) (*models.StreamGroup, error) {
+	return nil, fmt.Errorf("the webhook event streaming backend does not support stream groups")
+}
+
+func (mgmt *WebhookEventStreamMgmt) GetStreamGroupInfo(streamGroup *models.StreamGroup) (*StreamGroupInfo, error) {
+	return nil, fmt.Errorf("the webhook event streaming backend does not support stream groups")
+}
+
+func (mgmt *WebhookEventStreamMgmt) ResetStreamGroup(streamGroup *models.StreamGroup, start *models.StreamGroupStart) error {
+	return fmt.Errorf("the webhook event streaming backend does not support stream groups")
+}
+
+func (mgmt *WebhookEventStreamMgmt) DeleteStreamGroup(streamGroup *models.StreamGroup) error {
+	return fmt.Errorf("the webhook event streaming backend does not support stream groups")
+}
+
+func (mgmt *WebhookEventStreamMgmt) CreateMessageStreamGroupHandler(streamGroup *models.StreamGroup) (EventStreamer, error) {
+	return nil, fmt.Errorf("the webhook event streaming backend does not support stream groups")
+}
+
+// PublishMessage Stores a delivery of the event for each matching subscription of its project
+func (mgmt *WebhookEventStreamMgmt) PublishMessage(event *models.OutboxEvent) error {
+	subscriptions, err := mgmt.Webhooks.GetEnabledProjectWebhookSubscriptions(event.ProjectID)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	payload, err := mgmt.encodePayload(event, false)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	snapshotPayload, err := mgmt.encodePayload(event, true)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	headers := ""
+	if len(payload.Headers) > 0 {
+		encodedHeaders, err := json.Marshal(payload.Headers)
+		if err != nil {
+			log.Errorln(err.Error())
+			return err
+		}
+		headers = string(encodedHeaders)
+	}
+
+	var deliveries []*models.WebhookDelivery
+	for _, subscription := range subscriptions {
+		if !subscription.Matches(event) {
+			continue
+		}
+
+		subscriptionPayload := payload
+		if subscription.IncludeSnapshots {
+			subscriptionPayload = snapshotPayload
+		}
+
+		deliveries = append(deliveries, &models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			Resource:       event.Resource,
+			ResourceID:     event.ResourceID,
+			UpdateType:     event.UpdateType,
+			Payload:        string(subscriptionPayload.Data),
+			Headers:        headers,
+		})
+	}
+
+	err = mgmt.Webhooks.CreateWebhookDeliveries(deliveries)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// ValidateURL Checks if an url can be used as webhook endpoint
+func (mgmt *WebhookEventStreamMgmt) ValidateURL(endpoint string) error {
+	parsedURL, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+
+	if parsedURL.Host == "" {
+		return fmt.Errorf("webhook url has no host")
+	}
+
+	if parsedURL.Scheme != "https" && (parsedURL.Scheme != "http" || !mgmt.AllowHTTP) {
+		return fmt.Errorf("webhook url has to use https")
+	}
+
+	if mgmt.AllowPrivateNetworks {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookResolveTimeout)
+	defer cancel()
+
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, parsedURL.Hostname())
+	if err != nil {
+		log.Debug(err.Error())
+		return fmt.Errorf("could not resolve the host of the webhook url")
+	}
+
+	for _, address := range addresses {
+		if isPrivateAddress(address.IP) {
+			return fmt.Errorf("webhook url must not point to a loopback, link-local or private address")
+		}
+	}
+
+	return nil
+}
+
+// Rejects connections to loopback, link-local and private addresses unless they are allowed
+func (mgmt *WebhookEventStreamMgmt) checkDialAddress(network string, address string, conn syscall.RawConn) error {
+	if mgmt.AllowPrivateNetworks {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || isPrivateAddress(ip) {
+		return fmt.Errorf("webhook endpoint %v is a loopback, link-local or private address", host)
+	}
+
+	return nil
+}
+
+// Checks if an address is not publicly routable, e.g. the cloud metadata endpoint 169.254.169.254
+func isPrivateAddress(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Run Posts pending deliveries until the context is cancelled
+func (mgmt *WebhookEventStreamMgmt) Run(ctx context.Context) error {
+	ticker := time.NewTicker(mgmt.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := mgmt.DeliverPending(ctx)
+		if err != nil {
+			log.Errorln(err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverPending Posts a single batch of pending deliveries
+// The deliveries of different subscriptions are posted concurrently, the deliveries of a subscription one after another
+// in the order of their events. Deliveries that are not started within webhookDeliveryWindow are released again.
+func (mgmt *WebhookEventStreamMgmt) DeliverPending(ctx context.Context) error {
+	deliveries, err := mgmt.Webhooks.ClaimPendingWebhookDeliveries(mgmt.BatchSize, webhookClaimLease)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	deadline := time.Now().Add(webhookDeliveryWindow)
+
+	deliveryErrGrp := errgroup.Group{}
+	for _, subscriptionDeliveries := range groupSubscriptionDeliveries(deliveries) {
+		subscriptionDeliveries := subscriptionDeliveries
+		deliveryErrGrp.Go(func() error {
+			return mgmt.deliverSubscription(ctx, subscriptionDeliveries, deadline)
+		})
+	}
+
+	return deliveryErrGrp.Wait()
+}
+
+// Posts the deliveries of a single subscription in order, deliveries that are not started before the deadline are released
+func (mgmt *WebhookEventStreamMgmt) deliverSubscription(ctx context.Context, deliveries []*models.WebhookDelivery, deadline time.Time) error {
+	subscription, err := mgmt.Webhooks.GetWebhookSubscription(deliveries[0].SubscriptionID)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	for i, delivery := range deliveries {
+		if ctx.Err() != nil || time.Now().After(deadline) {
+			var remainingIDs []uuid.UUID
+			for _, remaining := range deliveries[i:] {
+				remainingIDs = append(remainingIDs, remaining.ID)
+			}
+
+			return mgmt.Webhooks.ReleaseWebhookDeliveries(remainingIDs)
+		}
+
+		statusCode, deliveryErr := mgmt.deliver(ctx, subscription, delivery)
+		if deliveryErr == nil {
+			err = mgmt.Webhooks.MarkWebhookDeliveryDelivered(delivery.ID, statusCode)
+		} else {
+			log.Debugf("delivery %v to %v failed: %v", delivery.ID, subscription.URL, deliveryErr.Error())
+			deadLetter := delivery.Attempts+1 >= mgmt.MaxAttempts
+			err = mgmt.Webhooks.MarkWebhookDeliveryFailed(delivery.ID, statusCode, deliveryErr, time.Now().Add(webhookBackoff(delivery.Attempts)), deadLetter)
+		}
+		if err != nil {
+			log.Errorln(err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Groups deliveries by their subscription, the deliveries of a subscription keep their order
+func groupSubscriptionDeliveries(deliveries []*models.WebhookDelivery) [][]*models.WebhookDelivery {
+	var groups [][]*models.WebhookDelivery
+	indices := make(map[uuid.UUID]int)
+
+	for _, delivery := range deliveries {
+		index, ok := indices[delivery.SubscriptionID]
+		if !ok {
+			index = len(groups)
+			indices[delivery.SubscriptionID] = index
+			groups = append(groups, nil)
+		}
+
+		groups[index] = append(groups[index], delivery)
+	}
+
+	return groups
+}
+
+// Posts a delivery to the endpoint of its subscription, responses with a status other than 2xx are treated as failure
+func (mgmt *WebhookEventStreamMgmt) deliver(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	signature, err := SignWebhookPayload(subscription.Secret, timestamp, body)
+	if err != nil {
+		return 0, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	if delivery.Headers != "" {
+		headers := make(map[string]string)
+		err = json.Unmarshal([]byte(delivery.Headers), &headers)
+		if err != nil {
+			return 0, err
+		}
+
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+	}
+	request.Header.Set(WebhookSignatureHeader, signature)
+	request.Header.Set(WebhookTimestampHeader, timestamp)
+	request.Header.Set(WebhookEventHeader, delivery.EventID.String())
+	request.Header.Set(WebhookDeliveryHeader, delivery.ID.String())
+
+	response, err := mgmt.Client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		responseBody, _ := ioutil.ReadAll(io.LimitReader(response.Body, webhookMaxErrorBody))
+		return response.StatusCode, fmt.Errorf("webhook endpoint responded with status %v: %v", response.StatusCode, string(responseBody))
+	}
+
+	return response.StatusCode, nil
+}
+
+// Encodes the payload of an event in the format of the backend
+func (mgmt *WebhookEventStreamMgmt) encodePayload(event *models.OutboxEvent, includeSnapshot bool) (*EncodedEvent, error) {
+	data, err := json.Marshal(NewWebhookPayload(event, includeSnapshot))
+	if err != nil {
+		return nil, err
+	}
+
+	return EncodeEvent(mgmt.EventFormat, event, data)
+}
+
+// NewWebhookPayload Creates the body that is delivered for an event
+func NewWebhookPayload(event *models.OutboxEvent, includeSnapshot bool) *WebhookPayload {
+	payload := &WebhookPayload{
+		EventID:    event.ID.String(),
+		Resource:   event.Resource,
+		ResourceID: event.ResourceID.String(),
+		UpdateType: event.UpdateType,
+		ProjectID:  event.ProjectID.String(),
+		Created:    event.CreatedAt,
+	}
+
+	if event.DatasetID != uuid.Nil {
+		payload.DatasetID = event.DatasetID.String()
+	}
+	if event.ObjectGroupID != uuid.Nil {
+		payload.ObjectGroupID = event.ObjectGroupID.String()
+	}
+	if event.DatasetVersionID != uuid.Nil {
+		payload.DatasetVersionID = event.DatasetVersionID.String()
+	}
+	if includeSnapshot && event.Snapshot != "" {
+		payload.Snapshot = json.RawMessage(event.Snapshot)
+	}
+	payload.Changes = NewEventChanges(event)
+
+	return payload
+}
+
+// NewEventChanges Returns the changes of an event, nil if the event has no change details
+func NewEventChanges(event *models.OutboxEvent) *EventChanges {
+	if event.RevisionID == uuid.Nil && event.AddedLabels == "" && event.RemovedLabels == "" {
+		return nil
+	}
+
+	changes := &EventChanges{
+		AddedLabels:   event.AddedLabelList(),
+		RemovedLabels: event.RemovedLabelList(),
+	}
+
+	if event.PreviousRevisionID != uuid.Nil {
+		changes.PreviousRevisionID = event.PreviousRevisionID.String()
+	}
+	if event.RevisionID != uuid.Nil {
+		changes.RevisionID = event.RevisionID.String()
+	}
+
+	return changes
+}
+
+// SignWebhookPayload Calculates the signature header of a delivery
+// The signature is the hex encoded HMAC-SHA256 of "<timestamp>.<body>" with the secret of the subscription, prefixed by "sha256=".
+// Receivers should recalculate the signature and reject deliveries with an old timestamp.
+func SignWebhookPayload(secret string, timestamp string, body []byte) (string, error) {
+	signedContent := append([]byte(timestamp+"."), body...)
+
+	signature, err := signing.HMAC_sha256([]byte(secret), signedContent)
+	if err != nil {
+		return "", err
+	}
+
+	return "sha256=" + hex.EncodeToString(signature), nil
+}
+
+// Returns the duration until the next attempt of a delivery that has already failed attempts times
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookMinBackoff
+	for i := 0; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= webhookMaxBackoff {
+			return webhookMaxBackoff
+		}
+	}
+
+	return backoff
+}
@@ -0,0 +1,151 @@
+package eventstreaming
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookDelivery(t *testing.T) {
+	secret := "testsecret"
+	receivedSignature := ""
+	responseStatus := http.StatusOK
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		signature, err := SignWebhookPayload(secret, r.Header.Get(WebhookTimestampHeader), body)
+		assert.NoError(t, err)
+		receivedSignature = r.Header.Get(WebhookSignatureHeader)
+		assert.Equal(t, signature, receivedSignature)
+
+		w.WriteHeader(responseStatus)
+	}))
+	defer server.Close()
+
+	mgmt := &WebhookEventStreamMgmt{Client: server.Client(), AllowHTTP: true, AllowPrivateNetworks: true}
+	assert.NoError(t, mgmt.ValidateURL(server.URL))
+
+	event := models.NewDatasetEvent(uuid.New(), uuid.New(), v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED)
+	event.ID = uuid.New()
+	payload, err := json.Marshal(NewWebhookPayload(event, false))
+	assert.NoError(t, err)
+
+	subscription := &models.WebhookSubscription{URL: server.URL, Secret: secret, ProjectID: event.ProjectID, Enabled: true}
+	delivery := &models.WebhookDelivery{EventID: event.ID, Payload: string(payload)}
+	delivery.ID = uuid.New()
+
+	statusCode, err := mgmt.deliver(context.Background(), subscription, delivery)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Contains(t, receivedSignature, "sha256=")
+
+	responseStatus = http.StatusInternalServerError
+	statusCode, err = mgmt.deliver(context.Background(), subscription, delivery)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, statusCode)
+}
+
+func TestGroupSubscriptionDeliveries(t *testing.T) {
+	subscriptionA := uuid.New()
+	subscriptionB := uuid.New()
+
+	var deliveries []*models.WebhookDelivery
+	for _, subscriptionID := range []uuid.UUID{subscriptionA, subscriptionB, subscriptionA, subscriptionA, subscriptionB} {
+		delivery := &models.WebhookDelivery{SubscriptionID: subscriptionID}
+		delivery.ID = uuid.New()
+		deliveries = append(deliveries, delivery)
+	}
+
+	groups := groupSubscriptionDeliveries(deliveries)
+	assert.Len(t, groups, 2)
+	assert.Equal(t, []*models.WebhookDelivery{deliveries[0], deliveries[2], deliveries[3]}, groups[0])
+	assert.Equal(t, []*models.WebhookDelivery{deliveries[1], deliveries[4]}, groups[1])
+	assert.Empty(t, groupSubscriptionDeliveries(nil))
+}
+
+func TestWebhookSubscriptionFilter(t *testing.T) {
+	projectID := uuid.New()
+	event := models.NewDatasetEvent(projectID, uuid.New(), v1notificationservices.EventNotificationMessage_UPDATE_TYPE_DELETED)
+
+	subscription := &models.WebhookSubscription{ProjectID: projectID, Enabled: true}
+	assert.True(t, subscription.Matches(event))
+
+	subscription.Resources = "RESOURCE_OBJECT_GROUP,RESOURCE_DATASET"
+	subscription.UpdateTypes = "UPDATE_TYPE_CREATED"
+	assert.False(t, subscription.Matches(event))
+
+	subscription.UpdateTypes = "UPDATE_TYPE_CREATED,UPDATE_TYPE_DELETED"
+	assert.True(t, subscription.Matches(event))
+
+	subscription.Enabled = false
+	assert.False(t, subscription.Matches(event))
+
+	assert.Error(t, (&WebhookEventStreamMgmt{}).ValidateURL("http://example.com/hook"))
+	assert.NoError(t, (&WebhookEventStreamMgmt{AllowPrivateNetworks: true}).ValidateURL("https://example.com/hook"))
+}
+
+func TestWebhookPrivateNetworks(t *testing.T) {
+	mgmt := &WebhookEventStreamMgmt{AllowHTTP: true}
+
+	for _, endpoint := range []string{
+		"http://127.0.0.1/hook",
+		"http://localhost:8080/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/hook",
+		"http://192.168.1.1/hook",
+		"http://100.64.0.1/hook",
+		"http://[::1]/hook",
+		"http://[fd00::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		assert.Error(t, mgmt.ValidateURL(endpoint), endpoint)
+	}
+
+	assert.NoError(t, mgmt.ValidateURL("http://93.184.216.34/hook"))
+	assert.NoError(t, mgmt.ValidateURL("http://[2606:2800:220:1:248:1893:25c8:1946]/hook"))
+
+	// The address is checked again when connecting
+	assert.Error(t, mgmt.checkDialAddress("tcp", "127.0.0.1:443", nil))
+	assert.Error(t, mgmt.checkDialAddress("tcp", "[fe80::1]:443", nil))
+	assert.NoError(t, mgmt.checkDialAddress("tcp", "93.184.216.34:443", nil))
+
+	mgmt.AllowPrivateNetworks = true
+	assert.NoError(t, mgmt.ValidateURL("http://127.0.0.1/hook"))
+	assert.NoError(t, mgmt.checkDialAddress("tcp", "127.0.0.1:443", nil))
+}
+
+func TestWebhookPayloadChanges(t *testing.T) {
+	previousRevisionID := uuid.New()
+	revisionID := uuid.New()
+
+	event := models.NewObjectGroupEvent(uuid.New(), uuid.New(), uuid.New(), v1notificationservices.EventNotificationMessage_UPDATE_TYPE_UPDATED).WithRevisionChange(
+		previousRevisionID,
+		revisionID,
+		[]models.Label{{Key: "stage", Value: "raw"}, {Key: "owner", Value: "a"}},
+		[]models.Label{{Key: "stage", Value: "processed"}, {Key: "owner", Value: "a"}},
+	)
+	event.Snapshot = `{"id":"test"}`
+
+	payload := NewWebhookPayload(event, false)
+	assert.Nil(t, payload.Snapshot)
+	assert.Equal(t, previousRevisionID.String(), payload.Changes.PreviousRevisionID)
+	assert.Equal(t, revisionID.String(), payload.Changes.RevisionID)
+	assert.Equal(t, []models.EventLabel{{Key: "stage", Value: "processed"}}, payload.Changes.AddedLabels)
+	assert.Equal(t, []models.EventLabel{{Key: "stage", Value: "raw"}}, payload.Changes.RemovedLabels)
+
+	payload = NewWebhookPayload(event, true)
+	assert.JSONEq(t, `{"id":"test"}`, string(payload.Snapshot))
+
+	assert.Nil(t, NewWebhookPayload(models.NewDatasetEvent(uuid.New(), uuid.New(), v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED), true).Changes)
+}
@@ -28,8 +28,8 @@ func (base *BaseModel) BeforeCreate(tx *gorm.DB) error {
 
 type Label struct {
 	BaseModel
-	Key      string
-	Value    string
+	Key      string    `gorm:"index:idx_labels_key_value"`
+	Value    string    `gorm:"index:idx_labels_key_value"`
 	ParentID uuid.UUID `gorm:"index"`
 }
 
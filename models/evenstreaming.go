@@ -1,7 +1,24 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
 
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	"github.com/google/uuid"
+)
+
+// Start policies of a stream group, they define the first event that is delivered to the group
+const (
+	StreamGroupStartAll      = "all"
+	StreamGroupStartNew      = "new"
+	StreamGroupStartSequence = "sequence"
+	StreamGroupStartTime     = "time"
+)
+
+// StreamGroup A durable consumer of the events of a resource
+// Resources and UpdateTypes are comma separated lists of the enum names of the events that are delivered, an empty
+// list matches all events. If LabelKey is set only events of resources with a label with this key and, if set,
+// LabelValue are delivered. IncludeSnapshots requests snapshots of the resources with the events the group receives.
 type StreamGroup struct {
 	BaseModel
 	Subject        string
@@ -10,4 +27,83 @@ type StreamGroup struct {
 	UseSubResource bool
 	ProjectID      uuid.UUID `gorm:"index"`
 	Project        Project
+	StartPolicy    string
+	StartSequence  uint64
+	StartTime      time.Time
+	Resources      string
+	UpdateTypes    string
+	LabelKey       string
+	LabelValue     string
+	// Snapshots are stored with the events of the group and provided by the events endpoint of the http api
+	IncludeSnapshots bool
+}
+
+// StreamGroupStart The position in the event stream at which the delivery of a stream group starts
+type StreamGroupStart struct {
+	Policy   string
+	Sequence uint64
+	Time     time.Time
+}
+
+// Start Returns the start position of the group, groups created without a policy start with all events
+func (group *StreamGroup) Start() *StreamGroupStart {
+	policy := group.StartPolicy
+	if policy == "" {
+		policy = StreamGroupStartAll
+	}
+
+	return &StreamGroupStart{
+		Policy:   policy,
+		Sequence: group.StartSequence,
+		Time:     group.StartTime,
+	}
+}
+
+// ResourceList Returns the resource types the group is filtered on
+func (group *StreamGroup) ResourceList() []string {
+	return splitList(group.Resources)
+}
+
+// UpdateTypeList Returns the update types the group is filtered on
+func (group *StreamGroup) UpdateTypeList() []string {
+	return splitList(group.UpdateTypes)
+}
+
+// HasLabelFilter Checks if the group is filtered on a label
+func (group *StreamGroup) HasLabelFilter() bool {
+	return group.LabelKey != ""
+}
+
+// MatchesMessage Checks if an event passes the resource and update type filters of the group
+func (group *StreamGroup) MatchesMessage(message *v1notificationservices.EventNotificationMessage) bool {
+	return listMatches(group.ResourceList(), message.GetResource().String()) && listMatches(group.UpdateTypeList(), message.GetUpdatedType().String())
+}
+
+// MatchesEvent Checks if an event of the project is within the resource of the group and passes its filters
+func (group *StreamGroup) MatchesEvent(event *OutboxEvent) bool {
+	if group.ProjectID != event.ProjectID {
+		return false
+	}
+
+	parentID := event.ProjectID
+	if group.ResourceType == v1notificationservices.CreateEventStreamingGroupRequest_EVENT_RESOURCES_DATASET_RESOURCE.String() {
+		parentID = event.DatasetID
+	}
+
+	return parentID == group.ResourceID && group.MatchesMessage(event.ToProtoModel()) && group.MatchesLabels(event.LabelList())
+}
+
+// MatchesLabels Checks if the labels of the resource of an event pass the label filter of the group
+func (group *StreamGroup) MatchesLabels(labels []EventLabel) bool {
+	if !group.HasLabelFilter() {
+		return true
+	}
+
+	for _, label := range labels {
+		if label.Key == group.LabelKey && (group.LabelValue == "" || label.Value == group.LabelValue) {
+			return true
+		}
+	}
+
+	return false
 }
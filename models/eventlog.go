@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventLogEntry An event that has been published by the Postgres event notification backend
+// The log is retained for EventNotifications.Postgres.Retention to start new stream groups with older events.
+type EventLogEntry struct {
+	BaseModel
+	Sequence int64  `gorm:"autoIncrement;index"`
+	Subject  string `gorm:"index"`
+	// Sequence of the outbox event, each event is logged only once
+	OutboxSequence int64 `gorm:"uniqueIndex"`
+	// Encoded event and the json encoded headers of the event format
+	Data    string
+	Headers string
+}
+
+// StreamGroupEvent An event of the log that has not been acknowledged by a stream group yet
+// Events without a chunk are waiting for delivery, events of a chunk are delivered again after the deadline of the chunk.
+type StreamGroupEvent struct {
+	BaseModel
+	StreamGroupID uuid.UUID `gorm:"uniqueIndex:idx_stream_group_events_sequence"`
+	Sequence      int64     `gorm:"uniqueIndex:idx_stream_group_events_sequence"`
+	ChunkID       uuid.UUID `gorm:"index"`
+	Deadline      time.Time
+	Deliveries    int
+}
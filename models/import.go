@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status values of an ImportJob
+const (
+	ImportJobStatusWaitingForUpload = "waiting_for_upload"
+	ImportJobStatusUploading        = "uploading"
+	ImportJobStatusRunning          = "running"
+	ImportJobStatusFinished         = "finished"
+	ImportJobStatusFailed           = "failed"
+)
+
+// ImportJob Imports an uploaded tar or zip archive into a dataset
+// The archive is staged in the bucket of the dataset, each top level directory of the archive becomes an object group.
+// The progress counters are updated while the job is running.
+type ImportJob struct {
+	BaseModel
+	Secret    string
+	Format    string
+	Status    string    `gorm:"index"`
+	ProjectID uuid.UUID `gorm:"index"`
+	Project   Project
+	DatasetID uuid.UUID `gorm:"index"`
+	Dataset   Dataset
+	CreatedBy string
+	// Location of the uploaded archive
+	StagingBucket string
+	StagingKey    string
+	ArchiveSize   int64
+	// Number of bytes of the archive that have been processed
+	ReadBytes           int64
+	ProcessedEntries    int64
+	FailedEntries       int64
+	ImportedObjects     int64
+	ImportedBytes       int64
+	CreatedObjectGroups int64
+	Message             string
+	StartedAt           time.Time
+	FinishedAt          time.Time
+	Errors              []ImportJobError
+}
+
+// ImportJobError An archive entry that could not be imported
+type ImportJobError struct {
+	BaseModel
+	ImportJobID uuid.UUID `gorm:"index"`
+	Entry       string
+	Message     string
+}
+
+// IsDone Checks if the job has finished, either successfully or with an error
+func (job *ImportJob) IsDone() bool {
+	return job.Status == ImportJobStatusFinished || job.Status == ImportJobStatusFailed
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// SchemaMigration A versioned migration that has been applied to the database schema
+type SchemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
@@ -0,0 +1,266 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// OutboxEvent An event notification that is written in the same transaction as the change it describes
+// The events are published to the event notification backend by a relay afterwards. The ids of the parent
+// resources are stored with the event, so that the subject of the event can be determined without reading
+// the resource, which might not exist anymore when the event is published.
+type OutboxEvent struct {
+	BaseModel
+	// Increasing sequence number, it is assigned when the event is written and does not follow the commit order
+	Sequence   int64     `gorm:"autoIncrement;index"`
+	Resource   string    `gorm:"index:idx_outbox_events_resource"`
+	ResourceID uuid.UUID `gorm:"index:idx_outbox_events_resource"`
+	// Position of the event among the events of its resource in the order in which their transactions committed,
+	// the events of a resource are published in this order
+	ResourceSequence int64
+	UpdateType       string
+	ProjectID        uuid.UUID
+	DatasetID        uuid.UUID
+	ObjectGroupID    uuid.UUID
+	DatasetVersionID uuid.UUID
+	Published        bool `gorm:"index"`
+	PublishedAt      time.Time
+	Attempts         int
+	NextAttemptAt    time.Time `gorm:"index"`
+	LastError        string
+	// Events that could not be published within the maximum number of attempts are not retried anymore
+	DeadLettered   bool `gorm:"index"`
+	DeadLetteredAt time.Time
+	// Json encoded proto of the resource at the time of the event, only stored if a consumer of the project requested snapshots
+	Snapshot string
+	// Revisions of an object group before and after the event
+	PreviousRevisionID uuid.UUID
+	RevisionID         uuid.UUID
+	// Json encoded lists of the labels that have been added and removed by the event
+	AddedLabels   string
+	RemovedLabels string
+	// Json encoded labels of the resource at the time of the event, the label filters of stream groups match on them
+	Labels string
+	// Oauth2 id of the user of a user event, it is only used to read the snapshot of the user and not stored
+	UserOauth2ID string `gorm:"-"`
+}
+
+// OutboxResource Tracks the positions of the events of a resource
+// Every transaction that writes events of the resource locks its row until it commits, the positions of the events
+// therefore follow the order in which their transactions commit.
+type OutboxResource struct {
+	Resource   string    `gorm:"primaryKey"`
+	ResourceID uuid.UUID `gorm:"primaryKey;type:uuid"`
+	// Position of the last event that has been written
+	LastSequence int64
+	// Position of the last event that has been published or dead lettered
+	PublishedSequence int64
+}
+
+// ResourceKey Returns a key that identifies the resource of the event
+func (event *OutboxEvent) ResourceKey() string {
+	return event.Resource + "." + event.ResourceID.String()
+}
+
+// Resource types of events on resources that are not part of the Resource enum of the API
+const (
+	EventResourceUser     = "RESOURCE_USER"
+	EventResourceAPIToken = "RESOURCE_API_TOKEN"
+)
+
+// EventLabel A label that has been changed by an event
+type EventLabel struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ToProtoModel Returns the notification message that is published for the event
+// The message can not describe users and api tokens, their events are published as update of their project.
+func (event *OutboxEvent) ToProtoModel() *v1notificationservices.EventNotificationMessage {
+	if event.Resource == EventResourceUser || event.Resource == EventResourceAPIToken {
+		return &v1notificationservices.EventNotificationMessage{
+			Resource:    v1storagemodels.Resource_RESOURCE_PROJECT,
+			ResourceId:  event.ProjectID.String(),
+			UpdatedType: v1notificationservices.EventNotificationMessage_UPDATE_TYPE_UPDATED,
+		}
+	}
+
+	return &v1notificationservices.EventNotificationMessage{
+		Resource:    v1storagemodels.Resource(v1storagemodels.Resource_value[event.Resource]),
+		ResourceId:  event.ResourceID.String(),
+		UpdatedType: v1notificationservices.EventNotificationMessage_UpdateType(v1notificationservices.EventNotificationMessage_UpdateType_value[event.UpdateType]),
+	}
+}
+
+// WithRevisionChange Adds the revisions and the changed labels of an object group to the event
+func (event *OutboxEvent) WithRevisionChange(previousRevisionID uuid.UUID, revisionID uuid.UUID, previousLabels []Label, labels []Label) *OutboxEvent {
+	event.PreviousRevisionID = previousRevisionID
+	event.RevisionID = revisionID
+
+	return event.WithLabelChange(previousLabels, labels)
+}
+
+// WithLabelChange Adds the labels that have been added and removed by the event
+func (event *OutboxEvent) WithLabelChange(previousLabels []Label, labels []Label) *OutboxEvent {
+	event.AddedLabels = encodeEventLabels(labelDifference(labels, previousLabels))
+	event.RemovedLabels = encodeEventLabels(labelDifference(previousLabels, labels))
+
+	return event
+}
+
+// WithLabels Adds the labels of the resource at the time of the event
+func (event *OutboxEvent) WithLabels(labels []Label) *OutboxEvent {
+	event.Labels = encodeEventLabels(labelDifference(labels, nil))
+
+	return event
+}
+
+// LabelList Returns the labels of the resource at the time of the event
+func (event *OutboxEvent) LabelList() []EventLabel {
+	return decodeEventLabels(event.Labels)
+}
+
+// AddedLabelList Returns the labels that have been added by the event
+func (event *OutboxEvent) AddedLabelList() []EventLabel {
+	return decodeEventLabels(event.AddedLabels)
+}
+
+// RemovedLabelList Returns the labels that have been removed by the event
+func (event *OutboxEvent) RemovedLabelList() []EventLabel {
+	return decodeEventLabels(event.RemovedLabels)
+}
+
+// ResourceEnum Returns the resource type of the event
+func (event *OutboxEvent) ResourceEnum() v1storagemodels.Resource {
+	return v1storagemodels.Resource(v1storagemodels.Resource_value[event.Resource])
+}
+
+// NewProjectEvent Creates an event for a project
+func NewProjectEvent(projectID uuid.UUID, updateType v1notificationservices.EventNotificationMessage_UpdateType) *OutboxEvent {
+	return &OutboxEvent{
+		Resource:   v1storagemodels.Resource_RESOURCE_PROJECT.String(),
+		ResourceID: projectID,
+		UpdateType: updateType.String(),
+		ProjectID:  projectID,
+	}
+}
+
+// NewDatasetEvent Creates an event for a dataset
+func NewDatasetEvent(projectID uuid.UUID, datasetID uuid.UUID, updateType v1notificationservices.EventNotificationMessage_UpdateType) *OutboxEvent {
+	return &OutboxEvent{
+		Resource:   v1storagemodels.Resource_RESOURCE_DATASET.String(),
+		ResourceID: datasetID,
+		UpdateType: updateType.String(),
+		ProjectID:  projectID,
+		DatasetID:  datasetID,
+	}
+}
+
+// NewObjectGroupEvent Creates an event for an object group
+func NewObjectGroupEvent(projectID uuid.UUID, datasetID uuid.UUID, objectGroupID uuid.UUID, updateType v1notificationservices.EventNotificationMessage_UpdateType) *OutboxEvent {
+	return &OutboxEvent{
+		Resource:      v1storagemodels.Resource_RESOURCE_OBJECT_GROUP.String(),
+		ResourceID:    objectGroupID,
+		UpdateType:    updateType.String(),
+		ProjectID:     projectID,
+		DatasetID:     datasetID,
+		ObjectGroupID: objectGroupID,
+	}
+}
+
+// NewObjectEvent Creates an event for an object
+func NewObjectEvent(projectID uuid.UUID, datasetID uuid.UUID, objectID uuid.UUID, updateType v1notificationservices.EventNotificationMessage_UpdateType) *OutboxEvent {
+	return &OutboxEvent{
+		Resource:   v1storagemodels.Resource_RESOURCE_OBJECT.String(),
+		ResourceID: objectID,
+		UpdateType: updateType.String(),
+		ProjectID:  projectID,
+		DatasetID:  datasetID,
+	}
+}
+
+// NewUserEvent Creates an event for the membership of a user in a project
+// The user is identified by the subject of its oauth2 id token, which is not a uuid. The resource id of the event is
+// derived from the project and the subject, so that all events of a membership have the same resource id.
+func NewUserEvent(projectID uuid.UUID, userOauth2ID string, updateType v1notificationservices.EventNotificationMessage_UpdateType) *OutboxEvent {
+	return &OutboxEvent{
+		Resource:     EventResourceUser,
+		ResourceID:   uuid.NewSHA1(projectID, []byte(userOauth2ID)),
+		UpdateType:   updateType.String(),
+		ProjectID:    projectID,
+		UserOauth2ID: userOauth2ID,
+	}
+}
+
+// NewAPITokenEvent Creates an event for an api token of a project
+func NewAPITokenEvent(projectID uuid.UUID, tokenID uuid.UUID, updateType v1notificationservices.EventNotificationMessage_UpdateType) *OutboxEvent {
+	return &OutboxEvent{
+		Resource:   EventResourceAPIToken,
+		ResourceID: tokenID,
+		UpdateType: updateType.String(),
+		ProjectID:  projectID,
+	}
+}
+
+// NewDatasetVersionEvent Creates an event for a dataset version
+func NewDatasetVersionEvent(projectID uuid.UUID, datasetID uuid.UUID, datasetVersionID uuid.UUID, updateType v1notificationservices.EventNotificationMessage_UpdateType) *OutboxEvent {
+	return &OutboxEvent{
+		Resource:         v1storagemodels.Resource_RESOURCE_DATASET_VERSION.String(),
+		ResourceID:       datasetVersionID,
+		UpdateType:       updateType.String(),
+		ProjectID:        projectID,
+		DatasetID:        datasetID,
+		DatasetVersionID: datasetVersionID,
+	}
+}
+
+// Returns the labels of labels that are not part of other
+func labelDifference(labels []Label, other []Label) []EventLabel {
+	existing := make(map[EventLabel]struct{})
+	for _, label := range other {
+		existing[EventLabel{Key: label.Key, Value: label.Value}] = struct{}{}
+	}
+
+	difference := []EventLabel{}
+	for _, label := range labels {
+		eventLabel := EventLabel{Key: label.Key, Value: label.Value}
+		if _, ok := existing[eventLabel]; !ok {
+			difference = append(difference, eventLabel)
+		}
+	}
+
+	return difference
+}
+
+func encodeEventLabels(labels []EventLabel) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	encoded, err := json.Marshal(labels)
+	if err != nil {
+		log.Errorln(err.Error())
+		return ""
+	}
+
+	return string(encoded)
+}
+
+func decodeEventLabels(encoded string) []EventLabel {
+	labels := []EventLabel{}
+	if encoded == "" {
+		return labels
+	}
+
+	err := json.Unmarshal([]byte(encoded), &labels)
+	if err != nil {
+		log.Errorln(err.Error())
+	}
+
+	return labels
+}
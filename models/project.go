@@ -19,6 +19,8 @@ type Project struct {
 	Labels      []Label    `gorm:"many2many:project_labels;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	APIToken    []APIToken `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	Datasets    []Dataset  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// Time deleted datasets and object groups are kept in the trash, the configured default is used if it is 0
+	TrashRetention time.Duration
 }
 
 func (project *Project) ToProtoModel(stats *v1storagemodels.ProjectStats) (*v1storagemodels.Project, error) {
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ResourceStats Usage statistics of a project, dataset, object group revision or dataset version
+// The statistics are updated in the transactions that create, delete and restore objects and object groups.
+// Resources without statistics have no objects.
+type ResourceStats struct {
+	ResourceID uuid.UUID `gorm:"primaryKey;type:uuid"`
+	// Resource enum name, e.g. RESOURCE_DATASET
+	Resource  string
+	ProjectID uuid.UUID `gorm:"index"`
+	// Number of objects, data objects of revisions and versions
+	ObjectCount int64
+	// Sum of the content length of the objects
+	AccSize int64
+	// Number of object groups, revisions of versions
+	ObjectGroupCount int64
+	MetaObjectCount  int64
+	UserCount        int64
+	UpdatedAt        time.Time
+}
+
+// AvgObjectSize Average content length of the objects, 0 without objects
+func (stats *ResourceStats) AvgObjectSize() float64 {
+	if stats.ObjectCount == 0 {
+		return 0
+	}
+
+	return float64(stats.AccSize) / float64(stats.ObjectCount)
+}
@@ -1,16 +1,47 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
+// Resource types that can be referenced by a StreamingEntry
+const (
+	StreamingEntryTypeDataset        = "dataset"
+	StreamingEntryTypeDatasetVersion = "datasetversion"
+	StreamingEntryTypeObjectGroups   = "objectgroups"
+	StreamingEntryTypeDateRange      = "daterange"
+)
+
+// StreamingEntry A single issued streaming link
+// The link itself only contains the id of the entry and a signature calculated with the secret of the entry.
+// An entry is valid until it expires, is revoked or the optional download limit is reached.
 type StreamingEntry struct {
 	BaseModel
-	UUID         string `gorm:"index"`
-	Secret       string
-	DatasetID    uuid.UUID
-	Dataset      Dataset
-	ProjectID    uuid.UUID
-	Project      Project
-	ObjectGroups []ObjectGroupRevision `gorm:"many2many:streaming_entry_object_groups;"`
+	Secret           string
+	ResourceType     string
+	StreamType       string
+	DatasetID        uuid.UUID `gorm:"index"`
+	Dataset          Dataset
+	DatasetVersionID uuid.UUID
+	StartDate        time.Time
+	EndDate          time.Time
+	ProjectID        uuid.UUID `gorm:"index"`
+	Project          Project
+	ObjectGroups     []ObjectGroupRevision `gorm:"many2many:streaming_entry_object_groups;"`
+	CreatedBy        string
+	ExpiresAt        time.Time `gorm:"index"`
+	MaxDownloads     int64
+	DownloadCount    int64
+	Revoked          bool `gorm:"index"`
+}
+
+// IsActive Checks if the entry can still be used to download data
+func (entry *StreamingEntry) IsActive() bool {
+	if entry.Revoked || !time.Now().Before(entry.ExpiresAt) {
+		return false
+	}
+
+	return entry.MaxDownloads == 0 || entry.DownloadCount < entry.MaxDownloads
 }
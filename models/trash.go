@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TrashEntry A deleted dataset or object group that can be restored until it is purged
+// The rows of the resource and of its objects are soft deleted with TrashedAt as deletion time, the rows and the data
+// in the object storage are removed after PurgeAt.
+type TrashEntry struct {
+	BaseModel
+	Resource   string    `gorm:"index:idx_trash_entries_resource"`
+	ResourceID uuid.UUID `gorm:"index:idx_trash_entries_resource"`
+	ProjectID  uuid.UUID `gorm:"index"`
+	DatasetID  uuid.UUID `gorm:"index"`
+	Name       string
+	TrashedAt  time.Time
+	PurgeAt    time.Time `gorm:"index"`
+	// Start of the purge, entries that are being purged can not be restored anymore
+	PurgingAt time.Time
+}
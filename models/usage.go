@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UsageSnapshot Storage usage of a project or dataset at a point in time
+// Snapshots are copied from the ResourceStats of the resource, they are kept after the resource has been deleted.
+// Deleting or purging a resource records a last snapshot without usage at the time of the deletion.
+type UsageSnapshot struct {
+	ResourceID uuid.UUID `gorm:"primaryKey;type:uuid"`
+	TakenAt    time.Time `gorm:"primaryKey"`
+	// Resource enum name, RESOURCE_PROJECT or RESOURCE_DATASET
+	Resource         string
+	ProjectID        uuid.UUID `gorm:"index"`
+	ObjectCount      int64
+	AccSize          int64
+	ObjectGroupCount int64
+}
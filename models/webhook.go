@@ -0,0 +1,92 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Delivery states of a WebhookDelivery
+const (
+	WebhookDeliveryStatusPending    = "pending"
+	WebhookDeliveryStatusDelivered  = "delivered"
+	WebhookDeliveryStatusDeadLetter = "dead_letter"
+)
+
+// WebhookSubscription An http endpoint of a project that receives the event notifications of the project
+// Resources and UpdateTypes are comma separated lists of the enum names of the events that are delivered,
+// an empty list matches all events. Subscriptions with IncludeSnapshots receive a snapshot of the resource with each event.
+type WebhookSubscription struct {
+	BaseModel
+	ProjectID        uuid.UUID `gorm:"index"`
+	URL              string
+	Secret           string
+	Description      string
+	Resources        string
+	UpdateTypes      string
+	Enabled          bool
+	IncludeSnapshots bool
+	CreatedBy        string
+}
+
+// ResourceList Returns the resource types the subscription is filtered on
+func (subscription *WebhookSubscription) ResourceList() []string {
+	return splitList(subscription.Resources)
+}
+
+// UpdateTypeList Returns the update types the subscription is filtered on
+func (subscription *WebhookSubscription) UpdateTypeList() []string {
+	return splitList(subscription.UpdateTypes)
+}
+
+// Matches Checks if an event passes the filters of the subscription
+func (subscription *WebhookSubscription) Matches(event *OutboxEvent) bool {
+	if !subscription.Enabled || subscription.ProjectID != event.ProjectID {
+		return false
+	}
+
+	return listMatches(subscription.ResourceList(), event.Resource) && listMatches(subscription.UpdateTypeList(), event.UpdateType)
+}
+
+// WebhookDelivery A single event that is delivered to a webhook subscription
+// The payload is stored with the delivery, so that it can be delivered again after the event has been removed from the outbox.
+type WebhookDelivery struct {
+	BaseModel
+	SubscriptionID uuid.UUID `gorm:"index;uniqueIndex:idx_webhook_deliveries_event"`
+	EventID        uuid.UUID `gorm:"uniqueIndex:idx_webhook_deliveries_event"`
+	Resource       string
+	ResourceID     uuid.UUID
+	UpdateType     string
+	Payload        string
+	// Json encoded http headers of the event format, e.g. the CloudEvents attributes
+	Headers        string
+	Status         string `gorm:"index"`
+	Attempts       int
+	NextAttemptAt  time.Time `gorm:"index"`
+	LastStatusCode int
+	LastError      string
+	DeliveredAt    time.Time
+}
+
+func splitList(list string) []string {
+	if list == "" {
+		return []string{}
+	}
+
+	return strings.Split(list, ",")
+}
+
+func listMatches(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+
+	for _, entry := range list {
+		if entry == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -32,6 +35,7 @@ const MAXCREATEBUCKETRETRY = 10
 type S3ObjectStorageHandler struct {
 	S3Client          *s3.Client
 	S3DownloadManager *manager.Downloader
+	S3UploadManager   *manager.Uploader
 	PresignClient     *s3.PresignClient
 	S3Endpoint        string
 	S3BucketPrefix    string
@@ -74,12 +78,14 @@ func (s3Handler *S3ObjectStorageHandler) New(S3BucketPrefix string) (*S3ObjectSt
 	presignClient := s3.NewPresignClient(client)
 
 	downloader := manager.NewDownloader(client)
+	uploader := manager.NewUploader(client)
 
 	s3Handler.S3Endpoint = s3Endpoint
 	s3Handler.S3Client = client
 	s3Handler.PresignClient = presignClient
 	s3Handler.S3BucketPrefix = S3BucketPrefix
 	s3Handler.S3DownloadManager = downloader
+	s3Handler.S3UploadManager = uploader
 
 	return s3Handler, nil
 }
@@ -283,49 +289,185 @@ func (s3Handler *S3ObjectStorageHandler) DeleteObjects(locations []*models.Locat
 	return nil
 }
 
-func (objectLoader *S3ObjectStorageHandler) ChunkedObjectDowload(location *models.Location, data chan []byte) error {
-	headObject, err := objectLoader.S3Client.HeadObject(context.Background(), &s3.HeadObjectInput{
+// ChunkedObjectDowload Downloads the object at the given location starting at the provided offset
+// and sends the data in chunks of S3ChunkSize into the provided channel.
+// It returns the number of bytes that have been sent into the channel, this can be used to resume a failed download.
+// If a single read from the object storage takes longer than the read timeout the download is aborted, a timeout of 0 disables the timeout.
+func (objectLoader *S3ObjectStorageHandler) ChunkedObjectDowload(ctx context.Context, location *models.Location, offset int64, readTimeout time.Duration, data chan []byte) (int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var readTimer *time.Timer
+	if readTimeout > 0 {
+		readTimer = time.AfterFunc(readTimeout, cancel)
+		defer readTimer.Stop()
+	}
+
+	input := &s3.GetObjectInput{
 		Bucket: &location.Bucket,
 		Key:    &location.Key,
-	})
+	}
+	if offset > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%v-", offset))
+	}
+
+	object, err := objectLoader.S3Client.GetObject(ctx, input)
 	if err != nil {
 		log.Println(err.Error())
-		return nil
+		return 0, err
 	}
+	defer object.Body.Close()
 
-	sumReadBytes := 0
-	toBeFinished := false
+	var sentBytes int64
 	for {
-		if headObject.ContentLength == 0 {
-			continue
+		buffer := make([]byte, S3ChunkSize)
+		readBytes, readErr := io.ReadFull(object.Body, buffer)
+		if readBytes > 0 {
+			if readTimer != nil {
+				readTimer.Stop()
+			}
+
+			select {
+			case data <- buffer[:readBytes]:
+				sentBytes = sentBytes + int64(readBytes)
+			case <-ctx.Done():
+				return sentBytes, ctx.Err()
+			}
+
+			if readTimer != nil {
+				readTimer.Reset(readTimeout)
+			}
 		}
 
-		readEndPos := sumReadBytes + S3ChunkSize
-		if readEndPos > int(headObject.ContentLength) {
-			readEndPos = (int(headObject.ContentLength) - 1) - sumReadBytes
-			toBeFinished = true
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			return sentBytes, nil
 		}
 
-		rangeToRead := fmt.Sprintf("Range: bytes=%v-%v", sumReadBytes, readEndPos)
+		if readErr != nil {
+			if ctx.Err() != nil {
+				readErr = fmt.Errorf("%v: %w", readErr.Error(), ctx.Err())
+			}
+			log.Println(readErr.Error())
+			return sentBytes, readErr
+		}
+	}
+}
 
-		buffer := make([]byte, readEndPos+sumReadBytes)
-		writerBuffer := manager.NewWriteAtBuffer(buffer)
-		readBytes, err := objectLoader.S3DownloadManager.Download(context.Background(), writerBuffer, &s3.GetObjectInput{
-			Bucket: &location.Bucket,
-			Key:    &location.Key,
-			Range:  aws.String(rangeToRead),
-		})
+// UploadObject Uploads the data of the provided reader to the given location
+// The size of the data does not have to be known in advance, larger objects are uploaded as multipart upload.
+func (s3Handler *S3ObjectStorageHandler) UploadObject(ctx context.Context, location *models.Location, data io.Reader) error {
+	_, err := s3Handler.S3UploadManager.Upload(ctx, &s3.PutObjectInput{
+		Bucket: &location.Bucket,
+		Key:    &location.Key,
+		Body:   data,
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetObjectSize Returns the size of the object stored at the given location
+func (s3Handler *S3ObjectStorageHandler) GetObjectSize(ctx context.Context, location *models.Location) (int64, error) {
+	head, err := s3Handler.S3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &location.Bucket,
+		Key:    &location.Key,
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return 0, err
+	}
+
+	return head.ContentLength, nil
+}
+
+// ObjectReader Returns a reader for the object stored at the given location, the reader has to be closed by the caller
+func (s3Handler *S3ObjectStorageHandler) ObjectReader(ctx context.Context, location *models.Location) (io.ReadCloser, error) {
+	object, err := s3Handler.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &location.Bucket,
+		Key:    &location.Key,
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return object.Body, nil
+}
+
+// ObjectReaderAt Random access to an object in the object storage
+// It is intended for formats like zip that have to be read out of order. Each read that is not served from the
+// read ahead buffer is a separate ranged request that fetches at least S3ChunkSize bytes.
+type ObjectReaderAt struct {
+	ctx       context.Context
+	s3Handler *S3ObjectStorageHandler
+	location  *models.Location
+	mutex     sync.Mutex
+	buffer    []byte
+	bufferOff int64
+}
+
+// NewObjectReaderAt Creates an io.ReaderAt for the object stored at the given location
+func (s3Handler *S3ObjectStorageHandler) NewObjectReaderAt(ctx context.Context, location *models.Location) *ObjectReaderAt {
+	return &ObjectReaderAt{
+		ctx:       ctx,
+		s3Handler: s3Handler,
+		location:  location,
+	}
+}
+
+// ReadAt Reads len(p) bytes of the object starting at offset off
+func (reader *ObjectReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	reader.mutex.Lock()
+	defer reader.mutex.Unlock()
+
+	if off < reader.bufferOff || off+int64(len(p)) > reader.bufferOff+int64(len(reader.buffer)) {
+		err := reader.fill(off, len(p))
 		if err != nil {
-			log.Println(err.Error())
-			return nil
+			return 0, err
 		}
+	}
 
-		data <- writerBuffer.Bytes()
+	n := copy(p, reader.buffer[off-reader.bufferOff:])
+	if n < len(p) {
+		return n, io.EOF
+	}
 
-		sumReadBytes = sumReadBytes + int(readBytes)
+	return n, nil
+}
 
-		if toBeFinished {
-			return nil
-		}
+// Fetches the range of the object that starts at off into the read ahead buffer
+func (reader *ObjectReaderAt) fill(off int64, size int) error {
+	if size < S3ChunkSize {
+		size = S3ChunkSize
+	}
+
+	object, err := reader.s3Handler.S3Client.GetObject(reader.ctx, &s3.GetObjectInput{
+		Bucket: &reader.location.Bucket,
+		Key:    &reader.location.Key,
+		Range:  aws.String(fmt.Sprintf("bytes=%v-%v", off, off+int64(size)-1)),
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+	defer object.Body.Close()
+
+	buffer := make([]byte, size)
+	n, err := io.ReadFull(object.Body, buffer)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		log.Println(err.Error())
+		return err
 	}
+
+	reader.buffer = buffer[:n]
+	reader.bufferOff = off
+
+	return nil
 }
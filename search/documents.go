@@ -0,0 +1,66 @@
+package search
+
+import (
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+)
+
+func projectDocument(project *models.Project) *Document {
+	return &Document{
+		Resource:    resourceProject,
+		ProjectID:   project.ID.String(),
+		Name:        project.Name,
+		Description: project.Description,
+		Labels:      labelTexts(project.Labels),
+	}
+}
+
+func datasetDocument(dataset *models.Dataset) *Document {
+	return &Document{
+		Resource:    resourceDataset,
+		ProjectID:   dataset.ProjectID.String(),
+		DatasetID:   dataset.ID.String(),
+		Name:        dataset.Name,
+		Description: dataset.Description,
+		Labels:      labelTexts(dataset.Labels),
+	}
+}
+
+// Object groups are indexed with the name, description and labels of their current revision and the filenames of its data objects
+func objectGroupDocument(objectGroup *models.ObjectGroup) *Document {
+	revision := objectGroup.CurrentObjectGroupRevision
+
+	filenames := make([]string, len(revision.DataObjects))
+	for i, object := range revision.DataObjects {
+		filenames[i] = object.Filename
+	}
+
+	return &Document{
+		Resource:    resourceObjectGroup,
+		ProjectID:   objectGroup.ProjectID.String(),
+		DatasetID:   objectGroup.DatasetID.String(),
+		Name:        revision.Name,
+		Description: revision.Description,
+		Filenames:   filenames,
+		Labels:      labelTexts(revision.Labels),
+	}
+}
+
+func objectDocument(object *models.Object) *Document {
+	return &Document{
+		Resource:  resourceObject,
+		ProjectID: object.ProjectID.String(),
+		DatasetID: object.DatasetID.String(),
+		Name:      object.Filename,
+		Filenames: []string{object.Filename},
+		Labels:    labelTexts(object.Labels),
+	}
+}
+
+func labelTexts(labels []models.Label) []string {
+	texts := make([]string, len(labels))
+	for i, label := range labels {
+		texts[i] = label.Key + " " + label.Value
+	}
+
+	return texts
+}
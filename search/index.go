@@ -0,0 +1,336 @@
+package search
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	bleveregexp "github.com/blevesearch/bleve/v2/analysis/tokenizer/regexp"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+	log "github.com/sirupsen/logrus"
+)
+
+// Splits names, filenames and labels into fragments of letters and digits, e.g. sample_01.fastq into sample, 01 and fastq
+const fragmentPattern = `[\p{L}\p{N}]+`
+
+const fragmentAnalyzer = "fragment"
+
+// Internal key of the time up to which the outbox events have been indexed
+var positionKey = []byte("outbox_position")
+
+var fragmentRegexp = regexp.MustCompile(fragmentPattern)
+
+// Number of documents that are read per search by FindDocuments and AllDocuments
+const findBatchSize = 1000
+
+// Maximum number of terms of a search query that are used
+const maxQueryTerms = 10
+
+// Field boosts, matches in names and filenames rank higher than matches in descriptions
+var searchFields = map[string]float64{
+	"name":        3,
+	"filenames":   3,
+	"labels":      2,
+	"description": 1,
+}
+
+// Document The indexed representation of a project, dataset, object group or object
+type Document struct {
+	// Resource enum name, e.g. RESOURCE_DATASET
+	Resource    string `json:"resource"`
+	ProjectID   string `json:"project_id"`
+	DatasetID   string `json:"dataset_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Filename of an object or the filenames of the data objects of an object group
+	Filenames []string `json:"filenames"`
+	// Labels as "key value"
+	Labels []string `json:"labels"`
+}
+
+// Request A search across the given projects
+type Request struct {
+	Query      string
+	ProjectIDs []string
+	// Resource enum names, all resources are searched if empty
+	Resources []string
+	Size      int
+	From      int
+}
+
+// Hit A matching resource, hits are ordered by their score
+type Hit struct {
+	ID        string
+	Resource  string
+	ProjectID string
+	DatasetID string
+	Name      string
+	Score     float64
+}
+
+// Result The hits of a search together with the total number of matches
+type Result struct {
+	Hits  []*Hit
+	Total uint64
+}
+
+// Index Embedded full-text index of the names, descriptions, filenames and labels of the resources
+type Index struct {
+	index bleve.Index
+}
+
+// OpenIndex Opens the index at path, a new index is created if it does not exist
+// An empty path creates an index in memory.
+func OpenIndex(path string) (*Index, error) {
+	if path == "" {
+		index, err := bleve.NewMemOnly(newIndexMapping())
+		if err != nil {
+			log.Errorln(err.Error())
+			return nil, err
+		}
+
+		return &Index{index: index}, nil
+	}
+
+	index, err := bleve.Open(path)
+	if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+		index, err = bleve.New(path, newIndexMapping())
+	}
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	return &Index{index: index}, nil
+}
+
+// CreateIndex Creates a new empty index at path, an existing index is removed
+func CreateIndex(path string) (*Index, error) {
+	err := os.RemoveAll(path)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	return OpenIndex(path)
+}
+
+func newIndexMapping() mapping.IndexMapping {
+	indexMapping := bleve.NewIndexMapping()
+
+	err := indexMapping.AddCustomTokenizer(fragmentAnalyzer, map[string]interface{}{
+		"type":   bleveregexp.Name,
+		"regexp": fragmentPattern,
+	})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	err = indexMapping.AddCustomAnalyzer(fragmentAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     fragmentAnalyzer,
+		"token_filters": []string{lowercase.Name},
+	})
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	keywordField := bleve.NewTextFieldMapping()
+	keywordField.Analyzer = keyword.Name
+
+	fragmentField := bleve.NewTextFieldMapping()
+	fragmentField.Analyzer = fragmentAnalyzer
+
+	descriptionField := bleve.NewTextFieldMapping()
+	descriptionField.Analyzer = standard.Name
+	descriptionField.Store = false
+
+	documentMapping := bleve.NewDocumentMapping()
+	documentMapping.AddFieldMappingsAt("resource", keywordField)
+	documentMapping.AddFieldMappingsAt("project_id", keywordField)
+	documentMapping.AddFieldMappingsAt("dataset_id", keywordField)
+	documentMapping.AddFieldMappingsAt("name", fragmentField)
+	documentMapping.AddFieldMappingsAt("filenames", fragmentField)
+	documentMapping.AddFieldMappingsAt("labels", fragmentField)
+	documentMapping.AddFieldMappingsAt("description", descriptionField)
+
+	indexMapping.DefaultMapping = documentMapping
+	indexMapping.DefaultAnalyzer = standard.Name
+
+	return indexMapping
+}
+
+// Close Closes the index
+func (index *Index) Close() error {
+	return index.index.Close()
+}
+
+// IndexDocuments Adds or replaces the documents with the given ids and removes the documents of the deleted ids
+func (index *Index) IndexDocuments(documents map[string]*Document, deleted []string) error {
+	batch := index.index.NewBatch()
+	for id, document := range documents {
+		err := batch.Index(id, document)
+		if err != nil {
+			log.Errorln(err.Error())
+			return err
+		}
+	}
+
+	for _, id := range deleted {
+		batch.Delete(id)
+	}
+
+	return index.index.Batch(batch)
+}
+
+// FindDocuments Returns the ids and resource types of all documents whose field has the given value, e.g. all
+// documents of a dataset
+func (index *Index) FindDocuments(field string, value string) (map[string]string, error) {
+	termQuery := bleve.NewTermQuery(value)
+	termQuery.SetField(field)
+
+	return index.findDocuments(termQuery)
+}
+
+// AllDocuments Returns the ids and resource types of all documents of the index
+func (index *Index) AllDocuments() (map[string]string, error) {
+	return index.findDocuments(bleve.NewMatchAllQuery())
+}
+
+// Reads all matches of a query in batches, ordered by their id
+func (index *Index) findDocuments(searchQuery query.Query) (map[string]string, error) {
+	documents := make(map[string]string)
+	var lastID string
+
+	for {
+		request := bleve.NewSearchRequestOptions(searchQuery, findBatchSize, 0, false)
+		request.SortBy([]string{"_id"})
+		request.Fields = []string{"resource"}
+		if lastID != "" {
+			request.SetSearchAfter([]string{lastID})
+		}
+
+		result, err := index.index.Search(request)
+		if err != nil {
+			log.Errorln(err.Error())
+			return nil, err
+		}
+
+		for _, hit := range result.Hits {
+			documents[hit.ID] = stringField(hit.Fields, "resource")
+			lastID = hit.ID
+		}
+
+		if len(result.Hits) < findBatchSize {
+			return documents, nil
+		}
+	}
+}
+
+// Position Returns the time up to which the outbox events have been indexed, zero if the index has never been built
+func (index *Index) Position() (time.Time, error) {
+	value, err := index.index.GetInternal(positionKey)
+	if err != nil || value == nil {
+		return time.Time{}, err
+	}
+
+	var position time.Time
+	err = position.UnmarshalText(value)
+
+	return position, err
+}
+
+// SetPosition Stores the time up to which the outbox events have been indexed
+func (index *Index) SetPosition(position time.Time) error {
+	value, err := position.MarshalText()
+	if err != nil {
+		return err
+	}
+
+	return index.index.SetInternal(positionKey, value)
+}
+
+// Search Returns the documents of the requested projects that contain all terms of the query, ranked by relevance
+// Terms match whole fragments of names, filenames and labels or their beginning, and words of descriptions.
+func (index *Index) Search(request *Request) (*Result, error) {
+	terms := fragmentRegexp.FindAllString(strings.ToLower(request.Query), maxQueryTerms)
+	if len(terms) == 0 || len(request.ProjectIDs) == 0 {
+		return &Result{}, nil
+	}
+
+	termQueries := make([]query.Query, len(terms))
+	for i, term := range terms {
+		var fieldQueries []query.Query
+		for field, boost := range searchFields {
+			matchQuery := bleve.NewMatchQuery(term)
+			matchQuery.SetField(field)
+			matchQuery.SetBoost(boost)
+
+			prefixQuery := bleve.NewPrefixQuery(term)
+			prefixQuery.SetField(field)
+			prefixQuery.SetBoost(boost / 2)
+
+			fieldQueries = append(fieldQueries, matchQuery, prefixQuery)
+		}
+		termQueries[i] = bleve.NewDisjunctionQuery(fieldQueries...)
+	}
+
+	searchQuery := bleve.NewBooleanQuery()
+	searchQuery.AddMust(termQueries...)
+	searchQuery.AddMust(termsQuery("project_id", request.ProjectIDs))
+	if len(request.Resources) > 0 {
+		searchQuery.AddMust(termsQuery("resource", request.Resources))
+	}
+
+	searchRequest := bleve.NewSearchRequestOptions(searchQuery, request.Size, request.From, false)
+	searchRequest.Fields = []string{"resource", "project_id", "dataset_id", "name"}
+
+	searchResult, err := index.index.Search(searchRequest)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	result := &Result{
+		Hits:  make([]*Hit, len(searchResult.Hits)),
+		Total: searchResult.Total,
+	}
+	for i, match := range searchResult.Hits {
+		result.Hits[i] = &Hit{
+			ID:        match.ID,
+			Resource:  stringField(match.Fields, "resource"),
+			ProjectID: stringField(match.Fields, "project_id"),
+			DatasetID: stringField(match.Fields, "dataset_id"),
+			Name:      stringField(match.Fields, "name"),
+			Score:     match.Score,
+		}
+	}
+
+	return result, nil
+}
+
+// Matches documents whose field has one of the values, the match does not contribute to the score
+func termsQuery(field string, values []string) query.Query {
+	termQueries := make([]query.Query, len(values))
+	for i, value := range values {
+		termQuery := bleve.NewTermQuery(value)
+		termQuery.SetField(field)
+		termQuery.SetBoost(0)
+		termQueries[i] = termQuery
+	}
+
+	return bleve.NewDisjunctionQuery(termQueries...)
+}
+
+func stringField(fields map[string]interface{}, name string) string {
+	value, _ := fields[name].(string)
+	return value
+}
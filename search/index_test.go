@@ -0,0 +1,82 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndexSearch(t *testing.T) {
+	index, err := OpenIndex("")
+	assert.NoError(t, err)
+	defer index.Close()
+
+	position, err := index.Position()
+	assert.NoError(t, err)
+	assert.True(t, position.IsZero())
+
+	err = index.IndexDocuments(map[string]*Document{
+		"dataset-1": {
+			Resource:    resourceDataset,
+			ProjectID:   "project-1",
+			DatasetID:   "dataset-1",
+			Name:        "Sequencing run",
+			Description: "Raw reads of the soil samples from the northern field",
+		},
+		"object-1": {
+			Resource:  resourceObject,
+			ProjectID: "project-1",
+			DatasetID: "dataset-1",
+			Name:      "sample_01.fastq.gz",
+			Filenames: []string{"sample_01.fastq.gz"},
+			Labels:    []string{"pipeline ingest"},
+		},
+		"object-2": {
+			Resource:  resourceObject,
+			ProjectID: "project-2",
+			DatasetID: "dataset-2",
+			Name:      "sample_02.fastq.gz",
+			Filenames: []string{"sample_02.fastq.gz"},
+		},
+	}, nil)
+	assert.NoError(t, err)
+
+	result, err := index.Search(&Request{Query: "soil", ProjectIDs: []string{"project-1", "project-2"}, Size: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), result.Total)
+	assert.Equal(t, "dataset-1", result.Hits[0].ID)
+	assert.Equal(t, resourceDataset, result.Hits[0].Resource)
+
+	// Fragments and prefixes of filenames match, other projects are not searched
+	result, err = index.Search(&Request{Query: "fast sample", ProjectIDs: []string{"project-1"}, Size: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), result.Total)
+	assert.Equal(t, "object-1", result.Hits[0].ID)
+	assert.Equal(t, "sample_01.fastq.gz", result.Hits[0].Name)
+
+	result, err = index.Search(&Request{Query: "sample", ProjectIDs: []string{"project-1", "project-2"}, Resources: []string{resourceObject}, Size: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), result.Total)
+
+	result, err = index.Search(&Request{Query: "ingest", ProjectIDs: []string{"project-1"}, Size: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), result.Total)
+
+	documents, err := index.FindDocuments("dataset_id", "dataset-1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"dataset-1": resourceDataset, "object-1": resourceObject}, documents)
+
+	err = index.IndexDocuments(nil, []string{"object-1"})
+	assert.NoError(t, err)
+
+	documents, err = index.AllDocuments()
+	assert.NoError(t, err)
+	assert.Len(t, documents, 2)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	assert.NoError(t, index.SetPosition(now))
+	position, err = index.Position()
+	assert.NoError(t, err)
+	assert.True(t, now.Equal(position))
+}
@@ -0,0 +1,411 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// Number of outbox events and resources that are read per database query
+const indexerBatchSize = 500
+
+// Events are read again for this time after the position of the index, events of transactions that commit later than
+// they are written are indexed as well
+const eventOverlap = 30 * time.Second
+
+var (
+	resourceProject     = v1storagemodels.Resource_RESOURCE_PROJECT.String()
+	resourceDataset     = v1storagemodels.Resource_RESOURCE_DATASET.String()
+	resourceObjectGroup = v1storagemodels.Resource_RESOURCE_OBJECT_GROUP.String()
+	resourceRevision    = v1storagemodels.Resource_RESOURCE_OBJECT_GROUP_REVISION.String()
+	resourceObject      = v1storagemodels.Resource_RESOURCE_OBJECT.String()
+)
+
+// Indexer Keeps the search index up to date with the events of the transactional outbox
+//
+// Every create, update and delete writes an outbox event, the indexer reads the current state of the changed
+// resources for these events and replaces or removes their documents. Resources that are read again produce the
+// same documents, events can be indexed more than once. If the index is older than the retention of the outbox
+// events it is rebuilt from the database.
+type Indexer struct {
+	Index        *Index
+	Read         *database.Read
+	Outbox       *database.Outbox
+	PollInterval time.Duration
+	// Published events are removed from the outbox after the retention
+	OutboxRetention time.Duration
+
+	// Events that have been indexed within the overlap of the position
+	indexed map[uuid.UUID]time.Time
+}
+
+// Run Indexes new events until the context is cancelled
+func (indexer *Indexer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(indexer.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := indexer.Update()
+		if err != nil {
+			log.Errorln(err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Update Indexes the resources of all events since the position of the index, the index is rebuilt if it has never
+// been built or if events might have been removed from the outbox in the meantime
+func (indexer *Indexer) Update() error {
+	position, err := indexer.Index.Position()
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	if position.IsZero() || (indexer.OutboxRetention > 0 && time.Since(position) > indexer.OutboxRetention) {
+		log.Infoln("search index is outdated, rebuilding it")
+		return indexer.Rebuild()
+	}
+
+	if indexer.indexed == nil {
+		indexer.indexed = make(map[uuid.UUID]time.Time)
+	}
+
+	start := time.Now()
+	since := position.Add(-eventOverlap)
+	var afterSequence int64
+
+	for {
+		events, err := indexer.Outbox.GetOutboxEventsSince(since, afterSequence, indexerBatchSize)
+		if err != nil {
+			return err
+		}
+
+		changes := newChangeSet()
+		for _, event := range events {
+			afterSequence = event.Sequence
+			if _, ok := indexer.indexed[event.ID]; ok {
+				continue
+			}
+
+			err := indexer.addEventChanges(changes, event)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = indexer.apply(changes)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			indexer.indexed[event.ID] = event.CreatedAt
+			if event.CreatedAt.After(position) {
+				position = event.CreatedAt
+			}
+		}
+
+		if len(events) < indexerBatchSize {
+			break
+		}
+	}
+
+	// The position moves forward without events as well, an idle index would be rebuilt after the retention otherwise
+	if floor := start.Add(-eventOverlap); floor.After(position) {
+		position = floor
+	}
+
+	for id, created := range indexer.indexed {
+		if created.Before(position.Add(-eventOverlap)) {
+			delete(indexer.indexed, id)
+		}
+	}
+
+	return indexer.Index.SetPosition(position)
+}
+
+// Rebuild Indexes all resources of the database and removes all other documents from the index
+func (indexer *Indexer) Rebuild() error {
+	start := time.Now()
+	indexedIDs := make(map[string]struct{})
+
+	err := indexer.indexAll(indexedIDs)
+	if err != nil {
+		return err
+	}
+
+	documents, err := indexer.Index.AllDocuments()
+	if err != nil {
+		return err
+	}
+
+	var stale []string
+	for id := range documents {
+		if _, ok := indexedIDs[id]; !ok {
+			stale = append(stale, id)
+		}
+	}
+
+	err = indexer.Index.IndexDocuments(nil, stale)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	indexer.indexed = make(map[uuid.UUID]time.Time)
+
+	log.Infof("indexed %v resources for search in %v", len(indexedIDs), time.Since(start))
+
+	return indexer.Index.SetPosition(start)
+}
+
+func (indexer *Indexer) indexAll(indexedIDs map[string]struct{}) error {
+	for _, resource := range []string{resourceProject, resourceDataset, resourceObjectGroup, resourceObject} {
+		afterID := uuid.Nil
+		for {
+			documents, lastID, count, err := indexer.readDocumentsAfter(resource, afterID)
+			if err != nil {
+				return err
+			}
+
+			err = indexer.Index.IndexDocuments(documents, nil)
+			if err != nil {
+				log.Errorln(err.Error())
+				return err
+			}
+
+			for id := range documents {
+				indexedIDs[id] = struct{}{}
+			}
+
+			if count < indexerBatchSize {
+				break
+			}
+			afterID = lastID
+		}
+	}
+
+	return nil
+}
+
+// Resources whose documents have to be read again, ids are grouped by resource type
+type changeSet map[string]map[uuid.UUID]struct{}
+
+func newChangeSet() changeSet {
+	return make(changeSet)
+}
+
+func (changes changeSet) add(resource string, id uuid.UUID) {
+	if id == uuid.Nil {
+		return
+	}
+
+	if _, ok := changes[resource]; !ok {
+		changes[resource] = make(map[uuid.UUID]struct{})
+	}
+	changes[resource][id] = struct{}{}
+}
+
+// Adds the resources that are changed by an event
+// Deleting or restoring datasets and object groups moves their objects to or out of the trash without events of their
+// own, these objects are read again as well.
+func (indexer *Indexer) addEventChanges(changes changeSet, event *models.OutboxEvent) error {
+	deleted := event.UpdateType == v1notificationservices.EventNotificationMessage_UPDATE_TYPE_DELETED.String()
+	created := event.UpdateType == v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED.String()
+
+	switch event.Resource {
+	case resourceProject:
+		changes.add(resourceProject, event.ResourceID)
+		if deleted {
+			return indexer.addIndexedDocuments(changes, "project_id", event.ResourceID)
+		}
+	case resourceDataset:
+		changes.add(resourceDataset, event.ResourceID)
+		if deleted {
+			return indexer.addIndexedDocuments(changes, "dataset_id", event.ResourceID)
+		}
+		if created {
+			return indexer.addDatasetResources(changes, event.ResourceID)
+		}
+	case resourceObjectGroup:
+		changes.add(resourceObjectGroup, event.ResourceID)
+		if deleted || created {
+			objectIDs, err := indexer.Read.GetObjectGroupObjectIDs(event.ResourceID)
+			if err != nil {
+				return err
+			}
+			for _, objectID := range objectIDs {
+				changes.add(resourceObject, objectID)
+			}
+		}
+	case resourceRevision:
+		changes.add(resourceObjectGroup, event.ObjectGroupID)
+	case resourceObject:
+		changes.add(resourceObject, event.ResourceID)
+	}
+
+	return nil
+}
+
+// Adds all indexed documents whose field has the value of id
+func (indexer *Indexer) addIndexedDocuments(changes changeSet, field string, id uuid.UUID) error {
+	documents, err := indexer.Index.FindDocuments(field, id.String())
+	if err != nil {
+		return err
+	}
+
+	for documentID, resource := range documents {
+		parsedID, err := uuid.Parse(documentID)
+		if err != nil {
+			log.Errorln(err.Error())
+			continue
+		}
+		changes.add(resource, parsedID)
+	}
+
+	return nil
+}
+
+// Adds the object groups and objects of a dataset that is created or restored
+func (indexer *Indexer) addDatasetResources(changes changeSet, datasetID uuid.UUID) error {
+	objectGroups, _, err := indexer.Read.GetDatasetObjectGroups(datasetID, nil)
+	if err != nil {
+		return err
+	}
+	for _, objectGroup := range objectGroups {
+		changes.add(resourceObjectGroup, objectGroup.ID)
+	}
+
+	objects, err := indexer.Read.GetAllDatasetObjects(datasetID)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		changes.add(resourceObject, object.ID)
+	}
+
+	return nil
+}
+
+// Reads the changed resources and replaces their documents, resources that do not exist anymore are removed
+func (indexer *Indexer) apply(changes changeSet) error {
+	for resource, ids := range changes {
+		idList := make([]uuid.UUID, 0, len(ids))
+		for id := range ids {
+			idList = append(idList, id)
+		}
+
+		for start := 0; start < len(idList); start += indexerBatchSize {
+			end := start + indexerBatchSize
+			if end > len(idList) {
+				end = len(idList)
+			}
+
+			documents, err := indexer.readDocuments(resource, idList[start:end])
+			if err != nil {
+				return err
+			}
+
+			var deleted []string
+			for _, id := range idList[start:end] {
+				if _, ok := documents[id.String()]; !ok {
+					deleted = append(deleted, id.String())
+				}
+			}
+
+			err = indexer.Index.IndexDocuments(documents, deleted)
+			if err != nil {
+				log.Errorln(err.Error())
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Reads the documents of the resources with the given ids
+func (indexer *Indexer) readDocuments(resource string, ids []uuid.UUID) (map[string]*Document, error) {
+	return indexer.readResourceDocuments(resource, func(dest interface{}, preloads []string) error {
+		return indexer.Read.GetResourcesByID(dest, ids, preloads...)
+	})
+}
+
+// Reads the documents of the next batch of resources after afterID, returns the last read id and the number of resources
+func (indexer *Indexer) readDocumentsAfter(resource string, afterID uuid.UUID) (map[string]*Document, uuid.UUID, int, error) {
+	lastID := afterID
+	count := 0
+
+	documents, err := indexer.readResourceDocuments(resource, func(dest interface{}, preloads []string) error {
+		return indexer.Read.GetResourcesAfter(dest, afterID, indexerBatchSize, preloads...)
+	})
+	if err != nil {
+		return nil, uuid.Nil, 0, err
+	}
+
+	for id := range documents {
+		count++
+		parsedID, err := uuid.Parse(id)
+		if err == nil && parsedID.String() > lastID.String() {
+			lastID = parsedID
+		}
+	}
+
+	return documents, lastID, count, nil
+}
+
+func (indexer *Indexer) readResourceDocuments(resource string, read func(dest interface{}, preloads []string) error) (map[string]*Document, error) {
+	documents := make(map[string]*Document)
+
+	switch resource {
+	case resourceProject:
+		var projects []*models.Project
+		if err := read(&projects, database.SearchPreloadsProject); err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			documents[project.ID.String()] = projectDocument(project)
+		}
+	case resourceDataset:
+		var datasets []*models.Dataset
+		if err := read(&datasets, database.SearchPreloadsDataset); err != nil {
+			return nil, err
+		}
+		for _, dataset := range datasets {
+			documents[dataset.ID.String()] = datasetDocument(dataset)
+		}
+	case resourceObjectGroup:
+		var objectGroups []*models.ObjectGroup
+		if err := read(&objectGroups, database.SearchPreloadsObjectGroup); err != nil {
+			return nil, err
+		}
+		for _, objectGroup := range objectGroups {
+			documents[objectGroup.ID.String()] = objectGroupDocument(objectGroup)
+		}
+	case resourceObject:
+		var objects []*models.Object
+		if err := read(&objects, database.SearchPreloadsObject); err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			documents[object.ID.String()] = objectDocument(object)
+		}
+	default:
+		return nil, fmt.Errorf("resource %v is not indexed", resource)
+	}
+
+	return documents, nil
+}
@@ -11,8 +11,7 @@ import (
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
-	"github.com/ScienceObjectsDB/CORE-Server/models"
-	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	"github.com/ScienceObjectsDB/CORE-Server/database"
 	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
 	v1storageservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/services/v1"
 )
@@ -55,18 +54,6 @@ func (endpoint *DatasetEndpoints) CreateDataset(ctx context.Context, request *v1
 		return nil, err
 	}
 
-	msg := &v1notificationservices.EventNotificationMessage{
-		ResourceId:  id,
-		Resource:    v1storagemodels.Resource_RESOURCE_DATASET,
-		UpdatedType: v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED,
-	}
-
-	err = endpoint.EventStreamMgmt.PublishMessage(msg)
-	if err != nil {
-		log.Errorln(err.Error())
-		return nil, status.Error(codes.Internal, "could not publish notification event")
-	}
-
 	response := v1storageservices.CreateDatasetResponse{
 		Id: id,
 	}
@@ -141,11 +128,17 @@ func (endpoint *DatasetEndpoints) GetDatasetObjects(ctx context.Context, request
 		return nil, err
 	}
 
-	objects, err := endpoint.ReadHandler.GetDatasetObjects(request)
+	page, err := endpoint.requestPage(ctx, request.GetPageRequest())
+	if err != nil {
+		return nil, err
+	}
+
+	objects, nextCursor, err := endpoint.ReadHandler.GetDatasetObjects(request, page)
 	if err != nil {
 		log.Errorln(err.Error())
 		return nil, err
 	}
+	setNextPageCursor(ctx, nextCursor)
 
 	var protoObjects []*v1storagemodels.Object
 	for _, object := range objects {
@@ -190,11 +183,17 @@ func (endpoint *DatasetEndpoints) GetDatasetVersions(ctx context.Context, reques
 		return nil, err
 	}
 
-	versions, err := endpoint.ReadHandler.GetDatasetVersions(requestID)
+	page, err := endpoint.requestPage(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, nextCursor, err := endpoint.ReadHandler.GetDatasetVersions(requestID, page)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
+	setNextPageCursor(ctx, nextCursor)
 
 	var protoVersions []*v1storagemodels.DatasetVersion
 	for _, version := range versions {
@@ -244,21 +243,32 @@ func (endpoint *DatasetEndpoints) GetDatasetObjectGroups(ctx context.Context, re
 		return nil, err
 	}
 
-	objectGroups, err := endpoint.ReadHandler.GetDatasetObjectGroups(requestID, request.GetPageRequest())
+	page, err := endpoint.requestPage(ctx, request.GetPageRequest())
+	if err != nil {
+		return nil, err
+	}
+
+	objectGroups, nextCursor, err := endpoint.ReadHandler.GetDatasetObjectGroups(requestID, page)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
+	setNextPageCursor(ctx, nextCursor)
+
+	revisionIDs := make([]uuid.UUID, len(objectGroups))
+	for i, objectGroup := range objectGroups {
+		revisionIDs[i] = objectGroup.CurrentObjectGroupRevision.ID
+	}
+
+	revisionStats, err := endpoint.StatsHandler.GetObjectGroupRevisionsStats(revisionIDs)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
 
 	var protoObjectGroups []*v1storagemodels.ObjectGroup
 	for _, objectGroup := range objectGroups {
-		stats, err := endpoint.StatsHandler.GetObjectGroupRevisionStats(&objectGroup.CurrentObjectGroupRevision)
-		if err != nil {
-			log.Errorln(err.Error())
-			return nil, err
-		}
-
-		protoObjectGroup, err := objectGroup.ToProtoModel(stats)
+		protoObjectGroup, err := objectGroup.ToProtoModel(revisionStats[objectGroup.CurrentObjectGroupRevision.ID])
 		if err != nil {
 			log.Errorln(err.Error())
 			return nil, status.Error(codes.Internal, "could not transform objectgroup into protobuf representation")
@@ -297,21 +307,32 @@ func (endpoint *DatasetEndpoints) GetObjectGroupRevisionsInDateRange(ctx context
 		return nil, err
 	}
 
-	objectGroupRevisions, err := endpoint.ReadHandler.GetObjectGroupRevisionsInDateRange(dataset.ID, request.Start.AsTime(), request.End.AsTime())
+	page, err := endpoint.requestPage(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	objectGroupRevisions, nextCursor, err := endpoint.ReadHandler.GetObjectGroupRevisionsInDateRange(dataset.ID, request.Start.AsTime(), request.End.AsTime(), page)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
+	setNextPageCursor(ctx, nextCursor)
+
+	revisionIDs := make([]uuid.UUID, len(objectGroupRevisions))
+	for i, objectGroupRevision := range objectGroupRevisions {
+		revisionIDs[i] = objectGroupRevision.ID
+	}
+
+	revisionStats, err := endpoint.StatsHandler.GetObjectGroupRevisionsStats(revisionIDs)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
 
 	var protoObjectGroups []*v1storagemodels.ObjectGroupRevision
 	for _, objectGroupRevision := range objectGroupRevisions {
-		stats, err := endpoint.StatsHandler.GetObjectGroupRevisionStats(objectGroupRevision)
-		if err != nil {
-			log.Errorln(err.Error())
-			return nil, err
-		}
-
-		protoObjectGroup, err := objectGroupRevision.ToProtoModel(stats)
+		protoObjectGroup, err := objectGroupRevision.ToProtoModel(revisionStats[objectGroupRevision.ID])
 		if err != nil {
 			log.Errorln(err.Error())
 			return nil, status.Error(codes.Internal, "could not transform objectgroup into protobuf representation")
@@ -409,7 +430,25 @@ func (endpoint *DatasetEndpoints) GetObjectGroupsStreamLink(ctx context.Context,
 		return nil, err
 	}
 
-	link, err := endpoint.ObjectStreamhandler.CreateStreamingLink(request, projectID)
+	err = database.ValidateLinkExpiry(request.GetExpiry())
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, err
+	}
+
+	maxDownloads, err := database.ParseLinkMaxDownloads(metadata)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, err
+	}
+
+	userID, err := endpoint.AuthzHandler.GetUserID(metadata)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	link, err := endpoint.ObjectStreamhandler.CreateStreamingLink(request, projectID, userID.String(), maxDownloads)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, status.Error(codes.Internal, "could not create link")
@@ -422,14 +461,9 @@ func (endpoint *DatasetEndpoints) GetObjectGroupsStreamLink(ctx context.Context,
 	return response, nil
 }
 
-// Updates a field of a dataset
-func (endpoint *DatasetEndpoints) UpdateDatasetField(_ context.Context, _ *v1storageservices.UpdateDatasetFieldRequest) (*v1storageservices.UpdateDatasetFieldResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "unimplemented")
-}
-
-// DeleteDataset Delete a dataset
-func (endpoint *DatasetEndpoints) DeleteDataset(ctx context.Context, request *v1storageservices.DeleteDatasetRequest) (*v1storageservices.DeleteDatasetResponse, error) {
-	requestID, err := uuid.Parse(request.GetId())
+// UpdateDatasetField Updates the name or the description of a dataset
+func (endpoint *DatasetEndpoints) UpdateDatasetField(ctx context.Context, request *v1storageservices.UpdateDatasetFieldRequest) (*v1storageservices.UpdateDatasetFieldResponse, error) {
+	requestID, err := uuid.Parse(request.GetUpdateRequest().GetId())
 	if err != nil {
 		log.Debug(err.Error())
 		return nil, status.Error(codes.InvalidArgument, "could not parse id")
@@ -452,32 +486,54 @@ func (endpoint *DatasetEndpoints) DeleteDataset(ctx context.Context, request *v1
 		return nil, err
 	}
 
-	objects, err := endpoint.ReadHandler.GetAllDatasetObjects(requestID)
+	var name, description *string
+	for field, value := range request.GetUpdateRequest().GetUpdatedStringFields() {
+		value := value
+		switch field {
+		case "name":
+			if value == "" {
+				return nil, status.Error(codes.InvalidArgument, "name can not be empty")
+			}
+			name = &value
+		case "description":
+			description = &value
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "field %v can not be updated", field)
+		}
+	}
+
+	err = endpoint.UpdateHandler.UpdateMetadata(v1storagemodels.Resource_RESOURCE_DATASET, requestID, name, description)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	var locations []*models.Location
-	for _, location := range objects {
-		locations = append(locations, &location.DefaultLocation)
+	return &v1storageservices.UpdateDatasetFieldResponse{}, nil
+}
+
+// DeleteDataset Delete a dataset
+func (endpoint *DatasetEndpoints) DeleteDataset(ctx context.Context, request *v1storageservices.DeleteDatasetRequest) (*v1storageservices.DeleteDatasetResponse, error) {
+	requestID, err := uuid.Parse(request.GetId())
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, status.Error(codes.InvalidArgument, "could not parse id")
 	}
 
-	err = endpoint.ObjectHandler.DeleteObjects(locations)
+	dataset, err := endpoint.ReadHandler.GetDataset(requestID)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	msg := &v1notificationservices.EventNotificationMessage{
-		ResourceId:  dataset.ID.String(),
-		Resource:    v1storagemodels.Resource_RESOURCE_DATASET,
-		UpdatedType: v1notificationservices.EventNotificationMessage_UPDATE_TYPE_DELETED,
-	}
-	err = endpoint.EventStreamMgmt.PublishMessage(msg)
+	metadata, _ := metadata.FromIncomingContext(ctx)
+
+	err = endpoint.AuthzHandler.Authorize(
+		dataset.ProjectID,
+		v1storagemodels.Right_RIGHT_WRITE,
+		metadata)
 	if err != nil {
-		log.Errorln(err.Error())
-		return nil, status.Error(codes.Internal, "could not publish notification event")
+		log.Println(err.Error())
+		return nil, err
 	}
 
 	err = endpoint.DeleteHandler.DeleteDataset(requestID)
@@ -538,17 +594,6 @@ func (endpoint *DatasetEndpoints) ReleaseDatasetVersion(ctx context.Context, req
 		Id: id.String(),
 	}
 
-	msg := &v1notificationservices.EventNotificationMessage{
-		ResourceId:  id.String(),
-		Resource:    v1storagemodels.Resource_RESOURCE_DATASET_VERSION,
-		UpdatedType: v1notificationservices.EventNotificationMessage_UPDATE_TYPE_CREATED,
-	}
-	err = endpoint.EventStreamMgmt.PublishMessage(msg)
-	if err != nil {
-		log.Errorln(err.Error())
-		return nil, status.Error(codes.Internal, "could not publish notification event")
-	}
-
 	return response, nil
 }
 
@@ -602,7 +647,12 @@ func (endpoint *DatasetEndpoints) GetDatasetVersionObjectGroups(ctx context.Cont
 		return nil, status.Error(codes.InvalidArgument, "could not parse dataset id")
 	}
 
-	version, err := endpoint.ReadHandler.GetDatasetVersionWithObjectGroups(requestID, request.GetPageRequest())
+	page, err := endpoint.requestPage(ctx, request.GetPageRequest())
+	if err != nil {
+		return nil, err
+	}
+
+	version, nextCursor, err := endpoint.ReadHandler.GetDatasetVersionWithObjectGroups(requestID, page)
 	if err != nil {
 		log.Errorln(err.Error())
 		return nil, err
@@ -619,15 +669,22 @@ func (endpoint *DatasetEndpoints) GetDatasetVersionObjectGroups(ctx context.Cont
 		return nil, err
 	}
 
+	setNextPageCursor(ctx, nextCursor)
+
+	revisionIDs := make([]uuid.UUID, len(version.ObjectGroupRevisions))
+	for i, objectGroupRevision := range version.ObjectGroupRevisions {
+		revisionIDs[i] = objectGroupRevision.ID
+	}
+
+	revisionStats, err := endpoint.StatsHandler.GetObjectGroupRevisionsStats(revisionIDs)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, status.Error(codes.Internal, "could not read objectgroup stats")
+	}
+
 	var protoObjectGroupRevisions []*v1storagemodels.ObjectGroupRevision
 	for _, objectGroupRevision := range version.ObjectGroupRevisions {
-		stats, err := endpoint.StatsHandler.GetObjectGroupRevisionStats(&objectGroupRevision)
-		if err != nil {
-			log.Errorln(err.Error())
-			return nil, status.Error(codes.Internal, "could not read objectgroup stats")
-		}
-
-		protoObjectGroupRevision, err := objectGroupRevision.ToProtoModel(stats)
+		protoObjectGroupRevision, err := objectGroupRevision.ToProtoModel(revisionStats[objectGroupRevision.ID])
 		if err != nil {
 			log.Errorln(err.Error())
 			return nil, status.Error(codes.Internal, "could not transform objectgroup into protobuf representation")
@@ -666,17 +723,6 @@ func (endpoint *DatasetEndpoints) DeleteDatasetVersion(ctx context.Context, requ
 		return nil, err
 	}
 
-	msg := &v1notificationservices.EventNotificationMessage{
-		ResourceId:  version.ID.String(),
-		Resource:    v1storagemodels.Resource_RESOURCE_DATASET_VERSION,
-		UpdatedType: v1notificationservices.EventNotificationMessage_UPDATE_TYPE_DELETED,
-	}
-	err = endpoint.EventStreamMgmt.PublishMessage(msg)
-	if err != nil {
-		log.Errorln(err.Error())
-		return nil, status.Error(codes.Internal, "could not publish notification event")
-	}
-
 	err = endpoint.DeleteHandler.DeleteDatasetVersion(requestID)
 	if err != nil {
 		log.Println(err.Error())
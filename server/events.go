@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/eventstreaming"
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Number of events that are returned if no limit is requested
+const defaultEventsLimit = 100
+
+// Maximum number of events that are returned by a single request
+const maxEventsLimit = 1000
+
+// Event Representation of an event notification together with its snapshot and changes
+type Event struct {
+	ID         string `json:"id"`
+	Sequence   int64  `json:"sequence"`
+	Resource   string `json:"resource"`
+	ResourceID string `json:"resource_id"`
+	UpdateType string `json:"update_type"`
+	Published  bool   `json:"published"`
+	// Set if the event could not be published within the maximum number of attempts
+	DeadLettered bool      `json:"dead_lettered,omitempty"`
+	Created      time.Time `json:"created"`
+	// Proto json of the resource at the time of the event, only stored if snapshots have been requested
+	Snapshot json.RawMessage              `json:"snapshot,omitempty"`
+	Changes  *eventstreaming.EventChanges `json:"changes,omitempty"`
+}
+
+type GetProjectEventsRequest struct {
+	ProjectID string `json:"project_id"`
+	// Optional id of a resource of the project, only its events are returned
+	ResourceID string `json:"resource_id"`
+	Limit      int    `json:"limit"`
+}
+
+type GetProjectEventsResponse struct {
+	Events []*Event `json:"events"`
+}
+
+// GetProjectEvents Returns the recent events of a project, newest events first
+// Events are available until they are removed from the outbox after EventNotifications.Outbox.Retention.
+func (endpoint *HTTPEndpoints) GetProjectEvents(ctx context.Context, request *GetProjectEventsRequest) (*GetProjectEventsResponse, error) {
+	projectID, err := uuid.Parse(request.ProjectID)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, status.Error(codes.InvalidArgument, "could not parse project id")
+	}
+
+	resourceID := uuid.Nil
+	if request.ResourceID != "" {
+		resourceID, err = uuid.Parse(request.ResourceID)
+		if err != nil {
+			log.Debug(err.Error())
+			return nil, status.Error(codes.InvalidArgument, "could not parse resource id")
+		}
+	}
+
+	limit := request.Limit
+	if limit <= 0 {
+		limit = defaultEventsLimit
+	}
+	if limit > maxEventsLimit {
+		limit = maxEventsLimit
+	}
+
+	metadata, _ := metadata.FromIncomingContext(ctx)
+
+	err = endpoint.AuthzHandler.Authorize(
+		projectID,
+		v1storagemodels.Right_RIGHT_READ,
+		metadata)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	events, err := endpoint.OutboxHandler.GetProjectOutboxEvents(projectID, resourceID, limit)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not read events")
+	}
+
+	response := &GetProjectEventsResponse{
+		Events: make([]*Event, len(events)),
+	}
+	for i, event := range events {
+		response.Events[i] = eventFromModel(event)
+	}
+
+	return response, nil
+}
+
+func eventFromModel(event *models.OutboxEvent) *Event {
+	responseEvent := &Event{
+		ID:           event.ID.String(),
+		Sequence:     event.Sequence,
+		Resource:     event.Resource,
+		ResourceID:   event.ResourceID.String(),
+		UpdateType:   event.UpdateType,
+		Published:    event.Published,
+		DeadLettered: event.DeadLettered,
+		Created:      event.CreatedAt,
+		Changes:      eventstreaming.NewEventChanges(event),
+	}
+
+	if event.Snapshot != "" {
+		responseEvent.Snapshot = json.RawMessage(event.Snapshot)
+	}
+
+	return responseEvent
+}
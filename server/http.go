@@ -0,0 +1,287 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ScienceObjectsDB/CORE-Server/streamingserver"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// HTTPEndpoints Management endpoints that are not part of the gRPC API
+// They are served as json http api by the data streaming server under /api/v1.
+// Requests are authorized with the same credentials as the gRPC endpoints, they have to be provided as http headers
+// with the same names as the corresponding gRPC metadata keys.
+type HTTPEndpoints struct {
+	*Endpoints
+	Importer *streamingserver.ArchiveImporter
+}
+
+// NewHTTPEndpoints New http management api
+func NewHTTPEndpoints(endpoints *Endpoints, importer *streamingserver.ArchiveImporter) (*HTTPEndpoints, error) {
+	httpEndpoints := &HTTPEndpoints{
+		Endpoints: endpoints,
+		Importer:  importer,
+	}
+
+	return httpEndpoints, nil
+}
+
+// RegisterRoutes Registers all routes of the http api
+func (endpoint *HTTPEndpoints) RegisterRoutes(router gin.IRouter) {
+	api := router.Group("/api/v1")
+
+	api.GET("/projects/:id/streaminglinks", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		return endpoint.GetProjectStreamingLinks(ctx, &GetProjectStreamingLinksRequest{ProjectID: c.Param("id")})
+	}))
+	api.DELETE("/streaminglinks/:id", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		return endpoint.RevokeStreamingLink(ctx, &RevokeStreamingLinkRequest{ID: c.Param("id")})
+	}))
+
+	api.POST("/datasets/:id/imports", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		request := &CreateImportJobRequest{}
+		err := bindJSON(c, request)
+		if err != nil {
+			return nil, err
+		}
+		request.DatasetID = c.Param("id")
+		return endpoint.CreateImportJob(ctx, request)
+	}))
+	api.POST("/imports/:id/start", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		return endpoint.StartImportJob(ctx, &StartImportJobRequest{ID: c.Param("id")})
+	}))
+	api.GET("/imports/:id", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		return endpoint.GetImportJob(ctx, &GetImportJobRequest{ID: c.Param("id")})
+	}))
+
+	api.POST("/projects/:id/webhooks", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		request := &CreateWebhookSubscriptionRequest{}
+		err := bindJSON(c, request)
+		if err != nil {
+			return nil, err
+		}
+		request.ProjectID = c.Param("id")
+		return endpoint.CreateWebhookSubscription(ctx, request)
+	}))
+	api.GET("/projects/:id/webhooks", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		return endpoint.GetProjectWebhookSubscriptions(ctx, &GetProjectWebhookSubscriptionsRequest{ProjectID: c.Param("id")})
+	}))
+	api.GET("/webhooks/:id", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		return endpoint.GetWebhookSubscription(ctx, &GetWebhookSubscriptionRequest{ID: c.Param("id")})
+	}))
+	api.PATCH("/webhooks/:id", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		request := &UpdateWebhookSubscriptionRequest{}
+		err := bindJSON(c, request)
+		if err != nil {
+			return nil, err
+		}
+		request.ID = c.Param("id")
+		return endpoint.UpdateWebhookSubscription(ctx, request)
+	}))
+	api.DELETE("/webhooks/:id", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		return endpoint.DeleteWebhookSubscription(ctx, &DeleteWebhookSubscriptionRequest{ID: c.Param("id")})
+	}))
+	api.GET("/webhooks/:id/deliveries", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		limit, _ := strconv.Atoi(c.Query("limit"))
+		return endpoint.GetWebhookDeliveries(ctx, &GetWebhookDeliveriesRequest{SubscriptionID: c.Param("id"), Status: c.Query("status"), Limit: limit})
+	}))
+	api.POST("/webhookdeliveries/:id/redeliver", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		return endpoint.RedeliverWebhookDelivery(ctx, &RedeliverWebhookDeliveryRequest{ID: c.Param("id")})
+	}))
+
+	api.GET("/projects/:id/events", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		limit, _ := strconv.Atoi(c.Query("limit"))
+		return endpoint.GetProjectEvents(ctx, &GetProjectEventsRequest{ProjectID: c.Param("id"), ResourceID: c.Query("resource_id"), Limit: limit})
+	}))
+	api.GET("/projects/:id/streamgroups", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		return endpoint.GetProjectStreamGroups(ctx, &GetProjectStreamGroupsRequest{ProjectID: c.Param("id")})
+	}))
+	api.GET("/streamgroups/:id", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		return endpoint.GetStreamGroup(ctx, &GetStreamGroupRequest{ID: c.Param("id")})
+	}))
+	api.POST("/streamgroups/:id/reset", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		request := &ResetStreamGroupRequest{}
+		err := bindJSON(c, request)
+		if err != nil {
+			return nil, err
+		}
+		request.ID = c.Param("id")
+		return endpoint.ResetStreamGroup(ctx, request)
+	}))
+	api.PATCH("/streamgroups/:id", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		request := &UpdateStreamGroupFiltersRequest{}
+		err := bindJSON(c, request)
+		if err != nil {
+			return nil, err
+		}
+		request.ID = c.Param("id")
+		return endpoint.UpdateStreamGroupFilters(ctx, request)
+	}))
+	api.PUT("/streamgroups/:id/snapshots", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		request := &SetStreamGroupSnapshotsRequest{}
+		err := bindJSON(c, request)
+		if err != nil {
+			return nil, err
+		}
+		request.ID = c.Param("id")
+		return endpoint.SetStreamGroupSnapshots(ctx, request)
+	}))
+	api.DELETE("/streamgroups/:id", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		return endpoint.DeleteStreamGroup(ctx, &DeleteStreamGroupRequest{ID: c.Param("id")})
+	}))
+
+	api.GET("/projects/:id/trash", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		return endpoint.GetProjectTrash(ctx, &GetProjectTrashRequest{ProjectID: c.Param("id")})
+	}))
+	api.PATCH("/projects/:id/trash", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		request := &UpdateProjectTrashRequest{}
+		err := bindJSON(c, request)
+		if err != nil {
+			return nil, err
+		}
+		request.ProjectID = c.Param("id")
+		return endpoint.UpdateProjectTrash(ctx, request)
+	}))
+	api.POST("/trash/:id/restore", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		return endpoint.RestoreTrashEntry(ctx, &RestoreTrashEntryRequest{ID: c.Param("id")})
+	}))
+
+	api.GET("/projects/:id/usage", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		return endpoint.GetUsage(ctx, &GetUsageRequest{
+			Resource: v1storagemodels.Resource_RESOURCE_PROJECT,
+			ID:       c.Param("id"),
+			Start:    c.Query("start"),
+			End:      c.Query("end"),
+		})
+	}))
+	api.GET("/datasets/:id/usage", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		return endpoint.GetUsage(ctx, &GetUsageRequest{
+			Resource: v1storagemodels.Resource_RESOURCE_DATASET,
+			ID:       c.Param("id"),
+			Start:    c.Query("start"),
+			End:      c.Query("end"),
+		})
+	}))
+
+	api.GET("/search", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		size, _ := strconv.Atoi(c.Query("size"))
+		from, _ := strconv.Atoi(c.Query("from"))
+		return endpoint.Search(ctx, &SearchRequest{
+			Query:     c.Query("q"),
+			ProjectID: c.Query("project_id"),
+			Resources: c.QueryArray("resource"),
+			Size:      size,
+			From:      from,
+		})
+	}))
+	api.GET("/projects/:id/labelquery", handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+		pageSize, _ := strconv.ParseInt(c.Query("page_size"), 10, 64)
+		return endpoint.QueryProjectLabels(ctx, &QueryProjectLabelsRequest{
+			ProjectID: c.Param("id"),
+			Resource:  c.Query("resource"),
+			Query:     c.Query("q"),
+			PageSize:  pageSize,
+			Cursor:    c.Query("cursor"),
+			LastUUID:  c.Query("last_uuid"),
+			Sort:      c.Query("sort"),
+			Statuses:  c.QueryArray("status"),
+		})
+	}))
+
+	for path, resource := range editableResourcePaths {
+		resource := resource
+		api.PATCH(fmt.Sprintf("/%v/:id", path), handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+			request := &UpdateResourceRequest{}
+			err := bindJSON(c, request)
+			if err != nil {
+				return nil, err
+			}
+			request.Resource = resource
+			request.ID = c.Param("id")
+			return endpoint.UpdateResource(ctx, request)
+		}))
+		api.PATCH(fmt.Sprintf("/%v/:id/labels", path), handleJSON(func(ctx context.Context, c *gin.Context) (interface{}, error) {
+			request := &UpdateResourceLabelsRequest{}
+			err := bindJSON(c, request)
+			if err != nil {
+				return nil, err
+			}
+			request.Resource = resource
+			request.ID = c.Param("id")
+			return endpoint.UpdateResourceLabels(ctx, request)
+		}))
+	}
+}
+
+// Wraps an endpoint function into a gin handler
+// The http headers of the request are passed as incoming gRPC metadata to the endpoint function, the returned
+// response is encoded as json. Returned gRPC status errors are translated into the corresponding http status codes.
+func handleJSON(handler func(ctx context.Context, c *gin.Context) (interface{}, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		md := metadata.MD{}
+		for key, values := range c.Request.Header {
+			md.Append(key, values...)
+		}
+
+		ctx := metadata.NewIncomingContext(c.Request.Context(), md)
+
+		response, err := handler(ctx, c)
+		if err != nil {
+			log.Debug(err.Error())
+			errStatus, _ := status.FromError(err)
+			c.AbortWithStatusJSON(httpStatusFromCode(errStatus.Code()), gin.H{"error": errStatus.Message()})
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// Decodes the json body of a request, returns an InvalidArgument error if the body can not be parsed
+func bindJSON(c *gin.Context, request interface{}) error {
+	err := c.ShouldBindJSON(request)
+	if err != nil {
+		log.Debug(err.Error())
+		return status.Error(codes.InvalidArgument, "could not parse request body")
+	}
+
+	return nil
+}
+
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
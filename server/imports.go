@@ -0,0 +1,235 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	"github.com/ScienceObjectsDB/CORE-Server/streamingserver"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ImportJob Representation of an archive import job and its progress
+type ImportJob struct {
+	ID                  string            `json:"id"`
+	ProjectID           string            `json:"project_id"`
+	DatasetID           string            `json:"dataset_id"`
+	Format              string            `json:"format"`
+	Status              string            `json:"status"`
+	Message             string            `json:"message,omitempty"`
+	CreatedBy           string            `json:"created_by"`
+	Created             time.Time         `json:"created"`
+	Started             *time.Time        `json:"started,omitempty"`
+	Finished            *time.Time        `json:"finished,omitempty"`
+	ArchiveSize         int64             `json:"archive_size"`
+	ReadBytes           int64             `json:"read_bytes"`
+	ProcessedEntries    int64             `json:"processed_entries"`
+	FailedEntries       int64             `json:"failed_entries"`
+	ImportedObjects     int64             `json:"imported_objects"`
+	ImportedBytes       int64             `json:"imported_bytes"`
+	CreatedObjectGroups int64             `json:"created_object_groups"`
+	Errors              []*ImportJobError `json:"errors"`
+}
+
+// ImportJobError An archive entry that could not be imported
+type ImportJobError struct {
+	Entry   string `json:"entry"`
+	Message string `json:"message"`
+}
+
+type CreateImportJobRequest struct {
+	DatasetID string `json:"dataset_id"`
+	// Format of the archive: tar, targz or zip
+	Format string `json:"format"`
+}
+
+type CreateImportJobResponse struct {
+	Job *ImportJob `json:"job"`
+	// Link of the data streaming server, the archive can be uploaded with a PUT request, the import starts after the upload
+	UploadURL string `json:"upload_url"`
+	// Presigned object storage link, the import has to be started explicitly after the upload
+	PresignedUploadURL string `json:"presigned_upload_url"`
+}
+
+type StartImportJobRequest struct {
+	ID string `json:"id"`
+}
+
+type StartImportJobResponse struct {
+	Job *ImportJob `json:"job"`
+}
+
+type GetImportJobRequest struct {
+	ID string `json:"id"`
+}
+
+type GetImportJobResponse struct {
+	Job *ImportJob `json:"job"`
+}
+
+// CreateImportJob Creates a job that imports an archive into a dataset and returns the links to upload the archive
+func (endpoint *HTTPEndpoints) CreateImportJob(ctx context.Context, request *CreateImportJobRequest) (*CreateImportJobResponse, error) {
+	datasetID, err := uuid.Parse(request.DatasetID)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, status.Error(codes.InvalidArgument, "could not parse dataset id")
+	}
+
+	format, err := streamingserver.ParseImportFormat(request.Format)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, status.Error(codes.InvalidArgument, "archive format has to be one of tar, targz or zip")
+	}
+
+	dataset, err := endpoint.ReadHandler.GetDataset(datasetID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.NotFound, "could not find dataset")
+	}
+
+	metadata, _ := metadata.FromIncomingContext(ctx)
+
+	err = endpoint.AuthzHandler.Authorize(
+		dataset.ProjectID,
+		v1storagemodels.Right_RIGHT_WRITE,
+		metadata)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	userID, err := endpoint.AuthzHandler.GetUserID(metadata)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	job, err := endpoint.Importer.ImportHandler.CreateImportJob(dataset, string(format), userID.String())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not create import job")
+	}
+
+	uploadURL, err := endpoint.Importer.ImportHandler.CreateImportUploadURL(job)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not create upload link")
+	}
+
+	presignedUploadURL, err := endpoint.ObjectHandler.CreateUploadLink(streamingserver.StagingLocation(job))
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not create upload link")
+	}
+
+	return &CreateImportJobResponse{
+		Job:                importJobFromModel(job),
+		UploadURL:          uploadURL,
+		PresignedUploadURL: presignedUploadURL,
+	}, nil
+}
+
+// StartImportJob Starts the import after the archive has been uploaded via the presigned upload link
+func (endpoint *HTTPEndpoints) StartImportJob(ctx context.Context, request *StartImportJobRequest) (*StartImportJobResponse, error) {
+	job, err := endpoint.authorizeImportJob(ctx, request.ID, v1storagemodels.Right_RIGHT_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	started, err := endpoint.Importer.StartImport(job, models.ImportJobStatusWaitingForUpload)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.FailedPrecondition, "could not start import, the archive has to be uploaded first")
+	}
+
+	if !started {
+		return nil, status.Error(codes.FailedPrecondition, "import job has already been started")
+	}
+
+	return &StartImportJobResponse{
+		Job: importJobFromModel(job),
+	}, nil
+}
+
+// GetImportJob Returns the progress and the entry errors of an import job
+func (endpoint *HTTPEndpoints) GetImportJob(ctx context.Context, request *GetImportJobRequest) (*GetImportJobResponse, error) {
+	job, err := endpoint.authorizeImportJob(ctx, request.ID, v1storagemodels.Right_RIGHT_READ)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetImportJobResponse{
+		Job: importJobFromModel(job),
+	}, nil
+}
+
+// Reads an import job and checks if the caller has the requested right on its project
+func (endpoint *HTTPEndpoints) authorizeImportJob(ctx context.Context, id string, right v1storagemodels.Right) (*models.ImportJob, error) {
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, status.Error(codes.InvalidArgument, "could not parse import job id")
+	}
+
+	job, err := endpoint.Importer.ImportHandler.GetImportJob(jobID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.NotFound, "could not find import job")
+	}
+
+	metadata, _ := metadata.FromIncomingContext(ctx)
+
+	err = endpoint.AuthzHandler.Authorize(
+		job.ProjectID,
+		right,
+		metadata)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func importJobFromModel(job *models.ImportJob) *ImportJob {
+	importJob := &ImportJob{
+		ID:                  job.ID.String(),
+		ProjectID:           job.ProjectID.String(),
+		DatasetID:           job.DatasetID.String(),
+		Format:              job.Format,
+		Status:              job.Status,
+		Message:             job.Message,
+		CreatedBy:           job.CreatedBy,
+		Created:             job.CreatedAt,
+		ArchiveSize:         job.ArchiveSize,
+		ReadBytes:           job.ReadBytes,
+		ProcessedEntries:    job.ProcessedEntries,
+		FailedEntries:       job.FailedEntries,
+		ImportedObjects:     job.ImportedObjects,
+		ImportedBytes:       job.ImportedBytes,
+		CreatedObjectGroups: job.CreatedObjectGroups,
+		Errors:              make([]*ImportJobError, len(job.Errors)),
+	}
+
+	if !job.StartedAt.IsZero() {
+		importJob.Started = &job.StartedAt
+	}
+
+	if !job.FinishedAt.IsZero() {
+		importJob.Finished = &job.FinishedAt
+	}
+
+	for i, importError := range job.Errors {
+		importJob.Errors[i] = &ImportJobError{
+			Entry:   importError.Entry,
+			Message: importError.Message,
+		}
+	}
+
+	return importJob
+}
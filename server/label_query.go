@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Number of results that are returned if no page size is requested
+const defaultLabelQueryPageSize = 100
+
+// Maximum number of results of a single label query request
+const maxLabelQueryPageSize = 1000
+
+type QueryProjectLabelsRequest struct {
+	ProjectID string `json:"project_id"`
+	// One of datasets, objectgroups, objectgrouprevisions or objects, defaults to objectgroups
+	Resource string `json:"resource"`
+	Query    string `json:"query"`
+	PageSize int64  `json:"page_size"`
+	// Next cursor of the previous page
+	Cursor string `json:"cursor"`
+	// Id of the last result of the previous page, only for pages sorted by id
+	LastUUID string `json:"last_uuid"`
+	// Sort key, one of id, created, name, revision or generated, a leading "-" sorts in descending order
+	Sort string `json:"sort"`
+	// Status enum names, e.g. STATUS_AVAILABLE
+	Statuses []string `json:"statuses"`
+}
+
+type QueryProjectLabelsResponse struct {
+	// Proto json of the matching resources in the requested order
+	Results []json.RawMessage `json:"results"`
+	// Cursor of the next page, empty if there are no further results
+	NextCursor string `json:"next_cursor"`
+	// Id of the last result, empty if there are no further results
+	LastUUID string `json:"last_uuid"`
+}
+
+// QueryProjectLabels Returns the datasets, object groups, object group revisions or objects of a project that match a label query
+func (endpoint *HTTPEndpoints) QueryProjectLabels(ctx context.Context, request *QueryProjectLabelsRequest) (*QueryProjectLabelsResponse, error) {
+	projectID, err := endpoint.authorizeProject(ctx, request.ProjectID, v1storagemodels.Right_RIGHT_READ)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := database.ParseLabelQuery(request.Query)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, status.Error(codes.InvalidArgument, "could not parse label query: "+err.Error())
+	}
+
+	pageSize := request.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultLabelQueryPageSize
+	}
+	if pageSize > maxLabelQueryPageSize {
+		pageSize = maxLabelQueryPageSize
+	}
+
+	page := &database.Page{
+		Size:     int(pageSize),
+		Cursor:   request.Cursor,
+		Sort:     request.Sort,
+		Statuses: request.Statuses,
+	}
+	if page.Cursor == "" {
+		page.Cursor = request.LastUUID
+	}
+
+	resource := request.Resource
+	if resource == "" {
+		resource = "objectgroups"
+	}
+
+	var results []proto.Message
+	var nextCursor string
+	switch resource {
+	case "datasets":
+		datasets, next, err := endpoint.ReadHandler.FindProjectDatasets(projectID, query, page)
+		if err != nil {
+			return nil, err
+		}
+		nextCursor = next
+		for _, dataset := range datasets {
+			protoDataset, err := dataset.ToProtoModel(nil)
+			if err != nil {
+				log.Errorln(err.Error())
+				return nil, status.Error(codes.Internal, "could not transform dataset into protobuf representation")
+			}
+			results = append(results, protoDataset)
+		}
+	case "objectgroups":
+		objectGroups, next, err := endpoint.ReadHandler.FindProjectObjectGroups(projectID, query, page)
+		if err != nil {
+			return nil, err
+		}
+		nextCursor = next
+		for _, objectGroup := range objectGroups {
+			protoObjectGroup, err := objectGroup.ToProtoModel(nil)
+			if err != nil {
+				log.Errorln(err.Error())
+				return nil, status.Error(codes.Internal, "could not transform objectgroup into protobuf representation")
+			}
+			results = append(results, protoObjectGroup)
+		}
+	case "objectgrouprevisions":
+		revisions, next, err := endpoint.ReadHandler.FindProjectObjectGroupRevisions(projectID, query, page)
+		if err != nil {
+			return nil, err
+		}
+		nextCursor = next
+		for _, revision := range revisions {
+			protoRevision, err := revision.ToProtoModel(nil)
+			if err != nil {
+				log.Errorln(err.Error())
+				return nil, status.Error(codes.Internal, "could not transform objectgroup revision into protobuf representation")
+			}
+			results = append(results, protoRevision)
+		}
+	case "objects":
+		objects, next, err := endpoint.ReadHandler.FindProjectObjects(projectID, query, page)
+		if err != nil {
+			return nil, err
+		}
+		nextCursor = next
+		for _, object := range objects {
+			protoObject, err := object.ToProtoModel()
+			if err != nil {
+				log.Errorln(err.Error())
+				return nil, status.Error(codes.Internal, "could not transform object into protobuf representation")
+			}
+			results = append(results, protoObject)
+		}
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown resource %v, expected datasets, objectgroups, objectgrouprevisions or objects", resource)
+	}
+
+	response := &QueryProjectLabelsResponse{
+		Results:    make([]json.RawMessage, len(results)),
+		NextCursor: nextCursor,
+	}
+	for i, result := range results {
+		encoded, err := protojson.Marshal(result)
+		if err != nil {
+			log.Errorln(err.Error())
+			return nil, status.Error(codes.Internal, "could not encode result")
+		}
+		response.Results[i] = encoded
+	}
+
+	if nextCursor != "" {
+		response.LastUUID = resultID(results[len(results)-1])
+	}
+
+	return response, nil
+}
+
+// Returns the id of a dataset, object group, object group revision or object
+func resultID(result proto.Message) string {
+	if resource, ok := result.(interface{ GetId() string }); ok {
+		return resource.GetId()
+	}
+
+	return ""
+}
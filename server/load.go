@@ -273,16 +273,21 @@ func (endpoint *LoadEndpoints) CreateDownloadLinkStream(request *v1storageservic
 	}
 
 	for objectGroupBatch := range objectGroupsChan {
+		revisionIDs := make([]uuid.UUID, len(objectGroupBatch))
+		for i, objectGroup := range objectGroupBatch {
+			revisionIDs[i] = objectGroup.CurrentObjectGroupRevision.ID
+		}
+
+		revisionStats, err := endpoint.StatsHandler.GetObjectGroupRevisionsStats(revisionIDs)
+		if err != nil {
+			log.Errorln(err.Error())
+			return err
+		}
+
 		objectGroupRevisions := make([]*v1storagemodels.ObjectGroupRevision, len(objectGroupBatch))
 		links := make([]*v1storageservices.InnerLinksResponse, len(objectGroupBatch))
 		for i, objectGroup := range objectGroupBatch {
-			objectGroupRevisionStats, err := endpoint.StatsHandler.GetObjectGroupRevisionStats(&objectGroup.CurrentObjectGroupRevision)
-			if err != nil {
-				log.Errorln(err.Error())
-				return err
-			}
-
-			protoObjectGroup, err := objectGroup.CurrentObjectGroupRevision.ToProtoModel(objectGroupRevisionStats)
+			protoObjectGroup, err := objectGroup.CurrentObjectGroupRevision.ToProtoModel(revisionStats[objectGroup.CurrentObjectGroupRevision.ID])
 			if err != nil {
 				log.Errorln(err.Error())
 				return status.Error(codes.Internal, "could not transform objectgroup into protobuf representation")
@@ -309,7 +314,7 @@ func (endpoint *LoadEndpoints) CreateDownloadLinkStream(request *v1storageservic
 			},
 		}
 
-		err := responseStream.Send(batchResponse)
+		err = responseStream.Send(batchResponse)
 		if err != nil {
 			log.Println(err.Error())
 			return err
@@ -444,6 +449,12 @@ func (endpoint *LoadEndpoints) CompleteMultipartUpload(ctx context.Context, requ
 		return nil, err
 	}
 
+	err = endpoint.UpdateHandler.CompleteMultipartUpload(object.ID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
 	response := &v1storageservices.CompleteMultipartUploadResponse{}
 
 	return response, nil
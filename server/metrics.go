@@ -0,0 +1,29 @@
+package server
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MetricsServer Serves the expvar metrics of the server, e.g. the pending chunks of the notification streams
+// The metrics are not authenticated, they are served on a separate internal address instead of the public apis.
+type MetricsServer struct {
+	Address string
+}
+
+// Run Serves the metrics under /debug/vars, an empty address disables the metrics server
+func (metrics *MetricsServer) Run() error {
+	if metrics.Address == "" {
+		log.Infoln("metrics server is disabled")
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	log.Println(fmt.Sprintf("Starting metrics server on %v", metrics.Address))
+	return http.ListenAndServe(metrics.Address, mux)
+}
@@ -2,8 +2,10 @@ package server
 
 import (
 	"context"
+	"errors"
 	"io"
 
+	"github.com/ScienceObjectsDB/CORE-Server/eventstreaming"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
@@ -60,6 +62,12 @@ func (notificationEndpoints *NotificationEndpoints) CreateEventStreamingGroup(ct
 		}
 	}
 
+	start, err := eventstreaming.StreamGroupStartFromRequest(request)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	err = notificationEndpoints.AuthzHandler.Authorize(
 		projectUUID,
 		v1storagemodels.Right_RIGHT_WRITE,
@@ -69,7 +77,7 @@ func (notificationEndpoints *NotificationEndpoints) CreateEventStreamingGroup(ct
 		return nil, err
 	}
 
-	streamGroup, err := notificationEndpoints.EventStreamMgmt.CreateStreamGroup(projectUUID, resourceUUID, &request.Resource, request.IncludeSubresource)
+	streamGroup, err := notificationEndpoints.EventStreamMgmt.CreateStreamGroup(projectUUID, resourceUUID, &request.Resource, request.IncludeSubresource, start)
 	if err != nil {
 		log.Errorln(err.Error())
 		return nil, err
@@ -124,10 +132,11 @@ func (notificationEndpoints *NotificationEndpoints) NotificationStreamGroup(stre
 		return err
 	}
 
-	errgrp := &errgroup.Group{}
+	// The streamer is stopped as soon as the client disconnects or one of the goroutines fails
+	errgrp, ctx := errgroup.WithContext(stream.Context())
 
 	errgrp.Go(func() error {
-		err = internalStreamer.StartStream()
+		err := internalStreamer.StartStream(ctx)
 		if err != nil {
 			log.Errorln(err.Error())
 			return err
@@ -138,7 +147,7 @@ func (notificationEndpoints *NotificationEndpoints) NotificationStreamGroup(stre
 
 	errgrp.Go(func() error {
 		for notification := range internalStreamer.GetResponseMessageChan() {
-			err = stream.Send(notification)
+			err := stream.Send(notification)
 			if err == io.EOF {
 				return nil
 			}
@@ -149,14 +158,14 @@ func (notificationEndpoints *NotificationEndpoints) NotificationStreamGroup(stre
 			}
 		}
 
-		return status.Error(codes.Internal, "internal channel closed unexpectedly")
+		return nil
 	})
 
 	errgrp.Go(func() error {
 		for {
 			request, err := stream.Recv()
 			if err == io.EOF {
-				return nil
+				return internalStreamer.CloseStream()
 			}
 
 			if err != nil {
@@ -171,6 +180,10 @@ func (notificationEndpoints *NotificationEndpoints) NotificationStreamGroup(stre
 
 			for _, ackChunkID := range ackRequest.GetAckChunkId() {
 				err = internalStreamer.AckChunk(ackChunkID)
+				if errors.Is(err, eventstreaming.ErrUnknownChunk) {
+					log.Debugln(err.Error())
+					continue
+				}
 				if err != nil {
 					log.Errorln(err.Error())
 					return err
@@ -8,7 +8,6 @@ import (
 
 	"github.com/ScienceObjectsDB/CORE-Server/database"
 	"github.com/ScienceObjectsDB/CORE-Server/models"
-	v1notficationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
 	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
 	v1storageservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/services/v1"
 	"google.golang.org/grpc/codes"
@@ -73,17 +72,6 @@ func (endpoint *ObjectServerEndpoints) CreateObjectGroup(ctx context.Context, re
 		},
 	}
 
-	err = endpoint.EventStreamMgmt.PublishMessage(&v1notficationservices.EventNotificationMessage{
-		Resource:    v1storagemodels.Resource_RESOURCE_OBJECT_GROUP,
-		ResourceId:  objectGroupResponse.ObjectGroupId,
-		UpdatedType: v1notficationservices.EventNotificationMessage_UPDATE_TYPE_CREATED,
-	})
-
-	if err != nil {
-		log.Errorln(err.Error())
-		return nil, status.Error(codes.Internal, "could not publish notification event")
-	}
-
 	return objectGroupResponse, nil
 }
 
@@ -184,19 +172,6 @@ func (endpoint *ObjectServerEndpoints) CreateObjectGroupBatch(ctx context.Contex
 		Responses: objectgroupResponseList,
 	}
 
-	for _, createdObjectGroup := range objectgroupResponseList {
-		err = endpoint.EventStreamMgmt.PublishMessage(&v1notficationservices.EventNotificationMessage{
-			Resource:    v1storagemodels.Resource_RESOURCE_OBJECT_GROUP,
-			ResourceId:  createdObjectGroup.GetObjectGroupId(),
-			UpdatedType: v1notficationservices.EventNotificationMessage_UPDATE_TYPE_CREATED,
-		})
-
-		if err != nil {
-			log.Println(err.Error())
-			return nil, err
-		}
-	}
-
 	return response, nil
 }
 
@@ -314,15 +289,13 @@ func (endpoint *ObjectServerEndpoints) UpdateObjectGroup(ctx context.Context, re
 		return nil, err
 	}
 
-	objectGroupRevision, err := endpoint.UpdateHandler.UpdateObjectGroup(request, &objectGroup.Dataset, &objectGroup.Project, objectGroup)
+	replaceLabels, err := database.ParseReplaceLabels(metadata)
 	if err != nil {
-		log.Errorln(err.Error())
+		log.Debug(err.Error())
 		return nil, err
 	}
 
-	msg := &v1notficationservices.EventNotificationMessage{Resource: v1storagemodels.Resource_RESOURCE_OBJECT_GROUP, ResourceId: objectGroupRevision.ObjectGroupID.String(), UpdatedType: v1notficationservices.EventNotificationMessage_UPDATE_TYPE_UPDATED}
-
-	err = endpoint.EventStreamMgmt.PublishMessage(msg)
+	_, err = endpoint.UpdateHandler.UpdateObjectGroup(request, &objectGroup.Dataset, &objectGroup.Project, objectGroup, replaceLabels)
 	if err != nil {
 		log.Errorln(err.Error())
 		return nil, err
@@ -356,6 +329,12 @@ func (endpoint *ObjectServerEndpoints) FinishObjectUpload(ctx context.Context, r
 		return nil, err
 	}
 
+	err = endpoint.UpdateHandler.FinishObjectUpload(object.ID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
 	finished := &v1storageservices.FinishObjectUploadResponse{}
 
 	return finished, nil
@@ -392,14 +371,6 @@ func (endpoint *ObjectServerEndpoints) FinishObjectGroupRevisionUpload(ctx conte
 		return nil, status.Error(codes.Internal, "could not finish objectgroup revision")
 	}
 
-	msg := &v1notficationservices.EventNotificationMessage{Resource: v1storagemodels.Resource_RESOURCE_OBJECT_GROUP, ResourceId: objectGroupRevision.ObjectGroupID.String(), UpdatedType: v1notficationservices.EventNotificationMessage_UPDATE_TYPE_AVAILABLE}
-
-	err = endpoint.EventStreamMgmt.PublishMessage(msg)
-	if err != nil {
-		log.Errorln(err.Error())
-		return nil, err
-	}
-
 	finished := &v1storageservices.FinishObjectGroupRevisionUploadResponse{}
 
 	return finished, nil
@@ -429,36 +400,6 @@ func (endpoint *ObjectServerEndpoints) DeleteObjectGroup(ctx context.Context, re
 		return nil, err
 	}
 
-	objects, err := endpoint.ReadHandler.GetAllObjectGroupObjects(requestID)
-	if err != nil {
-		log.Println(err.Error())
-		return nil, err
-	}
-
-	var locations []*models.Location
-	for _, object := range objects {
-		locations = append(locations, &object.DefaultLocation)
-	}
-
-	if len(objects) != 0 {
-		err = endpoint.ObjectHandler.DeleteObjects(locations)
-		if err != nil {
-			log.Println(err.Error())
-			return nil, err
-		}
-	}
-
-	err = endpoint.EventStreamMgmt.PublishMessage(&v1notficationservices.EventNotificationMessage{
-		Resource:    v1storagemodels.Resource_RESOURCE_OBJECT_GROUP,
-		ResourceId:  request.GetId(),
-		UpdatedType: v1notficationservices.EventNotificationMessage_UPDATE_TYPE_DELETED,
-	})
-
-	if err != nil {
-		log.Println(err.Error())
-		return nil, err
-	}
-
 	err = endpoint.DeleteHandler.DeleteObjectGroup(requestID)
 	if err != nil {
 		log.Println(err.Error())
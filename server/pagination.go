@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys of the page parameters of list requests
+// The page size and the cursor of a PageRequest in the request message take precedence over the metadata.
+const (
+	pageSizeMetadataKey   = "page-size"
+	pageCursorMetadataKey = "page-cursor"
+	// Sort key, one of id, created, name, revision or generated, a leading "-" sorts in descending order
+	pageSortMetadataKey = "page-sort"
+	// Comma separated status enum names, e.g. STATUS_AVAILABLE
+	pageStatusMetadataKey = "page-status"
+	// Response header with the cursor of the next page, it is missing on the last page
+	nextPageCursorMetadataKey = "next-page-cursor"
+)
+
+// Returns the requested page of a list request, pageRequest can be nil for requests without PageRequest
+func (endpoint *Endpoints) requestPage(ctx context.Context, pageRequest *v1storagemodels.PageRequest) (*database.Page, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	page := &database.Page{
+		Cursor: pageRequest.GetLastUuid(),
+		Sort:   firstMetadataValue(md, pageSortMetadataKey),
+	}
+
+	if page.Cursor == "" {
+		page.Cursor = firstMetadataValue(md, pageCursorMetadataKey)
+	}
+
+	for _, value := range md.Get(pageStatusMetadataKey) {
+		for _, pageStatus := range strings.Split(value, ",") {
+			if pageStatus = strings.TrimSpace(pageStatus); pageStatus != "" {
+				page.Statuses = append(page.Statuses, pageStatus)
+			}
+		}
+	}
+
+	size := int(pageRequest.GetPageSize())
+	if value := firstMetadataValue(md, pageSizeMetadataKey); size == 0 && value != "" {
+		var err error
+		size, err = strconv.Atoi(value)
+		if err != nil {
+			log.Debug(err.Error())
+			return nil, status.Error(codes.InvalidArgument, "could not parse page size")
+		}
+	}
+
+	page.Size = endpoint.pageSize(size)
+
+	return page, nil
+}
+
+// Returns the requested page size limited to the maximum page size, the default page size is used if none is requested
+func (endpoint *Endpoints) pageSize(requested int) int {
+	if requested <= 0 {
+		requested = endpoint.DefaultPageSize
+	}
+
+	if endpoint.MaxPageSize > 0 && (requested <= 0 || requested > endpoint.MaxPageSize) {
+		requested = endpoint.MaxPageSize
+	}
+
+	return requested
+}
+
+// Sends the cursor of the next page as response header
+func setNextPageCursor(ctx context.Context, cursor string) {
+	if cursor == "" {
+		return
+	}
+
+	err := grpc.SetHeader(ctx, metadata.Pairs(nextPageCursorMetadataKey, cursor))
+	if err != nil {
+		// Calls that are not served by the grpc server, e.g. from tests, can not send headers
+		log.Debug(err.Error())
+	}
+}
+
+func firstMetadataValue(md metadata.MD, key string) string {
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
@@ -7,7 +7,6 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/ScienceObjectsDB/CORE-Server/models"
-	v1notficationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
 	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
 	v1storageservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/services/v1"
 	"google.golang.org/grpc/codes"
@@ -54,17 +53,6 @@ func (endpoint *ProjectEndpoints) CreateProject(ctx context.Context, request *v1
 		Id: projectID,
 	}
 
-	err = endpoint.EventStreamMgmt.PublishMessage(&v1notficationservices.EventNotificationMessage{
-		Resource:    v1storagemodels.Resource(v1notficationservices.CreateEventStreamingGroupRequest_EVENT_RESOURCES_PROJECT_RESOURCE),
-		ResourceId:  projectID,
-		UpdatedType: v1notficationservices.EventNotificationMessage_UPDATE_TYPE_CREATED,
-	})
-
-	if err != nil {
-		log.Errorln(err.Error())
-		return nil, status.Error(codes.Internal, "could not publish notification event")
-	}
-
 	return response, nil
 }
 
@@ -142,7 +130,7 @@ func (endpoint *ProjectEndpoints) CreateAPIToken(ctx context.Context, request *v
 	return response, nil
 }
 
-//GetProjectDatasets Returns all datasets that belong to a certain project
+//GetProjectDatasets Returns a page of the datasets that belong to a certain project
 func (endpoint *ProjectEndpoints) GetProjectDatasets(ctx context.Context, request *v1storageservices.GetProjectDatasetsRequest) (*v1storageservices.GetProjectDatasetsResponse, error) {
 	requestID, err := uuid.Parse(request.GetId())
 	if err != nil {
@@ -161,11 +149,17 @@ func (endpoint *ProjectEndpoints) GetProjectDatasets(ctx context.Context, reques
 		return nil, err
 	}
 
-	datasets, err := endpoint.ReadHandler.GetProjectDatasets(requestID)
+	page, err := endpoint.requestPage(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	datasets, nextCursor, err := endpoint.ReadHandler.GetProjectDatasets(requestID, page)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
+	setNextPageCursor(ctx, nextCursor)
 
 	var protoDatasets []*v1storagemodels.Dataset
 	for _, dataset := range datasets {
@@ -190,7 +184,7 @@ func (endpoint *ProjectEndpoints) GetProjectDatasets(ctx context.Context, reques
 	return response, nil
 }
 
-//GetUserProjects Returns all projects that a specified user has access to
+//GetUserProjects Returns a page of the projects that a specified user has access to
 func (endpoint *ProjectEndpoints) GetUserProjects(ctx context.Context, request *v1storageservices.GetUserProjectsRequest) (*v1storageservices.GetUserProjectsResponse, error) {
 	metadata, _ := metadata.FromIncomingContext(ctx)
 
@@ -200,11 +194,17 @@ func (endpoint *ProjectEndpoints) GetUserProjects(ctx context.Context, request *
 		return nil, err
 	}
 
-	projects, err := endpoint.ReadHandler.GetUserProjects(userOauth2ID.String())
+	page, err := endpoint.requestPage(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, nextCursor, err := endpoint.ReadHandler.GetUserProjects(userOauth2ID.String(), page)
 	if err != nil {
 		log.Errorln(err.Error())
 		return nil, err
 	}
+	setNextPageCursor(ctx, nextCursor)
 
 	var protoProjects []*v1storagemodels.Project
 
@@ -282,11 +282,17 @@ func (endpoint *ProjectEndpoints) GetAPIToken(ctx context.Context, request *v1st
 		return nil, err
 	}
 
-	tokens, err := endpoint.ReadHandler.GetAPIToken(userID)
+	page, err := endpoint.requestPage(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, nextCursor, err := endpoint.ReadHandler.GetAPIToken(userID, page)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
+	setNextPageCursor(ctx, nextCursor)
 
 	var protoTokens []*v1storagemodels.APIToken
 	for _, token := range tokens {
@@ -321,6 +327,12 @@ func (endpoint *ProjectEndpoints) DeleteProject(ctx context.Context, request *v1
 		return nil, err
 	}
 
+	err = endpoint.TrashHandler.PurgeProjectTrash(requestID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
 	objects, err := endpoint.ReadHandler.GetAllProjectObjects(requestID)
 	if err != nil {
 		log.Println(err.Error())
@@ -338,17 +350,6 @@ func (endpoint *ProjectEndpoints) DeleteProject(ctx context.Context, request *v1
 		return nil, err
 	}
 
-	err = endpoint.EventStreamMgmt.PublishMessage(&v1notficationservices.EventNotificationMessage{
-		Resource:    v1storagemodels.Resource_RESOURCE_PROJECT,
-		ResourceId:  request.GetId(),
-		UpdatedType: v1notficationservices.EventNotificationMessage_UPDATE_TYPE_DELETED,
-	})
-
-	if err != nil {
-		log.Println(err.Error())
-		return nil, err
-	}
-
 	err = endpoint.DeleteHandler.DeleteProject(requestID)
 	if err != nil {
 		log.Println(err.Error())
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Paths of the resources whose metadata and labels can be changed by the http api
+var editableResourcePaths = map[string]v1storagemodels.Resource{
+	"projects":        v1storagemodels.Resource_RESOURCE_PROJECT,
+	"datasets":        v1storagemodels.Resource_RESOURCE_DATASET,
+	"datasetversions": v1storagemodels.Resource_RESOURCE_DATASET_VERSION,
+	"objects":         v1storagemodels.Resource_RESOURCE_OBJECT,
+}
+
+// Label A key value label of a resource
+type Label struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type UpdateResourceRequest struct {
+	Resource v1storagemodels.Resource `json:"-"`
+	ID       string                   `json:"id"`
+	// The filename of objects, released dataset versions can not be changed
+	Name *string `json:"name"`
+	// Objects have no description
+	Description *string `json:"description"`
+}
+
+type UpdateResourceResponse struct {
+}
+
+type UpdateResourceLabelsRequest struct {
+	Resource v1storagemodels.Resource `json:"-"`
+	ID       string                   `json:"id"`
+	// Labels that are added to the resource
+	Add []Label `json:"add"`
+	// Labels that are removed from the resource, labels without a value remove all labels with the key
+	Remove []Label `json:"remove"`
+	// Removes all existing labels before the new labels are added
+	Replace bool `json:"replace"`
+}
+
+type UpdateResourceLabelsResponse struct {
+	Labels []Label `json:"labels"`
+}
+
+// UpdateResource Changes the name or the description of a project, dataset, dataset version or object
+func (endpoint *HTTPEndpoints) UpdateResource(ctx context.Context, request *UpdateResourceRequest) (*UpdateResourceResponse, error) {
+	resourceID, err := endpoint.authorizeResource(ctx, request.Resource, request.ID, v1storagemodels.Right_RIGHT_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Name != nil && *request.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name can not be empty")
+	}
+
+	err = endpoint.UpdateHandler.UpdateMetadata(request.Resource, resourceID, request.Name, request.Description)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return &UpdateResourceResponse{}, nil
+}
+
+// UpdateResourceLabels Adds, removes or replaces the labels of a project, dataset, dataset version or object
+func (endpoint *HTTPEndpoints) UpdateResourceLabels(ctx context.Context, request *UpdateResourceLabelsRequest) (*UpdateResourceLabelsResponse, error) {
+	resourceID, err := endpoint.authorizeResource(ctx, request.Resource, request.ID, v1storagemodels.Right_RIGHT_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	add := make([]models.Label, len(request.Add))
+	for i, label := range request.Add {
+		if label.Key == "" {
+			return nil, status.Error(codes.InvalidArgument, "labels require a key")
+		}
+		add[i] = models.Label{Key: label.Key, Value: label.Value}
+	}
+
+	remove := make([]models.Label, len(request.Remove))
+	for i, label := range request.Remove {
+		if label.Key == "" {
+			return nil, status.Error(codes.InvalidArgument, "labels require a key")
+		}
+		remove[i] = models.Label{Key: label.Key, Value: label.Value}
+	}
+
+	err = endpoint.UpdateHandler.UpdateLabels(request.Resource, resourceID, add, remove, request.Replace)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	labels, err := endpoint.ReadHandler.GetResourceLabels(request.Resource, resourceID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not read labels")
+	}
+
+	response := &UpdateResourceLabelsResponse{
+		Labels: make([]Label, len(labels)),
+	}
+	for i, label := range labels {
+		response.Labels[i] = Label{Key: label.Key, Value: label.Value}
+	}
+
+	return response, nil
+}
+
+// Reads the project of a resource and checks the right of the request on it
+func (endpoint *HTTPEndpoints) authorizeResource(ctx context.Context, resource v1storagemodels.Resource, id string, right v1storagemodels.Right) (uuid.UUID, error) {
+	resourceID, err := uuid.Parse(id)
+	if err != nil {
+		log.Debug(err.Error())
+		return uuid.Nil, status.Error(codes.InvalidArgument, "could not parse id")
+	}
+
+	var projectID uuid.UUID
+
+	switch resource {
+	case v1storagemodels.Resource_RESOURCE_PROJECT:
+		projectID = resourceID
+	case v1storagemodels.Resource_RESOURCE_DATASET:
+		dataset, err := endpoint.ReadHandler.GetDataset(resourceID)
+		if err != nil {
+			log.Println(err.Error())
+			return uuid.Nil, status.Error(codes.NotFound, "could not find dataset")
+		}
+		projectID = dataset.ProjectID
+	case v1storagemodels.Resource_RESOURCE_DATASET_VERSION:
+		version, err := endpoint.ReadHandler.GetDatasetVersion(resourceID)
+		if err != nil {
+			log.Println(err.Error())
+			return uuid.Nil, status.Error(codes.NotFound, "could not find dataset version")
+		}
+		projectID = version.ProjectID
+	case v1storagemodels.Resource_RESOURCE_OBJECT:
+		object, err := endpoint.ReadHandler.GetObject(resourceID)
+		if err != nil {
+			log.Println(err.Error())
+			return uuid.Nil, status.Error(codes.NotFound, "could not find object")
+		}
+		projectID = object.ProjectID
+	default:
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "resource %v can not be changed", resource.String())
+	}
+
+	metadata, _ := metadata.FromIncomingContext(ctx)
+
+	err = endpoint.AuthzHandler.Authorize(
+		projectID,
+		right,
+		metadata)
+	if err != nil {
+		log.Println(err.Error())
+		return uuid.Nil, err
+	}
+
+	return resourceID, nil
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+
+	"github.com/ScienceObjectsDB/CORE-Server/search"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Number of hits that are returned if no size is requested
+const defaultSearchSize = 20
+
+// Maximum number of hits of a single search request
+const maxSearchSize = 100
+
+// Resources that are indexed for the search
+var searchableResources = map[string]struct{}{
+	v1storagemodels.Resource_RESOURCE_PROJECT.String():      {},
+	v1storagemodels.Resource_RESOURCE_DATASET.String():      {},
+	v1storagemodels.Resource_RESOURCE_OBJECT_GROUP.String(): {},
+	v1storagemodels.Resource_RESOURCE_OBJECT.String():       {},
+}
+
+// SearchHit A resource that matches a search, ordered by the score
+type SearchHit struct {
+	ID        string  `json:"id"`
+	Resource  string  `json:"resource"`
+	ProjectID string  `json:"project_id"`
+	DatasetID string  `json:"dataset_id,omitempty"`
+	Name      string  `json:"name"`
+	Score     float64 `json:"score"`
+}
+
+type SearchRequest struct {
+	Query string `json:"query"`
+	// Optional project, all projects of the user are searched if it is empty
+	ProjectID string `json:"project_id"`
+	// Resource enum names, e.g. RESOURCE_DATASET, all resources are searched if it is empty
+	Resources []string `json:"resources"`
+	Size      int      `json:"size"`
+	From      int      `json:"from"`
+}
+
+type SearchResponse struct {
+	Hits []*SearchHit `json:"hits"`
+	// Number of all matches, hits beyond size can be requested with from
+	Total uint64 `json:"total"`
+}
+
+// Search Searches the names, descriptions, filenames and labels of the projects the caller has read access to
+func (endpoint *HTTPEndpoints) Search(ctx context.Context, request *SearchRequest) (*SearchResponse, error) {
+	if endpoint.SearchIndex == nil {
+		return nil, status.Error(codes.Unimplemented, "search is not enabled")
+	}
+
+	for _, resource := range request.Resources {
+		if _, ok := searchableResources[resource]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "resource %v can not be searched", resource)
+		}
+	}
+
+	var projectIDs []string
+	if request.ProjectID != "" {
+		projectID, err := endpoint.authorizeProject(ctx, request.ProjectID, v1storagemodels.Right_RIGHT_READ)
+		if err != nil {
+			return nil, err
+		}
+		projectIDs = append(projectIDs, projectID.String())
+	} else {
+		var err error
+		projectIDs, err = endpoint.readableProjects(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	size := request.Size
+	if size <= 0 {
+		size = defaultSearchSize
+	}
+	if size > maxSearchSize {
+		size = maxSearchSize
+	}
+
+	from := request.From
+	if from < 0 {
+		from = 0
+	}
+
+	result, err := endpoint.SearchIndex.Search(&search.Request{
+		Query:      request.Query,
+		ProjectIDs: projectIDs,
+		Resources:  request.Resources,
+		Size:       size,
+		From:       from,
+	})
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, status.Error(codes.Internal, "could not search")
+	}
+
+	response := &SearchResponse{
+		Hits:  make([]*SearchHit, len(result.Hits)),
+		Total: result.Total,
+	}
+	for i, hit := range result.Hits {
+		response.Hits[i] = &SearchHit{
+			ID:        hit.ID,
+			Resource:  hit.Resource,
+			ProjectID: hit.ProjectID,
+			DatasetID: hit.DatasetID,
+			Name:      hit.Name,
+			Score:     hit.Score,
+		}
+	}
+
+	return response, nil
+}
+
+// Returns the ids of the projects of the user of the request that the request is allowed to read
+// Api tokens are only authorized for their own project.
+func (endpoint *HTTPEndpoints) readableProjects(ctx context.Context) ([]string, error) {
+	metadata, _ := metadata.FromIncomingContext(ctx)
+
+	userID, err := endpoint.AuthzHandler.GetUserID(metadata)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Unauthenticated, "could not authenticate request")
+	}
+
+	projects, _, err := endpoint.ReadHandler.GetUserProjects(userID.String(), nil)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, status.Error(codes.Internal, "could not read projects")
+	}
+
+	var projectIDs []string
+	for _, project := range projects {
+		if err := endpoint.AuthzHandler.Authorize(project.ID, v1storagemodels.Right_RIGHT_READ, metadata); err != nil {
+			continue
+		}
+		projectIDs = append(projectIDs, project.ID.String())
+	}
+
+	return projectIDs, nil
+}
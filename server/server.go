@@ -1,15 +1,16 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"net"
-	"os"
 
 	"github.com/ScienceObjectsDB/CORE-Server/authz"
 	"github.com/ScienceObjectsDB/CORE-Server/config"
 	"github.com/ScienceObjectsDB/CORE-Server/database"
 	"github.com/ScienceObjectsDB/CORE-Server/eventstreaming"
 	"github.com/ScienceObjectsDB/CORE-Server/objectstorage"
+	"github.com/ScienceObjectsDB/CORE-Server/search"
 	"github.com/ScienceObjectsDB/CORE-Server/streamingserver"
 	"golang.org/x/sync/errgroup"
 	"gorm.io/gorm"
@@ -32,11 +33,21 @@ type Endpoints struct {
 	CreateHandler       *database.Create
 	UpdateHandler       *database.Update
 	DeleteHandler       *database.Delete
+	TrashHandler        *database.Trash
 	StatsHandler        *database.Stats
+	UsageHandler        *database.Usage
 	AuthzHandler        authz.AuthInterface
 	ObjectHandler       *objectstorage.S3ObjectStorageHandler
 	ObjectStreamhandler *database.Streaming
+	ImportHandler       *database.Imports
+	OutboxHandler       *database.Outbox
+	WebhookHandler      *database.Webhooks
 	EventStreamMgmt     eventstreaming.EventStreamMgmt
+	// Full-text search index, nil if the search is not enabled
+	SearchIndex *search.Index
+	// Page sizes of list requests, 0 does not limit the results
+	DefaultPageSize int
+	MaxPageSize     int
 }
 
 type Server struct {
@@ -93,19 +104,101 @@ func Run() error {
 		return err
 	}
 
-	streamSigningSecret := os.Getenv("STREAMINGSIGNSECRET")
+	importer := &streamingserver.ArchiveImporter{
+		ImportHandler: endpoints.ImportHandler,
+		ObjectHandler: endpoints.ObjectHandler,
+	}
+
+	httpEndpoints, err := NewHTTPEndpoints(endpoints, importer)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
 
 	streamingServer := streamingserver.DataStreamingServer{
-		SigningSecret: streamSigningSecret,
-		ReadHandler:   datasetEndpoints.ReadHandler,
-		ObjectHandler: datasetEndpoints.ObjectHandler,
+		ReadHandler:      endpoints.ReadHandler,
+		StreamingHandler: endpoints.ObjectStreamhandler,
+		ObjectHandler:    endpoints.ObjectHandler,
+		PrefetchCount:    viper.GetInt(config.STREAMING_PREFETCH_OBJECTS),
+		ReadTimeout:      viper.GetDuration(config.STREAMING_READ_TIMEOUT),
+		Importer:         importer,
+	}
+
+	outboxRelay := &eventstreaming.OutboxRelay{
+		Outbox:          endpoints.OutboxHandler,
+		EventStreamMgmt: endpoints.EventStreamMgmt,
+		PollInterval:    viper.GetDuration(config.EVENTNOTIFICATION_OUTBOX_POLL_INTERVAL),
+		BatchSize:       viper.GetInt(config.EVENTNOTIFICATION_OUTBOX_BATCH_SIZE),
+		MaxAttempts:     viper.GetInt(config.EVENTNOTIFICATION_OUTBOX_MAX_ATTEMPTS),
+		Retention:       viper.GetDuration(config.EVENTNOTIFICATION_OUTBOX_RETENTION),
+	}
+
+	trashPurger := &TrashPurger{
+		Trash:    endpoints.TrashHandler,
+		Interval: viper.GetDuration(config.TRASH_PURGE_INTERVAL),
+	}
+
+	usageRecorder := &UsageRecorder{
+		Usage:    endpoints.UsageHandler,
+		Interval: viper.GetDuration(config.USAGE_SNAPSHOT_INTERVAL),
+	}
+
+	metricsServer := &MetricsServer{
+		Address: viper.GetString(config.METRICS_ADDRESS),
 	}
 
 	serverErrGrp := errgroup.Group{}
+
+	if endpoints.SearchIndex != nil {
+		indexer := &search.Indexer{
+			Index:           endpoints.SearchIndex,
+			Read:            endpoints.ReadHandler,
+			Outbox:          endpoints.OutboxHandler,
+			PollInterval:    viper.GetDuration(config.SEARCH_POLL_INTERVAL),
+			OutboxRetention: viper.GetDuration(config.EVENTNOTIFICATION_OUTBOX_RETENTION),
+		}
+
+		serverErrGrp.Go(func() error {
+			return indexer.Run(context.Background())
+		})
+	}
+
+	serverErrGrp.Go(func() error {
+		return streamingServer.Run(httpEndpoints.RegisterRoutes)
+	})
+
+	serverErrGrp.Go(func() error {
+		return metricsServer.Run()
+	})
+
+	serverErrGrp.Go(func() error {
+		return outboxRelay.Run(context.Background())
+	})
+
 	serverErrGrp.Go(func() error {
-		return streamingServer.Run()
+		return importer.Run(context.Background())
 	})
 
+	serverErrGrp.Go(func() error {
+		return trashPurger.Run(context.Background())
+	})
+
+	serverErrGrp.Go(func() error {
+		return usageRecorder.Run(context.Background())
+	})
+
+	if webhooks, ok := endpoints.EventStreamMgmt.(*eventstreaming.WebhookEventStreamMgmt); ok {
+		serverErrGrp.Go(func() error {
+			return webhooks.Run(context.Background())
+		})
+	}
+
+	if postgres, ok := endpoints.EventStreamMgmt.(*eventstreaming.PostgresEventStreamMgmt); ok {
+		serverErrGrp.Go(func() error {
+			return postgres.Run(context.Background())
+		})
+	}
+
 	v1storageservices.RegisterProjectServiceServer(grpcServer, projectEndpoints)
 	v1storageservices.RegisterDatasetServiceServer(grpcServer, datasetEndpoints)
 	v1storageservices.RegisterDatasetObjectsServiceServer(grpcServer, objectEndpoints)
@@ -123,7 +216,6 @@ func Run() error {
 // Creates the endpoint config based on the provided config.
 func createGenericEndpoint() (*Endpoints, error) {
 	streamingEndpoint := viper.GetString(config.STREAMING_ENDPOINT)
-	streamSigningSecret := os.Getenv(config.STREAMING_SECRET_ENV_VAR)
 
 	var db *gorm.DB
 	var err error
@@ -134,6 +226,12 @@ func createGenericEndpoint() (*Endpoints, error) {
 		return nil, err
 	}
 
+	err = database.CheckSchemaVersion(db)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
 	bucketName := viper.GetString(config.S3_BUCKET_PREFIX)
 
 	objectHandler := &objectstorage.S3ObjectStorageHandler{}
@@ -176,18 +274,44 @@ func createGenericEndpoint() (*Endpoints, error) {
 			Common: &commonHandler,
 		},
 		DeleteHandler: &database.Delete{
-			Common: &commonHandler,
+			Common:                &commonHandler,
+			DefaultTrashRetention: viper.GetDuration(config.TRASH_DEFAULT_RETENTION),
+		},
+		TrashHandler: &database.Trash{
+			Common:           &commonHandler,
+			DefaultRetention: viper.GetDuration(config.TRASH_DEFAULT_RETENTION),
 		},
 		StatsHandler: &database.Stats{
 			Common: &commonHandler,
 		},
+		UsageHandler: &database.Usage{
+			Common: &commonHandler,
+		},
 		AuthzHandler: authzHandler,
 		ObjectStreamhandler: &database.Streaming{
 			Common:            &commonHandler,
 			StreamingEndpoint: streamingEndpoint,
-			SigningSecret:     streamSigningSecret,
+			DefaultExpiry:     viper.GetDuration(config.STREAMING_LINKS_DEFAULT_EXPIRY),
+			MaxExpiry:         viper.GetDuration(config.STREAMING_LINKS_MAX_EXPIRY),
+			MaxDownloads:      viper.GetInt64(config.STREAMING_LINKS_MAX_DOWNLOADS),
 		},
+		ImportHandler: &database.Imports{
+			Common:            &commonHandler,
+			StreamingEndpoint: streamingEndpoint,
+		},
+		OutboxHandler:   &database.Outbox{Common: &commonHandler},
+		WebhookHandler:  &database.Webhooks{Common: &commonHandler},
 		EventStreamMgmt: eventStreamMgmt,
+		DefaultPageSize: viper.GetInt(config.PAGINATION_DEFAULT_PAGE_SIZE),
+		MaxPageSize:     viper.GetInt(config.PAGINATION_MAX_PAGE_SIZE),
+	}
+
+	if viper.GetBool(config.SEARCH_ENABLED) {
+		endpoints.SearchIndex, err = search.OpenIndex(viper.GetString(config.SEARCH_INDEX_PATH))
+		if err != nil {
+			log.Errorln(err.Error())
+			return nil, err
+		}
 	}
 
 	return endpoints, nil
@@ -0,0 +1,387 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1notificationservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/notification/services/v1"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// StreamGroup Representation of an event notification stream group
+type StreamGroup struct {
+	ID                  string     `json:"id"`
+	ProjectID           string     `json:"project_id"`
+	ResourceID          string     `json:"resource_id"`
+	ResourceType        string     `json:"resource_type"`
+	Subject             string     `json:"subject"`
+	IncludeSubResources bool       `json:"include_subresources"`
+	StartPolicy         string     `json:"start_policy"`
+	StartSequence       uint64     `json:"start_sequence,omitempty"`
+	StartTime           *time.Time `json:"start_time,omitempty"`
+	Resources           []string   `json:"resources"`
+	UpdateTypes         []string   `json:"update_types"`
+	LabelKey            string     `json:"label_key,omitempty"`
+	LabelValue          string     `json:"label_value,omitempty"`
+	IncludeSnapshots    bool       `json:"include_snapshots"`
+	Created             time.Time  `json:"created"`
+}
+
+// StreamGroupLag Delivery state of a stream group
+type StreamGroupLag struct {
+	// Number of events that have not been delivered yet
+	Pending uint64 `json:"pending"`
+	// Number of delivered events that have not been acknowledged
+	AckPending uint64 `json:"ack_pending"`
+	// Number of events that have been delivered again
+	Redelivered           uint64 `json:"redelivered"`
+	LastDeliveredSequence uint64 `json:"last_delivered_sequence"`
+	AckFloorSequence      uint64 `json:"ack_floor_sequence"`
+}
+
+type GetProjectStreamGroupsRequest struct {
+	ProjectID string `json:"project_id"`
+}
+
+type GetProjectStreamGroupsResponse struct {
+	StreamGroups []*StreamGroup `json:"stream_groups"`
+}
+
+type GetStreamGroupRequest struct {
+	ID string `json:"id"`
+}
+
+type GetStreamGroupResponse struct {
+	StreamGroup *StreamGroup    `json:"stream_group"`
+	Lag         *StreamGroupLag `json:"lag"`
+}
+
+type ResetStreamGroupRequest struct {
+	ID string `json:"id"`
+	// Start policy: all, new, sequence or time
+	Policy   string    `json:"policy"`
+	Sequence uint64    `json:"sequence"`
+	Time     time.Time `json:"time"`
+}
+
+type ResetStreamGroupResponse struct {
+	StreamGroup *StreamGroup `json:"stream_group"`
+}
+
+type UpdateStreamGroupFiltersRequest struct {
+	ID string `json:"id"`
+	// Resource types that are delivered, e.g. RESOURCE_OBJECT_GROUP, all resources if empty
+	Resources []string `json:"resources"`
+	// Update types that are delivered, e.g. UPDATE_TYPE_AVAILABLE, all update types if empty
+	UpdateTypes []string `json:"update_types"`
+	// Only events of resources with this label are delivered, no label filter if empty
+	LabelKey string `json:"label_key"`
+	// Required value of the label, any value if empty
+	LabelValue string `json:"label_value"`
+}
+
+type UpdateStreamGroupFiltersResponse struct {
+	StreamGroup *StreamGroup `json:"stream_group"`
+}
+
+type SetStreamGroupSnapshotsRequest struct {
+	ID string `json:"id"`
+	// Sends a snapshot of the resource with each event of the group
+	IncludeSnapshots bool `json:"include_snapshots"`
+}
+
+type SetStreamGroupSnapshotsResponse struct {
+	StreamGroup *StreamGroup `json:"stream_group"`
+}
+
+type DeleteStreamGroupRequest struct {
+	ID string `json:"id"`
+}
+
+type DeleteStreamGroupResponse struct {
+}
+
+// GetProjectStreamGroups Lists the event notification stream groups of a project
+func (endpoint *HTTPEndpoints) GetProjectStreamGroups(ctx context.Context, request *GetProjectStreamGroupsRequest) (*GetProjectStreamGroupsResponse, error) {
+	projectID, err := uuid.Parse(request.ProjectID)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, status.Error(codes.InvalidArgument, "could not parse project id")
+	}
+
+	metadata, _ := metadata.FromIncomingContext(ctx)
+
+	err = endpoint.AuthzHandler.Authorize(
+		projectID,
+		v1storagemodels.Right_RIGHT_READ,
+		metadata)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	streamGroups, err := endpoint.ReadHandler.GetProjectStreamGroups(projectID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not read stream groups")
+	}
+
+	response := &GetProjectStreamGroupsResponse{
+		StreamGroups: make([]*StreamGroup, len(streamGroups)),
+	}
+	for i, streamGroup := range streamGroups {
+		response.StreamGroups[i] = streamGroupFromModel(streamGroup)
+	}
+
+	return response, nil
+}
+
+// GetStreamGroup Returns a stream group together with its delivery lag
+func (endpoint *HTTPEndpoints) GetStreamGroup(ctx context.Context, request *GetStreamGroupRequest) (*GetStreamGroupResponse, error) {
+	streamGroup, err := endpoint.authorizeStreamGroup(ctx, request.ID, v1storagemodels.Right_RIGHT_READ)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := endpoint.EventStreamMgmt.GetStreamGroupInfo(streamGroup)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not read stream group state")
+	}
+
+	return &GetStreamGroupResponse{
+		StreamGroup: streamGroupFromModel(streamGroup),
+		Lag: &StreamGroupLag{
+			Pending:               info.Pending,
+			AckPending:            info.AckPending,
+			Redelivered:           info.Redelivered,
+			LastDeliveredSequence: info.LastDeliveredSequence,
+			AckFloorSequence:      info.AckFloorSequence,
+		},
+	}, nil
+}
+
+// ResetStreamGroup Restarts the delivery of a stream group at a new position
+// Unacknowledged events are dropped, connected clients have to reconnect to receive events from the new position.
+func (endpoint *HTTPEndpoints) ResetStreamGroup(ctx context.Context, request *ResetStreamGroupRequest) (*ResetStreamGroupResponse, error) {
+	start := &models.StreamGroupStart{
+		Policy:   request.Policy,
+		Sequence: request.Sequence,
+		Time:     request.Time,
+	}
+
+	switch start.Policy {
+	case models.StreamGroupStartAll, models.StreamGroupStartNew:
+	case models.StreamGroupStartSequence:
+		if start.Sequence == 0 {
+			return nil, status.Error(codes.InvalidArgument, "start sequence has to be greater than 0")
+		}
+	case models.StreamGroupStartTime:
+		if start.Time.IsZero() {
+			return nil, status.Error(codes.InvalidArgument, "start time is required")
+		}
+	default:
+		return nil, status.Error(codes.InvalidArgument, "policy has to be one of all, new, sequence or time")
+	}
+
+	streamGroup, err := endpoint.authorizeStreamGroup(ctx, request.ID, v1storagemodels.Right_RIGHT_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	previousStart := &models.StreamGroupStart{
+		Policy:   streamGroup.StartPolicy,
+		Sequence: streamGroup.StartSequence,
+		Time:     streamGroup.StartTime,
+	}
+
+	// The start is stored first, a consumer is never recreated from a start that has not been stored
+	err = endpoint.UpdateHandler.UpdateStreamGroupStart(streamGroup.ID, start)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not update stream group")
+	}
+
+	err = endpoint.EventStreamMgmt.ResetStreamGroup(streamGroup, start)
+	if err != nil {
+		log.Println(err.Error())
+
+		rollbackErr := endpoint.UpdateHandler.UpdateStreamGroupStart(streamGroup.ID, previousStart)
+		if rollbackErr != nil {
+			log.Println(rollbackErr.Error())
+		}
+
+		return nil, status.Error(codes.Internal, "could not reset stream group")
+	}
+
+	streamGroup.StartPolicy = start.Policy
+	streamGroup.StartSequence = start.Sequence
+	streamGroup.StartTime = start.Time
+
+	return &ResetStreamGroupResponse{
+		StreamGroup: streamGroupFromModel(streamGroup),
+	}, nil
+}
+
+// UpdateStreamGroupFilters Replaces the event filters of a stream group
+// The filters are applied when the events are streamed, connected clients have to reconnect to use the new filters.
+func (endpoint *HTTPEndpoints) UpdateStreamGroupFilters(ctx context.Context, request *UpdateStreamGroupFiltersRequest) (*UpdateStreamGroupFiltersResponse, error) {
+	err := validateEventFilters(request.Resources, request.UpdateTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.LabelKey == "" && request.LabelValue != "" {
+		return nil, status.Error(codes.InvalidArgument, "label value requires a label key")
+	}
+
+	streamGroup, err := endpoint.authorizeStreamGroup(ctx, request.ID, v1storagemodels.Right_RIGHT_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	err = endpoint.UpdateHandler.UpdateStreamGroupFilters(streamGroup.ID, request.Resources, request.UpdateTypes, request.LabelKey, request.LabelValue)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not update stream group")
+	}
+
+	streamGroup.Resources = strings.Join(request.Resources, ",")
+	streamGroup.UpdateTypes = strings.Join(request.UpdateTypes, ",")
+	streamGroup.LabelKey = request.LabelKey
+	streamGroup.LabelValue = request.LabelValue
+
+	return &UpdateStreamGroupFiltersResponse{
+		StreamGroup: streamGroupFromModel(streamGroup),
+	}, nil
+}
+
+// SetStreamGroupSnapshots Requests or stops snapshots of the resources with the events of a stream group
+// Snapshots are stored for events that are written afterwards, events that are already in the outbox have none.
+func (endpoint *HTTPEndpoints) SetStreamGroupSnapshots(ctx context.Context, request *SetStreamGroupSnapshotsRequest) (*SetStreamGroupSnapshotsResponse, error) {
+	streamGroup, err := endpoint.authorizeStreamGroup(ctx, request.ID, v1storagemodels.Right_RIGHT_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	err = endpoint.UpdateHandler.SetStreamGroupIncludeSnapshots(streamGroup.ID, request.IncludeSnapshots)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not update stream group")
+	}
+
+	streamGroup.IncludeSnapshots = request.IncludeSnapshots
+
+	return &SetStreamGroupSnapshotsResponse{
+		StreamGroup: streamGroupFromModel(streamGroup),
+	}, nil
+}
+
+// DeleteStreamGroup Deletes a stream group and its consumer in the event notification backend
+func (endpoint *HTTPEndpoints) DeleteStreamGroup(ctx context.Context, request *DeleteStreamGroupRequest) (*DeleteStreamGroupResponse, error) {
+	streamGroup, err := endpoint.authorizeStreamGroup(ctx, request.ID, v1storagemodels.Right_RIGHT_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	err = endpoint.EventStreamMgmt.DeleteStreamGroup(streamGroup)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not delete stream group")
+	}
+
+	err = endpoint.DeleteHandler.DeleteStreamGroup(streamGroup.ID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not delete stream group")
+	}
+
+	return &DeleteStreamGroupResponse{}, nil
+}
+
+// Reads a stream group and checks if the caller has the requested right on its project
+func (endpoint *HTTPEndpoints) authorizeStreamGroup(ctx context.Context, id string, right v1storagemodels.Right) (*models.StreamGroup, error) {
+	streamGroupID, err := uuid.Parse(id)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, status.Error(codes.InvalidArgument, "could not parse stream group id")
+	}
+
+	streamGroup, err := endpoint.ReadHandler.GetStreamGroup(streamGroupID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.NotFound, "could not find stream group")
+	}
+
+	metadata, _ := metadata.FromIncomingContext(ctx)
+
+	err = endpoint.AuthzHandler.Authorize(
+		streamGroup.ProjectID,
+		right,
+		metadata)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return streamGroup, nil
+}
+
+func streamGroupFromModel(streamGroup *models.StreamGroup) *StreamGroup {
+	start := streamGroup.Start()
+
+	group := &StreamGroup{
+		ID:                  streamGroup.ID.String(),
+		ProjectID:           streamGroup.ProjectID.String(),
+		ResourceID:          streamGroup.ResourceID.String(),
+		ResourceType:        streamGroup.ResourceType,
+		Subject:             streamGroup.Subject,
+		IncludeSubResources: streamGroup.UseSubResource,
+		StartPolicy:         start.Policy,
+		Resources:           streamGroup.ResourceList(),
+		UpdateTypes:         streamGroup.UpdateTypeList(),
+		LabelKey:            streamGroup.LabelKey,
+		LabelValue:          streamGroup.LabelValue,
+		IncludeSnapshots:    streamGroup.IncludeSnapshots,
+		Created:             streamGroup.CreatedAt,
+	}
+
+	switch start.Policy {
+	case models.StreamGroupStartSequence:
+		group.StartSequence = start.Sequence
+	case models.StreamGroupStartTime:
+		startTime := start.Time
+		group.StartTime = &startTime
+	}
+
+	return group
+}
+
+// Checks if the resource types and update types of an event filter are known enum names
+func validateEventFilters(resources []string, updateTypes []string) error {
+	for _, resource := range resources {
+		// The notification stream can not describe users and api tokens, their events are streamed as project updates
+		if resource == models.EventResourceUser || resource == models.EventResourceAPIToken {
+			return status.Errorf(codes.InvalidArgument, "events of %v are streamed as updates of RESOURCE_PROJECT, filter on RESOURCE_PROJECT instead", resource)
+		}
+
+		if _, ok := v1storagemodels.Resource_value[resource]; !ok {
+			return status.Errorf(codes.InvalidArgument, "unknown resource %v", resource)
+		}
+	}
+
+	for _, updateType := range updateTypes {
+		if _, ok := v1notificationservices.EventNotificationMessage_UpdateType_value[updateType]; !ok {
+			return status.Errorf(codes.InvalidArgument, "unknown update type %v", updateType)
+		}
+	}
+
+	return nil
+}
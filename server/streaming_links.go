@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// StreamingLink Representation of an issued streaming link
+type StreamingLink struct {
+	ID               string    `json:"id"`
+	ProjectID        string    `json:"project_id"`
+	DatasetID        string    `json:"dataset_id"`
+	DatasetVersionID string    `json:"dataset_version_id,omitempty"`
+	ResourceType     string    `json:"resource_type"`
+	StreamType       string    `json:"stream_type"`
+	CreatedBy        string    `json:"created_by"`
+	Created          time.Time `json:"created"`
+	Expires          time.Time `json:"expires"`
+	MaxDownloads     int64     `json:"max_downloads"`
+	DownloadCount    int64     `json:"download_count"`
+}
+
+type GetProjectStreamingLinksRequest struct {
+	ProjectID string `json:"project_id"`
+}
+
+type GetProjectStreamingLinksResponse struct {
+	Links []*StreamingLink `json:"links"`
+}
+
+type RevokeStreamingLinkRequest struct {
+	ID string `json:"id"`
+}
+
+type RevokeStreamingLinkResponse struct {
+}
+
+// GetProjectStreamingLinks Lists the active streaming links of a project
+func (endpoint *HTTPEndpoints) GetProjectStreamingLinks(ctx context.Context, request *GetProjectStreamingLinksRequest) (*GetProjectStreamingLinksResponse, error) {
+	projectID, err := uuid.Parse(request.ProjectID)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, status.Error(codes.InvalidArgument, "could not parse project id")
+	}
+
+	metadata, _ := metadata.FromIncomingContext(ctx)
+
+	err = endpoint.AuthzHandler.Authorize(
+		projectID,
+		v1storagemodels.Right_RIGHT_READ,
+		metadata)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	entries, err := endpoint.ObjectStreamhandler.GetProjectStreamingEntries(projectID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not read streaming links")
+	}
+
+	links := make([]*StreamingLink, len(entries))
+	for i, entry := range entries {
+		links[i] = streamingLinkFromEntry(entry)
+	}
+
+	return &GetProjectStreamingLinksResponse{
+		Links: links,
+	}, nil
+}
+
+// RevokeStreamingLink Revokes a streaming link, it can not be used for downloads afterwards
+func (endpoint *HTTPEndpoints) RevokeStreamingLink(ctx context.Context, request *RevokeStreamingLinkRequest) (*RevokeStreamingLinkResponse, error) {
+	entryID, err := uuid.Parse(request.ID)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, status.Error(codes.InvalidArgument, "could not parse streaming link id")
+	}
+
+	entry, err := endpoint.ObjectStreamhandler.GetStreamingEntry(entryID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.NotFound, "could not find streaming link")
+	}
+
+	metadata, _ := metadata.FromIncomingContext(ctx)
+
+	err = endpoint.AuthzHandler.Authorize(
+		entry.ProjectID,
+		v1storagemodels.Right_RIGHT_WRITE,
+		metadata)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	err = endpoint.ObjectStreamhandler.RevokeStreamingEntry(entry.ID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not revoke streaming link")
+	}
+
+	return &RevokeStreamingLinkResponse{}, nil
+}
+
+func streamingLinkFromEntry(entry *models.StreamingEntry) *StreamingLink {
+	link := &StreamingLink{
+		ID:            entry.ID.String(),
+		ProjectID:     entry.ProjectID.String(),
+		DatasetID:     entry.DatasetID.String(),
+		ResourceType:  entry.ResourceType,
+		StreamType:    entry.StreamType,
+		CreatedBy:     entry.CreatedBy,
+		Created:       entry.CreatedAt,
+		Expires:       entry.ExpiresAt,
+		MaxDownloads:  entry.MaxDownloads,
+		DownloadCount: entry.DownloadCount,
+	}
+
+	if entry.DatasetVersionID != uuid.Nil {
+		link.DatasetVersionID = entry.DatasetVersionID.String()
+	}
+
+	return link
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TrashEntry Representation of a deleted dataset or object group
+type TrashEntry struct {
+	ID         string    `json:"id"`
+	Resource   string    `json:"resource"`
+	ResourceID string    `json:"resource_id"`
+	ProjectID  string    `json:"project_id"`
+	DatasetID  string    `json:"dataset_id"`
+	Name       string    `json:"name"`
+	Deleted    time.Time `json:"deleted"`
+	PurgeAt    time.Time `json:"purge_at"`
+}
+
+type GetProjectTrashRequest struct {
+	ProjectID string `json:"project_id"`
+}
+
+type GetProjectTrashResponse struct {
+	// Time deleted resources are kept, e.g. 720h
+	Retention string        `json:"retention"`
+	Entries   []*TrashEntry `json:"entries"`
+}
+
+type UpdateProjectTrashRequest struct {
+	ProjectID string `json:"project_id"`
+	// Time deleted resources are kept, an empty retention resets it to the default of the server
+	Retention string `json:"retention"`
+}
+
+type UpdateProjectTrashResponse struct {
+	Retention string `json:"retention"`
+}
+
+type RestoreTrashEntryRequest struct {
+	ID string `json:"id"`
+}
+
+type RestoreTrashEntryResponse struct {
+	Entry *TrashEntry `json:"entry"`
+}
+
+// GetProjectTrash Lists the deleted datasets and object groups of a project
+func (endpoint *HTTPEndpoints) GetProjectTrash(ctx context.Context, request *GetProjectTrashRequest) (*GetProjectTrashResponse, error) {
+	projectID, err := endpoint.authorizeProject(ctx, request.ProjectID, v1storagemodels.Right_RIGHT_READ)
+	if err != nil {
+		return nil, err
+	}
+
+	retention, err := endpoint.TrashHandler.GetProjectTrashRetention(projectID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.NotFound, "could not find project")
+	}
+
+	entries, err := endpoint.TrashHandler.GetProjectTrash(projectID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not read trash")
+	}
+
+	response := &GetProjectTrashResponse{
+		Retention: retention.String(),
+		Entries:   make([]*TrashEntry, len(entries)),
+	}
+	for i, entry := range entries {
+		response.Entries[i] = trashEntryToHTTPModel(entry)
+	}
+
+	return response, nil
+}
+
+// UpdateProjectTrash Changes the time deleted resources of a project are kept in the trash
+func (endpoint *HTTPEndpoints) UpdateProjectTrash(ctx context.Context, request *UpdateProjectTrashRequest) (*UpdateProjectTrashResponse, error) {
+	projectID, err := endpoint.authorizeProject(ctx, request.ProjectID, v1storagemodels.Right_RIGHT_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	var retention time.Duration
+	if request.Retention != "" {
+		retention, err = time.ParseDuration(request.Retention)
+		if err != nil || retention <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "retention has to be a positive duration, e.g. 168h")
+		}
+	}
+
+	err = endpoint.TrashHandler.SetProjectTrashRetention(projectID, retention)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	retention, err = endpoint.TrashHandler.GetProjectTrashRetention(projectID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return &UpdateProjectTrashResponse{Retention: retention.String()}, nil
+}
+
+// RestoreTrashEntry Restores a deleted dataset or object group
+func (endpoint *HTTPEndpoints) RestoreTrashEntry(ctx context.Context, request *RestoreTrashEntryRequest) (*RestoreTrashEntryResponse, error) {
+	entryID, err := uuid.Parse(request.ID)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, status.Error(codes.InvalidArgument, "could not parse id")
+	}
+
+	entry, err := endpoint.TrashHandler.GetTrashEntry(entryID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.NotFound, "could not find trash entry")
+	}
+
+	_, err = endpoint.authorizeProject(ctx, entry.ProjectID.String(), v1storagemodels.Right_RIGHT_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err = endpoint.TrashHandler.RestoreTrashEntry(entryID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return &RestoreTrashEntryResponse{Entry: trashEntryToHTTPModel(entry)}, nil
+}
+
+// Parses a project id and checks the right of the request on the project
+func (endpoint *HTTPEndpoints) authorizeProject(ctx context.Context, id string, right v1storagemodels.Right) (uuid.UUID, error) {
+	projectID, err := uuid.Parse(id)
+	if err != nil {
+		log.Debug(err.Error())
+		return uuid.Nil, status.Error(codes.InvalidArgument, "could not parse project id")
+	}
+
+	metadata, _ := metadata.FromIncomingContext(ctx)
+
+	err = endpoint.AuthzHandler.Authorize(
+		projectID,
+		right,
+		metadata)
+	if err != nil {
+		log.Println(err.Error())
+		return uuid.Nil, err
+	}
+
+	return projectID, nil
+}
+
+func trashEntryToHTTPModel(entry *models.TrashEntry) *TrashEntry {
+	return &TrashEntry{
+		ID:         entry.ID.String(),
+		Resource:   entry.Resource,
+		ResourceID: entry.ResourceID.String(),
+		ProjectID:  entry.ProjectID.String(),
+		DatasetID:  entry.DatasetID.String(),
+		Name:       entry.Name,
+		Deleted:    entry.TrashedAt,
+		PurgeAt:    entry.PurgeAt,
+	}
+}
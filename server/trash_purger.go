@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	log "github.com/sirupsen/logrus"
+)
+
+// Number of expired trash entries that are purged per database query
+const trashPurgeBatchSize = 100
+
+// TrashPurger Removes datasets and object groups from the trash after the retention of their project has expired
+// Entries that can not be purged, e.g. because the object storage is not available, are retried in the next interval.
+// Every server runs a purger, each entry is claimed by the server that purges it and skipped by the others.
+type TrashPurger struct {
+	Trash    *database.Trash
+	Interval time.Duration
+}
+
+// Run Purges expired trash entries until the context is cancelled
+func (purger *TrashPurger) Run(ctx context.Context) error {
+	ticker := time.NewTicker(purger.Interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := purger.PurgeExpired()
+		if err != nil {
+			log.Errorln(err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeExpired Purges all trash entries whose retention has expired and returns the number of purged entries
+func (purger *TrashPurger) PurgeExpired() (int, error) {
+	purged := 0
+	failed := make(map[string]struct{})
+
+	for {
+		// Entries that failed in this run are returned again, the limit is raised to get new entries as well
+		limit := trashPurgeBatchSize + len(failed)
+		entries, err := purger.Trash.GetExpiredTrashEntries(time.Now(), limit)
+		if err != nil {
+			log.Errorln(err.Error())
+			return purged, err
+		}
+
+		attempted := 0
+		for _, entry := range entries {
+			if _, ok := failed[entry.ID.String()]; ok {
+				continue
+			}
+
+			attempted++
+			err := purger.Trash.PurgeTrashEntry(entry)
+			if err == database.ErrTrashEntryPurging {
+				failed[entry.ID.String()] = struct{}{}
+				continue
+			}
+			if err != nil {
+				log.Errorf("could not purge %v %v from the trash: %v", entry.Resource, entry.ResourceID.String(), err.Error())
+				failed[entry.ID.String()] = struct{}{}
+				continue
+			}
+
+			purged++
+		}
+
+		if attempted == 0 || len(entries) < limit {
+			return purged, nil
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Layouts of the start and end of usage requests, times without zone are UTC
+var usageTimeLayouts = []string{time.RFC3339, "2006-01-02", "2006-01"}
+
+// UsageSnapshot Storage usage of a project or dataset at a point in time
+type UsageSnapshot struct {
+	TakenAt          time.Time `json:"taken_at"`
+	ObjectCount      int64     `json:"object_count"`
+	ObjectGroupCount int64     `json:"object_group_count"`
+	AccSize          int64     `json:"acc_size"`
+}
+
+// UsageAccount Storage usage of a project or dataset in a calendar month
+type UsageAccount struct {
+	ResourceID string `json:"resource_id"`
+	// Name of the project in accounting reports, empty for deleted projects
+	Name string `json:"name,omitempty"`
+	// Month in the format 2006-01
+	Month            string  `json:"month"`
+	ObjectCount      int64   `json:"object_count"`
+	ObjectGroupCount int64   `json:"object_group_count"`
+	AccSize          int64   `json:"acc_size"`
+	MaxAccSize       int64   `json:"max_acc_size"`
+	ByteMonths       float64 `json:"byte_months"`
+}
+
+type GetUsageRequest struct {
+	Resource v1storagemodels.Resource `json:"resource"`
+	ID       string                   `json:"id"`
+	// Start of the range, defaults to the start of the month of end
+	Start string `json:"start"`
+	// End of the range, defaults to now
+	End string `json:"end"`
+}
+
+type GetUsageResponse struct {
+	Snapshots []*UsageSnapshot `json:"snapshots"`
+	Months    []*UsageAccount  `json:"months"`
+}
+
+// GetUsage Returns the recorded storage usage of a project or dataset between start and end
+// The first snapshot can be older than start, it holds the usage at start.
+func (endpoint *HTTPEndpoints) GetUsage(ctx context.Context, request *GetUsageRequest) (*GetUsageResponse, error) {
+	resourceID, err := endpoint.authorizeResource(ctx, request.Resource, request.ID, v1storagemodels.Right_RIGHT_READ)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := ParseUsageRange(request.Start, request.End)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := endpoint.UsageHandler.GetUsageSnapshots(resourceID, start, end)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not read usage")
+	}
+
+	response := &GetUsageResponse{
+		Snapshots: make([]*UsageSnapshot, len(snapshots)),
+		Months:    []*UsageAccount{},
+	}
+	for i, snapshot := range snapshots {
+		response.Snapshots[i] = &UsageSnapshot{
+			TakenAt:          snapshot.TakenAt,
+			ObjectCount:      snapshot.ObjectCount,
+			ObjectGroupCount: snapshot.ObjectGroupCount,
+			AccSize:          snapshot.AccSize,
+		}
+	}
+
+	for _, account := range database.AccountUsage(resourceID, snapshots, start, end) {
+		response.Months = append(response.Months, UsageAccountToHTTPModel(account, ""))
+	}
+
+	return response, nil
+}
+
+// ParseUsageRange Parses the start and end of a usage range, the end is limited to now
+// The end defaults to now and the start to the beginning of the month of the end.
+func ParseUsageRange(startValue string, endValue string) (time.Time, time.Time, error) {
+	now := time.Now().UTC()
+
+	end := now
+	if endValue != "" {
+		var err error
+		end, err = parseUsageTime(endValue)
+		if err != nil {
+			return time.Time{}, time.Time{}, status.Error(codes.InvalidArgument, "could not parse end, expected e.g. 2006-01-02")
+		}
+		if end.After(now) {
+			end = now
+		}
+	}
+
+	start := time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if startValue != "" {
+		var err error
+		start, err = parseUsageTime(startValue)
+		if err != nil {
+			return time.Time{}, time.Time{}, status.Error(codes.InvalidArgument, "could not parse start, expected e.g. 2006-01-02")
+		}
+	}
+
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, status.Error(codes.InvalidArgument, "start has to be before end")
+	}
+
+	return start, end, nil
+}
+
+func parseUsageTime(value string) (time.Time, error) {
+	var err error
+	for _, layout := range usageTimeLayouts {
+		var parsed time.Time
+		parsed, err = time.Parse(layout, value)
+		if err == nil {
+			return parsed.UTC(), nil
+		}
+	}
+
+	log.Debug(err.Error())
+	return time.Time{}, err
+}
+
+// UsageAccountToHTTPModel Converts the usage of a month into its json representation
+func UsageAccountToHTTPModel(account *database.UsageAccount, name string) *UsageAccount {
+	return &UsageAccount{
+		ResourceID:       account.ResourceID.String(),
+		Name:             name,
+		Month:            account.Month.Format("2006-01"),
+		ObjectCount:      account.ObjectCount,
+		ObjectGroupCount: account.ObjectGroupCount,
+		AccSize:          account.AccSize,
+		MaxAccSize:       account.MaxAccSize,
+		ByteMonths:       account.ByteMonths,
+	}
+}
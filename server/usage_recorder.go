@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	log "github.com/sirupsen/logrus"
+)
+
+// UsageRecorder Records the storage usage of all projects and datasets once per interval
+// Snapshots are taken at the start of the interval, e.g. at midnight UTC for a daily interval, servers that are started
+// within the same interval record it only once. Every server runs a recorder, the snapshots of all servers have the same
+// time and the snapshots of the later servers are skipped by ON CONFLICT DO NOTHING, which only costs a query per interval.
+type UsageRecorder struct {
+	Usage    *database.Usage
+	Interval time.Duration
+}
+
+// Run Records a snapshot per interval until the context is cancelled, an interval of 0 disables the recorder
+func (recorder *UsageRecorder) Run(ctx context.Context) error {
+	if recorder.Interval <= 0 {
+		log.Infoln("usage snapshots are disabled")
+		return nil
+	}
+
+	ticker := time.NewTicker(recorder.Interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := recorder.RecordSnapshot()
+		if err != nil {
+			log.Errorln(err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RecordSnapshot Records the snapshot of the current interval and returns the number of recorded resources
+func (recorder *UsageRecorder) RecordSnapshot() (int64, error) {
+	takenAt := time.Now().UTC().Truncate(recorder.Interval)
+
+	recorded, err := recorder.Usage.TakeUsageSnapshot(takenAt)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Debugf("recorded the usage of %v resources at %v", recorded, takenAt.Format(time.RFC3339))
+
+	return recorded, nil
+}
@@ -0,0 +1,435 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/eventstreaming"
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	v1storagemodels "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/models/v1"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Number of deliveries that are returned if no limit is requested
+const defaultWebhookDeliveriesLimit = 100
+
+// Maximum number of deliveries that are returned by a single request
+const maxWebhookDeliveriesLimit = 1000
+
+// WebhookSubscription Representation of a webhook subscription, the secret is only returned when the subscription is created
+type WebhookSubscription struct {
+	ID               string    `json:"id"`
+	ProjectID        string    `json:"project_id"`
+	URL              string    `json:"url"`
+	Description      string    `json:"description"`
+	Resources        []string  `json:"resources"`
+	UpdateTypes      []string  `json:"update_types"`
+	Enabled          bool      `json:"enabled"`
+	IncludeSnapshots bool      `json:"include_snapshots"`
+	CreatedBy        string    `json:"created_by"`
+	Created          time.Time `json:"created"`
+}
+
+// WebhookDelivery Representation of a single delivery of an event to a webhook subscription
+type WebhookDelivery struct {
+	ID             string     `json:"id"`
+	SubscriptionID string     `json:"subscription_id"`
+	EventID        string     `json:"event_id"`
+	Resource       string     `json:"resource"`
+	ResourceID     string     `json:"resource_id"`
+	UpdateType     string     `json:"update_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttempt    *time.Time `json:"next_attempt,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	Created        time.Time  `json:"created"`
+	Delivered      *time.Time `json:"delivered,omitempty"`
+}
+
+type CreateWebhookSubscriptionRequest struct {
+	ProjectID   string `json:"project_id"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	// Resource types that are delivered, e.g. RESOURCE_DATASET, all resources if empty
+	Resources []string `json:"resources"`
+	// Update types that are delivered, e.g. UPDATE_TYPE_CREATED, all update types if empty
+	UpdateTypes []string `json:"update_types"`
+	// Adds a snapshot of the resource to each delivery
+	IncludeSnapshots bool `json:"include_snapshots"`
+}
+
+type CreateWebhookSubscriptionResponse struct {
+	Subscription *WebhookSubscription `json:"subscription"`
+	// Secret that is used to sign the deliveries, it is only returned once
+	Secret string `json:"secret"`
+}
+
+type GetProjectWebhookSubscriptionsRequest struct {
+	ProjectID string `json:"project_id"`
+}
+
+type GetProjectWebhookSubscriptionsResponse struct {
+	Subscriptions []*WebhookSubscription `json:"subscriptions"`
+}
+
+type GetWebhookSubscriptionRequest struct {
+	ID string `json:"id"`
+}
+
+type GetWebhookSubscriptionResponse struct {
+	Subscription *WebhookSubscription `json:"subscription"`
+}
+
+type UpdateWebhookSubscriptionRequest struct {
+	ID               string `json:"id"`
+	Enabled          *bool  `json:"enabled"`
+	IncludeSnapshots *bool  `json:"include_snapshots"`
+}
+
+type UpdateWebhookSubscriptionResponse struct {
+	Subscription *WebhookSubscription `json:"subscription"`
+}
+
+type DeleteWebhookSubscriptionRequest struct {
+	ID string `json:"id"`
+}
+
+type DeleteWebhookSubscriptionResponse struct {
+}
+
+type GetWebhookDeliveriesRequest struct {
+	SubscriptionID string `json:"subscription_id"`
+	// Optional status filter: pending, delivered or dead_letter
+	Status string `json:"status"`
+	Limit  int    `json:"limit"`
+}
+
+type GetWebhookDeliveriesResponse struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+}
+
+type RedeliverWebhookDeliveryRequest struct {
+	ID string `json:"id"`
+}
+
+type RedeliverWebhookDeliveryResponse struct {
+}
+
+// CreateWebhookSubscription Registers an http endpoint that receives the event notifications of a project
+func (endpoint *HTTPEndpoints) CreateWebhookSubscription(ctx context.Context, request *CreateWebhookSubscriptionRequest) (*CreateWebhookSubscriptionResponse, error) {
+	webhooks, err := endpoint.webhookBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	projectID, err := uuid.Parse(request.ProjectID)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, status.Error(codes.InvalidArgument, "could not parse project id")
+	}
+
+	err = webhooks.ValidateURL(request.URL)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	err = validateWebhookEventFilters(request.Resources, request.UpdateTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, _ := metadata.FromIncomingContext(ctx)
+
+	err = endpoint.AuthzHandler.Authorize(
+		projectID,
+		v1storagemodels.Right_RIGHT_WRITE,
+		metadata)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	userID, err := endpoint.AuthzHandler.GetUserID(metadata)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	subscription := &models.WebhookSubscription{
+		ProjectID:        projectID,
+		URL:              request.URL,
+		Description:      request.Description,
+		Resources:        strings.Join(request.Resources, ","),
+		UpdateTypes:      strings.Join(request.UpdateTypes, ","),
+		IncludeSnapshots: request.IncludeSnapshots,
+		CreatedBy:        userID.String(),
+	}
+
+	err = endpoint.WebhookHandler.CreateWebhookSubscription(subscription)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not create webhook subscription")
+	}
+
+	return &CreateWebhookSubscriptionResponse{
+		Subscription: webhookSubscriptionFromModel(subscription),
+		Secret:       subscription.Secret,
+	}, nil
+}
+
+// GetProjectWebhookSubscriptions Lists the webhook subscriptions of a project
+func (endpoint *HTTPEndpoints) GetProjectWebhookSubscriptions(ctx context.Context, request *GetProjectWebhookSubscriptionsRequest) (*GetProjectWebhookSubscriptionsResponse, error) {
+	projectID, err := uuid.Parse(request.ProjectID)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, status.Error(codes.InvalidArgument, "could not parse project id")
+	}
+
+	metadata, _ := metadata.FromIncomingContext(ctx)
+
+	err = endpoint.AuthzHandler.Authorize(
+		projectID,
+		v1storagemodels.Right_RIGHT_READ,
+		metadata)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	subscriptions, err := endpoint.WebhookHandler.GetProjectWebhookSubscriptions(projectID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not read webhook subscriptions")
+	}
+
+	response := &GetProjectWebhookSubscriptionsResponse{
+		Subscriptions: make([]*WebhookSubscription, len(subscriptions)),
+	}
+	for i, subscription := range subscriptions {
+		response.Subscriptions[i] = webhookSubscriptionFromModel(subscription)
+	}
+
+	return response, nil
+}
+
+// GetWebhookSubscription Returns a single webhook subscription
+func (endpoint *HTTPEndpoints) GetWebhookSubscription(ctx context.Context, request *GetWebhookSubscriptionRequest) (*GetWebhookSubscriptionResponse, error) {
+	subscription, err := endpoint.authorizeWebhookSubscription(ctx, request.ID, v1storagemodels.Right_RIGHT_READ)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetWebhookSubscriptionResponse{
+		Subscription: webhookSubscriptionFromModel(subscription),
+	}, nil
+}
+
+// UpdateWebhookSubscription Enables or disables a webhook subscription and its snapshots
+func (endpoint *HTTPEndpoints) UpdateWebhookSubscription(ctx context.Context, request *UpdateWebhookSubscriptionRequest) (*UpdateWebhookSubscriptionResponse, error) {
+	subscription, err := endpoint.authorizeWebhookSubscription(ctx, request.ID, v1storagemodels.Right_RIGHT_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Enabled != nil {
+		err = endpoint.WebhookHandler.SetWebhookSubscriptionEnabled(subscription.ID, *request.Enabled)
+		if err != nil {
+			log.Println(err.Error())
+			return nil, status.Error(codes.Internal, "could not update webhook subscription")
+		}
+		subscription.Enabled = *request.Enabled
+	}
+
+	if request.IncludeSnapshots != nil {
+		err = endpoint.WebhookHandler.SetWebhookSubscriptionIncludeSnapshots(subscription.ID, *request.IncludeSnapshots)
+		if err != nil {
+			log.Println(err.Error())
+			return nil, status.Error(codes.Internal, "could not update webhook subscription")
+		}
+		subscription.IncludeSnapshots = *request.IncludeSnapshots
+	}
+
+	return &UpdateWebhookSubscriptionResponse{
+		Subscription: webhookSubscriptionFromModel(subscription),
+	}, nil
+}
+
+// DeleteWebhookSubscription Deletes a webhook subscription and its delivery history
+func (endpoint *HTTPEndpoints) DeleteWebhookSubscription(ctx context.Context, request *DeleteWebhookSubscriptionRequest) (*DeleteWebhookSubscriptionResponse, error) {
+	subscription, err := endpoint.authorizeWebhookSubscription(ctx, request.ID, v1storagemodels.Right_RIGHT_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	err = endpoint.WebhookHandler.DeleteWebhookSubscription(subscription.ID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not delete webhook subscription")
+	}
+
+	return &DeleteWebhookSubscriptionResponse{}, nil
+}
+
+// GetWebhookDeliveries Returns the delivery history of a webhook subscription, newest deliveries first
+func (endpoint *HTTPEndpoints) GetWebhookDeliveries(ctx context.Context, request *GetWebhookDeliveriesRequest) (*GetWebhookDeliveriesResponse, error) {
+	subscription, err := endpoint.authorizeWebhookSubscription(ctx, request.SubscriptionID, v1storagemodels.Right_RIGHT_READ)
+	if err != nil {
+		return nil, err
+	}
+
+	switch request.Status {
+	case "", models.WebhookDeliveryStatusPending, models.WebhookDeliveryStatusDelivered, models.WebhookDeliveryStatusDeadLetter:
+	default:
+		return nil, status.Error(codes.InvalidArgument, "status has to be one of pending, delivered or dead_letter")
+	}
+
+	limit := request.Limit
+	if limit <= 0 {
+		limit = defaultWebhookDeliveriesLimit
+	}
+	if limit > maxWebhookDeliveriesLimit {
+		limit = maxWebhookDeliveriesLimit
+	}
+
+	deliveries, err := endpoint.WebhookHandler.GetWebhookDeliveries(subscription.ID, request.Status, limit)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not read webhook deliveries")
+	}
+
+	response := &GetWebhookDeliveriesResponse{
+		Deliveries: make([]*WebhookDelivery, len(deliveries)),
+	}
+	for i, delivery := range deliveries {
+		response.Deliveries[i] = webhookDeliveryFromModel(delivery)
+	}
+
+	return response, nil
+}
+
+// RedeliverWebhookDelivery Schedules a delivery again, e.g. after it has been moved to the dead letters
+func (endpoint *HTTPEndpoints) RedeliverWebhookDelivery(ctx context.Context, request *RedeliverWebhookDeliveryRequest) (*RedeliverWebhookDeliveryResponse, error) {
+	deliveryID, err := uuid.Parse(request.ID)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, status.Error(codes.InvalidArgument, "could not parse delivery id")
+	}
+
+	delivery, err := endpoint.WebhookHandler.GetWebhookDelivery(deliveryID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.NotFound, "could not find webhook delivery")
+	}
+
+	_, err = endpoint.authorizeWebhookSubscription(ctx, delivery.SubscriptionID.String(), v1storagemodels.Right_RIGHT_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	err = endpoint.WebhookHandler.RedeliverWebhookDelivery(delivery.ID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.Internal, "could not schedule webhook delivery")
+	}
+
+	return &RedeliverWebhookDeliveryResponse{}, nil
+}
+
+// Returns the webhook backend, subscriptions can only be created if it is the configured event notification backend
+func (endpoint *HTTPEndpoints) webhookBackend() (*eventstreaming.WebhookEventStreamMgmt, error) {
+	webhooks, ok := endpoint.EventStreamMgmt.(*eventstreaming.WebhookEventStreamMgmt)
+	if !ok {
+		return nil, status.Error(codes.FailedPrecondition, "webhooks require the Webhook event notification backend")
+	}
+
+	return webhooks, nil
+}
+
+// Reads a webhook subscription and checks if the caller has the requested right on its project
+func (endpoint *HTTPEndpoints) authorizeWebhookSubscription(ctx context.Context, id string, right v1storagemodels.Right) (*models.WebhookSubscription, error) {
+	subscriptionID, err := uuid.Parse(id)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, status.Error(codes.InvalidArgument, "could not parse webhook subscription id")
+	}
+
+	subscription, err := endpoint.WebhookHandler.GetWebhookSubscription(subscriptionID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, status.Error(codes.NotFound, "could not find webhook subscription")
+	}
+
+	metadata, _ := metadata.FromIncomingContext(ctx)
+
+	err = endpoint.AuthzHandler.Authorize(
+		subscription.ProjectID,
+		right,
+		metadata)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+func webhookSubscriptionFromModel(subscription *models.WebhookSubscription) *WebhookSubscription {
+	return &WebhookSubscription{
+		ID:               subscription.ID.String(),
+		ProjectID:        subscription.ProjectID.String(),
+		URL:              subscription.URL,
+		Description:      subscription.Description,
+		Resources:        subscription.ResourceList(),
+		UpdateTypes:      subscription.UpdateTypeList(),
+		Enabled:          subscription.Enabled,
+		IncludeSnapshots: subscription.IncludeSnapshots,
+		CreatedBy:        subscription.CreatedBy,
+		Created:          subscription.CreatedAt,
+	}
+}
+
+func webhookDeliveryFromModel(delivery *models.WebhookDelivery) *WebhookDelivery {
+	webhookDelivery := &WebhookDelivery{
+		ID:             delivery.ID.String(),
+		SubscriptionID: delivery.SubscriptionID.String(),
+		EventID:        delivery.EventID.String(),
+		Resource:       delivery.Resource,
+		ResourceID:     delivery.ResourceID.String(),
+		UpdateType:     delivery.UpdateType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		Created:        delivery.CreatedAt,
+	}
+
+	if delivery.Status == models.WebhookDeliveryStatusPending {
+		webhookDelivery.NextAttempt = &delivery.NextAttemptAt
+	}
+
+	if !delivery.DeliveredAt.IsZero() {
+		webhookDelivery.Delivered = &delivery.DeliveredAt
+	}
+
+	return webhookDelivery
+}
+
+// Checks the event filters of a webhook subscription
+// Unlike the notification stream, webhook deliveries contain the resource type of user and api token events.
+func validateWebhookEventFilters(resources []string, updateTypes []string) error {
+	var apiResources []string
+	for _, resource := range resources {
+		if resource != models.EventResourceUser && resource != models.EventResourceAPIToken {
+			apiResources = append(apiResources, resource)
+		}
+	}
+
+	return validateEventFilters(apiResources, updateTypes)
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/base64"
 	"log"
 	"net/url"
 )
@@ -65,3 +66,29 @@ func VerifyHMAC_sha256(key string, url *url.URL) (bool, error) {
 
 	return true, nil
 }
+
+// SignID Calculates the url safe signature of an id with the given key.
+// This is used to sign links that only reference an entry in the database, e.g. a streaming entry.
+func SignID(key []byte, id string) (string, error) {
+	signature, err := HMAC_sha256(key, []byte(id))
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// VerifyIDSignature Verifies a signature created with SignID.
+func VerifyIDSignature(key []byte, id string, signature string) (bool, error) {
+	decodedSignature, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false, nil
+	}
+
+	calculatedSignature, err := HMAC_sha256(key, []byte(id))
+	if err != nil {
+		return false, err
+	}
+
+	return hmac.Equal(decodedSignature, calculatedSignature), nil
+}
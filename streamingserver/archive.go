@@ -0,0 +1,215 @@
+package streamingserver
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	v1storageservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/services/v1"
+)
+
+// ArchiveFormat Format of the archive that is created by the ObjectsPacker
+type ArchiveFormat string
+
+// Supported archive formats
+const (
+	ArchiveFormatTarGZ ArchiveFormat = "targz"
+	ArchiveFormatTar   ArchiveFormat = "tar"
+	ArchiveFormatZip   ArchiveFormat = "zip"
+	// BagIt bags (RFC 8493) serialized as tar or zip archive
+	ArchiveFormatBagIt    ArchiveFormat = "bagit"
+	ArchiveFormatBagItZip ArchiveFormat = "bagit-zip"
+)
+
+// FormatQueryParam Query parameter of a streaming link that can be used to select the archive format
+const FormatQueryParam = "format"
+
+// ParseArchiveFormat Parses the archive format as it is provided via the format query parameter
+func ParseArchiveFormat(format string) (ArchiveFormat, error) {
+	switch ArchiveFormat(format) {
+	case ArchiveFormatTarGZ, ArchiveFormatTar, ArchiveFormatZip, ArchiveFormatBagIt, ArchiveFormatBagItZip:
+		return ArchiveFormat(format), nil
+	default:
+		return "", fmt.Errorf("unknown archive format %v", format)
+	}
+}
+
+// ArchiveFormatFromStreamType Returns the archive format for the stream type requested when the link was created
+func ArchiveFormatFromStreamType(streamType string) (ArchiveFormat, error) {
+	switch streamType {
+	case v1storageservices.GetObjectGroupsStreamLinkRequest_STREAM_TYPE_ZIP.String():
+		return ArchiveFormatZip, nil
+	case v1storageservices.GetObjectGroupsStreamLinkRequest_STREAM_TYPE_TARGZ.String(),
+		v1storageservices.GetObjectGroupsStreamLinkRequest_STREAM_TYPE_UNSPECIFIED.String(),
+		"":
+		return ArchiveFormatTarGZ, nil
+	default:
+		return "", fmt.Errorf("could not handle requested data stream type %v", streamType)
+	}
+}
+
+// IsBagIt Checks if the archive contains a BagIt bag
+func (format ArchiveFormat) IsBagIt() bool {
+	return format == ArchiveFormatBagIt || format == ArchiveFormatBagItZip
+}
+
+// Returns the format of the underlying archive container
+func (format ArchiveFormat) container() ArchiveFormat {
+	switch format {
+	case ArchiveFormatBagIt:
+		return ArchiveFormatTar
+	case ArchiveFormatBagItZip:
+		return ArchiveFormatZip
+	default:
+		return format
+	}
+}
+
+// ContentType Http content type of the archive format
+func (format ArchiveFormat) ContentType() string {
+	switch format.container() {
+	case ArchiveFormatTar:
+		return "application/x-tar"
+	case ArchiveFormatZip:
+		return "application/zip"
+	default:
+		return "application/gzip"
+	}
+}
+
+// Extension File extension of the archive format
+func (format ArchiveFormat) Extension() string {
+	switch format.container() {
+	case ArchiveFormatTar:
+		return ".tar"
+	case ArchiveFormatZip:
+		return ".zip"
+	default:
+		return ".tar.gz"
+	}
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Filename Derives the filename of the archive from the provided name, e.g. the name of the dataset
+func (format ArchiveFormat) Filename(name string) string {
+	return SafeFilename(name) + format.Extension()
+}
+
+// SafeFilename Replaces all characters of a name that are not safe to use in a filename
+func SafeFilename(name string) string {
+	filename := strings.TrimLeft(unsafeFilenameChars.ReplaceAllString(name, "_"), ".")
+	if filename == "" {
+		filename = "dataset"
+	}
+
+	return filename
+}
+
+// archiveWriter Writes files and directories into a streamed archive
+type archiveWriter interface {
+	WriteDirectory(name string, modTime time.Time) error
+	// Starts a new file in the archive, the returned writer is valid until the next call to the archiveWriter
+	WriteFile(name string, size int64, modTime time.Time) (io.Writer, error)
+	Close() error
+}
+
+func newArchiveWriter(format ArchiveFormat, target io.Writer) (archiveWriter, error) {
+	switch format.container() {
+	case ArchiveFormatTarGZ:
+		gzipWriter := gzip.NewWriter(target)
+		return &tarArchiveWriter{
+			tarWriter:   tar.NewWriter(gzipWriter),
+			innerCloser: gzipWriter,
+		}, nil
+	case ArchiveFormatTar:
+		return &tarArchiveWriter{
+			tarWriter: tar.NewWriter(target),
+		}, nil
+	case ArchiveFormatZip:
+		return &zipArchiveWriter{
+			zipWriter: zip.NewWriter(target),
+		}, nil
+	default:
+		return nil, fmt.Errorf("could not handle requested archive format %v", format)
+	}
+}
+
+// tarArchiveWriter Writes a tar archive, optionally wrapped into a compression writer
+type tarArchiveWriter struct {
+	tarWriter   *tar.Writer
+	innerCloser io.Closer
+}
+
+func (writer *tarArchiveWriter) WriteDirectory(name string, modTime time.Time) error {
+	return writer.tarWriter.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     fmt.Sprintf("%v/", name),
+		ModTime:  modTime,
+		Mode:     0700,
+	})
+}
+
+func (writer *tarArchiveWriter) WriteFile(name string, size int64, modTime time.Time) (io.Writer, error) {
+	err := writer.tarWriter.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		ModTime:  modTime,
+		Mode:     0600,
+		Size:     size,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return writer.tarWriter, nil
+}
+
+func (writer *tarArchiveWriter) Close() error {
+	err := writer.tarWriter.Close()
+	if err != nil {
+		return err
+	}
+
+	if writer.innerCloser != nil {
+		return writer.innerCloser.Close()
+	}
+
+	return nil
+}
+
+// zipArchiveWriter Writes a zip archive without compression
+// The sizes are written in data descriptors after each file, ZIP64 records are used for files and archives larger than 4GiB.
+type zipArchiveWriter struct {
+	zipWriter *zip.Writer
+}
+
+func (writer *zipArchiveWriter) WriteDirectory(name string, modTime time.Time) error {
+	_, err := writer.zipWriter.CreateHeader(&zip.FileHeader{
+		Name:     fmt.Sprintf("%v/", name),
+		Method:   zip.Store,
+		Modified: modTime,
+	})
+
+	return err
+}
+
+func (writer *zipArchiveWriter) WriteFile(name string, size int64, modTime time.Time) (io.Writer, error) {
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Store,
+		Modified: modTime,
+	}
+	header.SetMode(0600)
+
+	return writer.zipWriter.CreateHeader(header)
+}
+
+func (writer *zipArchiveWriter) Close() error {
+	return writer.zipWriter.Close()
+}
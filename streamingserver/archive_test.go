@@ -0,0 +1,93 @@
+package streamingserver
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveFormats(t *testing.T) {
+	content := []byte("testcontent")
+
+	for _, format := range []ArchiveFormat{ArchiveFormatTar, ArchiveFormatZip} {
+		buffer := &bytes.Buffer{}
+		archive, err := newArchiveWriter(format, buffer)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		err = archive.WriteDirectory("group", time.Now())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		fileWriter, err := archive.WriteFile("group/file.txt", int64(len(content)), time.Now())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		_, err = fileWriter.Write(content)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		err = archive.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		files := make(map[string][]byte)
+		switch format {
+		case ArchiveFormatTar:
+			tarReader := tar.NewReader(buffer)
+			for {
+				header, err := tarReader.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+
+				data, err := io.ReadAll(tarReader)
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				files[header.Name] = data
+			}
+		case ArchiveFormatZip:
+			zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			for _, file := range zipReader.File {
+				assert.Equal(t, zip.Store, file.Method)
+				reader, err := file.Open()
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+
+				data, err := io.ReadAll(reader)
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				files[file.Name] = data
+			}
+		}
+
+		assert.Contains(t, files, "group/")
+		assert.Equal(t, content, files["group/file.txt"])
+	}
+}
+
+func TestArchiveFilename(t *testing.T) {
+	assert.Equal(t, "my_dataset.zip", ArchiveFormatZip.Filename("my dataset"))
+	assert.Equal(t, "dataset.tar.gz", ArchiveFormatTarGZ.Filename(""))
+	assert.Equal(t, "_.tar", ArchiveFormatTar.Filename("../"))
+}
@@ -0,0 +1,167 @@
+package streamingserver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+)
+
+// Names of the files of a BagIt bag (RFC 8493)
+const (
+	bagItFilename          = "bagit.txt"
+	bagInfoFilename        = "bag-info.txt"
+	bagPayloadFolder       = "data"
+	bagManifestFilename    = "manifest-sha256.txt"
+	bagTagManifestFilename = "tagmanifest-sha256.txt"
+)
+
+// BagInfoField A single metadata field of the bag-info.txt file
+type BagInfoField struct {
+	Label string
+	Value string
+}
+
+// NewBagInfo Creates the bag-info.txt fields that describe a dataset and optionally one of its versions
+// Payload-Oxum, Bag-Size and Bagging-Date are added by the packer.
+func NewBagInfo(dataset *models.Dataset, version *models.DatasetVersion) []BagInfoField {
+	fields := []BagInfoField{
+		{Label: "External-Identifier", Value: dataset.ID.String()},
+		{Label: "Bag-Group-Identifier", Value: dataset.ID.String()},
+		{Label: "Dataset-ID", Value: dataset.ID.String()},
+		{Label: "Dataset-Name", Value: dataset.Name},
+	}
+
+	if dataset.Description != "" {
+		fields = append(fields, BagInfoField{Label: "External-Description", Value: dataset.Description})
+	}
+
+	for _, label := range dataset.Labels {
+		fields = append(fields, BagInfoField{Label: "Dataset-Label", Value: fmt.Sprintf("%v=%v", label.Key, label.Value)})
+	}
+
+	if version != nil {
+		fields[0].Value = version.ID.String()
+		fields = append(fields,
+			BagInfoField{Label: "Dataset-Version-ID", Value: version.ID.String()},
+			BagInfoField{Label: "Dataset-Version", Value: fmt.Sprintf("%v.%v.%v", version.MajorVersion, version.MinorVersion, version.PatchVersion)},
+		)
+
+		if version.Stage != "" {
+			fields = append(fields, BagInfoField{Label: "Dataset-Version-Stage", Value: version.Stage})
+		}
+
+		if version.Name != "" {
+			fields = append(fields, BagInfoField{Label: "Dataset-Version-Name", Value: version.Name})
+		}
+
+		if version.Description != "" {
+			fields = append(fields, BagInfoField{Label: "Dataset-Version-Description", Value: version.Description})
+		}
+
+		for _, label := range version.Labels {
+			fields = append(fields, BagInfoField{Label: "Dataset-Version-Label", Value: fmt.Sprintf("%v=%v", label.Key, label.Value)})
+		}
+	}
+
+	return fields
+}
+
+// Returns the path of a payload file inside the archive
+func (packer *ObjectsPacker) bagPayloadPath(payloadPath string) string {
+	return path.Join(packer.BagName, bagPayloadFolder, payloadPath)
+}
+
+// Writes the bagit.txt declaration, it is the first file of each bag
+func (packer *ObjectsPacker) writeBagDeclaration(archive archiveWriter) ([]byte, error) {
+	declaration := []byte("BagIt-Version: 1.0\nTag-File-Character-Encoding: UTF-8\n")
+	err := writeArchiveFile(archive, path.Join(packer.BagName, bagItFilename), declaration)
+	if err != nil {
+		return nil, err
+	}
+
+	return declaration, nil
+}
+
+// Writes the payload manifest, bag-info.txt, the object manifest and the tag manifest of the bag
+// The checksums of the payload have been calculated while streaming the objects.
+func (packer *ObjectsPacker) writeBagTagFiles(archive archiveWriter, manifest *Manifest, declaration []byte) error {
+	objects := manifest.objects()
+
+	var payloadOctets int64
+	payloadManifest := &bytes.Buffer{}
+	for _, object := range objects {
+		payloadOctets = payloadOctets + object.Size
+		fmt.Fprintf(payloadManifest, "%v  %v\n", object.SHA256, encodeBagPath(path.Join(bagPayloadFolder, object.Path)))
+	}
+
+	bagInfo := &bytes.Buffer{}
+	fields := append([]BagInfoField{}, packer.BagInfo...)
+	fields = append(fields,
+		BagInfoField{Label: "Bagging-Date", Value: time.Now().UTC().Format("2006-01-02")},
+		BagInfoField{Label: "Bag-Size", Value: humanReadableSize(payloadOctets)},
+		BagInfoField{Label: "Payload-Oxum", Value: fmt.Sprintf("%v.%v", payloadOctets, len(objects))},
+	)
+	for _, field := range fields {
+		// Continuation lines of multiline values have to start with whitespace
+		value := strings.ReplaceAll(strings.TrimSpace(field.Value), "\n", "\n  ")
+		fmt.Fprintf(bagInfo, "%v: %v\n", field.Label, value)
+	}
+
+	encodedManifest, err := manifest.Encode()
+	if err != nil {
+		return err
+	}
+
+	tagFiles := []struct {
+		name    string
+		content []byte
+	}{
+		{name: bagItFilename, content: declaration},
+		{name: ManifestFilename, content: encodedManifest},
+		{name: bagManifestFilename, content: payloadManifest.Bytes()},
+		{name: bagInfoFilename, content: bagInfo.Bytes()},
+	}
+
+	tagManifest := &bytes.Buffer{}
+	for _, tagFile := range tagFiles {
+		// bagit.txt has already been written at the start of the bag
+		if tagFile.name != bagItFilename {
+			err := writeArchiveFile(archive, path.Join(packer.BagName, tagFile.name), tagFile.content)
+			if err != nil {
+				return err
+			}
+		}
+
+		checksum := sha256.Sum256(tagFile.content)
+		fmt.Fprintf(tagManifest, "%v  %v\n", hex.EncodeToString(checksum[:]), tagFile.name)
+	}
+
+	return writeArchiveFile(archive, path.Join(packer.BagName, bagTagManifestFilename), tagManifest.Bytes())
+}
+
+// Encodes a path for a manifest file, CR, LF and % have to be percent encoded
+func encodeBagPath(filepath string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(filepath)
+}
+
+func humanReadableSize(size int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB", "PB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1000 && unit < len(units)-1 {
+		value = value / 1000
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%v %v", size, units[unit])
+	}
+
+	return fmt.Sprintf("%.1f %v", value, units[unit])
+}
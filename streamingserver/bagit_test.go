@@ -0,0 +1,74 @@
+package streamingserver
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBagTagFiles(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	archive, err := newArchiveWriter(ArchiveFormatBagIt, buffer)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	packer := &ObjectsPacker{
+		Format:  ArchiveFormatBagIt,
+		BagName: "testbag",
+		BagInfo: []BagInfoField{{Label: "External-Description", Value: "first line\nsecond line"}},
+	}
+
+	declaration, err := packer.writeBagDeclaration(archive)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	manifest := newManifest()
+	manifest.ObjectGroups = append(manifest.ObjectGroups, &ManifestObjectGroup{
+		Objects: []*ManifestObject{
+			{Path: "group/b%.txt", Size: 1500, SHA256: "bb"},
+			{Path: "group/a.txt", Size: 10, SHA256: "aa"},
+		},
+	})
+
+	err = packer.writeBagTagFiles(archive, manifest, declaration)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = archive.Close()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	files := make(map[string]string)
+	tarReader := tar.NewReader(buffer)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		data, err := io.ReadAll(tarReader)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		files[header.Name] = string(data)
+	}
+
+	assert.Equal(t, "BagIt-Version: 1.0\nTag-File-Character-Encoding: UTF-8\n", files["testbag/bagit.txt"])
+	assert.Equal(t, "aa  data/group/a.txt\nbb  data/group/b%25.txt\n", files["testbag/manifest-sha256.txt"])
+	assert.Contains(t, files["testbag/bag-info.txt"], "External-Description: first line\n  second line\n")
+	assert.Contains(t, files["testbag/bag-info.txt"], "Payload-Oxum: 1510.2\n")
+	assert.Contains(t, files["testbag/bag-info.txt"], "Bag-Size: 1.5 KB\n")
+	assert.Contains(t, files, "testbag/manifest.json")
+	assert.Equal(t, 4, len(strings.Split(strings.TrimSpace(files["testbag/tagmanifest-sha256.txt"]), "\n")))
+}
@@ -0,0 +1,567 @@
+package streamingserver
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ScienceObjectsDB/CORE-Server/database"
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	"github.com/ScienceObjectsDB/CORE-Server/objectstorage"
+	"github.com/google/uuid"
+)
+
+// Interval in which the progress of a running import job is stored
+const importProgressInterval = time.Second
+
+// Maximum size of a manifest.json that is read during an import
+const maxImportManifestSize = 64 * 1024 * 1024
+
+// Interval in which uploading and running jobs are marked as alive
+const importHeartbeatInterval = time.Minute
+
+// Time after which an uploading or running job that has not been marked as alive is considered interrupted
+const importStaleTimeout = 5 * importHeartbeatInterval
+
+// ArchiveImporter Unpacks uploaded tar and zip archives into datasets
+//
+// Each top level directory of the archive becomes an object group, the files below it become the data objects of the group.
+// Files below metadata/<group>/ become metadata objects of the group, files directly below metadata/ become metadata objects
+// of the dataset. A manifest.json as it is written by the ObjectsPacker is used for the descriptions and labels of the
+// object groups and objects, archives created by a streaming link can therefore be imported again.
+// Entries that can not be imported are recorded as errors of the job without aborting the import.
+type ArchiveImporter struct {
+	ImportHandler *database.Imports
+	ObjectHandler *objectstorage.S3ObjectStorageHandler
+}
+
+// ParseImportFormat Parses the format of an uploaded archive, BagIt bags are not supported
+func ParseImportFormat(format string) (ArchiveFormat, error) {
+	archiveFormat, err := ParseArchiveFormat(format)
+	if err != nil {
+		return "", err
+	}
+
+	if archiveFormat.IsBagIt() {
+		return "", fmt.Errorf("archive format %v can not be imported", format)
+	}
+
+	return archiveFormat, nil
+}
+
+// StagingLocation Returns the location the archive of a job is uploaded to
+func StagingLocation(job *models.ImportJob) *models.Location {
+	return &models.Location{
+		Bucket: job.StagingBucket,
+		Key:    job.StagingKey,
+	}
+}
+
+// StartImport Starts the import of an uploaded archive in the background
+// The job has to be in the provided status, returns false if it is not, e.g. because it has already been started.
+func (importer *ArchiveImporter) StartImport(job *models.ImportJob, fromStatus string) (bool, error) {
+	size, err := importer.ObjectHandler.GetObjectSize(context.Background(), StagingLocation(job))
+	if err != nil {
+		log.Println(err.Error())
+		return false, fmt.Errorf("could not find uploaded archive")
+	}
+
+	err = importer.ImportHandler.SetImportJobArchiveSize(job.ID, size)
+	if err != nil {
+		log.Println(err.Error())
+		return false, err
+	}
+
+	started, err := importer.ImportHandler.TransitionImportJob(job.ID, []string{fromStatus}, models.ImportJobStatusRunning, "")
+	if err != nil {
+		log.Println(err.Error())
+		return false, err
+	}
+
+	if !started {
+		return false, nil
+	}
+
+	job.ArchiveSize = size
+	job.Status = models.ImportJobStatusRunning
+
+	go importer.runImport(job)
+
+	return true, nil
+}
+
+// FailImport Fails a job that is in the provided status and removes its uploaded archive
+func (importer *ArchiveImporter) FailImport(job *models.ImportJob, fromStatus string, message string) {
+	failed, err := importer.ImportHandler.TransitionImportJob(job.ID, []string{fromStatus}, models.ImportJobStatusFailed, message)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	if failed {
+		importer.deleteStagedArchives([]*models.ImportJob{job})
+	}
+}
+
+// Run Detects import jobs that have been interrupted, e.g. by a restart of their server, until the context is cancelled
+// Interrupted uploads are reset so that the archive can be uploaded again, interrupted imports are failed. Jobs are
+// interrupted once they have not been marked as alive for importStaleTimeout, jobs of other servers are not affected.
+func (importer *ArchiveImporter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(importHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		failed, err := importer.ImportHandler.ExpireStaleImportJobs(time.Now().Add(-importStaleTimeout))
+		if err != nil {
+			log.Errorln(err.Error())
+		}
+
+		for _, job := range failed {
+			log.Errorf("import job %v has been interrupted", job.ID.String())
+		}
+
+		// Objects that have already been uploaded by an interrupted import are not known and remain in the bucket
+		importer.deleteStagedArchives(failed)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Marks a job as alive in the importHeartbeatInterval until the returned function is called
+func (importer *ArchiveImporter) keepAlive(jobID uuid.UUID) func() {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(importHeartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := importer.ImportHandler.TouchImportJob(jobID)
+				if err != nil {
+					log.Println(err.Error())
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+func (importer *ArchiveImporter) deleteStagedArchives(jobs []*models.ImportJob) {
+	if len(jobs) == 0 {
+		return
+	}
+
+	locations := make([]*models.Location, len(jobs))
+	for i, job := range jobs {
+		locations[i] = StagingLocation(job)
+	}
+
+	err := importer.ObjectHandler.DeleteObjects(locations)
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// Runs an import job and stores its result
+// If the result can not be stored the job is failed by Run once it is considered interrupted.
+func (importer *ArchiveImporter) runImport(job *models.ImportJob) {
+	stopKeepAlive := importer.keepAlive(job.ID)
+	defer stopKeepAlive()
+
+	run := &importRun{
+		importer: importer,
+		job:      job,
+		groups:   make(map[string]*importGroup),
+	}
+
+	ctx := context.Background()
+
+	err := run.readArchive(ctx)
+	if err != nil {
+		run.deleteUploadedObjects()
+	} else {
+		run.createObjectGroups()
+	}
+
+	run.reportProgress(true)
+
+	status, message := models.ImportJobStatusFinished, ""
+	if err != nil {
+		status, message = models.ImportJobStatusFailed, err.Error()
+	}
+
+	_, err = importer.ImportHandler.TransitionImportJob(job.ID, []string{models.ImportJobStatusRunning}, status, message)
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	importer.deleteStagedArchives([]*models.ImportJob{job})
+}
+
+type importEntryType int
+
+const (
+	importEntryInvalid importEntryType = iota
+	importEntryIgnored
+	importEntryManifest
+	importEntryData
+	importEntryObjectGroupMetadata
+	importEntryDatasetMetadata
+)
+
+// State of a single running import
+type importRun struct {
+	importer         *ArchiveImporter
+	job              *models.ImportJob
+	manifest         *Manifest
+	groups           map[string]*importGroup
+	groupOrder       []*importGroup
+	datasetMetadata  []*importedObject
+	lastProgressSave time.Time
+	// Entries of streamed archives have to be read completely before the next entry can be read
+	streamed bool
+}
+
+// An object group that is assembled from the entries of the archive
+type importGroup struct {
+	name        string
+	dataObjects []*importedObject
+	metaObjects []*importedObject
+}
+
+// An uploaded object and the path of its entry in the archive
+type importedObject struct {
+	path   string
+	object models.Object
+}
+
+// Reads all entries of the archive and uploads the contained files
+// Only errors that prevent reading the rest of the archive are returned, all other errors are recorded as entry errors.
+func (run *importRun) readArchive(ctx context.Context) error {
+	location := StagingLocation(run.job)
+
+	format, err := ParseImportFormat(run.job.Format)
+	if err != nil {
+		return err
+	}
+
+	if format == ArchiveFormatZip {
+		zipReader, err := zip.NewReader(run.importer.ObjectHandler.NewObjectReaderAt(ctx, location), run.job.ArchiveSize)
+		if err != nil {
+			return fmt.Errorf("could not read archive: %w", err)
+		}
+
+		for _, file := range zipReader.File {
+			if !file.FileInfo().IsDir() {
+				fileReader, err := file.Open()
+				if err != nil {
+					run.entryError(file.Name, err)
+				} else {
+					err = run.importEntry(ctx, file.Name, int64(file.UncompressedSize64), fileReader)
+					fileReader.Close()
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			run.job.ReadBytes = run.job.ReadBytes + int64(file.CompressedSize64)
+			run.reportProgress(false)
+		}
+
+		return nil
+	}
+
+	body, err := run.importer.ObjectHandler.ObjectReader(ctx, location)
+	if err != nil {
+		return fmt.Errorf("could not read archive: %w", err)
+	}
+	defer body.Close()
+
+	archiveReader := &countingReader{reader: body}
+	var reader io.Reader = archiveReader
+	if format == ArchiveFormatTarGZ {
+		gzipReader, err := gzip.NewReader(archiveReader)
+		if err != nil {
+			return fmt.Errorf("could not read archive: %w", err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	run.streamed = true
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not read archive: %w", err)
+		}
+
+		if header.Typeflag == tar.TypeReg {
+			err = run.importEntry(ctx, header.Name, header.Size, tarReader)
+			if err != nil {
+				return err
+			}
+		}
+
+		run.job.ReadBytes = archiveReader.count
+		run.reportProgress(false)
+	}
+}
+
+// Imports a single file of the archive
+func (run *importRun) importEntry(ctx context.Context, name string, size int64, reader io.Reader) error {
+	run.job.ProcessedEntries++
+
+	entryPath := path.Clean("/" + name)[1:]
+	entryType, groupName, filename := classifyImportEntry(entryPath)
+
+	switch entryType {
+	case importEntryIgnored:
+		return nil
+	case importEntryInvalid:
+		run.entryError(name, fmt.Errorf("files have to be placed in the directory of an object group"))
+		return nil
+	case importEntryManifest:
+		manifest := &Manifest{}
+		err := json.NewDecoder(io.LimitReader(reader, maxImportManifestSize)).Decode(manifest)
+		if err != nil {
+			run.entryError(name, fmt.Errorf("could not parse manifest: %w", err))
+			return nil
+		}
+		run.manifest = manifest
+		return nil
+	}
+
+	object := run.importer.ImportHandler.NewImportedObject(run.job, filename, run.job.StagingBucket)
+	object.ContentLen = size
+
+	objectReader := &countingReader{reader: reader}
+	err := run.importer.ObjectHandler.UploadObject(ctx, &object.DefaultLocation, objectReader)
+	if err == nil && objectReader.count != size {
+		err = fmt.Errorf("size of entry does not match: expected %v bytes, got %v bytes", size, objectReader.count)
+	}
+	if err != nil {
+		run.entryError(name, err)
+		run.deleteObjects([]*importedObject{{object: object}})
+		if run.streamed && objectReader.count != size {
+			return fmt.Errorf("could not read entry %v: %w", name, err)
+		}
+		return nil
+	}
+
+	run.job.ImportedObjects++
+	run.job.ImportedBytes = run.job.ImportedBytes + size
+
+	imported := &importedObject{
+		path:   entryPath,
+		object: object,
+	}
+
+	if entryType == importEntryDatasetMetadata {
+		run.datasetMetadata = append(run.datasetMetadata, imported)
+		return nil
+	}
+
+	group, ok := run.groups[groupName]
+	if !ok {
+		group = &importGroup{name: groupName}
+		run.groups[groupName] = group
+		run.groupOrder = append(run.groupOrder, group)
+	}
+
+	if entryType == importEntryObjectGroupMetadata {
+		group.metaObjects = append(group.metaObjects, imported)
+	} else {
+		group.dataObjects = append(group.dataObjects, imported)
+	}
+
+	return nil
+}
+
+// Creates the object groups and dataset metadata objects from the uploaded objects
+// Descriptions and labels are taken from the manifest of the archive if it contains one.
+func (run *importRun) createObjectGroups() {
+	manifestGroups := make(map[string]*ManifestObjectGroup)
+	manifestObjects := make(map[string]*ManifestObject)
+	if run.manifest != nil {
+		if run.manifest.Dataset != nil {
+			for _, object := range run.manifest.Dataset.MetadataObjects {
+				manifestObjects[object.Path] = object
+			}
+		}
+
+		for _, group := range run.manifest.ObjectGroups {
+			manifestGroups[group.Name] = group
+			for _, object := range append(group.Objects, group.MetadataObjects...) {
+				manifestObjects[object.Path] = object
+			}
+		}
+	}
+
+	objects := func(importedObjects []*importedObject) []models.Object {
+		objects := make([]models.Object, len(importedObjects))
+		for i, imported := range importedObjects {
+			objects[i] = imported.object
+			if manifestObject, ok := manifestObjects[imported.path]; ok {
+				objects[i].Filetype = manifestObject.Filetype
+				objects[i].Labels = importLabels(manifestObject.Labels)
+			}
+		}
+		return objects
+	}
+
+	for _, group := range run.groupOrder {
+		importedGroup := &database.ImportedObjectGroup{
+			Name:        group.name,
+			DataObjects: objects(group.dataObjects),
+			MetaObjects: objects(group.metaObjects),
+		}
+
+		if manifestGroup, ok := manifestGroups[group.name]; ok {
+			importedGroup.Description = manifestGroup.Description
+			importedGroup.Labels = importLabels(manifestGroup.Labels)
+		}
+
+		_, err := run.importer.ImportHandler.CreateImportedObjectGroup(run.job, importedGroup)
+		if err != nil {
+			run.entryError(group.name, fmt.Errorf("could not create object group: %w", err))
+			run.deleteObjects(append(group.dataObjects, group.metaObjects...))
+			continue
+		}
+
+		run.job.CreatedObjectGroups++
+
+		run.reportProgress(false)
+	}
+
+	if len(run.datasetMetadata) > 0 {
+		err := run.importer.ImportHandler.AddImportedDatasetMetaObjects(run.job, objects(run.datasetMetadata))
+		if err != nil {
+			run.entryError(MetadataFolder, fmt.Errorf("could not add dataset metadata objects: %w", err))
+			run.deleteObjects(run.datasetMetadata)
+		}
+	}
+}
+
+// Records an entry that could not be imported
+func (run *importRun) entryError(entry string, err error) {
+	log.Debugf("could not import entry %v of import job %v: %v", entry, run.job.ID, err.Error())
+
+	run.job.FailedEntries++
+	err = run.importer.ImportHandler.AddImportJobError(run.job.ID, entry, err.Error())
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// Stores the progress of the job, unless it has been stored recently
+func (run *importRun) reportProgress(force bool) {
+	if !force && time.Since(run.lastProgressSave) < importProgressInterval {
+		return
+	}
+
+	err := run.importer.ImportHandler.UpdateImportJobProgress(run.job)
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	run.lastProgressSave = time.Now()
+}
+
+// Removes all objects that have been uploaded by a failed import
+func (run *importRun) deleteUploadedObjects() {
+	objects := run.datasetMetadata
+	for _, group := range run.groupOrder {
+		objects = append(objects, group.dataObjects...)
+		objects = append(objects, group.metaObjects...)
+	}
+
+	run.deleteObjects(objects)
+}
+
+func (run *importRun) deleteObjects(objects []*importedObject) {
+	locations := make([]*models.Location, len(objects))
+	for i := range objects {
+		locations[i] = &objects[i].object.DefaultLocation
+	}
+
+	err := run.importer.ObjectHandler.DeleteObjects(locations)
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// Determines how an entry of an archive is imported based on its cleaned path
+// Returns the type of the entry, the name of the object group and the filename of the object within the group.
+func classifyImportEntry(entryPath string) (importEntryType, string, string) {
+	parts := strings.SplitN(entryPath, "/", 2)
+	if len(parts) == 1 {
+		switch entryPath {
+		case ManifestFilename:
+			return importEntryManifest, "", ""
+		case ChecksumsFilename:
+			return importEntryIgnored, "", ""
+		default:
+			return importEntryInvalid, "", ""
+		}
+	}
+
+	if parts[0] != MetadataFolder {
+		return importEntryData, objectGroupName(parts[0]), parts[1]
+	}
+
+	metadataParts := strings.SplitN(parts[1], "/", 2)
+	if len(metadataParts) == 1 {
+		return importEntryDatasetMetadata, "", parts[1]
+	}
+
+	return importEntryObjectGroupMetadata, objectGroupName(metadataParts[0]), metadataParts[1]
+}
+
+func importLabels(manifestLabels []*ManifestLabel) []models.Label {
+	labels := make([]models.Label, len(manifestLabels))
+	for i, label := range manifestLabels {
+		labels[i] = models.Label{
+			Key:   label.Key,
+			Value: label.Value,
+		}
+	}
+
+	return labels
+}
+
+// countingReader Counts the bytes read from the wrapped reader
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (reader *countingReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	reader.count = reader.count + int64(n)
+	return n, err
+}
@@ -0,0 +1,36 @@
+package streamingserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyImportEntry(t *testing.T) {
+	tests := []struct {
+		path      string
+		entryType importEntryType
+		group     string
+		filename  string
+	}{
+		{path: "manifest.json", entryType: importEntryManifest},
+		{path: "SHA256SUMS", entryType: importEntryIgnored},
+		{path: "file.txt", entryType: importEntryInvalid},
+		{path: "group/file.txt", entryType: importEntryData, group: "group", filename: "file.txt"},
+		{path: "group/nested/file.txt", entryType: importEntryData, group: "group", filename: "nested/file.txt"},
+		{path: "metadata/dataset.json", entryType: importEntryDatasetMetadata, filename: "dataset.json"},
+		{path: "metadata/group/meta.json", entryType: importEntryObjectGroupMetadata, group: "group", filename: "meta.json"},
+		// Folders of object groups with reserved names
+		{path: "_metadata/file.txt", entryType: importEntryData, group: "metadata", filename: "file.txt"},
+		{path: "metadata/_manifest.json/meta.json", entryType: importEntryObjectGroupMetadata, group: "manifest.json", filename: "meta.json"},
+		{path: "__metadata/file.txt", entryType: importEntryData, group: "_metadata", filename: "file.txt"},
+		{path: "_group/file.txt", entryType: importEntryData, group: "_group", filename: "file.txt"},
+	}
+
+	for _, test := range tests {
+		entryType, group, filename := classifyImportEntry(test.path)
+		assert.Equal(t, test.entryType, entryType, test.path)
+		assert.Equal(t, test.group, group, test.path)
+		assert.Equal(t, test.filename, filename, test.path)
+	}
+}
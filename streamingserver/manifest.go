@@ -0,0 +1,214 @@
+package streamingserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+)
+
+// Names of the additional files and folders that are written into each archive
+const (
+	ManifestFilename  = "manifest.json"
+	ChecksumsFilename = "SHA256SUMS"
+	MetadataFolder    = "metadata"
+)
+
+// Prefix of the folders of object groups whose name collides with the name of a generated file or folder
+const escapedGroupPrefix = "_"
+
+// ManifestVersion Version of the manifest format
+const ManifestVersion = 1
+
+// Manifest Machine readable description of the content of an archive
+// It can be used to verify the content of the archive and to re-import it.
+type Manifest struct {
+	ManifestVersion  int                    `json:"manifest_version"`
+	Created          time.Time              `json:"created"`
+	Dataset          *ManifestDataset       `json:"dataset,omitempty"`
+	DatasetVersionID string                 `json:"dataset_version_id,omitempty"`
+	ObjectGroups     []*ManifestObjectGroup `json:"object_groups"`
+}
+
+// ManifestDataset Dataset the archived object groups belong to
+type ManifestDataset struct {
+	ID              string            `json:"id"`
+	Name            string            `json:"name"`
+	Description     string            `json:"description,omitempty"`
+	Labels          []*ManifestLabel  `json:"labels"`
+	MetadataObjects []*ManifestObject `json:"metadata_objects"`
+}
+
+// ManifestObjectGroup A single archived object group revision
+type ManifestObjectGroup struct {
+	ObjectGroupID   string            `json:"object_group_id"`
+	RevisionID      string            `json:"revision_id"`
+	RevisionNumber  uint64            `json:"revision_number"`
+	Name            string            `json:"name"`
+	Description     string            `json:"description,omitempty"`
+	Generated       time.Time         `json:"generated"`
+	Labels          []*ManifestLabel  `json:"labels"`
+	Objects         []*ManifestObject `json:"objects"`
+	MetadataObjects []*ManifestObject `json:"metadata_objects"`
+}
+
+// ManifestObject A single archived object, the path is relative to the root of the archive
+type ManifestObject struct {
+	ID       string           `json:"id"`
+	Filename string           `json:"filename"`
+	Filetype string           `json:"filetype,omitempty"`
+	Path     string           `json:"path"`
+	Size     int64            `json:"size"`
+	SHA256   string           `json:"sha256"`
+	Labels   []*ManifestLabel `json:"labels"`
+}
+
+// ManifestLabel A key value label
+type ManifestLabel struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func newManifest() *Manifest {
+	return &Manifest{
+		ManifestVersion: ManifestVersion,
+		Created:         time.Now().UTC(),
+		ObjectGroups:    make([]*ManifestObjectGroup, 0),
+	}
+}
+
+func newManifestDataset(dataset *models.Dataset) *ManifestDataset {
+	return &ManifestDataset{
+		ID:              dataset.ID.String(),
+		Name:            dataset.Name,
+		Description:     dataset.Description,
+		Labels:          manifestLabels(dataset.Labels),
+		MetadataObjects: make([]*ManifestObject, 0),
+	}
+}
+
+func newManifestObjectGroup(revision *models.ObjectGroupRevision) *ManifestObjectGroup {
+	return &ManifestObjectGroup{
+		ObjectGroupID:   revision.ObjectGroupID.String(),
+		RevisionID:      revision.ID.String(),
+		RevisionNumber:  revision.RevisionNumber,
+		Name:            revision.Name,
+		Description:     revision.Description,
+		Generated:       revision.Generated,
+		Labels:          manifestLabels(revision.Labels),
+		Objects:         make([]*ManifestObject, 0),
+		MetadataObjects: make([]*ManifestObject, 0),
+	}
+}
+
+func newManifestObject(object *models.Object, path string, checksum string) *ManifestObject {
+	return &ManifestObject{
+		ID:       object.ID.String(),
+		Filename: object.Filename,
+		Filetype: object.Filetype,
+		Path:     path,
+		Size:     object.ContentLen,
+		SHA256:   checksum,
+		Labels:   manifestLabels(object.Labels),
+	}
+}
+
+// Returns the folder of an object group in an archive
+// Names of generated files and folders, e.g. metadata or manifest.json, are prefixed with an underscore. Names that only
+// differ from them by leading underscores get another underscore, so objectGroupName restores every name. Path
+// separators are replaced with underscores and the names "." and ".." are prefixed as well, so the folder is always a
+// single directory within the archive.
+func objectGroupFolder(name string) string {
+	folder := strings.NewReplacer("/", escapedGroupPrefix, "\\", escapedGroupPrefix).Replace(name)
+
+	switch {
+	case folder == "", folder == ".", folder == "..":
+		return escapedGroupPrefix + folder
+	case isReservedName(strings.TrimLeft(folder, escapedGroupPrefix)):
+		return escapedGroupPrefix + folder
+	}
+
+	return folder
+}
+
+// Returns the path of an object below a folder of an archive
+// Backslashes are treated as separators, absolute paths and ".." segments are resolved within the folder in the same
+// way as entries are cleaned on import, so an entry can not be written outside of the archive root.
+func objectPath(folder string, filename string) string {
+	entryPath := path.Clean("/" + strings.ReplaceAll(filename, "\\", "/"))[1:]
+	if entryPath == "" {
+		entryPath = escapedGroupPrefix
+	}
+
+	return path.Join(folder, entryPath)
+}
+
+// Returns the name of an object group from its folder in an archive, it reverts objectGroupFolder
+func objectGroupName(folder string) string {
+	if strings.HasPrefix(folder, escapedGroupPrefix) && isReservedName(strings.TrimLeft(folder, escapedGroupPrefix)) {
+		return strings.TrimPrefix(folder, escapedGroupPrefix)
+	}
+
+	return folder
+}
+
+func isReservedName(name string) bool {
+	switch name {
+	case ManifestFilename, ChecksumsFilename, MetadataFolder:
+		return true
+	default:
+		return false
+	}
+}
+
+func manifestLabels(labels []models.Label) []*ManifestLabel {
+	manifestLabels := make([]*ManifestLabel, len(labels))
+	for i, label := range labels {
+		manifestLabels[i] = &ManifestLabel{
+			Key:   label.Key,
+			Value: label.Value,
+		}
+	}
+
+	return manifestLabels
+}
+
+// Encode Encodes the manifest as indented json
+func (manifest *Manifest) Encode() ([]byte, error) {
+	return json.MarshalIndent(manifest, "", "  ")
+}
+
+// Checksums Creates the content of a SHA256SUMS file for all objects in the manifest
+// The format is compatible with sha256sum --check
+func (manifest *Manifest) Checksums() []byte {
+	buffer := &bytes.Buffer{}
+	for _, object := range manifest.objects() {
+		fmt.Fprintf(buffer, "%v  %v\n", object.SHA256, object.Path)
+	}
+
+	return buffer.Bytes()
+}
+
+// Returns all objects of the manifest sorted by their path
+func (manifest *Manifest) objects() []*ManifestObject {
+	var objects []*ManifestObject
+	if manifest.Dataset != nil {
+		objects = append(objects, manifest.Dataset.MetadataObjects...)
+	}
+
+	for _, objectGroup := range manifest.ObjectGroups {
+		objects = append(objects, objectGroup.Objects...)
+		objects = append(objects, objectGroup.MetadataObjects...)
+	}
+
+	sort.SliceStable(objects, func(i, j int) bool {
+		return objects[i].Path < objects[j].Path
+	})
+
+	return objects
+}
@@ -0,0 +1,81 @@
+package streamingserver
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestChecksums(t *testing.T) {
+	manifest := newManifest()
+	manifest.Dataset = &ManifestDataset{
+		MetadataObjects: []*ManifestObject{{Path: "metadata/dataset.json", SHA256: "aa"}},
+	}
+	manifest.ObjectGroups = append(manifest.ObjectGroups, &ManifestObjectGroup{
+		Objects:         []*ManifestObject{{Path: "group/b.fastq", SHA256: "bb"}, {Path: "group/a.fastq", SHA256: "cc"}},
+		MetadataObjects: []*ManifestObject{{Path: "metadata/group/meta.json", SHA256: "dd"}},
+	})
+
+	expected := "cc  group/a.fastq\nbb  group/b.fastq\naa  metadata/dataset.json\ndd  metadata/group/meta.json\n"
+	assert.Equal(t, expected, string(manifest.Checksums()))
+
+	encodedManifest, err := manifest.Encode()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	decodedManifest := &Manifest{}
+	err = json.Unmarshal(encodedManifest, decodedManifest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, ManifestVersion, decodedManifest.ManifestVersion)
+	assert.Len(t, decodedManifest.ObjectGroups, 1)
+}
+
+func TestObjectGroupFolder(t *testing.T) {
+	tests := map[string]string{
+		"group":         "group",
+		"_group":        "_group",
+		"metadata":      "_metadata",
+		"manifest.json": "_manifest.json",
+		"SHA256SUMS":    "_SHA256SUMS",
+		"_metadata":     "__metadata",
+	}
+
+	for name, folder := range tests {
+		assert.Equal(t, folder, objectGroupFolder(name), name)
+		assert.Equal(t, name, objectGroupName(folder), folder)
+	}
+
+	unsafeTests := map[string]string{
+		"..":       "_..",
+		".":        "_.",
+		"":         "_",
+		"../group": ".._group",
+		"/group":   "_group",
+		"a\\..\\b": "a_.._b",
+	}
+
+	for name, folder := range unsafeTests {
+		assert.Equal(t, folder, objectGroupFolder(name), name)
+	}
+}
+
+func TestObjectPath(t *testing.T) {
+	tests := map[string]string{
+		"file.txt":           "group/file.txt",
+		"sub/file.txt":       "group/sub/file.txt",
+		"../../etc/passwd":   "group/etc/passwd",
+		"/etc/passwd":        "group/etc/passwd",
+		"sub/../../file.txt": "group/file.txt",
+		"..\\..\\file.txt":   "group/file.txt",
+		"..":                 "group/_",
+	}
+
+	for filename, entryPath := range tests {
+		assert.Equal(t, entryPath, objectPath("group", filename), filename)
+	}
+}
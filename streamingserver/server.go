@@ -1,7 +1,10 @@
 package streamingserver
 
 import (
+	"context"
 	"fmt"
+	"mime"
+	"time"
 
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
@@ -10,39 +13,64 @@ import (
 	"github.com/ScienceObjectsDB/CORE-Server/models"
 	"github.com/ScienceObjectsDB/CORE-Server/objectstorage"
 	"github.com/ScienceObjectsDB/CORE-Server/signing"
-	v1storageservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/services/v1"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/sync/errgroup"
 )
 
 // DataStreamingServer Provides endpoint to stream a given set of objects via a presigned http get call
 // The used link has to be created via the regular gRPC endpoints and can be used by anyone who has the link
+// until it expires or is revoked.
 type DataStreamingServer struct {
-	SigningSecret string
-	ReadHandler   *database.Read
-	ObjectHandler *objectstorage.S3ObjectStorageHandler
+	ReadHandler      *database.Read
+	StreamingHandler *database.Streaming
+	ObjectHandler    *objectstorage.S3ObjectStorageHandler
+	// Number of objects that are downloaded in advance while streaming
+	PrefetchCount int
+	// Maximum duration of a single read from the object storage
+	ReadTimeout time.Duration
+	// Optional, accepts the archive uploads of import jobs if set
+	Importer *ArchiveImporter
 }
 
 // Starts the server on port 9011
-func (server *DataStreamingServer) Run() error {
+// Additional routes, e.g. of the http api, can be registered via the provided route functions.
+func (server *DataStreamingServer) Run(routes ...func(router gin.IRouter)) error {
 	r := gin.Default()
-	r.GET("/dataset", server.datasetStream)
+	server.RegisterRoutes(r)
+
+	for _, route := range routes {
+		route(r)
+	}
 
 	return r.Run(":9011")
 }
 
-// Handles a stream that bundles all objectgroups of a dataset into a single byte stream
-func (server *DataStreamingServer) datasetStream(c *gin.Context) {
-	c.Request.URL.Host = c.Request.Host
-	if c.Request.URL.Scheme == "" && c.Request.Host == "localhost" {
-		c.Request.URL.Scheme = "http"
+// RegisterRoutes Registers the data streaming routes
+func (server *DataStreamingServer) RegisterRoutes(router gin.IRouter) {
+	router.GET(database.StreamingPath, server.entryStream)
+
+	if server.Importer != nil {
+		router.PUT(database.ImportUploadPath, server.importUpload)
 	}
+}
 
-	if c.Request.URL.Scheme == "" && c.Request.Host != "localhost" {
-		c.Request.URL.Scheme = "https"
+// Handles a stream that bundles all objectgroups referenced by a streaming entry into a single byte stream
+func (server *DataStreamingServer) entryStream(c *gin.Context) {
+	entryID, err := uuid.Parse(c.Query("id"))
+	if err != nil {
+		log.Debug(err.Error())
+		c.AbortWithError(400, fmt.Errorf("could not parse id value"))
+		return
 	}
 
-	verified, err := signing.VerifyHMAC_sha256(server.SigningSecret, c.Request.URL)
+	entry, err := server.StreamingHandler.GetStreamingEntry(entryID)
+	if err != nil {
+		log.Println(err.Error())
+		c.AbortWithStatus(403)
+		return
+	}
+
+	verified, err := signing.VerifyIDSignature([]byte(entry.Secret), entry.ID.String(), c.Query("sign"))
 	if err != nil {
 		log.Println(err.Error())
 		c.AbortWithStatus(503)
@@ -54,45 +82,253 @@ func (server *DataStreamingServer) datasetStream(c *gin.Context) {
 		return
 	}
 
-	datasetIDString := c.Query("id")
-	datasetID := uuid.MustParse(datasetIDString)
+	if !entry.IsActive() {
+		c.AbortWithStatus(410)
+		return
+	}
+
+	format, err := ArchiveFormatFromStreamType(entry.StreamType)
+	if queryFormat, ok := c.GetQuery(FormatQueryParam); ok {
+		format, err = ParseArchiveFormat(queryFormat)
+	}
 	if err != nil {
-		log.Println(err.Error())
-		c.AbortWithError(400, fmt.Errorf("could not parse id value"))
+		log.Debug(err.Error())
+		c.AbortWithError(400, fmt.Errorf("could not handle requested archive format"))
 		return
 	}
 
-	c.Status(200)
-	c.Header("Content-Disposition", `attachment; filename="test.tar.gz"`)
+	archiveName := entry.Dataset.Name
+	var bagInfo []BagInfoField
+	if format.IsBagIt() {
+		var version *models.DatasetVersion
+		if entry.ResourceType == models.StreamingEntryTypeDatasetVersion {
+			version, err = server.ReadHandler.GetDatasetVersion(entry.DatasetVersionID)
+			if err != nil {
+				log.Println(err.Error())
+				c.AbortWithStatus(503)
+				return
+			}
+			archiveName = fmt.Sprintf("%v_%v.%v.%v", archiveName, version.MajorVersion, version.MinorVersion, version.PatchVersion)
+		}
+		bagInfo = NewBagInfo(&entry.Dataset, version)
+	}
 
-	objectGroups, err := server.ReadHandler.GetDatasetObjectGroups(datasetID, nil)
+	consumed, err := server.StreamingHandler.ConsumeStreamingEntry(entry.ID)
 	if err != nil {
 		log.Println(err.Error())
 		c.AbortWithStatus(503)
 		return
 	}
 
+	if !consumed {
+		c.AbortWithStatus(410)
+		return
+	}
+
+	c.Status(200)
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": format.Filename(archiveName)}))
+
 	packer := ObjectsPacker{
-		StreamType:    v1storageservices.GetObjectGroupsStreamLinkRequest_STREAM_TYPE_TARGZ,
+		Format:        format,
 		TargetWrite:   c.Writer,
 		ObjectHandler: server.ObjectHandler,
+		Dataset:       &entry.Dataset,
+		PrefetchCount: server.PrefetchCount,
+		ReadTimeout:   server.ReadTimeout,
+		BagName:       SafeFilename(archiveName),
+		BagInfo:       bagInfo,
+	}
+
+	if entry.ResourceType == models.StreamingEntryTypeDatasetVersion {
+		packer.DatasetVersionID = entry.DatasetVersionID.String()
 	}
 
-	objectGroupsChan := make(chan *models.ObjectGroup, 10)
+	// The request context is cancelled when the client disconnects
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	objectGroupsChan := make(chan *models.ObjectGroupRevision, 10)
 	objectGroupsErrGrp := errgroup.Group{}
 	objectGroupsErrGrp.Go(func() error {
-		defer close(objectGroupsChan)
-		for _, objectGroup := range objectGroups {
-			objectGroupsChan <- objectGroup
+		err := server.loadEntryObjectGroups(ctx, entry, objectGroupsChan)
+		if err != nil {
+			// Cancel before closing the channel, otherwise the packer would finish an incomplete archive
+			cancel()
 		}
-
-		return nil
+		close(objectGroupsChan)
+		return err
 	})
 
-	err = packer.PackageObjects(objectGroupsChan)
+	err = packer.PackageObjects(ctx, objectGroupsChan)
+	if err != nil {
+		log.Println(err.Error())
+		cancel()
+		objectGroupsErrGrp.Wait()
+		abortStream(c)
+		return
+	}
+
+	err = objectGroupsErrGrp.Wait()
+	if err != nil {
+		log.Println(err.Error())
+		abortStream(c)
+		return
+	}
+}
+
+// Accepts the archive of an import job and starts the import once the upload has finished
+func (server *DataStreamingServer) importUpload(c *gin.Context) {
+	jobID, err := uuid.Parse(c.Query("id"))
+	if err != nil {
+		log.Debug(err.Error())
+		c.AbortWithError(400, fmt.Errorf("could not parse id value"))
+		return
+	}
+
+	job, err := server.Importer.ImportHandler.GetImportJob(jobID)
+	if err != nil {
+		log.Println(err.Error())
+		c.AbortWithStatus(403)
+		return
+	}
+
+	verified, err := signing.VerifyIDSignature([]byte(job.Secret), job.ID.String(), c.Query("sign"))
+	if err != nil {
+		log.Println(err.Error())
+		c.AbortWithStatus(503)
+		return
+	}
+
+	if !verified {
+		c.AbortWithStatus(403)
+		return
+	}
+
+	uploading, err := server.Importer.ImportHandler.TransitionImportJob(job.ID, []string{models.ImportJobStatusWaitingForUpload}, models.ImportJobStatusUploading, "")
 	if err != nil {
 		log.Println(err.Error())
 		c.AbortWithStatus(503)
 		return
 	}
+
+	if !uploading {
+		c.AbortWithStatus(409)
+		return
+	}
+
+	stopKeepAlive := server.Importer.keepAlive(job.ID)
+	err = server.ObjectHandler.UploadObject(c.Request.Context(), StagingLocation(job), c.Request.Body)
+	stopKeepAlive()
+	if err != nil {
+		log.Println(err.Error())
+		_, err = server.Importer.ImportHandler.TransitionImportJob(job.ID, []string{models.ImportJobStatusUploading}, models.ImportJobStatusWaitingForUpload, "upload of the archive failed")
+		if err != nil {
+			log.Println(err.Error())
+		}
+		c.AbortWithStatus(503)
+		return
+	}
+
+	started, err := server.Importer.StartImport(job, models.ImportJobStatusUploading)
+	if err != nil {
+		log.Println(err.Error())
+		server.Importer.FailImport(job, models.ImportJobStatusUploading, err.Error())
+		c.AbortWithStatus(503)
+		return
+	}
+
+	if !started {
+		c.AbortWithStatus(409)
+		return
+	}
+
+	c.JSON(202, gin.H{"id": job.ID.String(), "status": job.Status})
+}
+
+// Aborts a failed stream
+// If parts of the archive have already been sent the connection is closed without finishing the response,
+// so that clients notice the error instead of receiving a silently truncated archive.
+func abortStream(c *gin.Context) {
+	if !c.Writer.Written() {
+		c.AbortWithStatus(503)
+		return
+	}
+
+	c.Abort()
+	conn, _, err := c.Writer.Hijack()
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	err = conn.Close()
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// Sends all object group revisions referenced by the entry into the provided channel
+func (server *DataStreamingServer) loadEntryObjectGroups(ctx context.Context, entry *models.StreamingEntry, objectGroupsChan chan *models.ObjectGroupRevision) error {
+	sendObjectGroup := func(objectGroup *models.ObjectGroupRevision) error {
+		select {
+		case objectGroupsChan <- objectGroup:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	switch entry.ResourceType {
+	case models.StreamingEntryTypeObjectGroups:
+		for i := range entry.ObjectGroups {
+			err := sendObjectGroup(&entry.ObjectGroups[i])
+			if err != nil {
+				return err
+			}
+		}
+	case models.StreamingEntryTypeDatasetVersion:
+		version, _, err := server.ReadHandler.GetDatasetVersionWithObjectGroups(entry.DatasetVersionID, nil)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		for i := range version.ObjectGroupRevisions {
+			err := sendObjectGroup(&version.ObjectGroupRevisions[i])
+			if err != nil {
+				return err
+			}
+		}
+	case models.StreamingEntryTypeDataset, models.StreamingEntryTypeDateRange:
+		batchesChan := make(chan []*models.ObjectGroup, 1)
+		batchesErrGrp := errgroup.Group{}
+		batchesErrGrp.Go(func() error {
+			defer close(batchesChan)
+			if entry.ResourceType == models.StreamingEntryTypeDateRange {
+				return server.ReadHandler.GetObjectGroupsInDateRangeBatches(entry.DatasetID, entry.StartDate, entry.EndDate, batchesChan)
+			}
+			return server.ReadHandler.GetDatasetObjectGroupsBatches(entry.DatasetID, batchesChan)
+		})
+
+		var err error
+		for batch := range batchesChan {
+			for _, objectGroup := range batch {
+				if err == nil {
+					err = sendObjectGroup(&objectGroup.CurrentObjectGroupRevision)
+				}
+			}
+		}
+
+		batchesErr := batchesErrGrp.Wait()
+		if err != nil {
+			return err
+		}
+
+		return batchesErr
+	default:
+		return fmt.Errorf("unknown streaming entry type %v", entry.ResourceType)
+	}
+
+	return nil
 }
@@ -1,27 +1,52 @@
 package streamingserver
 
 import (
-	"archive/tar"
-	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
+	"path"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/ScienceObjectsDB/CORE-Server/models"
-	"github.com/ScienceObjectsDB/CORE-Server/objectstorage"
-	v1storageservices "github.com/ScienceObjectsDB/go-api/sciobjsdb/api/storage/services/v1"
 	"golang.org/x/sync/errgroup"
 )
 
+// Number of downloaded chunks that are buffered per object
+const chunkBufferSize = 2
+
 // ObjectsPacker packages a stream of stored objects into a single download link
 // This can be used to e.g. provide data to a cooperation partner
 // The provided link is secured using hmac
+//
+// Besides the data objects of each group the archive contains the metadata objects in a separate folder,
+// a manifest that describes the content and a SHA256SUMS file with the checksums of all objects.
+// The objects are written in order while the next PrefetchCount objects are already downloaded in parallel.
 type ObjectsPacker struct {
-	StreamType    v1storageservices.GetObjectGroupsStreamLinkRequest_StreamType
+	Format        ArchiveFormat
 	TargetWrite   FlushingWriter
-	ObjectHandler *objectstorage.S3ObjectStorageHandler
+	ObjectHandler ObjectDownloader
+	// Optional, the metadata objects and labels of the dataset are included if set
+	Dataset *models.Dataset
+	// Optional, the id of the packaged dataset version
+	DatasetVersionID string
+	// Number of objects that are downloaded in advance
+	PrefetchCount int
+	// Maximum duration of a single read from the object storage, 0 disables the timeout
+	ReadTimeout time.Duration
+	// Name of the top level directory of a BagIt bag, only used for the BagIt formats
+	BagName string
+	// Metadata of a BagIt bag, only used for the BagIt formats
+	BagInfo []BagInfoField
+}
+
+// ObjectDownloader Downloads stored objects in chunks, it is implemented by the S3ObjectStorageHandler
+type ObjectDownloader interface {
+	ChunkedObjectDowload(ctx context.Context, location *models.Location, offset int64, readTimeout time.Duration, data chan []byte) (int64, error)
 }
 
 // FlushingWriter Interface to represent a flushable writer
@@ -30,94 +55,374 @@ type FlushingWriter interface {
 	http.Flusher
 }
 
+type packEntryType int
+
+const (
+	packEntryObjectGroup packEntryType = iota
+	packEntryDatasetMetadata
+	packEntryData
+	packEntryObjectGroupMetadata
+)
+
+// A single entry of the archive in archive order, either the start of a new object group or an object
+type packEntry struct {
+	entryType   packEntryType
+	objectGroup *models.ObjectGroupRevision
+	object      *models.Object
+	path        string
+	download    *objectDownload
+}
+
+// A running download of a single object
+// err is only valid after chunks has been closed
+type objectDownload struct {
+	chunks chan []byte
+	err    error
+}
+
 // PackageObjects takes all objects from the provided channel and packages them into a single bytes stream
 // and writes the stream into the provided TargetWrite writer
-// Packaging details depend on the configuration of the ObjectsPacker interface
-func (packer *ObjectsPacker) PackageObjects(objectGroups chan *models.ObjectGroup) error {
-	switch packer.StreamType {
-	case v1storageservices.GetObjectGroupsStreamLinkRequest_STREAM_TYPE_TARGZ:
-		return packer.handleTarGZStream(objectGroups)
-	default:
-		{
-			return fmt.Errorf("could not handle requested data stream type")
+// Packaging details depend on the configured archive format of the ObjectsPacker
+// An error is returned if any object could not be packaged, the archive is incomplete in this case.
+// The packaging is aborted when the provided context is cancelled, e.g. because the client disconnected.
+func (packer *ObjectsPacker) PackageObjects(ctx context.Context, objectGroups chan *models.ObjectGroupRevision) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	archive, err := newArchiveWriter(packer.Format, packer.TargetWrite)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	entries := make(chan *packEntry, packer.PrefetchCount)
+	plannerErrGrp := errgroup.Group{}
+	plannerErrGrp.Go(func() error {
+		defer close(entries)
+		return packer.planEntries(ctx, objectGroups, entries)
+	})
+
+	err = packer.writeEntries(ctx, archive, entries)
+	if err != nil {
+		log.Println(err.Error())
+		cancel()
+		for entry := range entries {
+			if entry.download != nil {
+				for range entry.download.chunks {
+				}
+			}
 		}
+		plannerErrGrp.Wait()
+		return err
 	}
+
+	err = plannerErrGrp.Wait()
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
 }
 
-// Packer implementation to bundle objects into a tar archive and compress the resulting bytestream with gunzip
-// The data is written and read in chunks
-func (packer *ObjectsPacker) handleTarGZStream(objectGroups chan *models.ObjectGroup) error {
-	gunzipWriter := gzip.NewWriter(packer.TargetWrite)
-	tarWriter := tar.NewWriter(gunzipWriter)
+// Creates the entries of the archive in order and starts the downloads of the objects
+// The number of started downloads that have not yet been written is limited by the capacity of the entries channel.
+func (packer *ObjectsPacker) planEntries(ctx context.Context, objectGroups chan *models.ObjectGroupRevision, entries chan *packEntry) error {
+	sendEntry := func(entry *packEntry) error {
+		if entry.object != nil {
+			entry.download = packer.startDownload(ctx, entry.object)
+		}
 
-	for objectGroup := range objectGroups {
-		groupName := objectGroup.CurrentObjectGroupRevision.Name
-		err := tarWriter.WriteHeader(&tar.Header{
-			Name:    fmt.Sprintf("%v/", groupName),
-			ModTime: objectGroup.UpdatedAt,
+		select {
+		case entries <- entry:
+			return nil
+		case <-ctx.Done():
+			if entry.download != nil {
+				for range entry.download.chunks {
+				}
+			}
+			return ctx.Err()
+		}
+	}
+
+	if packer.Dataset != nil {
+		for i := range packer.Dataset.MetaObjects {
+			object := &packer.Dataset.MetaObjects[i]
+			err := sendEntry(&packEntry{
+				entryType: packEntryDatasetMetadata,
+				object:    object,
+				path:      objectPath(MetadataFolder, object.Filename),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		var objectGroup *models.ObjectGroupRevision
+		var ok bool
+
+		select {
+		case objectGroup, ok = <-objectGroups:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if !ok {
+			break
+		}
+
+		err := sendEntry(&packEntry{
+			entryType:   packEntryObjectGroup,
+			objectGroup: objectGroup,
+			path:        objectGroupFolder(objectGroup.Name),
 		})
 		if err != nil {
-			log.Println(err.Error())
 			return err
 		}
-		for _, object := range objectGroup.CurrentObjectGroupRevision.DataObjects {
-			err = tarWriter.WriteHeader(&tar.Header{
-				Name:    fmt.Sprintf("%v/%v", objectGroup.CurrentObjectGroupRevision.Name, object.Filename),
-				ModTime: object.UpdatedAt,
-				Mode:    0700,
-				Size:    object.ContentLen,
+
+		for i := range objectGroup.DataObjects {
+			object := &objectGroup.DataObjects[i]
+			err := sendEntry(&packEntry{
+				entryType: packEntryData,
+				object:    object,
+				path:      objectPath(objectGroupFolder(objectGroup.Name), object.Filename),
 			})
 			if err != nil {
-				log.Println(err.Error())
 				return err
 			}
+		}
 
-			chunkChannel := make(chan []byte, 10)
-			chunkedLoaderWaitGrop := errgroup.Group{}
-			chunkedLoaderWaitGrop.Go(func() error {
-				err := packer.ObjectHandler.ChunkedObjectDowload(&object.Locations[0], chunkChannel)
-				if err != nil {
-					log.Println(err.Error())
-					return err
-				}
-
-				close(chunkChannel)
-				return nil
+		for i := range objectGroup.MetaObjects {
+			object := &objectGroup.MetaObjects[i]
+			err := sendEntry(&packEntry{
+				entryType: packEntryObjectGroupMetadata,
+				object:    object,
+				path:      objectPath(path.Join(MetadataFolder, objectGroupFolder(objectGroup.Name)), object.Filename),
 			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// The object groups channel is also closed if the source of the object groups failed
+	return ctx.Err()
+}
+
+// Writes the entries in order into the archive, followed by the manifest and the checksums
+func (packer *ObjectsPacker) writeEntries(ctx context.Context, archive archiveWriter, entries chan *packEntry) error {
+	manifest := newManifest()
+	manifest.DatasetVersionID = packer.DatasetVersionID
+	if packer.Dataset != nil {
+		manifest.Dataset = newManifestDataset(packer.Dataset)
+	}
+
+	var bagDeclaration []byte
+	if packer.Format.IsBagIt() {
+		var err error
+		bagDeclaration, err = packer.writeBagDeclaration(archive)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+	}
+
+	var manifestObjectGroup *ManifestObjectGroup
 
-			err := packer.writeObjectsData(chunkChannel, tarWriter)
+	for entry := range entries {
+		if entry.entryType == packEntryObjectGroup {
+			err := archive.WriteDirectory(packer.archivePath(entry.path), entry.objectGroup.UpdatedAt)
 			if err != nil {
 				log.Println(err.Error())
 				return err
 			}
+
+			manifestObjectGroup = newManifestObjectGroup(entry.objectGroup)
+			manifest.ObjectGroups = append(manifest.ObjectGroups, manifestObjectGroup)
+			continue
 		}
+
+		manifestObject, err := packer.writeObject(archive, entry)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		switch entry.entryType {
+		case packEntryDatasetMetadata:
+			manifest.Dataset.MetadataObjects = append(manifest.Dataset.MetadataObjects, manifestObject)
+		case packEntryData:
+			manifestObjectGroup.Objects = append(manifestObjectGroup.Objects, manifestObject)
+		case packEntryObjectGroupMetadata:
+			manifestObjectGroup.MetadataObjects = append(manifestObjectGroup.MetadataObjects, manifestObject)
+		}
+	}
+
+	// Do not finish the archive if the entries are incomplete
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	err := packer.writeTrailer(archive, manifest, bagDeclaration)
+	if err != nil {
+		log.Println(err.Error())
+		return err
 	}
 
-	err := tarWriter.Close()
+	err = archive.Close()
 	if err != nil {
 		log.Println(err.Error())
 		return err
 	}
 
-	err = gunzipWriter.Close()
+	packer.TargetWrite.Flush()
+
+	return nil
+}
+
+// Writes the files that describe the archive content after all objects have been written
+func (packer *ObjectsPacker) writeTrailer(archive archiveWriter, manifest *Manifest, bagDeclaration []byte) error {
+	if packer.Format.IsBagIt() {
+		return packer.writeBagTagFiles(archive, manifest, bagDeclaration)
+	}
+
+	encodedManifest, err := manifest.Encode()
+	if err != nil {
+		return err
+	}
+
+	err = writeArchiveFile(archive, ManifestFilename, encodedManifest)
+	if err != nil {
+		return err
+	}
+
+	return writeArchiveFile(archive, ChecksumsFilename, manifest.Checksums())
+}
+
+// Returns the path inside the archive for a path of the packaged content
+func (packer *ObjectsPacker) archivePath(contentPath string) string {
+	if packer.Format.IsBagIt() {
+		return packer.bagPayloadPath(contentPath)
+	}
+
+	return contentPath
+}
+
+// Writes the downloaded data of a single object into the archive and calculates its checksum on the fly
+func (packer *ObjectsPacker) writeObject(archive archiveWriter, entry *packEntry) (*ManifestObject, error) {
+	fileWriter, err := archive.WriteFile(packer.archivePath(entry.path), entry.object.ContentLen, entry.object.UpdatedAt)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	hash := sha256.New()
+
+	writtenBytes, err := packer.writeObjectsData(entry.download.chunks, io.MultiWriter(fileWriter, hash))
 	if err != nil {
 		log.Println(err.Error())
+		return nil, err
+	}
+
+	if entry.download.err != nil {
+		log.Println(entry.download.err.Error())
+		return nil, entry.download.err
+	}
+
+	if writtenBytes != entry.object.ContentLen {
+		return nil, fmt.Errorf("size of object %v does not match: expected %v bytes, got %v bytes", entry.object.ID, entry.object.ContentLen, writtenBytes)
+	}
+
+	return newManifestObject(entry.object, entry.path, hex.EncodeToString(hash.Sum(nil))), nil
+}
+
+// Starts the download of an object in the background
+func (packer *ObjectsPacker) startDownload(ctx context.Context, object *models.Object) *objectDownload {
+	download := &objectDownload{
+		chunks: make(chan []byte, chunkBufferSize),
+	}
+
+	go func() {
+		defer close(download.chunks)
+		download.err = packer.downloadObject(ctx, object, download.chunks)
+	}()
+
+	return download
+}
+
+// Downloads an object from its locations, if a location fails the download is resumed from the next location
+func (packer *ObjectsPacker) downloadObject(ctx context.Context, object *models.Object, chunks chan []byte) error {
+	locations := objectLocations(object)
+	if len(locations) == 0 {
+		return fmt.Errorf("object %v has no location", object.ID)
+	}
+
+	var offset int64
+	var err error
+	for _, location := range locations {
+		var sentBytes int64
+		sentBytes, err = packer.ObjectHandler.ChunkedObjectDowload(ctx, location, offset, packer.ReadTimeout, chunks)
+		offset = offset + sentBytes
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		log.Warnf("could not download object %v from location %v, trying next location: %v", object.ID, location.ID, err.Error())
+	}
+
+	return fmt.Errorf("could not download object %v from any location: %w", object.ID, err)
+}
+
+// Returns the locations of an object, starting with the default location
+func objectLocations(object *models.Object) []*models.Location {
+	var locations []*models.Location
+	if object.DefaultLocation.Key != "" {
+		locations = append(locations, &object.DefaultLocation)
+	}
+
+	for i := range object.Locations {
+		if object.Locations[i].ID == object.DefaultLocation.ID && len(locations) > 0 {
+			continue
+		}
+		locations = append(locations, &object.Locations[i])
+	}
+
+	return locations
+}
+
+// Writes a file that is generated by the packer itself into the archive
+func writeArchiveFile(archive archiveWriter, name string, content []byte) error {
+	fileWriter, err := archive.WriteFile(name, int64(len(content)), time.Now())
+	if err != nil {
 		return err
 	}
 
+	_, err = fileWriter.Write(content)
+	if err != nil {
+		return fmt.Errorf("could not write %v: %w", name, err)
+	}
+
 	return nil
 }
 
-// Writes the provided data buffer into the provided writter
-func (packer *ObjectsPacker) writeObjectsData(data chan []byte, writer io.Writer) error {
+// Writes the provided data buffer into the provided writter and returns the number of written bytes
+func (packer *ObjectsPacker) writeObjectsData(data chan []byte, writer io.Writer) (int64, error) {
+	var writtenBytes int64
 	for chunk := range data {
-		_, err := writer.Write(chunk)
+		n, err := writer.Write(chunk)
+		writtenBytes = writtenBytes + int64(n)
 		if err != nil {
 			log.Println(err.Error())
-			return err
+			return writtenBytes, err
 		}
 		packer.TargetWrite.Flush()
 	}
 
-	return nil
+	return writtenBytes, nil
 }
@@ -0,0 +1,312 @@
+package streamingserver
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ScienceObjectsDB/CORE-Server/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type bufferWriter struct {
+	bytes.Buffer
+}
+
+func (writer *bufferWriter) Flush() {}
+
+// Serves the data of locations by their key, locations in failAfter return an error after the given number of bytes
+type fakeDownloader struct {
+	data      map[string][]byte
+	failAfter map[string]int
+	// Called before a download is started
+	onStart func(location *models.Location)
+
+	mutex   sync.Mutex
+	started []string
+	offsets map[string]int64
+}
+
+func (downloader *fakeDownloader) ChunkedObjectDowload(ctx context.Context, location *models.Location, offset int64, readTimeout time.Duration, data chan []byte) (int64, error) {
+	downloader.mutex.Lock()
+	downloader.started = append(downloader.started, location.Key)
+	if downloader.offsets == nil {
+		downloader.offsets = make(map[string]int64)
+	}
+	downloader.offsets[location.Key] = offset
+	downloader.mutex.Unlock()
+
+	if downloader.onStart != nil {
+		downloader.onStart(location)
+	}
+
+	content := downloader.data[location.Key][offset:]
+	failAfter, fails := downloader.failAfter[location.Key]
+	if fails && failAfter < len(content) {
+		content = content[:failAfter]
+	}
+
+	var sent int64
+	for _, b := range content {
+		select {
+		case data <- []byte{b}:
+			sent++
+		case <-ctx.Done():
+			return sent, ctx.Err()
+		}
+	}
+
+	if fails {
+		return sent, errors.New("connection reset")
+	}
+
+	return sent, nil
+}
+
+func (downloader *fakeDownloader) startedCount() int {
+	downloader.mutex.Lock()
+	defer downloader.mutex.Unlock()
+	return len(downloader.started)
+}
+
+func newPackedObject(filename string, size int, keys ...string) models.Object {
+	object := models.Object{Filename: filename, ContentLen: int64(size)}
+	object.ID = uuid.New()
+	for _, key := range keys {
+		location := models.Location{Key: key}
+		location.ID = uuid.New()
+		object.Locations = append(object.Locations, location)
+	}
+	if len(object.Locations) > 0 {
+		object.DefaultLocation = object.Locations[0]
+	}
+
+	return object
+}
+
+func packObjectGroups(ctx context.Context, packer *ObjectsPacker, revisions ...*models.ObjectGroupRevision) error {
+	objectGroups := make(chan *models.ObjectGroupRevision, len(revisions))
+	for _, revision := range revisions {
+		objectGroups <- revision
+	}
+	close(objectGroups)
+
+	return packer.PackageObjects(ctx, objectGroups)
+}
+
+// Returns the files of a tar archive, the archives of failed packagings end after the complete files
+func readTarFiles(data []byte) map[string]string {
+	files := make(map[string]string)
+	tarReader := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := tarReader.Next()
+		if err != nil {
+			break
+		}
+
+		content, err := io.ReadAll(tarReader)
+		if err != nil {
+			break
+		}
+		files[header.Name] = string(content)
+	}
+
+	return files
+}
+
+func TestPackageObjectsPrefetch(t *testing.T) {
+	downloader := &fakeDownloader{
+		data: map[string][]byte{"a": []byte("aaa"), "b": []byte("bbb"), "c": []byte("ccc")},
+	}
+
+	// The first download only starts after the next object is downloaded in advance
+	prefetched := make(chan struct{})
+	downloader.onStart = func(location *models.Location) {
+		switch location.Key {
+		case "a":
+			select {
+			case <-prefetched:
+			case <-time.After(5 * time.Second):
+			}
+		case "b":
+			close(prefetched)
+		}
+	}
+
+	revision := &models.ObjectGroupRevision{
+		Name: "group",
+		DataObjects: []models.Object{
+			newPackedObject("a.txt", 3, "a"),
+			newPackedObject("b.txt", 3, "b"),
+			newPackedObject("c.txt", 3, "c"),
+		},
+	}
+
+	target := &bufferWriter{}
+	packer := &ObjectsPacker{Format: ArchiveFormatTar, TargetWrite: target, ObjectHandler: downloader, PrefetchCount: 2}
+
+	err := packObjectGroups(context.Background(), packer, revision)
+	assert.NoError(t, err)
+
+	select {
+	case <-prefetched:
+	default:
+		t.Fatal("the second object was not downloaded in advance")
+	}
+
+	files := readTarFiles(target.Bytes())
+	assert.Equal(t, "aaa", files["group/a.txt"])
+	assert.Equal(t, "bbb", files["group/b.txt"])
+	assert.Equal(t, "ccc", files["group/c.txt"])
+	assert.Contains(t, files, ManifestFilename)
+	assert.Contains(t, files, ChecksumsFilename)
+}
+
+func TestPackageObjectsLocationFallback(t *testing.T) {
+	downloader := &fakeDownloader{
+		data:      map[string][]byte{"primary": []byte("0123456789"), "replica": []byte("0123456789")},
+		failAfter: map[string]int{"primary": 4},
+	}
+
+	revision := &models.ObjectGroupRevision{
+		Name:        "group",
+		DataObjects: []models.Object{newPackedObject("data.bin", 10, "primary", "replica")},
+	}
+
+	target := &bufferWriter{}
+	packer := &ObjectsPacker{Format: ArchiveFormatTar, TargetWrite: target, ObjectHandler: downloader, PrefetchCount: 1}
+
+	err := packObjectGroups(context.Background(), packer, revision)
+	assert.NoError(t, err)
+
+	// The download is resumed at the offset the failed location has reached
+	assert.Equal(t, []string{"primary", "replica"}, downloader.started)
+	assert.Equal(t, int64(4), downloader.offsets["replica"])
+
+	files := readTarFiles(target.Bytes())
+	assert.Equal(t, "0123456789", files["group/data.bin"])
+	assert.Contains(t, files[ChecksumsFilename], "group/data.bin")
+
+	// All locations fail
+	downloader = &fakeDownloader{
+		data:      map[string][]byte{"primary": []byte("0123456789"), "replica": []byte("0123456789")},
+		failAfter: map[string]int{"primary": 4, "replica": 2},
+	}
+	target = &bufferWriter{}
+	packer = &ObjectsPacker{Format: ArchiveFormatTar, TargetWrite: target, ObjectHandler: downloader, PrefetchCount: 1}
+
+	err = packObjectGroups(context.Background(), packer, revision)
+	assert.Error(t, err)
+	assert.NotContains(t, readTarFiles(target.Bytes()), ManifestFilename)
+}
+
+func TestPackageObjectsCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	downloader := &fakeDownloader{
+		data: map[string][]byte{"a": []byte("aaa"), "b": []byte("bbb")},
+	}
+	downloader.onStart = func(location *models.Location) {
+		if location.Key == "b" {
+			// The client disconnects while the second object is downloaded
+			cancel()
+		}
+	}
+
+	revision := &models.ObjectGroupRevision{
+		Name: "group",
+		DataObjects: []models.Object{
+			newPackedObject("a.txt", 3, "a"),
+			newPackedObject("b.txt", 3, "b"),
+		},
+	}
+
+	target := &bufferWriter{}
+	packer := &ObjectsPacker{Format: ArchiveFormatTar, TargetWrite: target, ObjectHandler: downloader, PrefetchCount: 1}
+
+	done := make(chan error)
+	go func() {
+		done <- packObjectGroups(ctx, packer, revision)
+	}()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("packaging was not aborted")
+	}
+
+	// An aborted archive is not finished with a manifest
+	assert.NotContains(t, target.String(), ManifestFilename)
+}
+
+func TestPackageObjectsSizeMismatch(t *testing.T) {
+	downloader := &fakeDownloader{
+		data: map[string][]byte{"short": []byte("12345"), "next": []byte("next")},
+	}
+
+	revision := &models.ObjectGroupRevision{
+		Name: "group",
+		DataObjects: []models.Object{
+			newPackedObject("short.bin", 10, "short"),
+			newPackedObject("next.bin", 4, "next"),
+		},
+	}
+
+	target := &bufferWriter{}
+	packer := &ObjectsPacker{Format: ArchiveFormatTar, TargetWrite: target, ObjectHandler: downloader, PrefetchCount: 1}
+
+	err := packObjectGroups(context.Background(), packer, revision)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match")
+	assert.NotContains(t, target.String(), ManifestFilename)
+	assert.NotContains(t, target.String(), "next.bin")
+
+	// Objects without location abort the archive as well
+	revision.DataObjects = []models.Object{newPackedObject("missing.bin", 1)}
+	target = &bufferWriter{}
+	packer = &ObjectsPacker{Format: ArchiveFormatTar, TargetWrite: target, ObjectHandler: downloader, PrefetchCount: 1}
+
+	err = packObjectGroups(context.Background(), packer, revision)
+	assert.Error(t, err)
+	assert.NotContains(t, target.String(), ManifestFilename)
+}
+
+func TestPackageObjectsUnsafePaths(t *testing.T) {
+	downloader := &fakeDownloader{
+		data: map[string][]byte{"a": []byte("aaa"), "b": []byte("bbb"), "c": []byte("ccc")},
+	}
+
+	revision := &models.ObjectGroupRevision{
+		Name: "..",
+		DataObjects: []models.Object{
+			newPackedObject("../../a.txt", 3, "a"),
+			newPackedObject("/b.txt", 3, "b"),
+		},
+		MetaObjects: []models.Object{
+			newPackedObject("..\\c.txt", 3, "c"),
+		},
+	}
+
+	target := &bufferWriter{}
+	packer := &ObjectsPacker{Format: ArchiveFormatTar, TargetWrite: target, ObjectHandler: downloader, PrefetchCount: 2}
+
+	err := packObjectGroups(context.Background(), packer, revision)
+	assert.NoError(t, err)
+
+	files := readTarFiles(target.Bytes())
+	assert.Equal(t, "aaa", files["_../a.txt"])
+	assert.Equal(t, "bbb", files["_../b.txt"])
+	assert.Equal(t, "ccc", files["metadata/_../c.txt"])
+	for name := range files {
+		assert.False(t, strings.HasPrefix(name, "/"), name)
+		assert.NotContains(t, strings.Split(name, "/"), "..", name)
+	}
+}